@@ -18,6 +18,7 @@ import (
 const (
 	targetsChannels = "channels"
 	targetsReleases = "releases"
+	targetsRevoked  = "revoked"
 
 	channelsDir = targetsChannels
 	releasesDir = targetsReleases
@@ -96,6 +97,10 @@ func (c Client) releaseTargetNamePrefix(release string) string {
 	return path.Join(targetsReleases, release)
 }
 
+func (c Client) revokedReleaseTargetName(release string) string {
+	return path.Join(targetsRevoked, release)
+}
+
 func (c Client) channelPath(group, channel string) string {
 	return filepath.Join(c.dir, channelsDir, group, channel)
 }
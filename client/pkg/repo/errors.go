@@ -40,6 +40,26 @@ func (e ChannelReleaseNotFoundLocallyErr) Error() string {
 	return fmt.Sprintf("channel release %q not found locally (group: %q, channel: %q)", e.Release, e.Group, e.Channel)
 }
 
+type ChannelReleaseRevokedErr struct {
+	RepoName string
+	Release  string
+	Group    string
+	Channel  string
+}
+
+func NewChannelReleaseRevokedErr(repoName, group, channel, release string) error {
+	return ChannelReleaseRevokedErr{
+		RepoName: repoName,
+		Release:  release,
+		Group:    group,
+		Channel:  channel,
+	}
+}
+
+func (e ChannelReleaseRevokedErr) Error() string {
+	return fmt.Sprintf("release %q was revoked (group: %q, channel: %q)", e.Release, e.Group, e.Channel)
+}
+
 type ChannelReleaseBinSeveralFilesFoundErr struct {
 	RepoName string
 	Release  string
@@ -10,5 +10,5 @@ type TufInterface interface {
 	Setup(rootVersion int64, rootSha512 string) error
 	Update() error
 	DownloadFile(targetName string, dest string, destMode os.FileMode) error
-	GetTargets() (data.TargetFiles, error)
+	GetTargets(prefix string) (data.TargetFiles, error)
 }
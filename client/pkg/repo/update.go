@@ -34,12 +34,12 @@ func (c Client) UpdateChannel(group, channel string) error {
 		channelPath := c.channelPath(group, channel)
 		channelTmpPath := c.channelTmpPath(group, channel)
 		{ // create tmp channel if channel is not up-to-date
-			targets, err := c.tufClient.GetTargets()
+			targetName := c.channelTargetName(group, channel)
+			targets, err := c.tufClient.GetTargets(targetName)
 			if err != nil {
 				return err
 			}
 
-			targetName := c.channelTargetName(group, channel)
 			targetMeta, ok := targets[targetName]
 			if !ok {
 				return fmt.Errorf("channel %[2]q not found in the repository (group: %[1]q)", group, channel)
@@ -72,6 +72,18 @@ func (c Client) UpdateChannel(group, channel string) error {
 			if deferErr != nil {
 				return fmt.Errorf("unable to get channel release: %s", deferErr)
 			}
+
+			// The release targets are removed from the repository on revocation, only the revocation mark is left
+			revokedTargetName := c.revokedReleaseTargetName(release)
+			revokedTargets, err := c.tufClient.GetTargets(revokedTargetName)
+			if err != nil {
+				return err
+			}
+
+			if _, revoked := revokedTargets[revokedTargetName]; revoked {
+				deferErr = NewChannelReleaseRevokedErr(c.repoName, group, channel, release)
+				return deferErr
+			}
 		}
 
 		if deferErr = c.syncChannelReleaseWithLock(release); deferErr != nil {
@@ -186,7 +198,7 @@ func (c Client) selectAppropriateReleaseTargets(release string) (targets data.Ta
 		"any-any",
 	} {
 		prefix := path.Join(releaseTargetNamePrefix, osArch)
-		targets, err = c.filterTargets(prefix + "/")
+		targets, err = c.tufClient.GetTargets(prefix + "/")
 		if err != nil {
 			return nil, "", err
 		}
@@ -221,22 +233,6 @@ func (c Client) syncFile(targetName string, targetMeta data.TargetFileMeta, dest
 	return c.tufClient.DownloadFile(targetName, dest, destMode)
 }
 
-func (c Client) filterTargets(prefix string) (data.TargetFiles, error) {
-	targets, err := c.tufClient.GetTargets()
-	if err != nil {
-		return nil, err
-	}
-
-	result := data.TargetFiles{}
-	for name, meta := range targets {
-		if strings.HasPrefix(name, prefix) {
-			result[name] = meta
-		}
-	}
-
-	return result, nil
-}
-
 func isLocalFileUpToDate(path string, targetMeta data.TargetFileMeta) (bool, error) {
 	exist, err := util.IsRegularFileExist(path)
 	if err != nil {
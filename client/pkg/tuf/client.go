@@ -1,6 +1,7 @@
 package tuf
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"time"
@@ -9,6 +10,7 @@ import (
 	tufClient "github.com/theupdateframework/go-tuf/client"
 	leveldbstore "github.com/theupdateframework/go-tuf/client/leveldbstore"
 	"github.com/theupdateframework/go-tuf/data"
+	"github.com/theupdateframework/go-tuf/verify"
 	"github.com/werf/lockgate"
 	"github.com/werf/lockgate/pkg/file_locker"
 
@@ -24,6 +26,10 @@ type Client struct {
 	repoUrl           string
 	metaLocalStoreDir string
 	locker            lockgate.Locker
+
+	remote tufClient.RemoteStore
+	// delegatedTargets are the verified delegated roles targets by the role metadata target name.
+	delegatedTargets map[string]data.TargetFiles
 }
 
 func NewClient(repoUrl, metaLocalStoreDir, locksPath string) (*Client, error) {
@@ -85,13 +91,19 @@ func (c *Client) initTufClient() error {
 		}
 	}
 
+	return c.initTufClientWithLocalStore(localMemory)
+}
+
+func (c *Client) initTufClientWithLocalStore(localStore tufClient.LocalStore) error {
 	remote, err := tufClient.HTTPRemoteStore(c.repoUrl, nil, nil)
 	if err != nil {
 		return fmt.Errorf("unable to init http remote store: %s", err)
 	}
 
-	c.Client = tufClient.NewClient(localMemory, remote)
-	c.ReadOnlyLocalStore = localMemory
+	c.Client = tufClient.NewClient(localStore, remote)
+	c.ReadOnlyLocalStore = localStore
+	c.remote = remote
+	c.delegatedTargets = make(map[string]data.TargetFiles)
 
 	return nil
 }
@@ -141,7 +153,12 @@ func (c *Client) setup(rootVersion int64, rootSha512 string) error {
 		return fmt.Errorf("unable to reinit tuf client: %s", err)
 	}
 
-	if err := c.Client.Init(rootKeys, len(rootKeys)); err != nil {
+	rootThreshold, err := parseRootThreshold(jsonData)
+	if err != nil {
+		return fmt.Errorf("unable to parse root threshold: %s", err)
+	}
+
+	if err := c.Client.Init(rootKeys, rootThreshold); err != nil {
 		return err
 	}
 
@@ -152,10 +169,40 @@ func (c *Client) setup(rootVersion int64, rootSha512 string) error {
 	return nil
 }
 
+// parseRootThreshold returns the number of the root keys required to sign root.json.
+// The root.json is trusted by the checksum, so the threshold is taken from it as well.
+func parseRootThreshold(jsonData []byte) (int, error) {
+	signed := &data.Signed{}
+	if err := json.Unmarshal(jsonData, signed); err != nil {
+		return 0, err
+	}
+
+	root := &data.Root{}
+	if err := json.Unmarshal(signed.Signed, root); err != nil {
+		return 0, err
+	}
+
+	role, ok := root.Roles["root"]
+	if !ok {
+		return 0, fmt.Errorf("root role not found")
+	}
+
+	if role.Threshold < 1 {
+		return 0, fmt.Errorf("invalid root role threshold %d", role.Threshold)
+	}
+
+	return role.Threshold, nil
+}
+
 func (c *Client) Update() error {
+	if err := c.updateRoot(); err != nil {
+		return fmt.Errorf("unable to update tuf root: %s", err)
+	}
+
 	if _, err := c.Client.Update(); err != nil && !tufClient.IsLatestSnapshot(err) {
 		return fmt.Errorf("unable to update tuf meta: %s", err)
 	}
+	c.delegatedTargets = make(map[string]data.TargetFiles)
 
 	return lockgate.WithAcquire(
 		c.locker, metaLocalStoreDirLockName,
@@ -170,6 +217,141 @@ func (c *Client) Update() error {
 	)
 }
 
+// updateRoot walks the published root.json versions one by one starting from the trusted one.
+// Every next version must be signed by the threshold of both the trusted and its own root keys,
+// so the client follows any number of root keys rotations made since the last update.
+func (c *Client) updateRoot() error {
+	localMeta, err := c.ReadOnlyLocalStore.GetMeta()
+	if err != nil {
+		return fmt.Errorf("unable to get local meta: %s", err)
+	}
+
+	trustedRootJSON, ok := localMeta["root.json"]
+	if !ok {
+		return nil
+	}
+
+	trustedRoot, err := parseRoot(trustedRootJSON)
+	if err != nil {
+		return fmt.Errorf("unable to parse local root.json: %s", err)
+	}
+
+	latestRootJSON, err := c.DownloadMetaUnsafe("root.json", tufClient.DefaultRootDownloadLimit)
+	if err != nil {
+		return fmt.Errorf("unable to download root.json: %s", err)
+	}
+
+	latestRoot, err := parseRoot(latestRootJSON)
+	if err != nil {
+		return fmt.Errorf("unable to parse root.json: %s", err)
+	}
+
+	if latestRoot.Version <= trustedRoot.Version {
+		return nil
+	}
+
+	for version := trustedRoot.Version + 1; version <= latestRoot.Version; version++ {
+		rootBasename := fmt.Sprintf("%d.root.json", version)
+
+		rootJSON, err := c.DownloadMetaUnsafe(rootBasename, tufClient.DefaultRootDownloadLimit)
+		if err != nil {
+			return fmt.Errorf("unable to download %q: %s", rootBasename, err)
+		}
+
+		root, err := verifyNextRoot(trustedRoot, rootJSON)
+		if err != nil {
+			return fmt.Errorf("unable to verify %q: %s", rootBasename, err)
+		}
+
+		trustedRoot, trustedRootJSON = root, rootJSON
+	}
+
+	// The local metadata of other roles might be signed by the revoked keys,
+	// so it is downloaded again and verified with the new root.
+	localMemory := tufClient.MemoryLocalStore()
+	if err := localMemory.SetMeta("root.json", trustedRootJSON); err != nil {
+		return fmt.Errorf("unable to set meta: %s", err)
+	}
+
+	return c.initTufClientWithLocalStore(localMemory)
+}
+
+func verifyNextRoot(trustedRoot *data.Root, rootJSON []byte) (*data.Root, error) {
+	signed, root, err := parseSignedRoot(rootJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	if root.Type != "root" {
+		return nil, fmt.Errorf("unexpected type %q", root.Type)
+	}
+
+	if root.Version != trustedRoot.Version+1 {
+		return nil, fmt.Errorf("unexpected version %d, expected %d", root.Version, trustedRoot.Version+1)
+	}
+
+	for _, desc := range []struct {
+		name string
+		root *data.Root
+	}{
+		{"trusted", trustedRoot},
+		{"own", root},
+	} {
+		db, err := newRootDB(desc.root)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := db.VerifySignatures(signed, "root"); err != nil {
+			return nil, fmt.Errorf("%s root keys: %s", desc.name, err)
+		}
+	}
+
+	return root, nil
+}
+
+func newRootDB(root *data.Root) (*verify.DB, error) {
+	db := verify.NewDB()
+	for id, key := range root.Keys {
+		if err := db.AddKey(id, key); err != nil {
+			// The key ids not derived from the key are ignored as in the tuf client.
+			if _, ok := err.(verify.ErrWrongID); !ok {
+				return nil, err
+			}
+		}
+	}
+
+	role, ok := root.Roles["root"]
+	if !ok {
+		return nil, fmt.Errorf("root role not found")
+	}
+
+	if err := db.AddRole("root", role); err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
+func parseRoot(rootJSON []byte) (*data.Root, error) {
+	_, root, err := parseSignedRoot(rootJSON)
+	return root, err
+}
+
+func parseSignedRoot(rootJSON []byte) (*data.Signed, *data.Root, error) {
+	signed := &data.Signed{}
+	if err := json.Unmarshal(rootJSON, signed); err != nil {
+		return nil, nil, err
+	}
+
+	root := &data.Root{}
+	if err := json.Unmarshal(signed.Signed, root); err != nil {
+		return nil, nil, err
+	}
+
+	return signed, root, nil
+}
+
 func (c *Client) saveMeta() error {
 	localDB, err := leveldbstore.FileLocalStore(c.metaLocalStoreDir)
 	if err != nil {
@@ -192,7 +374,3 @@ func (c *Client) saveMeta() error {
 
 	return nil
 }
-
-func (c Client) GetTargets() (data.TargetFiles, error) {
-	return c.Client.Targets()
-}
@@ -0,0 +1,394 @@
+package tuf
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/theupdateframework/go-tuf"
+	"github.com/theupdateframework/go-tuf/data"
+	"github.com/theupdateframework/go-tuf/sign"
+	tufUtil "github.com/theupdateframework/go-tuf/util"
+
+	"github.com/werf/trdl/client/pkg/util"
+)
+
+func TestClientUpdate_RootRotations(t *testing.T) {
+	repo := newTestRepo(t)
+
+	client, err := NewClient(repo.server.URL, filepath.Join(t.TempDir(), "meta"), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Setup(1, util.Sha512Checksum(repo.meta["1.root.json"])); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each new root is signed only by the previous and the new root keys,
+	// so the client trusting the first root has to walk through the intermediate one
+	repo.rotateRootKey(t)
+	repo.rotateRootKey(t)
+
+	if err := client.Update(); err != nil {
+		t.Fatalf("unable to update: %s", err)
+	}
+
+	localMeta, err := client.ReadOnlyLocalStore.GetMeta()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(repo.meta["3.root.json"], localMeta["root.json"]) {
+		t.Errorf("expected local root.json to be 3.root.json")
+	}
+
+	targets, err := client.GetTargets("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := targets["file"]; !ok {
+		t.Errorf("expected target %q, got %v", "file", targets)
+	}
+}
+
+func TestClientUpdate_RootNotSignedByTrustedKeys(t *testing.T) {
+	repo := newTestRepo(t)
+
+	client, err := NewClient(repo.server.URL, filepath.Join(t.TempDir(), "meta"), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Setup(1, util.Sha512Checksum(repo.meta["1.root.json"])); err != nil {
+		t.Fatal(err)
+	}
+
+	// The previous root key does not sign the new root
+	repo.rootKeys = nil
+	repo.rotateRootKey(t)
+
+	err = client.Update()
+	if err == nil || !strings.Contains(err.Error(), "2.root.json") {
+		t.Fatalf("expected 2.root.json verification error, got %v", err)
+	}
+}
+
+func TestClientUpdate_RootThreshold(t *testing.T) {
+	repo := newTestRepo(t)
+
+	// 2.root.json requires both root keys to sign the next root
+	if _, err := repo.GenKey("root"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.SetThreshold("root", 2); err != nil {
+		t.Fatal(err)
+	}
+
+	repo.commit(t)
+
+	client, err := NewClient(repo.server.URL, filepath.Join(t.TempDir(), "meta"), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Setup(2, util.Sha512Checksum(repo.meta["2.root.json"])); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 3.root.json is signed by only one of the trusted root keys
+	if _, err := repo.GenKey("root"); err != nil {
+		t.Fatal(err)
+	}
+
+	repo.commit(t)
+
+	signed := &data.Signed{}
+	if err := json.Unmarshal(repo.meta["3.root.json"], signed); err != nil {
+		t.Fatal(err)
+	}
+	signed.Signatures = signed.Signatures[:1]
+
+	if repo.meta["3.root.json"], err = json.Marshal(signed); err != nil {
+		t.Fatal(err)
+	}
+
+	err = client.Update()
+	if err == nil || !strings.Contains(err.Error(), "did not meet threshold") {
+		t.Fatalf("expected 3.root.json threshold error, got %v", err)
+	}
+}
+
+func TestClientGetTargets_Delegations(t *testing.T) {
+	repo := newTestRepo(t)
+	repo.addDelegation(t, "releases-1", []string{"releases/1."}, map[string][]byte{"releases/1.0.0/any-any/bin/app": []byte("app")}, nil)
+
+	client := newTestClient(t, repo)
+
+	// The metadata of the role not trusted for the prefix is not downloaded
+	delegationFile := repo.files["delegations/releases-1.json"]
+	delete(repo.files, "delegations/releases-1.json")
+	if _, err := client.GetTargets("channels/"); err != nil {
+		t.Fatal(err)
+	}
+	repo.files["delegations/releases-1.json"] = delegationFile
+
+	targets, err := client.GetTargets("releases/1.0.0/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := targets["releases/1.0.0/any-any/bin/app"]; !ok || len(targets) != 1 {
+		t.Fatalf("expected the delegated target, got %v", targets)
+	}
+
+	dest := &bufferDestination{}
+	if err := client.Download("releases/1.0.0/any-any/bin/app", dest); err != nil {
+		t.Fatal(err)
+	}
+
+	if dest.String() != "app" {
+		t.Errorf("expected %q, got %q", "app", dest.String())
+	}
+}
+
+func TestClientGetTargets_UntrustedDelegation(t *testing.T) {
+	for _, desc := range []struct {
+		name         string
+		pathPrefixes []string
+		signingKey   bool
+	}{
+		{"target outside of the role paths", []string{"releases/2."}, false},
+		{"metadata signed by the other key", []string{"releases/1."}, true},
+	} {
+		t.Run(desc.name, func(t *testing.T) {
+			repo := newTestRepo(t)
+
+			var signingKey *sign.PrivateKey
+			if desc.signingKey {
+				var err error
+				if signingKey, err = sign.GenerateEd25519Key(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			repo.addDelegation(t, "releases-1", desc.pathPrefixes, map[string][]byte{"releases/1.0.0/any-any/bin/app": []byte("app")}, signingKey)
+
+			client := newTestClient(t, repo)
+			if _, err := client.GetTargets("releases/"); err == nil {
+				t.Fatal("expected delegated role verification error")
+			}
+		})
+	}
+}
+
+type testRepo struct {
+	*tuf.Repo
+	server *httptest.Server
+
+	meta     map[string]json.RawMessage
+	files    map[string][]byte
+	rootKeys []*sign.PrivateKey
+}
+
+func newTestRepo(t *testing.T) *testRepo {
+	repo := &testRepo{meta: make(map[string]json.RawMessage), files: map[string][]byte{"file": []byte("data")}}
+
+	var err error
+	repo.Repo, err = tuf.NewRepo(&testLocalStore{
+		LocalStore: tuf.MemoryStore(repo.meta, repo.files),
+		repo:       repo,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.Init(false); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, role := range []string{"root", "targets", "snapshot", "timestamp"} {
+		if _, err := repo.GenKey(role); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := repo.AddTarget("file", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	repo.commit(t)
+
+	repo.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/")
+		if targetName := strings.TrimPrefix(name, "targets/"); targetName != name {
+			if file, ok := repo.files[targetName]; ok {
+				_, _ = w.Write(file)
+				return
+			}
+		}
+
+		meta, ok := repo.meta[name]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+
+		_, _ = w.Write(meta)
+	}))
+	t.Cleanup(repo.server.Close)
+
+	return repo
+}
+
+func newTestClient(t *testing.T, repo *testRepo) *Client {
+	client, err := NewClient(repo.server.URL, filepath.Join(t.TempDir(), "meta"), t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Setup(1, util.Sha512Checksum(repo.meta["1.root.json"])); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.Update(); err != nil {
+		t.Fatal(err)
+	}
+
+	return client
+}
+
+// addDelegation publishes the delegated role metadata listing the targets as the server does.
+// The metadata is signed by the signingKey instead of the role key, if it is passed.
+func (repo *testRepo) addDelegation(t *testing.T, role string, pathPrefixes []string, targets map[string][]byte, signingKey *sign.PrivateKey) {
+	key, err := sign.GenerateEd25519Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if signingKey == nil {
+		signingKey = key
+	}
+
+	meta := delegatedTargets{Role: role, Targets: data.TargetFiles{}}
+	for name, file := range targets {
+		repo.files[name] = file
+
+		targetMeta, err := tufUtil.GenerateTargetFileMeta(bytes.NewReader(file))
+		if err != nil {
+			t.Fatal(err)
+		}
+		meta.Targets[name] = targetMeta
+	}
+
+	signed, err := sign.Marshal(meta, signingKey.Signer())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	name := delegationsDir + role + ".json"
+	if repo.files[name], err = json.Marshal(signed); err != nil {
+		t.Fatal(err)
+	}
+
+	custom, err := json.Marshal(map[string]interface{}{"delegation": delegation{
+		Role:         role,
+		Keys:         map[string]*data.Key{key.PublicData().IDs()[0]: key.PublicData()},
+		Threshold:    1,
+		PathPrefixes: pathPrefixes,
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.AddTarget(name, custom); err != nil {
+		t.Fatal(err)
+	}
+
+	repo.commit(t)
+}
+
+// rotateRootKey replaces the root key and publishes the new root.json signed by both the old and the new keys.
+func (repo *testRepo) rotateRootKey(t *testing.T) {
+	root, err := parseRoot(repo.meta["root.json"])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newKey, err := sign.GenerateEd25519Key()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expires := time.Now().Add(time.Hour)
+	if err := repo.AddPrivateKeyWithExpires("root", newKey, expires); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range root.Roles["root"].KeyIDs {
+		if err := repo.RevokeKeyWithExpires("root", id, expires); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	repo.commit(t)
+	repo.rootKeys = []*sign.PrivateKey{newKey}
+}
+
+func (repo *testRepo) commit(t *testing.T) {
+	if err := repo.Snapshot(tuf.CompressionTypeNone); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.Timestamp(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testLocalStore signs root.json with the root keys of the test repo only,
+// so the revoked root keys do not sign the next root versions.
+type testLocalStore struct {
+	tuf.LocalStore
+	repo *testRepo
+}
+
+func (store *testLocalStore) GetSigningKeys(role string) ([]sign.Signer, error) {
+	if role != "root" {
+		return store.LocalStore.GetSigningKeys(role)
+	}
+
+	var signers []sign.Signer
+	for _, key := range store.repo.rootKeys {
+		signers = append(signers, key.Signer())
+	}
+
+	return signers, nil
+}
+
+func (store *testLocalStore) SavePrivateKey(role string, key *sign.PrivateKey) error {
+	if role != "root" {
+		return store.LocalStore.SavePrivateKey(role, key)
+	}
+
+	store.repo.rootKeys = append(store.repo.rootKeys, key)
+
+	return nil
+}
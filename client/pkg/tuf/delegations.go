@@ -0,0 +1,248 @@
+package tuf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	tufClient "github.com/theupdateframework/go-tuf/client"
+	"github.com/theupdateframework/go-tuf/data"
+	tufUtil "github.com/theupdateframework/go-tuf/util"
+	"github.com/theupdateframework/go-tuf/verify"
+)
+
+// delegationsDir is the targets directory the delegated roles metadata is published in.
+// The metadata target is pinned by targets.json, which custom data contains the delegated role keys
+// and the target paths the role is trusted for.
+const delegationsDir = "delegations/"
+
+type delegation struct {
+	Role         string               `json:"role"`
+	Keys         map[string]*data.Key `json:"keys"`
+	Threshold    int                  `json:"threshold"`
+	PathPrefixes []string             `json:"path_prefixes"`
+}
+
+type delegatedTargets struct {
+	Role    string           `json:"role"`
+	Targets data.TargetFiles `json:"targets"`
+}
+
+// GetTargets returns the targets with the name prefix.
+// Only the metadata of the delegated roles trusted for the prefix is downloaded.
+func (c Client) GetTargets(prefix string) (data.TargetFiles, error) {
+	topLevelTargets, err := c.Client.Targets()
+	if err != nil {
+		return nil, err
+	}
+
+	res := data.TargetFiles{}
+	for name, targetMeta := range topLevelTargets {
+		if strings.HasPrefix(name, delegationsDir) {
+			d, err := parseDelegation(name, targetMeta)
+			if err != nil {
+				return nil, err
+			}
+
+			if !d.isTrustedForPrefix(prefix) {
+				continue
+			}
+
+			targets, err := c.getDelegatedTargets(name, d)
+			if err != nil {
+				return nil, fmt.Errorf("unable to get delegated role %q targets: %s", d.Role, err)
+			}
+
+			for delegatedName, delegatedMeta := range targets {
+				if strings.HasPrefix(delegatedName, prefix) {
+					res[delegatedName] = delegatedMeta
+				}
+			}
+		}
+	}
+
+	for name, targetMeta := range topLevelTargets {
+		if strings.HasPrefix(name, prefix) {
+			res[name] = targetMeta
+		}
+	}
+
+	return res, nil
+}
+
+func parseDelegation(name string, targetMeta data.TargetFileMeta) (*delegation, error) {
+	if targetMeta.Custom == nil {
+		return nil, fmt.Errorf("target %q has no delegation", name)
+	}
+
+	var custom struct {
+		Delegation *delegation `json:"delegation"`
+	}
+	if err := json.Unmarshal(*targetMeta.Custom, &custom); err != nil {
+		return nil, fmt.Errorf("unable to parse target %q delegation: %s", name, err)
+	}
+
+	if custom.Delegation == nil {
+		return nil, fmt.Errorf("target %q has no delegation", name)
+	}
+
+	return custom.Delegation, nil
+}
+
+// isTrustedForPrefix checks whether the role might list the targets with the name prefix.
+func (d *delegation) isTrustedForPrefix(prefix string) bool {
+	for _, pathPrefix := range d.PathPrefixes {
+		if strings.HasPrefix(prefix, pathPrefix) || strings.HasPrefix(pathPrefix, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (d *delegation) isTrustedForTarget(name string) bool {
+	for _, pathPrefix := range d.PathPrefixes {
+		if strings.HasPrefix(name, pathPrefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// getDelegatedTargets downloads the delegated role metadata and verifies it with the role keys.
+func (c Client) getDelegatedTargets(name string, d *delegation) (data.TargetFiles, error) {
+	if targets, ok := c.delegatedTargets[name]; ok {
+		return targets, nil
+	}
+
+	dest := &bufferDestination{}
+	if err := c.Client.Download(name, dest); err != nil {
+		return nil, fmt.Errorf("unable to download %q: %s", name, err)
+	}
+
+	signed := &data.Signed{}
+	if err := json.Unmarshal(dest.Bytes(), signed); err != nil {
+		return nil, fmt.Errorf("unable to parse %q: %s", name, err)
+	}
+
+	db := verify.NewDB()
+	role := &data.Role{Threshold: d.Threshold}
+	for id, key := range d.Keys {
+		if err := db.AddKey(id, key); err != nil {
+			return nil, err
+		}
+		role.KeyIDs = append(role.KeyIDs, id)
+	}
+
+	// The verification database accepts the top-level roles only
+	if err := db.AddRole("targets", role); err != nil {
+		return nil, err
+	}
+
+	if err := db.VerifySignatures(signed, "targets"); err != nil {
+		return nil, fmt.Errorf("unable to verify %q: %s", name, err)
+	}
+
+	meta := &delegatedTargets{}
+	if err := json.Unmarshal(signed.Signed, meta); err != nil {
+		return nil, fmt.Errorf("unable to parse %q: %s", name, err)
+	}
+
+	if meta.Role != d.Role {
+		return nil, fmt.Errorf("unexpected role %q in %q", meta.Role, name)
+	}
+
+	for targetName := range meta.Targets {
+		if !d.isTrustedForTarget(targetName) {
+			return nil, fmt.Errorf("delegated role %q is not trusted for the target %q", d.Role, targetName)
+		}
+	}
+
+	c.delegatedTargets[name] = meta.Targets
+
+	return meta.Targets, nil
+}
+
+// downloadDelegatedTarget downloads the target listed in the delegated role metadata
+// and verifies it as the tuf client does with the targets listed in targets.json.
+func (c Client) downloadDelegatedTarget(name string, dest tufClient.Destination) (err error) {
+	defer func() {
+		if err != nil {
+			_ = dest.Delete()
+		}
+	}()
+
+	targets, err := c.GetTargets(name)
+	if err != nil {
+		return err
+	}
+
+	targetMeta, ok := targets[name]
+	if !ok {
+		return tufClient.ErrUnknownTarget{Name: name}
+	}
+
+	r, size, err := c.getRemoteTarget(name, targetMeta.Hashes)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	if size >= 0 && size != targetMeta.Length {
+		return tufClient.ErrWrongSize{File: name, Actual: size, Expected: targetMeta.Length}
+	}
+
+	stream := io.LimitReader(r, targetMeta.Length)
+	actual, err := tufUtil.GenerateTargetFileMeta(io.TeeReader(stream, dest), targetMeta.HashAlgorithms()...)
+	if err != nil {
+		return tufClient.ErrDownloadFailed{File: name, Err: err}
+	}
+
+	if err := tufUtil.TargetFileMetaEqual(actual, targetMeta); err != nil {
+		return tufClient.ErrDownloadFailed{File: name, Err: err}
+	}
+
+	return nil
+}
+
+// getRemoteTarget gets the target by one of the hash-prefixed paths, if the repository uses consistent snapshots.
+func (c Client) getRemoteTarget(name string, hashes data.Hashes) (io.ReadCloser, int64, error) {
+	localMeta, err := c.ReadOnlyLocalStore.GetMeta()
+	if err != nil {
+		return nil, 0, fmt.Errorf("unable to get local meta: %s", err)
+	}
+
+	root, err := parseRoot(localMeta["root.json"])
+	if err != nil {
+		return nil, 0, fmt.Errorf("unable to parse local root.json: %s", err)
+	}
+
+	if !root.ConsistentSnapshot {
+		return c.remote.GetTarget(name)
+	}
+
+	for _, hashedPath := range tufUtil.HashedPaths(name, hashes) {
+		r, size, err := c.remote.GetTarget(hashedPath)
+		if tufClient.IsNotFound(err) {
+			continue
+		} else if err != nil {
+			return nil, 0, err
+		}
+
+		return r, size, nil
+	}
+
+	return nil, 0, tufClient.ErrNotFound{File: name}
+}
+
+type bufferDestination struct {
+	bytes.Buffer
+}
+
+func (d *bufferDestination) Delete() error {
+	d.Reset()
+	return nil
+}
@@ -36,8 +36,17 @@ func (t *destinationFile) Delete() error {
 	return os.Remove(t.Name())
 }
 
+// Download downloads the target listed either in targets.json or in the delegated role metadata.
 func (c Client) Download(targetName string, destination tufClient.Destination) error {
-	return c.Client.Download(tufUtil.NormalizeTarget(targetName), destination)
+	targetName = tufUtil.NormalizeTarget(targetName)
+
+	if _, err := c.Client.Target(targetName); tufClient.IsNotFound(err) {
+		return c.downloadDelegatedTarget(targetName, destination)
+	} else if err != nil {
+		return err
+	}
+
+	return c.Client.Download(targetName, destination)
 }
 
 func (c Client) DownloadMetaUnsafe(targetName string, maxMetaSize int64) ([]byte, error) {
@@ -9,8 +9,11 @@ import (
 	"github.com/hashicorp/vault/sdk/logical"
 
 	"github.com/werf/trdl/server/pkg/git"
+	"github.com/werf/trdl/server/pkg/gitsign"
 	"github.com/werf/trdl/server/pkg/pgp"
 	"github.com/werf/trdl/server/pkg/publisher"
+	"github.com/werf/trdl/server/pkg/signer_group"
+	"github.com/werf/trdl/server/pkg/sshsig"
 	"github.com/werf/trdl/server/pkg/tasks_manager"
 )
 
@@ -74,10 +77,20 @@ func (b *Backend) InitPaths(modules ...BackendModuleInterface) {
 		[]*framework.Path{
 			configurePath(b),
 			releasePath(b),
+			releaseRevokePath(b),
 			publishPath(b),
+			bootstrapPath(b),
+			pgpSigningKeyRotatePath(b),
 		},
+		releasesPaths(b),
+		releasesGCPaths(b),
+		rootPaths(b),
+		configureTufKeysPaths(b),
 		git.CredentialsPaths(),
 		pgp.Paths(),
+		sshsig.Paths(),
+		gitsign.Paths(),
+		signer_group.Paths(),
 	)
 
 	for _, module := range modules {
@@ -48,6 +48,8 @@ func (m *MockedTasksManager) RunTask(_ context.Context, _ logical.Storage, _ fun
 type MockedPublisher struct {
 	mock.Mock
 	publisher.Interface
+
+	RepositoryOptions publisher.RepositoryOptions
 }
 
 func (m *MockedPublisher) Paths() []*framework.Path {
@@ -60,9 +62,78 @@ func (m *MockedPublisher) PeriodicFunc(_ context.Context, _ *logical.Request) er
 	return nil
 }
 
-func (m *MockedPublisher) GetRepository(_ context.Context, _ logical.Storage, _ publisher.RepositoryOptions) (publisher.RepositoryInterface, error) {
-	m.Called()
-	return nil, nil
+func (m *MockedPublisher) GetRepository(_ context.Context, _ logical.Storage, options publisher.RepositoryOptions) (publisher.RepositoryInterface, error) {
+	args := m.Called()
+	m.RepositoryOptions = options
+	repository, _ := args.Get(0).(publisher.RepositoryInterface)
+	return repository, nil
+}
+
+func (m *MockedPublisher) GetReleaseTargets(_ context.Context, _ publisher.RepositoryInterface, _ string) ([]*publisher.ReleaseTarget, error) {
+	args := m.Called()
+	targets, _ := args.Get(0).([]*publisher.ReleaseTarget)
+	return targets, nil
+}
+
+func (m *MockedPublisher) GetExistingReleases(_ context.Context, _ publisher.RepositoryInterface) ([]string, error) {
+	args := m.Called()
+	releases, _ := args.Get(0).([]string)
+	return releases, nil
+}
+
+func (m *MockedPublisher) IsRepositoryKeysRotationDue(_ context.Context, _ logical.Storage) (bool, error) {
+	args := m.Called()
+	return args.Bool(0), nil
+}
+
+func (m *MockedPublisher) IsPGPSigningPublicKeyPublished(_ context.Context, _ logical.Storage, _ publisher.RepositoryInterface) (bool, error) {
+	args := m.Called()
+	return args.Bool(0), nil
+}
+
+type MockedRepository struct {
+	mock.Mock
+	publisher.RepositoryInterface
+}
+
+func (m *MockedRepository) GetExpiredPrivKeysRoles() []string {
+	args := m.Called()
+	roles, _ := args.Get(0).([]string)
+	return roles
+}
+
+func (m *MockedRepository) GetExpiringMetadataRoles(_ context.Context) ([]string, error) {
+	args := m.Called()
+	roles, _ := args.Get(0).([]string)
+	return roles, nil
+}
+
+func (m *MockedRepository) GetStaleStagingFiles(_ context.Context) ([]string, error) {
+	args := m.Called()
+	files, _ := args.Get(0).([]string)
+	return files, nil
+}
+
+func (m *MockedRepository) IsConsistentSnapshot() (bool, error) {
+	args := m.Called()
+	return args.Bool(0), nil
+}
+
+func (m *MockedRepository) IsRootOffline() (bool, error) {
+	args := m.Called()
+	return args.Bool(0), nil
+}
+
+func (m *MockedRepository) GetDivergedStorageMirrors(_ context.Context) ([]string, error) {
+	args := m.Called()
+	mirrors, _ := args.Get(0).([]string)
+	return mirrors, nil
+}
+
+func (m *MockedPublisher) GetRevokedReleases(_ context.Context, _ publisher.RepositoryInterface) ([]string, error) {
+	args := m.Called()
+	releases, _ := args.Get(0).([]string)
+	return releases, nil
 }
 
 type MockedBackendPeriodic struct {
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/theupdateframework/go-tuf/data"
+	"github.com/theupdateframework/go-tuf/sign"
+
+	"github.com/werf/trdl/server/pkg/keyhelper"
+)
+
+const passphraseEnvName = "TRDL_SERVER_KEYS_PASSPHRASE"
+
+func NewCmdSignRoot() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sign-root UNSIGNED_ROOT_FILE",
+		Short: "Sign the pending root.json with the offline root keys",
+		Long: fmt.Sprintf(`Sign the pending root.json with the offline root keys.
+
+UNSIGNED_ROOT_FILE is the "signed" field of the plugin root/unsigned path ("-" to read from stdin).
+The signatures are printed in the format accepted by the plugin root/signatures path.
+The passphrase of the encrypted keys file is taken from the %s environment variable.`, passphraseEnvName),
+		Example: `  vault read -field=signed trdl/root/unsigned > root.unsigned.json
+  trdl-server sign-root --keys-file root-keys.json root.unsigned.json > root.signatures.json
+  vault write trdl/root/signatures - < root.signatures.json`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if signRootData.KeysFile == "" {
+				return fmt.Errorf("--keys-file required")
+			}
+
+			return signRoot(args[0])
+		},
+	}
+
+	cmd.Flags().StringVarP(&signRootData.KeysFile, "keys-file", "k", "", "Path to the keys file (the go-tuf keys file format)")
+
+	return cmd
+}
+
+var signRootData struct {
+	KeysFile string
+}
+
+func signRoot(unsignedRootFile string) error {
+	keys, err := loadKeys(signRootData.KeysFile)
+	if err != nil {
+		return err
+	}
+
+	var unsignedRoot []byte
+	if unsignedRootFile == "-" {
+		unsignedRoot, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		unsignedRoot, err = ioutil.ReadFile(unsignedRootFile)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to read unsigned root.json: %s", err)
+	}
+
+	// The root.json is signed in the canonical form, as the signatures are verified by the TUF clients
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(bytes.TrimSpace(unsignedRoot), &decoded); err != nil {
+		return fmt.Errorf("unable to unmarshal unsigned root.json: %s", err)
+	}
+
+	if decoded["_type"] != "root" {
+		return fmt.Errorf("unexpected unsigned root.json type %q", decoded["_type"])
+	}
+
+	var signers []sign.Signer
+	for _, key := range keys {
+		signers = append(signers, key.Signer())
+	}
+
+	signed, err := sign.Marshal(decoded, signers...)
+	if err != nil {
+		return fmt.Errorf("unable to sign root.json: %s", err)
+	}
+
+	return printJSON(os.Stdout, map[string]interface{}{
+		"signatures": signed.Signatures,
+	})
+}
+
+func NewCmdPublicKeys() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "public-keys",
+		Short: "Print the public keys of the offline root keys",
+		Long: fmt.Sprintf(`Print the public keys of the offline root keys.
+
+The public keys are printed in the format accepted by the plugin root/unsigned path.
+The passphrase of the encrypted keys file is taken from the %s environment variable.`, passphraseEnvName),
+		Example: `  trdl-server public-keys --keys-file root-keys.json > root.keys.json
+  vault write trdl/root/unsigned - < root.keys.json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if publicKeysData.KeysFile == "" {
+				return fmt.Errorf("--keys-file required")
+			}
+
+			keys, err := loadKeys(publicKeysData.KeysFile)
+			if err != nil {
+				return err
+			}
+
+			var publicKeys []*data.Key
+			for _, key := range keys {
+				publicKeys = append(publicKeys, key.PublicData())
+			}
+
+			return printJSON(os.Stdout, map[string]interface{}{
+				"root_keys": publicKeys,
+			})
+		},
+	}
+
+	cmd.Flags().StringVarP(&publicKeysData.KeysFile, "keys-file", "k", "", "Path to the keys file (the go-tuf keys file format)")
+
+	return cmd
+}
+
+var publicKeysData struct {
+	KeysFile string
+}
+
+func loadKeys(keysFile string) ([]*sign.PrivateKey, error) {
+	f, err := os.Open(keysFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open keys file: %s", err)
+	}
+	defer f.Close()
+
+	keys, err := keyhelper.LoadKeys(f, []byte(os.Getenv(passphraseEnvName)))
+	if err != nil {
+		return nil, fmt.Errorf("unable to load keys from %q: %s", keysFile, err)
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no keys found in %q", keysFile)
+	}
+
+	return keys, nil
+}
+
+func printJSON(w io.Writer, v interface{}) error {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintln(w, string(out))
+	return err
+}
+
+func main() {
+	rootCmd := &cobra.Command{
+		Use:   "trdl-server",
+		Short: "The trdl server helper commands to work with the offline keys",
+	}
+
+	rootCmd.AddCommand(NewCmdSignRoot(), NewCmdPublicKeys())
+
+	if err := rootCmd.Execute(); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %s\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const fieldNameBootstrapRepo = "repo"
+
+func bootstrapPath(b *Backend) *framework.Path {
+	return &framework.Path{
+		Pattern: `bootstrap$`,
+		Fields: map[string]*framework.FieldSchema{
+			fieldNameBootstrapRepo: {
+				Type:        framework.TypeString,
+				Description: "The repository name used in the trdl add command (the plugin mount name by default)",
+				Required:    false,
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.ReadOperation: &framework.PathOperation{
+				Callback: b.pathBootstrapRead,
+				Summary:  pathBootstrapHelpSyn,
+			},
+		},
+
+		HelpSynopsis:    pathBootstrapHelpSyn,
+		HelpDescription: pathBootstrapHelpDesc,
+	}
+}
+
+func (b *Backend) pathBootstrapRead(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
+	cfg, err := getConfiguration(ctx, req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get configuration from storage: %s", err)
+	}
+
+	if cfg == nil {
+		return errorResponseConfigurationNotFound, nil
+	}
+
+	bootstrap, err := b.Publisher.GetRootBootstrap(ctx, req.Storage, cfg.RepositoryOptions())
+	if err != nil {
+		return nil, fmt.Errorf("unable to get root.json bootstrap information: %s", err)
+	}
+
+	if bootstrap == nil {
+		return logical.ErrorResponse("TUF repository is not initialized"), nil
+	}
+
+	resp := &logical.Response{
+		Data: map[string]interface{}{
+			"root_version": bootstrap.Version,
+			"root_sha512":  bootstrap.Sha512,
+			"key_ids":      bootstrap.KeyIDs,
+		},
+	}
+
+	if cfg.PublicURL == "" {
+		resp.AddWarning(fmt.Sprintf("The trdl add command is not available: the %q configuration field is not set", fieldNamePublicURL))
+		return resp, nil
+	}
+
+	repo := fields.Get(fieldNameBootstrapRepo).(string)
+	if repo == "" {
+		repo = strings.Trim(req.MountPoint, "/")
+	}
+	if repo == "" {
+		repo = "REPO"
+	}
+
+	resp.Data["trdl_add_command"] = fmt.Sprintf("trdl add %s %s %d %s", repo, cfg.PublicURL, bootstrap.Version, bootstrap.Sha512)
+
+	return resp, nil
+}
+
+const (
+	pathBootstrapHelpSyn  = "Get the information to bootstrap the trdl clients"
+	pathBootstrapHelpDesc = "Get the version, the sha512 checksum and the key IDs of the published root.json along with the trdl add command to add the repository on the client side"
+)
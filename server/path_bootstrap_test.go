@@ -0,0 +1,124 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha512"
+	"encoding/hex"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/werf/trdl/server/pkg/publisher"
+)
+
+type PathBootstrapCallbackSuite struct {
+	CommonSuite
+}
+
+func (suite *PathBootstrapCallbackSuite) SetupTest() {
+	suite.CommonSuite.SetupTest()
+	suite.req.Path = "bootstrap"
+	suite.req.Operation = logical.ReadOperation
+}
+
+func (suite *PathBootstrapCallbackSuite) TestConfigurationNotFound() {
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), errorResponseConfigurationNotFound, resp)
+}
+
+func (suite *PathBootstrapCallbackSuite) TestRepositoryNotInitialized() {
+	suite.putLocalConfiguration()
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), logical.ErrorResponse("TUF repository is not initialized"), resp)
+}
+
+func (suite *PathBootstrapCallbackSuite) TestRead() {
+	cfg := suite.putLocalConfiguration()
+	suite.publishRepository(cfg)
+
+	rootJSON, err := ioutil.ReadFile(filepath.Join(cfg.LocalPath, "1.root.json"))
+	assert.Nil(suite.T(), err)
+	checksum := sha512.Sum512(rootJSON)
+	expectedSha512 := hex.EncodeToString(checksum[:])
+
+	suite.req.MountPoint = "trdl/"
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	if !assert.NotNil(suite.T(), resp) {
+		return
+	}
+
+	assert.False(suite.T(), resp.IsError())
+	assert.Equal(suite.T(), 1, resp.Data["root_version"])
+	assert.Equal(suite.T(), expectedSha512, resp.Data["root_sha512"])
+	assert.Equal(suite.T(), "trdl add trdl https://trdl.example.com 1 "+expectedSha512, resp.Data["trdl_add_command"])
+
+	keyIDs := resp.Data["key_ids"].(map[string][]string)
+	assert.Len(suite.T(), keyIDs["root"], 3)
+	assert.Len(suite.T(), keyIDs["targets"], 2)
+	assert.Len(suite.T(), keyIDs["snapshot"], 1)
+	assert.Len(suite.T(), keyIDs["timestamp"], 1)
+
+	suite.req.Data = map[string]interface{}{fieldNameBootstrapRepo: "myrepo"}
+	resp, err = suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "trdl add myrepo https://trdl.example.com 1 "+expectedSha512, resp.Data["trdl_add_command"])
+}
+
+func (suite *PathBootstrapCallbackSuite) TestRead_PublicURLNotConfigured() {
+	cfg := completeConfiguration()
+	cfg.PublicURL = ""
+	cfg = suite.putLocalConfigurationWith(cfg)
+	suite.publishRepository(cfg)
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	if assert.NotNil(suite.T(), resp) {
+		assert.False(suite.T(), resp.IsError())
+		assert.NotContains(suite.T(), resp.Data, "trdl_add_command")
+		assert.Len(suite.T(), resp.Warnings, 1)
+	}
+}
+
+func (suite *PathBootstrapCallbackSuite) putLocalConfiguration() *configuration {
+	return suite.putLocalConfigurationWith(completeConfiguration())
+}
+
+func (suite *PathBootstrapCallbackSuite) putLocalConfigurationWith(cfg *configuration) *configuration {
+	cfg.StorageType = publisher.StorageTypeLocal
+	cfg.LocalPath = suite.T().TempDir()
+	cfg.StorageMirrors = nil
+	cfg.TufConsistentSnapshot = false
+
+	err := putConfiguration(suite.ctx, suite.storage, cfg)
+	assert.Nil(suite.T(), err)
+
+	suite.backend.Publisher = publisher.NewPublisher(suite.backend.Logger())
+
+	return cfg
+}
+
+func (suite *PathBootstrapCallbackSuite) publishRepository(cfg *configuration) {
+	opts := cfg.RepositoryOptions()
+	opts.InitializeTUFKeys = true
+	opts.InitializePGPSigningKey = true
+
+	repository, err := suite.backend.Publisher.GetRepository(suite.ctx, suite.storage, opts)
+	if !assert.Nil(suite.T(), err) {
+		suite.T().FailNow()
+	}
+
+	assert.Nil(suite.T(), repository.StageTarget(suite.ctx, "file", bytes.NewBufferString("data")))
+	assert.Nil(suite.T(), repository.CommitStaged(suite.ctx))
+}
+
+func TestBackendPathBootstrapCallback(t *testing.T) {
+	suite.Run(t, new(PathBootstrapCallbackSuite))
+}
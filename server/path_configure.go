@@ -2,12 +2,15 @@ package server
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/fatih/structs"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
 
+	trdlGit "github.com/werf/trdl/server/pkg/git"
 	"github.com/werf/trdl/server/pkg/publisher"
 	"github.com/werf/trdl/server/pkg/util"
 )
@@ -17,13 +20,36 @@ const (
 	fieldNameGitTrdlPath                                = "git_trdl_path"
 	fieldNameGitTrdlChannelsPath                        = "git_trdl_channels_path"
 	fieldNameGitTrdlChannelsBranch                      = "git_trdl_channels_branch"
+	fieldNameGitCachePath                               = "git_cache_path"
 	fieldNameInitialLastPublishedGitCommit              = "initial_last_published_git_commit"
 	fieldNameRequiredNumberOfVerifiedSignaturesOnCommit = "required_number_of_verified_signatures_on_commit"
+	fieldNameStorageType                                = "storage_type"
+	fieldNameLocalPath                                  = "local_path"
+	fieldNameStorageMirrors                             = "storage_mirrors"
 	fieldNameS3Endpoint                                 = "s3_endpoint"
 	fieldNameS3Region                                   = "s3_region"
 	fieldNameS3AccessKeyID                              = "s3_access_key_id"
 	fieldNameS3SecretAccessKey                          = "s3_secret_access_key"
 	fieldNameS3BucketName                               = "s3_bucket_name"
+	fieldNamePublicURL                                  = "public_url"
+	fieldNameTufConsistentSnapshot                      = "tuf_consistent_snapshot"
+	fieldNameTufDelegations                             = "tuf_delegations"
+	fieldNameTufRootExpirationPeriod                    = "tuf_root_expiration_period"
+	fieldNameTufTargetsExpirationPeriod                 = "tuf_targets_expiration_period"
+	fieldNameTufSnapshotExpirationPeriod                = "tuf_snapshot_expiration_period"
+	fieldNameTufTimestampExpirationPeriod               = "tuf_timestamp_expiration_period"
+	fieldNameTufRootExpirationThreshold                 = "tuf_root_expiration_threshold"
+	fieldNameTufTargetsExpirationThreshold              = "tuf_targets_expiration_threshold"
+	fieldNameTufSnapshotExpirationThreshold             = "tuf_snapshot_expiration_threshold"
+	fieldNameTufTimestampExpirationThreshold            = "tuf_timestamp_expiration_threshold"
+	fieldNameTufRootKeysNumber                          = "tuf_root_keys_number"
+	fieldNameTufTargetsKeysNumber                       = "tuf_targets_keys_number"
+	fieldNameTufSnapshotKeysNumber                      = "tuf_snapshot_keys_number"
+	fieldNameTufTimestampKeysNumber                     = "tuf_timestamp_keys_number"
+	fieldNameTufRootKeysThreshold                       = "tuf_root_keys_threshold"
+	fieldNameTufTargetsKeysThreshold                    = "tuf_targets_keys_threshold"
+	fieldNameTufSnapshotKeysThreshold                   = "tuf_snapshot_keys_threshold"
+	fieldNameTufTimestampKeysThreshold                  = "tuf_timestamp_keys_threshold"
 
 	storageKeyConfiguration = "configuration"
 )
@@ -55,6 +81,11 @@ func configurePath(b *Backend) *framework.Path {
 				Description: "A special Git branch to store the trdl channels configuration file",
 				Required:    false,
 			},
+			fieldNameGitCachePath: {
+				Type:        framework.TypeString,
+				Description: "The plugin directory to keep the persistent git repository cache, so only the required tag or branch is fetched incrementally (the repository is cloned into memory on every operation by default)",
+				Required:    false,
+			},
 			fieldNameInitialLastPublishedGitCommit: {
 				Type:        framework.TypeString,
 				Description: "The initial commit for the last successful publication",
@@ -65,30 +96,142 @@ func configurePath(b *Backend) *framework.Path {
 				Description: "The required number of verified signatures for a commit",
 				Required:    true,
 			},
+			fieldNameStorageType: {
+				Type:          framework.TypeString,
+				Description:   "The storage type of the TUF repository: s3 or local",
+				Default:       publisher.StorageTypeS3,
+				AllowedValues: []interface{}{publisher.StorageTypeS3, publisher.StorageTypeLocal},
+				Required:      false,
+			},
+			fieldNameLocalPath: {
+				Type:        framework.TypeString,
+				Description: "The directory to store the TUF repository (required for the local storage type)",
+				Required:    false,
+			},
+			fieldNameStorageMirrors: {
+				Type:        framework.TypeSlice,
+				Description: "Additional storages mirroring the TUF repository. Each mirror is an object with the storage_type field and the local_path or s3_* fields of the corresponding storage type",
+				Required:    false,
+			},
 			fieldNameS3BucketName: {
 				Type:        framework.TypeString,
-				Description: "The S3 storage bucket name",
-				Required:    true,
+				Description: "The S3 storage bucket name (required for the s3 storage type)",
+				Required:    false,
 			},
 			fieldNameS3Endpoint: {
 				Type:        framework.TypeString,
-				Description: "The S3 storage endpoint",
-				Required:    true,
+				Description: "The S3 storage endpoint (required for the s3 storage type)",
+				Required:    false,
 			},
 			fieldNameS3Region: {
 				Type:        framework.TypeString,
-				Description: "The S3 storage region",
-				Required:    true,
+				Description: "The S3 storage region (required for the s3 storage type)",
+				Required:    false,
 			},
 			fieldNameS3AccessKeyID: {
 				Type:        framework.TypeString,
-				Description: "The S3 storage access key id",
-				Required:    true,
+				Description: "The S3 storage access key id (required for the s3 storage type)",
+				Required:    false,
 			},
 			fieldNameS3SecretAccessKey: {
 				Type:        framework.TypeString,
-				Description: "The S3 storage access key id",
-				Required:    true,
+				Description: "The S3 storage access key id (required for the s3 storage type)",
+				Required:    false,
+			},
+			fieldNamePublicURL: {
+				Type:        framework.TypeString,
+				Description: "The public URL of the TUF repository the trdl clients download it from (used in the trdl add command returned by the bootstrap path)",
+				Required:    false,
+			},
+			fieldNameTufConsistentSnapshot: {
+				Type:        framework.TypeBool,
+				Description: "Publish the TUF repository with consistent snapshots (hash-prefixed targets and version-prefixed metadata). The existing repository is migrated to the new layout in the background",
+				Required:    false,
+			},
+			fieldNameTufDelegations: {
+				Type:        framework.TypeBool,
+				Description: "Publish the release and channel targets in the delegated TUF roles metadata: one role per major version of the releases and one role for the channels, each signed by its own key. The clients download only the metadata of the role being updated. The existing targets are moved into the delegated roles by the next publication. Requires the trdl client with the delegations support",
+				Required:    false,
+			},
+			fieldNameTufRootExpirationPeriod: {
+				Type:        framework.TypeDurationSecond,
+				Description: "The expiration period of the TUF repository root.json (1 year by default)",
+				Required:    false,
+			},
+			fieldNameTufTargetsExpirationPeriod: {
+				Type:        framework.TypeDurationSecond,
+				Description: "The expiration period of the TUF repository targets.json (90 days by default)",
+				Required:    false,
+			},
+			fieldNameTufSnapshotExpirationPeriod: {
+				Type:        framework.TypeDurationSecond,
+				Description: "The expiration period of the TUF repository snapshot.json (7 days by default)",
+				Required:    false,
+			},
+			fieldNameTufTimestampExpirationPeriod: {
+				Type:        framework.TypeDurationSecond,
+				Description: "The expiration period of the TUF repository timestamp.json (1 day by default)",
+				Required:    false,
+			},
+			fieldNameTufRootExpirationThreshold: {
+				Type:        framework.TypeDurationSecond,
+				Description: "Re-sign the TUF repository root.json when it expires within this period (60 days by default)",
+				Required:    false,
+			},
+			fieldNameTufTargetsExpirationThreshold: {
+				Type:        framework.TypeDurationSecond,
+				Description: "Re-sign the TUF repository targets.json when it expires within this period (30 days by default)",
+				Required:    false,
+			},
+			fieldNameTufSnapshotExpirationThreshold: {
+				Type:        framework.TypeDurationSecond,
+				Description: "Re-sign the TUF repository snapshot.json when it expires within this period (3 days by default)",
+				Required:    false,
+			},
+			fieldNameTufTimestampExpirationThreshold: {
+				Type:        framework.TypeDurationSecond,
+				Description: "Re-sign the TUF repository timestamp.json when it expires within this period (12 hours by default)",
+				Required:    false,
+			},
+			fieldNameTufRootKeysNumber: {
+				Type:        framework.TypeInt,
+				Description: "The number of the TUF repository root role private keys (1 by default). The keys are rotated on change",
+				Required:    false,
+			},
+			fieldNameTufTargetsKeysNumber: {
+				Type:        framework.TypeInt,
+				Description: "The number of the TUF repository targets role private keys (1 by default). The keys are rotated on change",
+				Required:    false,
+			},
+			fieldNameTufSnapshotKeysNumber: {
+				Type:        framework.TypeInt,
+				Description: "The number of the TUF repository snapshot role private keys (1 by default). The keys are rotated on change",
+				Required:    false,
+			},
+			fieldNameTufTimestampKeysNumber: {
+				Type:        framework.TypeInt,
+				Description: "The number of the TUF repository timestamp role private keys (1 by default). The keys are rotated on change",
+				Required:    false,
+			},
+			fieldNameTufRootKeysThreshold: {
+				Type:        framework.TypeInt,
+				Description: "The number of the TUF repository root role keys required to sign root.json (1 by default)",
+				Required:    false,
+			},
+			fieldNameTufTargetsKeysThreshold: {
+				Type:        framework.TypeInt,
+				Description: "The number of the TUF repository targets role keys required to sign targets.json (1 by default)",
+				Required:    false,
+			},
+			fieldNameTufSnapshotKeysThreshold: {
+				Type:        framework.TypeInt,
+				Description: "The number of the TUF repository snapshot role keys required to sign snapshot.json (1 by default)",
+				Required:    false,
+			},
+			fieldNameTufTimestampKeysThreshold: {
+				Type:        framework.TypeInt,
+				Description: "The number of the TUF repository timestamp role keys required to sign timestamp.json (1 by default)",
+				Required:    false,
 			},
 		},
 		Operations: map[logical.Operation]framework.OperationHandler{
@@ -122,13 +265,91 @@ func (b *Backend) pathConfigureCreateOrUpdate(ctx context.Context, req *logical.
 		GitTrdlPath:                   fields.Get(fieldNameGitTrdlPath).(string),
 		GitTrdlChannelsPath:           fields.Get(fieldNameGitTrdlChannelsPath).(string),
 		GitTrdlChannelsBranch:         fields.Get(fieldNameGitTrdlChannelsBranch).(string),
+		GitCachePath:                  fields.Get(fieldNameGitCachePath).(string),
 		InitialLastPublishedGitCommit: fields.Get(fieldNameInitialLastPublishedGitCommit).(string),
 		RequiredNumberOfVerifiedSignaturesOnCommit: fields.Get(fieldNameRequiredNumberOfVerifiedSignaturesOnCommit).(int),
-		S3Endpoint:        fields.Get(fieldNameS3Endpoint).(string),
-		S3Region:          fields.Get(fieldNameS3Region).(string),
-		S3AccessKeyID:     fields.Get(fieldNameS3AccessKeyID).(string),
-		S3SecretAccessKey: fields.Get(fieldNameS3SecretAccessKey).(string),
-		S3BucketName:      fields.Get(fieldNameS3BucketName).(string),
+		StorageType:                     fields.Get(fieldNameStorageType).(string),
+		LocalPath:                       fields.Get(fieldNameLocalPath).(string),
+		S3Endpoint:                      fields.Get(fieldNameS3Endpoint).(string),
+		S3Region:                        fields.Get(fieldNameS3Region).(string),
+		S3AccessKeyID:                   fields.Get(fieldNameS3AccessKeyID).(string),
+		S3SecretAccessKey:               fields.Get(fieldNameS3SecretAccessKey).(string),
+		S3BucketName:                    fields.Get(fieldNameS3BucketName).(string),
+		PublicURL:                       fields.Get(fieldNamePublicURL).(string),
+		TufConsistentSnapshot:           fields.Get(fieldNameTufConsistentSnapshot).(bool),
+		TufDelegations:                  fields.Get(fieldNameTufDelegations).(bool),
+		TufRootExpirationPeriod:         fields.Get(fieldNameTufRootExpirationPeriod).(int),
+		TufTargetsExpirationPeriod:      fields.Get(fieldNameTufTargetsExpirationPeriod).(int),
+		TufSnapshotExpirationPeriod:     fields.Get(fieldNameTufSnapshotExpirationPeriod).(int),
+		TufTimestampExpirationPeriod:    fields.Get(fieldNameTufTimestampExpirationPeriod).(int),
+		TufRootExpirationThreshold:      fields.Get(fieldNameTufRootExpirationThreshold).(int),
+		TufTargetsExpirationThreshold:   fields.Get(fieldNameTufTargetsExpirationThreshold).(int),
+		TufSnapshotExpirationThreshold:  fields.Get(fieldNameTufSnapshotExpirationThreshold).(int),
+		TufTimestampExpirationThreshold: fields.Get(fieldNameTufTimestampExpirationThreshold).(int),
+		TufRootKeysNumber:               fields.Get(fieldNameTufRootKeysNumber).(int),
+		TufTargetsKeysNumber:            fields.Get(fieldNameTufTargetsKeysNumber).(int),
+		TufSnapshotKeysNumber:           fields.Get(fieldNameTufSnapshotKeysNumber).(int),
+		TufTimestampKeysNumber:          fields.Get(fieldNameTufTimestampKeysNumber).(int),
+		TufRootKeysThreshold:            fields.Get(fieldNameTufRootKeysThreshold).(int),
+		TufTargetsKeysThreshold:         fields.Get(fieldNameTufTargetsKeysThreshold).(int),
+		TufSnapshotKeysThreshold:        fields.Get(fieldNameTufSnapshotKeysThreshold).(int),
+		TufTimestampKeysThreshold:       fields.Get(fieldNameTufTimestampKeysThreshold).(int),
+	}
+
+	switch cfg.StorageType {
+	case publisher.StorageTypeS3:
+		for _, fieldName := range []string{
+			fieldNameS3BucketName,
+			fieldNameS3Endpoint,
+			fieldNameS3Region,
+			fieldNameS3AccessKeyID,
+			fieldNameS3SecretAccessKey,
+		} {
+			if req.Get(fieldName) == nil {
+				return logical.ErrorResponse("Required field %q must be set", fieldName), nil
+			}
+		}
+	case publisher.StorageTypeLocal:
+		if cfg.LocalPath == "" {
+			return logical.ErrorResponse("Required field %q must be set", fieldNameLocalPath), nil
+		}
+	default:
+		return logical.ErrorResponse("Unsupported storage type %q: expected %q or %q", cfg.StorageType, publisher.StorageTypeS3, publisher.StorageTypeLocal), nil
+	}
+
+	{
+		storageMirrors, err := parseStorageMirrors(fields.Get(fieldNameStorageMirrors).([]interface{}))
+		if err != nil {
+			return logical.ErrorResponse("Invalid field %q: %s", fieldNameStorageMirrors, err), nil
+		}
+		cfg.StorageMirrors = storageMirrors
+	}
+
+	{
+		effectiveCfg := *cfg
+		effectiveCfg.setTufExpirationDefaults()
+
+		for role, settings := range effectiveCfg.tufExpirationSettings() {
+			if *settings.Threshold >= *settings.Period {
+				return logical.ErrorResponse("The TUF repository %s expiration threshold (%ds) must be less than the expiration period (%ds)", role, *settings.Threshold, *settings.Period), nil
+			}
+		}
+
+		effectiveCfg.setTufKeysDefaults()
+
+		for role, settings := range effectiveCfg.tufKeysSettings() {
+			if *settings.Number < 1 || *settings.Threshold < 1 {
+				return logical.ErrorResponse("The TUF repository %s keys number and keys threshold must be positive", role), nil
+			}
+
+			if *settings.Threshold > *settings.Number {
+				return logical.ErrorResponse("The TUF repository %s keys threshold (%d) must not exceed the keys number (%d)", role, *settings.Threshold, *settings.Number), nil
+			}
+		}
+	}
+
+	if err := b.pruneOutdatedGitCache(ctx, req.Storage, cfg); err != nil {
+		return nil, err
 	}
 
 	if err := putConfiguration(ctx, req.Storage, cfg); err != nil {
@@ -138,6 +359,31 @@ func (b *Backend) pathConfigureCreateOrUpdate(ctx context.Context, req *logical.
 	return nil, nil
 }
 
+// pruneOutdatedGitCache removes the git cache of the current configuration if the new configuration does not use it anymore.
+// The new configuration is nil when the configuration is deleted.
+func (b *Backend) pruneOutdatedGitCache(ctx context.Context, storage logical.Storage, newCfg *configuration) error {
+	oldCfg, err := getConfiguration(ctx, storage)
+	if err != nil {
+		return fmt.Errorf("unable to get configuration from storage: %s", err)
+	}
+
+	if oldCfg == nil || oldCfg.GitCachePath == "" {
+		return nil
+	}
+
+	if newCfg != nil && newCfg.GitCachePath == oldCfg.GitCachePath && newCfg.GitRepoUrl == oldCfg.GitRepoUrl {
+		return nil
+	}
+
+	b.Logger().Debug(fmt.Sprintf("Pruning git cache of %q in %q", oldCfg.GitRepoUrl, oldCfg.GitCachePath))
+
+	if err := trdlGit.PruneCache(oldCfg.GitCachePath, oldCfg.GitRepoUrl); err != nil {
+		return fmt.Errorf("unable to prune git cache: %s", err)
+	}
+
+	return nil
+}
+
 func (b *Backend) pathConfigureRead(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
 	cfg, err := getConfiguration(ctx, req.Storage)
 	if err != nil {
@@ -148,10 +394,17 @@ func (b *Backend) pathConfigureRead(ctx context.Context, req *logical.Request, _
 		return errorResponseConfigurationNotFound, nil
 	}
 
+	cfg.setTufExpirationDefaults()
+	cfg.setTufKeysDefaults()
+
 	return &logical.Response{Data: structs.Map(cfg)}, nil
 }
 
 func (b *Backend) pathConfigureDelete(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	if err := b.pruneOutdatedGitCache(ctx, req.Storage, nil); err != nil {
+		return nil, err
+	}
+
 	if err := deleteConfiguration(ctx, req.Storage); err != nil {
 		return nil, fmt.Errorf("unable to delete configuration: %s", err)
 	}
@@ -160,26 +413,205 @@ func (b *Backend) pathConfigureDelete(ctx context.Context, req *logical.Request,
 }
 
 type configuration struct {
-	GitRepoUrl                                 string `structs:"git_repo_url" json:"git_repo_url"`
-	GitTrdlPath                                string `structs:"git_trdl_path" json:"git_trdl_path"`
-	GitTrdlChannelsPath                        string `structs:"git_trdl_channels_path" json:"git_trdl_channels_path"`
-	GitTrdlChannelsBranch                      string `structs:"git_trdl_channels_branch" json:"git_trdl_channels_branch"`
-	InitialLastPublishedGitCommit              string `structs:"initial_last_published_git_commit" json:"initial_last_published_git_commit"`
-	RequiredNumberOfVerifiedSignaturesOnCommit int    `structs:"required_number_of_verified_signatures_on_commit" json:"required_number_of_verified_signatures_on_commit"`
-	S3Endpoint                                 string `structs:"s3_endpoint" json:"s3_endpoint"`
-	S3Region                                   string `structs:"s3_region" json:"s3_region"`
-	S3AccessKeyID                              string `structs:"s3_access_key_id" json:"s3_access_key_id"`
-	S3SecretAccessKey                          string `structs:"s3_secret_access_key" json:"s3_secret_access_key"`
-	S3BucketName                               string `structs:"s3_bucket_name" json:"s3_bucket_name"`
+	GitRepoUrl                                 string                       `structs:"git_repo_url" json:"git_repo_url"`
+	GitTrdlPath                                string                       `structs:"git_trdl_path" json:"git_trdl_path"`
+	GitTrdlChannelsPath                        string                       `structs:"git_trdl_channels_path" json:"git_trdl_channels_path"`
+	GitTrdlChannelsBranch                      string                       `structs:"git_trdl_channels_branch" json:"git_trdl_channels_branch"`
+	GitCachePath                               string                       `structs:"git_cache_path" json:"git_cache_path"`
+	InitialLastPublishedGitCommit              string                       `structs:"initial_last_published_git_commit" json:"initial_last_published_git_commit"`
+	RequiredNumberOfVerifiedSignaturesOnCommit int                          `structs:"required_number_of_verified_signatures_on_commit" json:"required_number_of_verified_signatures_on_commit"`
+	StorageType                                string                       `structs:"storage_type" json:"storage_type"`
+	LocalPath                                  string                       `structs:"local_path" json:"local_path"`
+	StorageMirrors                             []storageMirrorConfiguration `structs:"storage_mirrors" json:"storage_mirrors"`
+	S3Endpoint                                 string                       `structs:"s3_endpoint" json:"s3_endpoint"`
+	S3Region                                   string                       `structs:"s3_region" json:"s3_region"`
+	S3AccessKeyID                              string                       `structs:"s3_access_key_id" json:"s3_access_key_id"`
+	S3SecretAccessKey                          string                       `structs:"s3_secret_access_key" json:"s3_secret_access_key"`
+	S3BucketName                               string                       `structs:"s3_bucket_name" json:"s3_bucket_name"`
+	PublicURL                                  string                       `structs:"public_url" json:"public_url"`
+	TufConsistentSnapshot                      bool                         `structs:"tuf_consistent_snapshot" json:"tuf_consistent_snapshot"`
+	TufDelegations                             bool                         `structs:"tuf_delegations" json:"tuf_delegations"`
+	TufRootExpirationPeriod                    int                          `structs:"tuf_root_expiration_period" json:"tuf_root_expiration_period"`
+	TufTargetsExpirationPeriod                 int                          `structs:"tuf_targets_expiration_period" json:"tuf_targets_expiration_period"`
+	TufSnapshotExpirationPeriod                int                          `structs:"tuf_snapshot_expiration_period" json:"tuf_snapshot_expiration_period"`
+	TufTimestampExpirationPeriod               int                          `structs:"tuf_timestamp_expiration_period" json:"tuf_timestamp_expiration_period"`
+	TufRootExpirationThreshold                 int                          `structs:"tuf_root_expiration_threshold" json:"tuf_root_expiration_threshold"`
+	TufTargetsExpirationThreshold              int                          `structs:"tuf_targets_expiration_threshold" json:"tuf_targets_expiration_threshold"`
+	TufSnapshotExpirationThreshold             int                          `structs:"tuf_snapshot_expiration_threshold" json:"tuf_snapshot_expiration_threshold"`
+	TufTimestampExpirationThreshold            int                          `structs:"tuf_timestamp_expiration_threshold" json:"tuf_timestamp_expiration_threshold"`
+	TufRootKeysNumber                          int                          `structs:"tuf_root_keys_number" json:"tuf_root_keys_number"`
+	TufTargetsKeysNumber                       int                          `structs:"tuf_targets_keys_number" json:"tuf_targets_keys_number"`
+	TufSnapshotKeysNumber                      int                          `structs:"tuf_snapshot_keys_number" json:"tuf_snapshot_keys_number"`
+	TufTimestampKeysNumber                     int                          `structs:"tuf_timestamp_keys_number" json:"tuf_timestamp_keys_number"`
+	TufRootKeysThreshold                       int                          `structs:"tuf_root_keys_threshold" json:"tuf_root_keys_threshold"`
+	TufTargetsKeysThreshold                    int                          `structs:"tuf_targets_keys_threshold" json:"tuf_targets_keys_threshold"`
+	TufSnapshotKeysThreshold                   int                          `structs:"tuf_snapshot_keys_threshold" json:"tuf_snapshot_keys_threshold"`
+	TufTimestampKeysThreshold                  int                          `structs:"tuf_timestamp_keys_threshold" json:"tuf_timestamp_keys_threshold"`
 }
 
 func (cfg *configuration) RepositoryOptions() publisher.RepositoryOptions {
-	return publisher.RepositoryOptions{
-		S3Endpoint:        cfg.S3Endpoint,
-		S3Region:          cfg.S3Region,
-		S3AccessKeyID:     cfg.S3AccessKeyID,
-		S3SecretAccessKey: cfg.S3SecretAccessKey,
-		S3BucketName:      cfg.S3BucketName,
+	opts := publisher.RepositoryOptions{
+		StorageOptions: publisher.StorageOptions{
+			StorageType:       cfg.StorageType,
+			LocalPath:         cfg.LocalPath,
+			S3Endpoint:        cfg.S3Endpoint,
+			S3Region:          cfg.S3Region,
+			S3AccessKeyID:     cfg.S3AccessKeyID,
+			S3SecretAccessKey: cfg.S3SecretAccessKey,
+			S3BucketName:      cfg.S3BucketName,
+		},
+		TufConsistentSnapshot:   cfg.TufConsistentSnapshot,
+		TufDelegations:          cfg.TufDelegations,
+		TufExpirationPeriods:    make(map[string]time.Duration),
+		TufExpirationThresholds: make(map[string]time.Duration),
+		TufKeysNumbers:          make(map[string]int),
+		TufKeysThresholds:       make(map[string]int),
+	}
+
+	for _, mirror := range cfg.StorageMirrors {
+		opts.StorageMirrors = append(opts.StorageMirrors, publisher.StorageOptions{
+			StorageType:       mirror.StorageType,
+			LocalPath:         mirror.LocalPath,
+			S3Endpoint:        mirror.S3Endpoint,
+			S3Region:          mirror.S3Region,
+			S3AccessKeyID:     mirror.S3AccessKeyID,
+			S3SecretAccessKey: mirror.S3SecretAccessKey,
+			S3BucketName:      mirror.S3BucketName,
+		})
+	}
+
+	for role, settings := range cfg.tufExpirationSettings() {
+		opts.TufExpirationPeriods[role] = time.Duration(*settings.Period) * time.Second
+		opts.TufExpirationThresholds[role] = time.Duration(*settings.Threshold) * time.Second
+	}
+
+	for role, settings := range cfg.tufKeysSettings() {
+		opts.TufKeysNumbers[role] = *settings.Number
+		opts.TufKeysThresholds[role] = *settings.Threshold
+	}
+
+	return opts
+}
+
+type storageMirrorConfiguration struct {
+	StorageType       string `structs:"storage_type" json:"storage_type"`
+	LocalPath         string `structs:"local_path" json:"local_path,omitempty"`
+	S3Endpoint        string `structs:"s3_endpoint" json:"s3_endpoint,omitempty"`
+	S3Region          string `structs:"s3_region" json:"s3_region,omitempty"`
+	S3AccessKeyID     string `structs:"s3_access_key_id" json:"s3_access_key_id,omitempty"`
+	S3SecretAccessKey string `structs:"s3_secret_access_key" json:"s3_secret_access_key,omitempty"`
+	S3BucketName      string `structs:"s3_bucket_name" json:"s3_bucket_name,omitempty"`
+}
+
+func parseStorageMirrors(rawMirrors []interface{}) ([]storageMirrorConfiguration, error) {
+	var mirrors []storageMirrorConfiguration
+
+	for i, rawMirror := range rawMirrors {
+		var mirror storageMirrorConfiguration
+
+		switch v := rawMirror.(type) {
+		case string:
+			if err := json.Unmarshal([]byte(v), &mirror); err != nil {
+				return nil, fmt.Errorf("unable to parse mirror %d: %s", i, err)
+			}
+		default:
+			data, err := json.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("unable to parse mirror %d: %s", i, err)
+			}
+			if err := json.Unmarshal(data, &mirror); err != nil {
+				return nil, fmt.Errorf("unable to parse mirror %d: %s", i, err)
+			}
+		}
+
+		if err := mirror.validate(); err != nil {
+			return nil, fmt.Errorf("mirror %d: %s", i, err)
+		}
+
+		mirrors = append(mirrors, mirror)
+	}
+
+	return mirrors, nil
+}
+
+func (mirror storageMirrorConfiguration) validate() error {
+	var requiredFields map[string]string
+
+	switch mirror.StorageType {
+	case publisher.StorageTypeS3:
+		requiredFields = map[string]string{
+			fieldNameS3BucketName:      mirror.S3BucketName,
+			fieldNameS3Endpoint:        mirror.S3Endpoint,
+			fieldNameS3Region:          mirror.S3Region,
+			fieldNameS3AccessKeyID:     mirror.S3AccessKeyID,
+			fieldNameS3SecretAccessKey: mirror.S3SecretAccessKey,
+		}
+	case publisher.StorageTypeLocal:
+		requiredFields = map[string]string{
+			fieldNameLocalPath: mirror.LocalPath,
+		}
+	default:
+		return fmt.Errorf("unsupported storage type %q: expected %q or %q", mirror.StorageType, publisher.StorageTypeS3, publisher.StorageTypeLocal)
+	}
+
+	for _, fieldName := range []string{fieldNameLocalPath, fieldNameS3BucketName, fieldNameS3Endpoint, fieldNameS3Region, fieldNameS3AccessKeyID, fieldNameS3SecretAccessKey} {
+		if value, hasKey := requiredFields[fieldName]; hasKey && value == "" {
+			return fmt.Errorf("required field %q must be set", fieldName)
+		}
+	}
+
+	return nil
+}
+
+type tufRoleExpirationSettings struct {
+	Period    *int
+	Threshold *int
+}
+
+func (cfg *configuration) tufExpirationSettings() map[string]tufRoleExpirationSettings {
+	return map[string]tufRoleExpirationSettings{
+		"root":      {Period: &cfg.TufRootExpirationPeriod, Threshold: &cfg.TufRootExpirationThreshold},
+		"targets":   {Period: &cfg.TufTargetsExpirationPeriod, Threshold: &cfg.TufTargetsExpirationThreshold},
+		"snapshot":  {Period: &cfg.TufSnapshotExpirationPeriod, Threshold: &cfg.TufSnapshotExpirationThreshold},
+		"timestamp": {Period: &cfg.TufTimestampExpirationPeriod, Threshold: &cfg.TufTimestampExpirationThreshold},
+	}
+}
+
+// setTufExpirationDefaults sets the effective values of unset TUF repository expiration settings.
+func (cfg *configuration) setTufExpirationDefaults() {
+	for role, settings := range cfg.tufExpirationSettings() {
+		if *settings.Period == 0 {
+			*settings.Period = int(publisher.DefaultExpirationPeriods[role] / time.Second)
+		}
+		if *settings.Threshold == 0 {
+			*settings.Threshold = int(publisher.DefaultExpirationThresholds[role] / time.Second)
+		}
+	}
+}
+
+type tufRoleKeysSettings struct {
+	Number    *int
+	Threshold *int
+}
+
+func (cfg *configuration) tufKeysSettings() map[string]tufRoleKeysSettings {
+	return map[string]tufRoleKeysSettings{
+		"root":      {Number: &cfg.TufRootKeysNumber, Threshold: &cfg.TufRootKeysThreshold},
+		"targets":   {Number: &cfg.TufTargetsKeysNumber, Threshold: &cfg.TufTargetsKeysThreshold},
+		"snapshot":  {Number: &cfg.TufSnapshotKeysNumber, Threshold: &cfg.TufSnapshotKeysThreshold},
+		"timestamp": {Number: &cfg.TufTimestampKeysNumber, Threshold: &cfg.TufTimestampKeysThreshold},
+	}
+}
+
+// setTufKeysDefaults sets the effective values of unset TUF repository keys settings.
+func (cfg *configuration) setTufKeysDefaults() {
+	for _, settings := range cfg.tufKeysSettings() {
+		if *settings.Number == 0 {
+			*settings.Number = 1
+		}
+		if *settings.Threshold == 0 {
+			*settings.Threshold = 1
+		}
 	}
 }
 
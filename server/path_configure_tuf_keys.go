@@ -0,0 +1,121 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+
+	"github.com/werf/trdl/server/pkg/keyhelper"
+	"github.com/werf/trdl/server/pkg/publisher"
+	"github.com/werf/trdl/server/pkg/util"
+)
+
+const (
+	fieldNameTufKeysImportRoot       = "root"
+	fieldNameTufKeysImportTargets    = "targets"
+	fieldNameTufKeysImportSnapshot   = "snapshot"
+	fieldNameTufKeysImportTimestamp  = "timestamp"
+	fieldNameTufKeysImportPassphrase = "passphrase"
+)
+
+func configureTufKeysPaths(b *Backend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: `configure/tuf_keys/import$`,
+			Fields: map[string]*framework.FieldSchema{
+				fieldNameTufKeysImportRoot: {
+					Type:        framework.TypeString,
+					Description: "The persisted root keys (the content of the go-tuf keys/root.json). The root is kept offline if not specified",
+					Required:    false,
+				},
+				fieldNameTufKeysImportTargets: {
+					Type:        framework.TypeString,
+					Description: "The persisted targets keys (the content of the go-tuf keys/targets.json)",
+					Required:    true,
+				},
+				fieldNameTufKeysImportSnapshot: {
+					Type:        framework.TypeString,
+					Description: "The persisted snapshot keys (the content of the go-tuf keys/snapshot.json)",
+					Required:    true,
+				},
+				fieldNameTufKeysImportTimestamp: {
+					Type:        framework.TypeString,
+					Description: "The persisted timestamp keys (the content of the go-tuf keys/timestamp.json)",
+					Required:    true,
+				},
+				fieldNameTufKeysImportPassphrase: {
+					Type:        framework.TypeString,
+					Description: "The passphrase of the encrypted keys",
+					Required:    false,
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.CreateOperation: &framework.PathOperation{
+					Callback: b.pathConfigureTufKeysImport,
+					Summary:  pathConfigureTufKeysImportHelpSyn,
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.pathConfigureTufKeysImport,
+					Summary:  pathConfigureTufKeysImportHelpSyn,
+				},
+			},
+
+			HelpSynopsis:    pathConfigureTufKeysImportHelpSyn,
+			HelpDescription: pathConfigureTufKeysImportHelpDesc,
+		},
+	}
+}
+
+func (b *Backend) pathConfigureTufKeysImport(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
+	if errResp := util.CheckRequiredFields(req, fields); errResp != nil {
+		return errResp, nil
+	}
+
+	cfg, err := getConfiguration(ctx, req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get configuration from storage: %s", err)
+	}
+
+	if cfg == nil {
+		return errorResponseConfigurationNotFound, nil
+	}
+
+	passphrase := []byte(fields.Get(fieldNameTufKeysImportPassphrase).(string))
+
+	var privKeys publisher.TufRepoPrivKeys
+	for fieldName, roleKeys := range map[string]*publisher.TufRolePrivKeys{
+		fieldNameTufKeysImportRoot:      &privKeys.Root,
+		fieldNameTufKeysImportTargets:   &privKeys.Targets,
+		fieldNameTufKeysImportSnapshot:  &privKeys.Snapshot,
+		fieldNameTufKeysImportTimestamp: &privKeys.Timestamp,
+	} {
+		persistedKeys := fields.Get(fieldName).(string)
+		if persistedKeys == "" {
+			continue
+		}
+
+		keys, err := keyhelper.LoadKeys(strings.NewReader(persistedKeys), passphrase)
+		if err != nil {
+			return logical.ErrorResponse("Invalid field %q: %s", fieldName, err), nil
+		}
+
+		*roleKeys = keys
+	}
+
+	if err := b.Publisher.ImportRepositoryKeys(ctx, req.Storage, cfg.RepositoryOptions(), privKeys); err == publisher.ErrRepositoryKeysAlreadyExist {
+		return logical.ErrorResponse("TUF repository keys already exist"), nil
+	} else if err != nil {
+		return logical.ErrorResponse("Unable to import TUF repository keys: %s", err), nil
+	}
+
+	return nil, nil
+}
+
+const (
+	pathConfigureTufKeysImportHelpSyn  = "Import the existing TUF repository keys"
+	pathConfigureTufKeysImportHelpDesc = "Import the TUF repository keys persisted by the go-tuf CLI, so the existing repository is published by the plugin without re-bootstrapping the clients. The keys are validated against root.json of the repository and can be imported only if the plugin does not have the repository keys yet. The root keys are optional: without them the root is kept offline and root.json is signed with the root/unsigned and root/signatures paths"
+)
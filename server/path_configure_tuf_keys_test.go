@@ -0,0 +1,180 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/theupdateframework/go-tuf/encrypted"
+	"github.com/theupdateframework/go-tuf/sign"
+
+	"github.com/werf/trdl/server/pkg/keyhelper"
+	"github.com/werf/trdl/server/pkg/publisher"
+)
+
+type PathConfigureTufKeysImportCallbackSuite struct {
+	CommonSuite
+}
+
+func (suite *PathConfigureTufKeysImportCallbackSuite) SetupTest() {
+	suite.CommonSuite.SetupTest()
+	suite.req.Path = "configure/tuf_keys/import"
+	suite.req.Operation = logical.UpdateOperation
+}
+
+func (suite *PathConfigureTufKeysImportCallbackSuite) TestRequiredFields() {
+	err := putConfiguration(suite.ctx, suite.storage, completeConfiguration())
+	assert.Nil(suite.T(), err)
+
+	for _, fieldName := range []string{fieldNameTufKeysImportTargets, fieldNameTufKeysImportSnapshot, fieldNameTufKeysImportTimestamp} {
+		suite.req.Data = suite.persistedKeysData()
+		delete(suite.req.Data, fieldName)
+
+		resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+		assert.Nil(suite.T(), err)
+		assert.Equal(suite.T(), logical.ErrorResponse("Required field %q must be set", fieldName), resp)
+	}
+}
+
+func (suite *PathConfigureTufKeysImportCallbackSuite) TestConfigurationNotFound() {
+	suite.req.Data = suite.persistedKeysData()
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), errorResponseConfigurationNotFound, resp)
+}
+
+func (suite *PathConfigureTufKeysImportCallbackSuite) TestInvalidKeys() {
+	err := putConfiguration(suite.ctx, suite.storage, completeConfiguration())
+	assert.Nil(suite.T(), err)
+
+	pub := &importKeysPublisher{}
+	suite.backend.Publisher = pub
+
+	suite.req.Data = suite.persistedKeysData()
+	suite.req.Data[fieldNameTufKeysImportSnapshot] = "not a key"
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	if assert.NotNil(suite.T(), resp) {
+		assert.True(suite.T(), resp.IsError())
+	}
+
+	assert.False(suite.T(), pub.imported)
+}
+
+func (suite *PathConfigureTufKeysImportCallbackSuite) TestEncryptedKeys() {
+	err := putConfiguration(suite.ctx, suite.storage, completeConfiguration())
+	assert.Nil(suite.T(), err)
+
+	pub := &importKeysPublisher{}
+	suite.backend.Publisher = pub
+
+	key := generateKey(suite.T())
+	encryptedData, err := encrypted.Marshal([]*sign.PrivateKey{key}, []byte("secret"))
+	assert.Nil(suite.T(), err)
+
+	suite.req.Data = suite.persistedKeysData()
+	suite.req.Data[fieldNameTufKeysImportRoot] = persistedKeys(suite.T(), keyhelper.PersistedKeys{Encrypted: true, Data: encryptedData})
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	if assert.NotNil(suite.T(), resp) {
+		assert.True(suite.T(), resp.IsError())
+	}
+	assert.False(suite.T(), pub.imported)
+
+	suite.req.Data[fieldNameTufKeysImportPassphrase] = "secret"
+
+	resp, err = suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), resp)
+
+	if assert.True(suite.T(), pub.imported) {
+		assert.Equal(suite.T(), publisher.TufRolePrivKeys{key}, pub.privKeys.Root)
+		assert.Len(suite.T(), pub.privKeys.Targets, 1)
+		assert.Len(suite.T(), pub.privKeys.Snapshot, 1)
+		assert.Len(suite.T(), pub.privKeys.Timestamp, 1)
+	}
+}
+
+func (suite *PathConfigureTufKeysImportCallbackSuite) TestKeysAlreadyExist() {
+	err := putConfiguration(suite.ctx, suite.storage, completeConfiguration())
+	assert.Nil(suite.T(), err)
+
+	suite.backend.Publisher = &importKeysPublisher{err: publisher.ErrRepositoryKeysAlreadyExist}
+
+	suite.req.Data = suite.persistedKeysData()
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), logical.ErrorResponse("TUF repository keys already exist"), resp)
+}
+
+func (suite *PathConfigureTufKeysImportCallbackSuite) TestRootNotFound() {
+	cfg := completeConfiguration()
+	cfg.StorageType = "local"
+	cfg.LocalPath = suite.T().TempDir()
+	cfg.StorageMirrors = nil
+	err := putConfiguration(suite.ctx, suite.storage, cfg)
+	assert.Nil(suite.T(), err)
+
+	suite.backend.Publisher = publisher.NewPublisher(suite.backend.Logger())
+
+	suite.req.Data = suite.persistedKeysData()
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), logical.ErrorResponse("Unable to import TUF repository keys: %s", `"root.json" not found in the repository`), resp)
+}
+
+func (suite *PathConfigureTufKeysImportCallbackSuite) persistedKeysData() map[string]interface{} {
+	data := map[string]interface{}{}
+	for _, fieldName := range []string{fieldNameTufKeysImportTargets, fieldNameTufKeysImportSnapshot, fieldNameTufKeysImportTimestamp} {
+		keysData, err := json.Marshal([]*sign.PrivateKey{generateKey(suite.T())})
+		assert.Nil(suite.T(), err)
+
+		data[fieldName] = persistedKeys(suite.T(), keyhelper.PersistedKeys{Data: keysData})
+	}
+
+	return data
+}
+
+func TestBackendPathConfigureTufKeysImportCallback(t *testing.T) {
+	suite.Run(t, new(PathConfigureTufKeysImportCallbackSuite))
+}
+
+type importKeysPublisher struct {
+	publisher.Interface
+	err      error
+	imported bool
+	privKeys publisher.TufRepoPrivKeys
+}
+
+func (p *importKeysPublisher) ImportRepositoryKeys(_ context.Context, _ logical.Storage, _ publisher.RepositoryOptions, privKeys publisher.TufRepoPrivKeys) error {
+	if p.err != nil {
+		return p.err
+	}
+
+	p.imported = true
+	p.privKeys = privKeys
+
+	return nil
+}
+
+func generateKey(t *testing.T) *sign.PrivateKey {
+	key, err := sign.GenerateEd25519Key()
+	assert.Nil(t, err)
+
+	return key
+}
+
+func persistedKeys(t *testing.T, pk keyhelper.PersistedKeys) string {
+	data, err := json.Marshal(pk)
+	assert.Nil(t, err)
+
+	return string(data)
+}
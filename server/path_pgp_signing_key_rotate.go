@@ -0,0 +1,157 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/werf/logboek"
+
+	"github.com/werf/trdl/server/pkg/pgp"
+	"github.com/werf/trdl/server/pkg/publisher"
+	"github.com/werf/trdl/server/pkg/tasks_manager"
+)
+
+const (
+	fieldNameOverlapPeriod          = "overlap_period"
+	fieldNameResignReleases         = "resign_releases"
+	fieldNamePGPSigningKeyAlgorithm = "algorithm"
+
+	defaultPGPSigningKeyOverlapPeriod = 30 * 24 * time.Hour
+)
+
+func pgpSigningKeyRotatePath(b *Backend) *framework.Path {
+	return &framework.Path{
+		Pattern: `pgp_signing_key/rotate$`,
+		Fields: map[string]*framework.FieldSchema{
+			fieldNameOverlapPeriod: {
+				Type:        framework.TypeDurationSecond,
+				Description: "The period the public part of the current key remains published after the rotation (30 days by default, 0 to stop trusting the current key right away)",
+				Default:     int(defaultPGPSigningKeyOverlapPeriod.Seconds()),
+				Required:    false,
+			},
+			fieldNamePGPSigningKeyAlgorithm: {
+				Type:        framework.TypeString,
+				Description: "The algorithm of the new key: " + strings.Join(pgp.SigningKeyAlgorithms, ", ") + " (the algorithm of the current key by default)",
+				Required:    false,
+			},
+			fieldNameResignReleases: {
+				Type:        framework.TypeBool,
+				Description: "Re-sign the existing releases with the new key",
+				Default:     false,
+				Required:    false,
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.CreateOperation: &framework.PathOperation{
+				Callback: b.pathPGPSigningKeyRotate,
+				Summary:  pathPGPSigningKeyRotateHelpSyn,
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathPGPSigningKeyRotate,
+				Summary:  pathPGPSigningKeyRotateHelpSyn,
+			},
+		},
+
+		HelpSynopsis:    pathPGPSigningKeyRotateHelpSyn,
+		HelpDescription: pathPGPSigningKeyRotateHelpDesc,
+	}
+}
+
+func (b *Backend) pathPGPSigningKeyRotate(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
+	overlapPeriod := time.Duration(fields.Get(fieldNameOverlapPeriod).(int)) * time.Second
+	resignReleases := fields.Get(fieldNameResignReleases).(bool)
+
+	algorithm := fields.Get(fieldNamePGPSigningKeyAlgorithm).(string)
+	if algorithm != "" && !pgp.IsValidSigningKeyAlgorithm(algorithm) {
+		return logical.ErrorResponse("Unsupported PGP signing key algorithm %q: expected one of %s", algorithm, strings.Join(pgp.SigningKeyAlgorithms, ", ")), nil
+	}
+
+	cfg, err := getConfiguration(ctx, req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get configuration from storage: %s", err)
+	}
+
+	if cfg == nil && resignReleases {
+		return errorResponseConfigurationNotFound, nil
+	}
+
+	// The public key target is updated right away if the repository is already initialized.
+	// The releases are re-signed with the new key set by RotatePGPSigningKey, which also creates the first key.
+	var publisherRepository publisher.RepositoryInterface
+	if cfg != nil {
+		opts := cfg.RepositoryOptions()
+		opts.InitializeTUFKeys = false
+		opts.SkipPGPSigningKey = true
+
+		publisherRepository, err = b.Publisher.GetRepository(ctx, req.Storage, opts)
+		if err == publisher.ErrUninitializedRepositoryKeys {
+			if resignReleases {
+				return logical.ErrorResponse("TUF repository is not initialized"), nil
+			}
+		} else if err != nil {
+			return nil, fmt.Errorf("error getting publisher repository: %s", err)
+		}
+	}
+
+	taskUUID, err := b.TasksManager.RunTask(context.Background(), req.Storage, func(ctx context.Context, storage logical.Storage) error {
+		logboek.Context(ctx).Default().LogF("Started task\n")
+		b.Logger().Debug("Started task")
+
+		logboek.Context(ctx).Default().LogF("Rotating PGP signing key\n")
+		b.Logger().Debug("Rotating PGP signing key")
+
+		if err := b.Publisher.RotatePGPSigningKey(ctx, storage, algorithm, overlapPeriod); err != nil {
+			return fmt.Errorf("unable to rotate pgp signing key: %s", err)
+		}
+
+		if publisherRepository != nil {
+			if resignReleases {
+				logboek.Context(ctx).Default().LogF("Re-signing releases\n")
+				b.Logger().Debug("Re-signing releases")
+
+				if err := b.Publisher.StageReleaseSignatures(ctx, publisherRepository); err != nil {
+					return fmt.Errorf("unable to re-sign releases: %s", err)
+				}
+			}
+
+			if err := b.Publisher.StagePGPSigningPublicKey(ctx, storage, publisherRepository); err != nil {
+				return fmt.Errorf("unable to publish pgp signing public key: %s", err)
+			}
+
+			logboek.Context(ctx).Default().LogF("Committing TUF repository state\n")
+			b.Logger().Debug("Committing TUF repository state")
+
+			if err := publisherRepository.CommitStaged(ctx); err != nil {
+				return fmt.Errorf("unable to commit new tuf repository state: %s", err)
+			}
+		}
+
+		logboek.Context(ctx).Default().LogF("Task finished\n")
+		b.Logger().Debug("Task finished")
+
+		return nil
+	})
+	if err != nil {
+		if err == tasks_manager.ErrBusy {
+			return logical.ErrorResponse("busy"), nil
+		}
+
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"task_uuid": taskUUID,
+		},
+	}, nil
+}
+
+const (
+	pathPGPSigningKeyRotateHelpSyn  = "Rotate the PGP signing key"
+	pathPGPSigningKeyRotateHelpDesc = "Generate the new PGP key to sign the release artifacts in the background. The public part of the current key is kept published with the pgp_signing_key path and the pgp/signing_key.asc TUF target during the overlap period, so the signatures made by either key are trusted. The algorithm of the new key can be changed. Optionally the existing releases are re-signed with the new key"
+)
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type PathPGPSigningKeyRotateCallbackSuite struct {
+	CommonSuite
+}
+
+func (suite *PathPGPSigningKeyRotateCallbackSuite) SetupTest() {
+	suite.CommonSuite.SetupTest()
+	suite.req.Path = "pgp_signing_key/rotate"
+	suite.req.Operation = logical.UpdateOperation
+}
+
+func (suite *PathPGPSigningKeyRotateCallbackSuite) TestRotate() {
+	suite.mockedTasksManager.On("RunTask")
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), &logical.Response{Data: map[string]interface{}{"task_uuid": "UUID"}}, resp)
+
+	suite.mockedTasksManager.AssertExpectations(suite.T())
+}
+
+func (suite *PathPGPSigningKeyRotateCallbackSuite) TestBusy() {
+	suite.mockedTasksManager.IsBusy = true
+	suite.mockedTasksManager.On("RunTask")
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), logical.ErrorResponse("busy"), resp)
+}
+
+func (suite *PathPGPSigningKeyRotateCallbackSuite) TestUnsupportedAlgorithm() {
+	suite.req.Data = map[string]interface{}{
+		fieldNamePGPSigningKeyAlgorithm: "dsa",
+	}
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), logical.ErrorResponse("Unsupported PGP signing key algorithm %q: expected one of %s", "dsa", "rsa, ed25519, ecdsa-p256"), resp)
+
+	suite.mockedTasksManager.AssertNotCalled(suite.T(), "RunTask")
+}
+
+func (suite *PathPGPSigningKeyRotateCallbackSuite) TestResignReleases_ConfigurationNotFound() {
+	suite.req.Data = map[string]interface{}{
+		fieldNameResignReleases: true,
+	}
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), errorResponseConfigurationNotFound, resp)
+
+	suite.mockedTasksManager.AssertNotCalled(suite.T(), "RunTask")
+}
+
+func TestBackendPathPGPSigningKeyRotateCallback(t *testing.T) {
+	suite.Run(t, new(PathPGPSigningKeyRotateCallbackSuite))
+}
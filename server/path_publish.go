@@ -6,8 +6,8 @@ import (
 	"strings"
 
 	"github.com/Masterminds/semver"
+	"github.com/fatih/structs"
 	"github.com/go-git/go-git/v5"
-	"github.com/go-git/go-git/v5/plumbing/transport/http"
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
@@ -16,8 +16,11 @@ import (
 
 	"github.com/werf/trdl/server/pkg/config"
 	trdlGit "github.com/werf/trdl/server/pkg/git"
+	"github.com/werf/trdl/server/pkg/gitsign"
 	"github.com/werf/trdl/server/pkg/pgp"
 	"github.com/werf/trdl/server/pkg/publisher"
+	"github.com/werf/trdl/server/pkg/signer_group"
+	"github.com/werf/trdl/server/pkg/sshsig"
 	"github.com/werf/trdl/server/pkg/tasks_manager"
 	"github.com/werf/trdl/server/pkg/util"
 )
@@ -30,6 +33,10 @@ func NewErrPublishingNonExistingReleases(releases []string) error {
 	return util.NewLogicalError("publishing non existing releases: %v", releases)
 }
 
+func NewErrPublishingRevokedReleases(releases []string) error {
+	return util.NewLogicalError("publishing revoked releases: %v", releases)
+}
+
 func publishPath(b *Backend) *framework.Path {
 	return &framework.Path{
 		Pattern: `publish$`,
@@ -42,6 +49,10 @@ func publishPath(b *Backend) *framework.Path {
 				Type:        framework.TypeString,
 				Description: "Git password",
 			},
+			fieldNameDryRun: {
+				Type:        framework.TypeBool,
+				Description: "Verify the trdl channels configuration without publishing it. The changes of the published channels are returned as the task result",
+			},
 		},
 
 		Operations: map[logical.Operation]framework.OperationHandler{
@@ -79,12 +90,7 @@ func (b *Backend) pathPublish(ctx context.Context, req *logical.Request, fields
 		return nil, fmt.Errorf("unable to get git credential from storage: %s", err)
 	}
 
-	gitUsername := fields.Get(fieldNameGitUsername).(string)
-	gitPassword := fields.Get(fieldNameGitPassword).(string)
-	if gitCredentialFromStorage != nil && gitUsername == "" && gitPassword == "" {
-		gitUsername = gitCredentialFromStorage.Username
-		gitPassword = gitCredentialFromStorage.Password
-	}
+	gitCredential := resolveGitCredential(gitCredentialFromStorage, fields.Get(fieldNameGitUsername).(string), fields.Get(fieldNameGitPassword).(string))
 
 	lastPublishedGitCommit := cfg.InitialLastPublishedGitCommit
 	{
@@ -98,10 +104,16 @@ func (b *Backend) pathPublish(ctx context.Context, req *logical.Request, fields
 		}
 	}
 
+	dryRun := fields.Get(fieldNameDryRun).(bool)
+
 	opts := cfg.RepositoryOptions()
-	opts.InitializeTUFKeys = true
-	opts.InitializePGPSigningKey = true
+	opts.InitializeTUFKeys = !dryRun
+	opts.InitializePGPSigningKey = !dryRun
+	opts.SkipPGPSigningKey = dryRun
 	publisherRepository, err := b.Publisher.GetRepository(ctx, req.Storage, opts)
+	if dryRun && err == publisher.ErrUninitializedRepositoryKeys {
+		return logical.ErrorResponse("TUF repository is not initialized: there are no releases to publish"), nil
+	}
 	if err != nil {
 		return nil, fmt.Errorf("error getting publisher repository: %s", err)
 	}
@@ -110,76 +122,22 @@ func (b *Backend) pathPublish(ctx context.Context, req *logical.Request, fields
 		logboek.Context(ctx).Default().LogF("Started task\n")
 		b.Logger().Debug("Started task")
 
-		logboek.Context(ctx).Default().LogF("Cloning git repo\n")
-		b.Logger().Debug("Cloning git repo")
-
-		gitBranch := cfg.GitTrdlChannelsBranch
-		gitRepo, err := cloneGitRepositoryBranch(cfg.GitRepoUrl, gitBranch, gitUsername, gitPassword)
+		headCommit, trdlChannelsCfg, err := b.getVerifiedTrdlChannelsConfig(ctx, req.Storage, cfg, publisherRepository, gitCredential, lastPublishedGitCommit)
 		if err != nil {
-			return fmt.Errorf("unable to clone git repository: %s", err)
+			return err
 		}
 
-		headRef, err := gitRepo.Head()
-		if err != nil {
-			return fmt.Errorf("error getting git repo branch %q head reference: %s", gitBranch, err)
+		if dryRun {
+			return b.publishDryRun(ctx, publisherRepository, headCommit, trdlChannelsCfg)
 		}
-		headCommit := headRef.Hash().String()
-
-		if lastPublishedGitCommit == headCommit {
-			logboek.Context(ctx).Default().LogF("Head commit %q not changed: skipping publish task\n", headCommit)
-			b.Logger().Debug(fmt.Sprintf("Head commit %q not changed: skipping publish task", headCommit))
 
+		if trdlChannelsCfg == nil {
 			return nil
 		}
 
-		if lastPublishedGitCommit != "" {
-			logboek.Context(ctx).Default().LogF("Checking previously published commit %q is ancestor to the current head commit %q\n", lastPublishedGitCommit, headCommit)
-			b.Logger().Debug(fmt.Sprintf("Checking previously published commit %q is ancestor to the current head commit %q", lastPublishedGitCommit, headCommit))
-
-			isAncestor, err := trdlGit.IsAncestor(gitRepo, lastPublishedGitCommit, headRef.Hash().String())
-			if err != nil {
-				return err
-			}
-
-			if !isAncestor {
-				return fmt.Errorf("cannot publish git commit %q which is not desdendant of previously published git commit %q", headRef.Hash().String(), lastPublishedGitCommit)
-			}
-		}
-
-		logboek.Context(ctx).Default().LogF("Verifying tag PGP signatures of the commit %q\n", headCommit)
-		b.Logger().Debug(fmt.Sprintf("Verifying tag PGP signatures of the commit %q", headCommit))
-
-		trustedPGPPublicKeys, err := pgp.GetTrustedPGPPublicKeys(ctx, req.Storage)
-		if err != nil {
-			return fmt.Errorf("unable to get trusted PGP public keys: %s", err)
-		}
-
-		if err := trdlGit.VerifyCommitSignatures(gitRepo, headRef.Hash().String(), trustedPGPPublicKeys, cfg.RequiredNumberOfVerifiedSignaturesOnCommit, b.Logger()); err != nil {
-			return fmt.Errorf("signature verification failed: %s", err)
-		}
-
-		logboek.Context(ctx).Default().LogF("Verified commit signatures\n")
-		b.Logger().Debug("Verified commit signatures")
-
-		logboek.Context(ctx).Default().LogF("Getting trdl_channels.yaml configuration from the commit %q\n", headCommit)
-		b.Logger().Debug(fmt.Sprintf("Getting trdl_channels.yaml configuration from the commit %q\n", headCommit))
-
-		cfg, err := GetTrdlChannelsConfig(gitRepo, cfg.GitTrdlChannelsPath)
-		if err != nil {
-			return fmt.Errorf("error getting trdl channels config: %s", err)
-		}
-
-		cfgDump, _ := yaml.Marshal(cfg)
-		logboek.Context(ctx).Default().LogF("Got trdl channels config:\n%s\n---\n", cfgDump)
-		b.Logger().Debug(fmt.Sprintf("Got trdl channels config:\n%s\n---", cfgDump))
-
-		if err := ValidatePublishConfig(ctx, b.Publisher, publisherRepository, cfg, b.Logger()); err != nil {
-			return fmt.Errorf("unable to publish bad config: %s", err)
-		}
-
 		logboek.Context(ctx).Default().LogF("Publishing trdl channels config into the TUF repository\n")
 		b.Logger().Debug("Publishing trdl channels config into the TUF repository")
-		if err := b.Publisher.StageChannelsConfig(ctx, publisherRepository, cfg); err != nil {
+		if err := b.Publisher.StageChannelsConfig(ctx, publisherRepository, trdlChannelsCfg); err != nil {
 			return fmt.Errorf("error publishing trdl channels into the repository: %s", err)
 		}
 
@@ -221,6 +179,164 @@ func (b *Backend) pathPublish(ctx context.Context, req *logical.Request, fields
 	}, nil
 }
 
+// getVerifiedTrdlChannelsConfig clones the trdl channels branch, verifies the head commit and returns the validated trdl channels configuration.
+// The configuration is nil when the head commit has already been published.
+func (b *Backend) getVerifiedTrdlChannelsConfig(ctx context.Context, storage logical.Storage, cfg *configuration, publisherRepository publisher.RepositoryInterface, gitCredential *trdlGit.GitCredential, lastPublishedGitCommit string) (string, *config.TrdlChannels, error) {
+	logboek.Context(ctx).Default().LogF("Cloning git repo\n")
+	b.Logger().Debug("Cloning git repo")
+
+	gitBranch := cfg.GitTrdlChannelsBranch
+	gitRepo, releaseGitRepo, err := cloneGitRepositoryBranch(cfg.GitRepoUrl, cfg.GitCachePath, gitBranch, gitCredential)
+	if err != nil {
+		return "", nil, fmt.Errorf("unable to clone git repository: %s", err)
+	}
+	defer releaseGitRepo()
+
+	headRef, err := gitRepo.Head()
+	if err != nil {
+		return "", nil, fmt.Errorf("error getting git repo branch %q head reference: %s", gitBranch, err)
+	}
+	headCommit := headRef.Hash().String()
+
+	if lastPublishedGitCommit == headCommit {
+		logboek.Context(ctx).Default().LogF("Head commit %q not changed: skipping publish task\n", headCommit)
+		b.Logger().Debug(fmt.Sprintf("Head commit %q not changed: skipping publish task", headCommit))
+
+		return headCommit, nil, nil
+	}
+
+	if lastPublishedGitCommit != "" {
+		logboek.Context(ctx).Default().LogF("Checking previously published commit %q is ancestor to the current head commit %q\n", lastPublishedGitCommit, headCommit)
+		b.Logger().Debug(fmt.Sprintf("Checking previously published commit %q is ancestor to the current head commit %q", lastPublishedGitCommit, headCommit))
+
+		isAncestor, err := trdlGit.IsAncestor(gitRepo, lastPublishedGitCommit, headRef.Hash().String())
+		if err != nil {
+			return "", nil, err
+		}
+
+		if !isAncestor {
+			return "", nil, fmt.Errorf("cannot publish git commit %q which is not desdendant of previously published git commit %q", headRef.Hash().String(), lastPublishedGitCommit)
+		}
+	}
+
+	logboek.Context(ctx).Default().LogF("Verifying signatures of the commit %q\n", headCommit)
+	b.Logger().Debug(fmt.Sprintf("Verifying signatures of the commit %q", headCommit))
+
+	trustedPGPPublicKeys, err := pgp.GetTrustedPGPPublicKeys(ctx, storage)
+	if err != nil {
+		return "", nil, fmt.Errorf("unable to get trusted PGP public keys: %s", err)
+	}
+
+	trustedSSHPublicKeys, err := sshsig.GetTrustedSSHPublicKeys(ctx, storage)
+	if err != nil {
+		return "", nil, fmt.Errorf("unable to get trusted SSH public keys: %s", err)
+	}
+
+	gitsignPolicy, err := gitsign.GetTrustPolicy(ctx, storage)
+	if err != nil {
+		return "", nil, fmt.Errorf("unable to get gitsign trust policy: %s", err)
+	}
+
+	if err := trdlGit.VerifyCommitSignatures(gitRepo, headRef.Hash().String(), trustedPGPPublicKeys, trustedSSHPublicKeys, gitsignPolicy, cfg.RequiredNumberOfVerifiedSignaturesOnCommit, b.Logger()); err != nil {
+		return "", nil, fmt.Errorf("signature verification failed: %s", err)
+	}
+
+	signerGroups, err := signer_group.GetSignerGroups(ctx, storage)
+	if err != nil {
+		return "", nil, fmt.Errorf("unable to get signer groups: %s", err)
+	}
+
+	if len(signerGroups) != 0 {
+		logboek.Context(ctx).Default().LogF("Verifying signer groups of the commit %q\n", headCommit)
+		b.Logger().Debug(fmt.Sprintf("Verifying signer groups of the commit %q", headCommit))
+
+		results, err := trdlGit.VerifyCommitSignerGroups(gitRepo, headRef.Hash().String(), signerGroups, b.Logger())
+		b.logSignerGroupVerificationResults(ctx, results)
+		if err != nil {
+			return "", nil, fmt.Errorf("signature verification failed: %s", err)
+		}
+	}
+
+	logboek.Context(ctx).Default().LogF("Verified commit signatures\n")
+	b.Logger().Debug("Verified commit signatures")
+
+	logboek.Context(ctx).Default().LogF("Getting trdl_channels.yaml configuration from the commit %q\n", headCommit)
+	b.Logger().Debug(fmt.Sprintf("Getting trdl_channels.yaml configuration from the commit %q\n", headCommit))
+
+	trdlChannelsCfg, err := GetTrdlChannelsConfig(gitRepo, cfg.GitTrdlChannelsPath)
+	if err != nil {
+		return "", nil, fmt.Errorf("error getting trdl channels config: %s", err)
+	}
+
+	cfgDump, _ := yaml.Marshal(trdlChannelsCfg)
+	logboek.Context(ctx).Default().LogF("Got trdl channels config:\n%s\n---\n", cfgDump)
+	b.Logger().Debug(fmt.Sprintf("Got trdl channels config:\n%s\n---", cfgDump))
+
+	if err := ValidatePublishConfig(ctx, b.Publisher, publisherRepository, trdlChannelsCfg, b.Logger()); err != nil {
+		return "", nil, fmt.Errorf("unable to publish bad config: %s", err)
+	}
+
+	return headCommit, trdlChannelsCfg, nil
+}
+
+// publishDryRun sets the changes of the published channels as the task result without touching the TUF repository.
+func (b *Backend) publishDryRun(ctx context.Context, publisherRepository publisher.RepositoryInterface, headCommit string, trdlChannelsCfg *config.TrdlChannels) error {
+	changes := []map[string]interface{}{}
+
+	if trdlChannelsCfg != nil {
+		publishedChannels, err := b.Publisher.GetChannels(ctx, publisherRepository)
+		if err != nil {
+			return fmt.Errorf("error getting published channels: %s", err)
+		}
+
+		for _, change := range computeChannelsChanges(publishedChannels, trdlChannelsCfg) {
+			logboek.Context(ctx).Default().LogF("Dry run: group %q channel %q: %q -> %q\n", change.Group, change.Channel, change.FromVersion, change.ToVersion)
+			changes = append(changes, structs.Map(change))
+		}
+	}
+
+	tasks_manager.SetTaskResult(ctx, map[string]interface{}{
+		"head_commit": headCommit,
+		"changes":     changes,
+	})
+
+	logboek.Context(ctx).Default().LogF("Task finished\n")
+	b.Logger().Debug("Task finished")
+
+	return nil
+}
+
+type channelChange struct {
+	Group       string `structs:"group"`
+	Channel     string `structs:"channel"`
+	FromVersion string `structs:"from_version"`
+	ToVersion   string `structs:"to_version"`
+}
+
+// computeChannelsChanges compares the published channels with the trdl channels configuration.
+// An empty FromVersion means the channel is not published yet.
+func computeChannelsChanges(publishedChannels map[string]map[string]string, trdlChannelsCfg *config.TrdlChannels) []channelChange {
+	var changes []channelChange
+
+	for _, group := range trdlChannelsCfg.Groups {
+		for _, channel := range group.Channels {
+			fromVersion := publishedChannels[group.Name][channel.Name]
+			if fromVersion == channel.Version {
+				continue
+			}
+
+			changes = append(changes, channelChange{
+				Group:       group.Name,
+				Channel:     channel.Name,
+				FromVersion: fromVersion,
+				ToVersion:   channel.Version,
+			})
+		}
+	}
+
+	return changes
+}
+
 func ValidatePublishConfig(ctx context.Context, publisher publisher.Interface, publisherRepository publisher.RepositoryInterface, config *config.TrdlChannels, logger hclog.Logger) error {
 	existingReleases, err := publisher.GetExistingReleases(ctx, publisherRepository)
 	if err != nil {
@@ -230,7 +346,13 @@ func ValidatePublishConfig(ctx context.Context, publisher publisher.Interface, p
 	logboek.Context(ctx).Default().LogF("Got existing releases list: %v\n", existingReleases)
 	logger.Debug(fmt.Sprintf("Got existing releases list: %v\n", existingReleases))
 
+	revokedReleases, err := publisher.GetRevokedReleases(ctx, publisherRepository)
+	if err != nil {
+		return fmt.Errorf("error getting revoked releases: %s", err)
+	}
+
 	var nonExistingReleases []string
+	var publishingRevokedReleases []string
 
 	processedGroups := map[string]bool{}
 
@@ -266,6 +388,29 @@ func ValidatePublishConfig(ctx context.Context, publisher publisher.Interface, p
 				return fmt.Errorf("bad version %q, expected semver without \"v\" prefix", channel.Version)
 			}
 
+			isRevoked := false
+			for _, release := range revokedReleases {
+				if channel.Version == release {
+					isRevoked = true
+					break
+				}
+			}
+
+			if isRevoked {
+				appendRevokedRelease := true
+
+				for _, release := range publishingRevokedReleases {
+					if release == channel.Version {
+						appendRevokedRelease = false
+						break
+					}
+				}
+
+				if appendRevokedRelease {
+					publishingRevokedReleases = append(publishingRevokedReleases, channel.Version)
+				}
+			}
+
 			releaseExists := false
 			for _, release := range existingReleases {
 				if channel.Version == release {
@@ -295,6 +440,10 @@ func ValidatePublishConfig(ctx context.Context, publisher publisher.Interface, p
 		processedGroups[group.Name] = true
 	}
 
+	if len(publishingRevokedReleases) > 0 {
+		return NewErrPublishingRevokedReleases(publishingRevokedReleases)
+	}
+
 	if len(nonExistingReleases) > 0 {
 		return NewErrPublishingNonExistingReleases(nonExistingReleases)
 	}
@@ -306,25 +455,14 @@ func NewErrIncorrectChannelName(chnl string) error {
 	return fmt.Errorf(`got incorrect channel name %q: expected "alpha", "beta", "ea", "stable" or "rock-solid"`, chnl)
 }
 
-func cloneGitRepositoryBranch(url, gitBranch, username, password string) (*git.Repository, error) {
+func cloneGitRepositoryBranch(url, gitCachePath, gitBranch string, gitCredential *trdlGit.GitCredential) (*git.Repository, func(), error) {
 	cloneGitOptions := trdlGit.CloneOptions{
 		BranchName:        gitBranch,
 		RecurseSubmodules: git.DefaultSubmoduleRecursionDepth,
+		Credential:        gitCredential,
 	}
 
-	if username != "" && password != "" {
-		cloneGitOptions.Auth = &http.BasicAuth{
-			Username: username,
-			Password: password,
-		}
-	}
-
-	gitRepo, err := trdlGit.CloneInMemory(url, cloneGitOptions)
-	if err != nil {
-		return nil, err
-	}
-
-	return gitRepo, nil
+	return cloneGitRepository(url, gitCachePath, cloneGitOptions)
 }
 
 func GetTrdlChannelsConfig(gitRepo *git.Repository, trdlChannelsPath string) (*config.TrdlChannels, error) {
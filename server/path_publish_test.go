@@ -7,6 +7,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 
+	"github.com/werf/trdl/server/pkg/config"
 	"github.com/werf/trdl/server/pkg/tasks_manager"
 )
 
@@ -49,6 +50,36 @@ func (suite *PathPublishCallbackSuite) TestBasic() {
 	suite.mockedTasksManager.AssertExpectations(suite.T())
 }
 
+func (suite *PathPublishCallbackSuite) TestDryRun() {
+	err := putConfiguration(suite.ctx, suite.storage, completeConfiguration())
+	assert.Nil(suite.T(), err)
+
+	suite.req.Data = map[string]interface{}{fieldNameDryRun: true}
+
+	suite.mockedPublisher.On("GetRepository").Return(nil)
+	suite.mockedTasksManager.On("RunTask").Return("UUID", nil)
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	if assert.NotNil(suite.T(), resp) {
+		assert.Equal(
+			suite.T(),
+			map[string]interface{}{
+				"task_uuid": "UUID",
+			},
+			resp.Data,
+		)
+	}
+
+	// the publisher repository is only read
+	assert.False(suite.T(), suite.mockedPublisher.RepositoryOptions.InitializeTUFKeys)
+	assert.False(suite.T(), suite.mockedPublisher.RepositoryOptions.InitializePGPSigningKey)
+	assert.True(suite.T(), suite.mockedPublisher.RepositoryOptions.SkipPGPSigningKey)
+
+	suite.mockedPublisher.AssertExpectations(suite.T())
+	suite.mockedTasksManager.AssertExpectations(suite.T())
+}
+
 func (suite *PathPublishCallbackSuite) TestBusy() {
 	err := putConfiguration(suite.ctx, suite.storage, completeConfiguration())
 	assert.Nil(suite.T(), err)
@@ -70,3 +101,35 @@ func (suite *PathPublishCallbackSuite) TestBusy() {
 func TestBackendPathPublishCallback(t *testing.T) {
 	suite.Run(t, new(PathPublishCallbackSuite))
 }
+
+func TestComputeChannelsChanges(t *testing.T) {
+	publishedChannels := map[string]map[string]string{
+		"1.2": {
+			"stable": "1.2.3",
+			"alpha":  "1.2.5",
+		},
+	}
+
+	trdlChannelsCfg := &config.TrdlChannels{
+		Groups: []config.TrdlGroup{
+			{
+				Name: "1.2",
+				Channels: []config.TrdlGroupChannel{
+					{Name: "alpha", Version: "1.2.5"},
+					{Name: "stable", Version: "1.2.5"},
+				},
+			},
+			{
+				Name: "1.3",
+				Channels: []config.TrdlGroupChannel{
+					{Name: "alpha", Version: "1.3.0"},
+				},
+			},
+		},
+	}
+
+	assert.Equal(t, []channelChange{
+		{Group: "1.2", Channel: "stable", FromVersion: "1.2.3", ToVersion: "1.2.5"},
+		{Group: "1.3", Channel: "alpha", FromVersion: "", ToVersion: "1.3.0"},
+	}, computeChannelsChanges(publishedChannels, trdlChannelsCfg))
+}
@@ -3,11 +3,14 @@ package server
 import (
 	"archive/tar"
 	"context"
+	"crypto/sha512"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/Masterminds/semver"
 	"github.com/djherbis/buffer"
@@ -15,7 +18,6 @@ import (
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/client"
 	git "github.com/go-git/go-git/v5"
-	"github.com/go-git/go-git/v5/plumbing/transport/http"
 	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
@@ -25,7 +27,11 @@ import (
 	"github.com/werf/trdl/server/pkg/config"
 	"github.com/werf/trdl/server/pkg/docker"
 	trdlGit "github.com/werf/trdl/server/pkg/git"
+	"github.com/werf/trdl/server/pkg/gitsign"
 	"github.com/werf/trdl/server/pkg/pgp"
+	"github.com/werf/trdl/server/pkg/publisher"
+	"github.com/werf/trdl/server/pkg/signer_group"
+	"github.com/werf/trdl/server/pkg/sshsig"
 	"github.com/werf/trdl/server/pkg/tasks_manager"
 	"github.com/werf/trdl/server/pkg/util"
 )
@@ -34,6 +40,7 @@ const (
 	fieldNameGitTag      = "git_tag"
 	fieldNameGitUsername = "git_username"
 	fieldNameGitPassword = "git_password"
+	fieldNameDryRun      = "dry_run"
 )
 
 func releasePath(b *Backend) *framework.Path {
@@ -53,6 +60,10 @@ func releasePath(b *Backend) *framework.Path {
 				Type:        framework.TypeString,
 				Description: "Git password",
 			},
+			fieldNameDryRun: {
+				Type:        framework.TypeBool,
+				Description: "Build and validate the release artifacts without publishing them into the TUF repository. The targets that would have been published are returned as the task result",
+			},
 		},
 
 		Operations: map[logical.Operation]framework.OperationHandler{
@@ -104,21 +115,47 @@ func (b *Backend) pathRelease(ctx context.Context, req *logical.Request, fields
 	}
 	releaseName := strings.TrimPrefix(gitTag, "v")
 
-	gitUsername := fields.Get(fieldNameGitUsername).(string)
-	gitPassword := fields.Get(fieldNameGitPassword).(string)
-	if gitCredentialFromStorage != nil && gitUsername == "" && gitPassword == "" {
-		gitUsername = gitCredentialFromStorage.Username
-		gitPassword = gitCredentialFromStorage.Password
-	}
+	gitCredential := resolveGitCredential(gitCredentialFromStorage, fields.Get(fieldNameGitUsername).(string), fields.Get(fieldNameGitPassword).(string))
+
+	dryRun := fields.Get(fieldNameDryRun).(bool)
 
+	// The TUF repository is only read in the dry run mode
 	opts := cfg.RepositoryOptions()
-	opts.InitializeTUFKeys = true
-	opts.InitializePGPSigningKey = true
+	opts.InitializeTUFKeys = !dryRun
+	opts.InitializePGPSigningKey = !dryRun
+	opts.SkipPGPSigningKey = dryRun
 	publisherRepository, err := b.Publisher.GetRepository(ctx, req.Storage, opts)
-	if err != nil {
+	if err == publisher.ErrUninitializedRepositoryKeys && dryRun {
+		// The repository keys are initialized by the first release, so the repository is empty
+		publisherRepository = nil
+	} else if err != nil {
 		return nil, fmt.Errorf("error getting publisher repository: %s", err)
 	}
 
+	if publisherRepository != nil {
+		existingReleases, err := b.Publisher.GetExistingReleases(ctx, publisherRepository)
+		if err != nil {
+			return nil, fmt.Errorf("error getting existing releases: %s", err)
+		}
+
+		for _, existingRelease := range existingReleases {
+			if existingRelease == releaseName {
+				return logical.ErrorResponse("release %q already exists", releaseName), nil
+			}
+		}
+
+		revokedReleases, err := b.Publisher.GetRevokedReleases(ctx, publisherRepository)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get revoked releases: %s", err)
+		}
+
+		for _, release := range revokedReleases {
+			if release == releaseName {
+				return logical.ErrorResponse("release %q was revoked and cannot be released again", releaseName), nil
+			}
+		}
+	}
+
 	taskUUID, err := b.TasksManager.RunTask(context.Background(), req.Storage, func(ctx context.Context, storage logical.Storage) error {
 		logboek.Context(ctx).Default().LogF("Started task\n")
 		b.Logger().Debug("Started task")
@@ -126,24 +163,51 @@ func (b *Backend) pathRelease(ctx context.Context, req *logical.Request, fields
 		logboek.Context(ctx).Default().LogF("Cloning git repo\n")
 		b.Logger().Debug("Cloning git repo")
 
-		gitRepo, err := cloneGitRepositoryTag(cfg.GitRepoUrl, gitTag, gitUsername, gitPassword)
+		gitRepo, releaseGitRepo, err := cloneGitRepositoryTag(cfg.GitRepoUrl, cfg.GitCachePath, gitTag, gitCredential)
 		if err != nil {
 			return fmt.Errorf("unable to clone git repository: %s", err)
 		}
+		defer releaseGitRepo()
 
-		logboek.Context(ctx).Default().LogF("Verifying tag PGP signatures of the git tag %q\n", gitTag)
-		b.Logger().Debug(fmt.Sprintf("Verifying tag PGP signatures of the git tag %q", gitTag))
+		logboek.Context(ctx).Default().LogF("Verifying signatures of the git tag %q\n", gitTag)
+		b.Logger().Debug(fmt.Sprintf("Verifying signatures of the git tag %q", gitTag))
 
 		trustedPGPPublicKeys, err := pgp.GetTrustedPGPPublicKeys(ctx, req.Storage)
 		if err != nil {
 			return fmt.Errorf("unable to get trusted PGP public keys: %s", err)
 		}
 
+		trustedSSHPublicKeys, err := sshsig.GetTrustedSSHPublicKeys(ctx, req.Storage)
+		if err != nil {
+			return fmt.Errorf("unable to get trusted SSH public keys: %s", err)
+		}
+
+		gitsignPolicy, err := gitsign.GetTrustPolicy(ctx, req.Storage)
+		if err != nil {
+			return fmt.Errorf("unable to get gitsign trust policy: %s", err)
+		}
+
 		b.Logger().Debug(fmt.Sprintf("[DEBUG-SIGNATURES] trustedPGPPublicKeys >%v<", trustedPGPPublicKeys))
-		if err := trdlGit.VerifyTagSignatures(gitRepo, gitTag, trustedPGPPublicKeys, cfg.RequiredNumberOfVerifiedSignaturesOnCommit, b.Logger()); err != nil {
+		if err := trdlGit.VerifyTagSignatures(gitRepo, gitTag, trustedPGPPublicKeys, trustedSSHPublicKeys, gitsignPolicy, cfg.RequiredNumberOfVerifiedSignaturesOnCommit, b.Logger()); err != nil {
 			return fmt.Errorf("signature verification failed: %s", err)
 		}
 
+		signerGroups, err := signer_group.GetSignerGroups(ctx, req.Storage)
+		if err != nil {
+			return fmt.Errorf("unable to get signer groups: %s", err)
+		}
+
+		if len(signerGroups) != 0 {
+			logboek.Context(ctx).Default().LogF("Verifying signer groups of the git tag %q\n", gitTag)
+			b.Logger().Debug(fmt.Sprintf("Verifying signer groups of the git tag %q", gitTag))
+
+			results, err := trdlGit.VerifyTagSignerGroups(gitRepo, gitTag, signerGroups, b.Logger())
+			b.logSignerGroupVerificationResults(ctx, results)
+			if err != nil {
+				return fmt.Errorf("signature verification failed: %s", err)
+			}
+		}
+
 		logboek.Context(ctx).Default().LogF("Getting trdl.yaml configuration from the git tag %q\n", gitTag)
 		b.Logger().Debug(fmt.Sprintf("Getting trdl.yaml configuration from the git tag %q\n", gitTag))
 
@@ -168,6 +232,20 @@ func (b *Backend) pathRelease(ctx context.Context, req *logical.Request, fields
 			}
 		}()
 
+		if dryRun {
+			targets, err := dryRunReleaseArtifacts(ctx, tar.NewReader(tarReader), releaseName, b.Logger())
+			if err != nil {
+				return err
+			}
+
+			tasks_manager.SetTaskResult(ctx, map[string]interface{}{"targets": targets})
+
+			logboek.Context(ctx).Default().LogF("Task finished\n")
+			b.Logger().Debug("Task finished")
+
+			return nil
+		}
+
 		{
 			twArtifacts := tar.NewReader(tarReader)
 			for {
@@ -191,6 +269,10 @@ func (b *Backend) pathRelease(ctx context.Context, req *logical.Request, fields
 				}
 			}
 
+			if err := b.Publisher.StagePGPSigningPublicKey(ctx, storage, publisherRepository); err != nil {
+				return fmt.Errorf("unable to publish pgp signing public key: %s", err)
+			}
+
 			logboek.Context(ctx).Default().LogF("Committing TUF repository state\n")
 			b.Logger().Debug("Committing TUF repository state")
 
@@ -199,6 +281,23 @@ func (b *Backend) pathRelease(ctx context.Context, req *logical.Request, fields
 			}
 		}
 
+		{
+			headRef, err := gitRepo.Head()
+			if err != nil {
+				return fmt.Errorf("unable to get git repository head: %s", err)
+			}
+
+			record := &releaseRecord{
+				GitTag:      gitTag,
+				GitCommit:   headRef.Hash().String(),
+				PublishedAt: time.Now().UTC(),
+			}
+
+			if err := putReleaseRecord(ctx, storage, releaseName, record); err != nil {
+				return fmt.Errorf("unable to put release %q record into storage: %s", releaseName, err)
+			}
+		}
+
 		logboek.Context(ctx).Default().LogF("Task finished\n")
 		b.Logger().Debug("Task finished")
 
@@ -219,25 +318,112 @@ func (b *Backend) pathRelease(ctx context.Context, req *logical.Request, fields
 	}, nil
 }
 
-func cloneGitRepositoryTag(url, gitTag, username, password string) (*git.Repository, error) {
+// dryRunReleaseArtifacts validates the release artifacts and returns the targets that would have been published.
+func dryRunReleaseArtifacts(ctx context.Context, twArtifacts *tar.Reader, releaseName string, logger hclog.Logger) ([]map[string]interface{}, error) {
+	var invalidPaths []string
+	var targets []map[string]interface{}
+
+	for {
+		hdr, err := twArtifacts.Next()
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("error reading next tar artifact header: %s", err)
+		}
+
+		if hdr.Typeflag == tar.TypeDir {
+			continue
+		}
+
+		if err := publisher.ValidateReleaseTargetPath(hdr.Name); err != nil {
+			logboek.Context(ctx).Default().LogF("Invalid release artifact %q: %s\n", hdr.Name, err)
+			logger.Debug(fmt.Sprintf("Invalid release artifact %q: %s", hdr.Name, err))
+
+			invalidPaths = append(invalidPaths, hdr.Name)
+			continue
+		}
+
+		hash := sha512.New()
+		length, err := io.Copy(hash, twArtifacts)
+		if err != nil {
+			return nil, fmt.Errorf("error reading release artifact %q: %s", hdr.Name, err)
+		}
+
+		targets = append(targets, map[string]interface{}{
+			"path":   path.Join("releases", releaseName, hdr.Name),
+			"length": length,
+			"sha512": hex.EncodeToString(hash.Sum(nil)),
+		})
+	}
+
+	if len(invalidPaths) > 0 {
+		return nil, fmt.Errorf("invalid release artifacts: %s", strings.Join(invalidPaths, ", "))
+	}
+
+	logboek.Context(ctx).Default().LogF("Dry run: the following targets would have been published into the TUF repository:\n")
+	for _, target := range targets {
+		logboek.Context(ctx).Default().LogF("  %s length=%d sha512=%s\n", target["path"], target["length"], target["sha512"])
+		logger.Debug(fmt.Sprintf("Dry run target %s", target["path"]))
+	}
+
+	return targets, nil
+}
+
+func cloneGitRepositoryTag(url, gitCachePath, gitTag string, gitCredential *trdlGit.GitCredential) (*git.Repository, func(), error) {
 	cloneGitOptions := trdlGit.CloneOptions{
 		TagName:           gitTag,
 		RecurseSubmodules: git.DefaultSubmoduleRecursionDepth,
+		Credential:        gitCredential,
 	}
 
-	if username != "" && password != "" {
-		cloneGitOptions.Auth = &http.BasicAuth{
-			Username: username,
-			Password: password,
-		}
+	return cloneGitRepository(url, gitCachePath, cloneGitOptions)
+}
+
+// cloneGitRepository clones the git repository into memory or fetches it into the persistent cache if the cache path is configured.
+// The returned release function must be called when the repository is no longer used.
+func cloneGitRepository(url, gitCachePath string, cloneGitOptions trdlGit.CloneOptions) (*git.Repository, func(), error) {
+	if gitCachePath != "" {
+		return trdlGit.CloneWithCache(gitCachePath, url, cloneGitOptions)
 	}
 
 	gitRepo, err := trdlGit.CloneInMemory(url, cloneGitOptions)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return gitRepo, nil
+	return gitRepo, func() {}, nil
+}
+
+// resolveGitCredential returns the stored git credential with the username and password overridden by the request fields.
+func resolveGitCredential(gitCredentialFromStorage *trdlGit.GitCredential, gitUsername, gitPassword string) *trdlGit.GitCredential {
+	gitCredential := &trdlGit.GitCredential{}
+	if gitCredentialFromStorage != nil {
+		*gitCredential = *gitCredentialFromStorage
+	}
+
+	if gitUsername != "" || gitPassword != "" {
+		gitCredential.Username = gitUsername
+		gitCredential.Password = gitPassword
+	}
+
+	return gitCredential
+}
+
+func (b *Backend) logSignerGroupVerificationResults(ctx context.Context, results []*trdlGit.SignerGroupVerificationResult) {
+	for _, result := range results {
+		var msg string
+		if result.Satisfied() {
+			msg = fmt.Sprintf("Signer group %q satisfied: %d required verified signature(s)", result.Name, result.RequiredNumberOfVerifiedSignatures)
+		} else {
+			msg = fmt.Sprintf("Signer group %q not satisfied: %d of %d required verified signature(s) missing", result.Name, result.MissingNumberOfVerifiedSignatures, result.RequiredNumberOfVerifiedSignatures)
+		}
+
+		logboek.Context(ctx).Default().LogF("%s\n", msg)
+		b.Logger().Debug(msg)
+	}
 }
 
 func getTrdlConfig(gitRepo *git.Repository, gitTag string, trdlPath string) (*config.Trdl, error) {
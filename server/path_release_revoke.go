@@ -0,0 +1,148 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/werf/logboek"
+
+	"github.com/werf/trdl/server/pkg/publisher"
+	"github.com/werf/trdl/server/pkg/tasks_manager"
+)
+
+func releaseRevokePath(b *Backend) *framework.Path {
+	return &framework.Path{
+		Pattern: `release/` + framework.GenericNameRegex(fieldNameVersion) + `/revoke$`,
+		Fields: map[string]*framework.FieldSchema{
+			fieldNameVersion: {
+				Type:        framework.TypeString,
+				Description: "Release version",
+				Required:    true,
+			},
+		},
+
+		Operations: map[logical.Operation]framework.OperationHandler{
+			logical.CreateOperation: &framework.PathOperation{
+				Callback: b.pathReleaseRevoke,
+				Summary:  pathReleaseRevokeHelpSyn,
+			},
+			logical.UpdateOperation: &framework.PathOperation{
+				Callback: b.pathReleaseRevoke,
+				Summary:  pathReleaseRevokeHelpSyn,
+			},
+		},
+
+		HelpSynopsis:    pathReleaseRevokeHelpSyn,
+		HelpDescription: pathReleaseRevokeHelpDesc,
+	}
+}
+
+func (b *Backend) pathReleaseRevoke(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
+	cfg, err := getConfiguration(ctx, req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get configuration from storage: %s", err)
+	}
+
+	if cfg == nil {
+		return errorResponseConfigurationNotFound, nil
+	}
+
+	version := fields.Get(fieldNameVersion).(string)
+	if err := ValidateReleaseVersion(version); err != nil {
+		return logical.ErrorResponse("%s validation failed: %s", fieldNameVersion, err), nil
+	}
+	releaseName := strings.TrimPrefix(version, "v")
+
+	publisherRepository, err := b.getPublishedRepository(ctx, req.Storage, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if publisherRepository == nil {
+		return logical.ErrorResponse("release %q not found", releaseName), nil
+	}
+
+	if errResp, err := b.checkReleaseRevocable(ctx, publisherRepository, releaseName); errResp != nil || err != nil {
+		return errResp, err
+	}
+
+	taskUUID, err := b.TasksManager.RunTask(context.Background(), req.Storage, func(ctx context.Context, storage logical.Storage) error {
+		logboek.Context(ctx).Default().LogF("Started task\n")
+		b.Logger().Debug("Started task")
+
+		logboek.Context(ctx).Default().LogF("Revoking release %q\n", releaseName)
+		b.Logger().Debug(fmt.Sprintf("Revoking release %q", releaseName))
+
+		if err := b.Publisher.StageReleaseRevocation(ctx, publisherRepository, releaseName); err != nil {
+			return fmt.Errorf("unable to revoke release %q: %s", releaseName, err)
+		}
+
+		logboek.Context(ctx).Default().LogF("Committing TUF repository state\n")
+		b.Logger().Debug("Committing TUF repository state")
+
+		if err := publisherRepository.CommitStaged(ctx); err != nil {
+			return fmt.Errorf("unable to commit new tuf repository state: %s", err)
+		}
+
+		logboek.Context(ctx).Default().LogF("Marking release %q record as revoked\n", releaseName)
+		b.Logger().Debug(fmt.Sprintf("Marking release %q record as revoked", releaseName))
+
+		if err := markReleaseRecordRevoked(ctx, storage, releaseName); err != nil {
+			return fmt.Errorf("unable to mark release %q record as revoked: %s", releaseName, err)
+		}
+
+		logboek.Context(ctx).Default().LogF("Task finished\n")
+		b.Logger().Debug("Task finished")
+
+		return nil
+	})
+	if err != nil {
+		if err == tasks_manager.ErrBusy {
+			return logical.ErrorResponse("busy"), nil
+		}
+
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"task_uuid": taskUUID,
+		},
+	}, nil
+}
+
+// checkReleaseRevocable returns the error response if the release does not exist or is already revoked.
+// The channels which still reference the release are not checked: the clients report such release as revoked.
+func (b *Backend) checkReleaseRevocable(ctx context.Context, publisherRepository publisher.RepositoryInterface, releaseName string) (*logical.Response, error) {
+	revokedReleases, err := b.Publisher.GetRevokedReleases(ctx, publisherRepository)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get revoked releases: %s", err)
+	}
+
+	for _, release := range revokedReleases {
+		if release == releaseName {
+			return logical.ErrorResponse("release %q is already revoked", releaseName), nil
+		}
+	}
+
+	existingReleases, err := b.Publisher.GetExistingReleases(ctx, publisherRepository)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get existing releases: %s", err)
+	}
+
+	for _, release := range existingReleases {
+		if release == releaseName {
+			return nil, nil
+		}
+	}
+
+	return logical.ErrorResponse("release %q not found", releaseName), nil
+}
+
+const (
+	pathReleaseRevokeHelpSyn  = "Revoke a release"
+	pathReleaseRevokeHelpDesc = "Remove the release targets and signatures from the TUF repository, delete their files from the storage and mark the release as revoked. The revoked release is reported by the releases/VERSION path. The channels cannot be published with the revoked release afterwards"
+)
@@ -0,0 +1,101 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/werf/trdl/server/pkg/config"
+	"github.com/werf/trdl/server/pkg/publisher"
+)
+
+type PathReleaseRevokeCallbackSuite struct {
+	CommonSuite
+}
+
+func (suite *PathReleaseRevokeCallbackSuite) SetupTest() {
+	suite.CommonSuite.SetupTest()
+	suite.req.Path = "release/v1.0.1/revoke"
+	suite.req.Operation = logical.CreateOperation
+}
+
+func (suite *PathReleaseRevokeCallbackSuite) TestConfigurationNotFound() {
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), errorResponseConfigurationNotFound, resp)
+}
+
+func (suite *PathReleaseRevokeCallbackSuite) TestInvalidVersion() {
+	err := putConfiguration(suite.ctx, suite.storage, completeConfiguration())
+	assert.Nil(suite.T(), err)
+
+	suite.req.Path = "release/latest/revoke"
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	if assert.NotNil(suite.T(), resp) {
+		assert.True(suite.T(), resp.IsError())
+	}
+
+	suite.mockedPublisher.AssertNotCalled(suite.T(), "GetRepository")
+}
+
+func (suite *PathReleaseRevokeCallbackSuite) TestUninitializedRepository() {
+	err := putConfiguration(suite.ctx, suite.storage, completeConfiguration())
+	assert.Nil(suite.T(), err)
+
+	suite.mockedPublisher.On("GetRepository").Return(nil)
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), logical.ErrorResponse("release %q not found", "1.0.1"), resp)
+
+	suite.mockedPublisher.AssertExpectations(suite.T())
+	suite.mockedTasksManager.AssertNotCalled(suite.T(), "RunTask")
+}
+
+func TestBackendPathReleaseRevokeCallback(t *testing.T) {
+	suite.Run(t, new(PathReleaseRevokeCallbackSuite))
+}
+
+type releasesPublisher struct {
+	publisher.Interface
+	existing []string
+	revoked  []string
+}
+
+func (p *releasesPublisher) GetExistingReleases(_ context.Context, _ publisher.RepositoryInterface) ([]string, error) {
+	return p.existing, nil
+}
+
+func (p *releasesPublisher) GetRevokedReleases(_ context.Context, _ publisher.RepositoryInterface) ([]string, error) {
+	return p.revoked, nil
+}
+
+func TestValidatePublishConfig_RevokedRelease(t *testing.T) {
+	pub := &releasesPublisher{existing: []string{"1.0.0", "1.0.2"}, revoked: []string{"1.0.1"}}
+
+	trdlChannelsCfg := &config.TrdlChannels{
+		Groups: []config.TrdlGroup{
+			{
+				Name: "1",
+				Channels: []config.TrdlGroupChannel{
+					{Name: "stable", Version: "1.0.0"},
+					{Name: "alpha", Version: "1.0.2"},
+				},
+			},
+		},
+	}
+
+	err := ValidatePublishConfig(context.Background(), pub, nil, trdlChannelsCfg, hclog.NewNullLogger())
+	assert.Nil(t, err)
+
+	trdlChannelsCfg.Groups[0].Channels[0].Version = "1.0.1"
+
+	err = ValidatePublishConfig(context.Background(), pub, nil, trdlChannelsCfg, hclog.NewNullLogger())
+	assert.Equal(t, NewErrPublishingRevokedReleases([]string{"1.0.1"}), err)
+}
@@ -1,8 +1,12 @@
 package server
 
 import (
+	"archive/tar"
+	"bytes"
+	"context"
 	"testing"
 
+	"github.com/hashicorp/go-hclog"
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
@@ -42,7 +46,9 @@ func (suite *PathReleaseCallbackSuite) TestBasic() {
 
 	suite.req.Data = map[string]interface{}{fieldNameGitTag: fieldGitTagValidValue}
 
-	suite.mockedPublisher.On("GetRepository").Return(nil)
+	suite.mockedPublisher.On("GetRepository").Return(&MockedRepository{})
+	suite.mockedPublisher.On("GetExistingReleases").Return(nil)
+	suite.mockedPublisher.On("GetRevokedReleases").Return(nil)
 	suite.mockedTasksManager.On("RunTask").Return("UUID", nil)
 
 	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
@@ -61,6 +67,82 @@ func (suite *PathReleaseCallbackSuite) TestBasic() {
 	suite.mockedTasksManager.AssertExpectations(suite.T())
 }
 
+func (suite *PathReleaseCallbackSuite) TestDryRun() {
+	err := putConfiguration(suite.ctx, suite.storage, completeConfiguration())
+	assert.Nil(suite.T(), err)
+
+	suite.req.Data = map[string]interface{}{
+		fieldNameGitTag: fieldGitTagValidValue,
+		fieldNameDryRun: true,
+	}
+
+	suite.mockedPublisher.On("GetRepository").Return(&MockedRepository{})
+	suite.mockedPublisher.On("GetExistingReleases").Return([]string{"1.0.0"})
+	suite.mockedPublisher.On("GetRevokedReleases").Return(nil)
+	suite.mockedTasksManager.On("RunTask").Return("UUID", nil)
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	if assert.NotNil(suite.T(), resp) {
+		assert.Equal(
+			suite.T(),
+			map[string]interface{}{
+				"task_uuid": "UUID",
+			},
+			resp.Data,
+		)
+	}
+
+	// the publisher repository is only read
+	assert.False(suite.T(), suite.mockedPublisher.RepositoryOptions.InitializeTUFKeys)
+	assert.False(suite.T(), suite.mockedPublisher.RepositoryOptions.InitializePGPSigningKey)
+	assert.True(suite.T(), suite.mockedPublisher.RepositoryOptions.SkipPGPSigningKey)
+
+	suite.mockedPublisher.AssertExpectations(suite.T())
+	suite.mockedTasksManager.AssertExpectations(suite.T())
+}
+
+func (suite *PathReleaseCallbackSuite) TestReleaseAlreadyExists() {
+	err := putConfiguration(suite.ctx, suite.storage, completeConfiguration())
+	assert.Nil(suite.T(), err)
+
+	suite.mockedPublisher.On("GetRepository").Return(&MockedRepository{})
+	suite.mockedPublisher.On("GetExistingReleases").Return([]string{"1.0.1"})
+
+	// the dry run fails the same way as the release
+	for _, dryRun := range []bool{false, true} {
+		suite.req.Data = map[string]interface{}{
+			fieldNameGitTag: fieldGitTagValidValue,
+			fieldNameDryRun: dryRun,
+		}
+
+		resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+		assert.Nil(suite.T(), err)
+		assert.Equal(suite.T(), logical.ErrorResponse("release %q already exists", "1.0.1"), resp)
+	}
+
+	suite.mockedPublisher.AssertExpectations(suite.T())
+	suite.mockedTasksManager.AssertNotCalled(suite.T(), "RunTask")
+}
+
+func (suite *PathReleaseCallbackSuite) TestRevokedRelease() {
+	err := putConfiguration(suite.ctx, suite.storage, completeConfiguration())
+	assert.Nil(suite.T(), err)
+
+	suite.req.Data = map[string]interface{}{fieldNameGitTag: fieldGitTagValidValue}
+
+	suite.mockedPublisher.On("GetRepository").Return(&MockedRepository{})
+	suite.mockedPublisher.On("GetExistingReleases").Return(nil)
+	suite.mockedPublisher.On("GetRevokedReleases").Return([]string{"1.0.1"})
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), logical.ErrorResponse("release %q was revoked and cannot be released again", "1.0.1"), resp)
+
+	suite.mockedPublisher.AssertExpectations(suite.T())
+	suite.mockedTasksManager.AssertNotCalled(suite.T(), "RunTask")
+}
+
 func (suite *PathReleaseCallbackSuite) TestBusy() {
 	err := putConfiguration(suite.ctx, suite.storage, completeConfiguration())
 	assert.Nil(suite.T(), err)
@@ -70,7 +152,9 @@ func (suite *PathReleaseCallbackSuite) TestBusy() {
 
 	suite.req.Data = map[string]interface{}{fieldNameGitTag: fieldGitTagValidValue}
 
-	suite.mockedPublisher.On("GetRepository").Return(nil)
+	suite.mockedPublisher.On("GetRepository").Return(&MockedRepository{})
+	suite.mockedPublisher.On("GetExistingReleases").Return(nil)
+	suite.mockedPublisher.On("GetRevokedReleases").Return(nil)
 	suite.mockedTasksManager.On("RunTask").Return("", tasks_manager.ErrBusy)
 
 	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
@@ -84,3 +168,35 @@ func (suite *PathReleaseCallbackSuite) TestBusy() {
 func TestBackendPathReleaseCallback(t *testing.T) {
 	suite.Run(t, new(PathReleaseCallbackSuite))
 }
+
+func TestDryRunReleaseArtifacts(t *testing.T) {
+	buildTar := func(names ...string) *tar.Reader {
+		buf := bytes.NewBuffer(nil)
+		tw := tar.NewWriter(buf)
+		for _, name := range names {
+			assert.Nil(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: 4, Typeflag: tar.TypeReg}))
+			_, err := tw.Write([]byte("data"))
+			assert.Nil(t, err)
+		}
+		assert.Nil(t, tw.Close())
+		return tar.NewReader(buf)
+	}
+
+	targets, err := dryRunReleaseArtifacts(context.Background(), buildTar("linux-amd64/bin/tool", "any-any/README.md"), "1.0.1", hclog.NewNullLogger())
+	assert.Nil(t, err)
+	assert.Equal(t, []map[string]interface{}{
+		{
+			"path":   "releases/1.0.1/linux-amd64/bin/tool",
+			"length": int64(4),
+			"sha512": "77c7ce9a5d86bb386d443bb96390faa120633158699c8844c30b13ab0bf92760b7e4416aea397db91b4ac0e5dd56b8ef7e4b066162ab1fdc088319ce6defc876",
+		},
+		{
+			"path":   "releases/1.0.1/any-any/README.md",
+			"length": int64(4),
+			"sha512": "77c7ce9a5d86bb386d443bb96390faa120633158699c8844c30b13ab0bf92760b7e4416aea397db91b4ac0e5dd56b8ef7e4b066162ab1fdc088319ce6defc876",
+		},
+	}, targets)
+
+	_, err = dryRunReleaseArtifacts(context.Background(), buildTar("linux-amd64/bin/tool", "bin/tool", "solaris-amd64/bin/tool"), "1.0.1", hclog.NewNullLogger())
+	assert.EqualError(t, err, "invalid release artifacts: bin/tool, solaris-amd64/bin/tool")
+}
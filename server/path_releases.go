@@ -0,0 +1,308 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"github.com/fatih/structs"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+
+	"github.com/werf/trdl/server/pkg/publisher"
+)
+
+const (
+	fieldNameVersion = "version"
+
+	storageKeyPrefixRelease = "releases/"
+)
+
+type releaseRecord struct {
+	GitTag      string     `json:"git_tag"`
+	GitCommit   string     `json:"git_commit"`
+	PublishedAt time.Time  `json:"published_at"`
+	RevokedAt   *time.Time `json:"revoked_at,omitempty"`
+}
+
+type releaseTarget struct {
+	Path          string            `structs:"path"`
+	Length        int64             `structs:"length"`
+	Hashes        map[string]string `structs:"hashes"`
+	SignaturePath string            `structs:"signature_path"`
+}
+
+func releasesPaths(b *Backend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: `releases/?$`,
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ListOperation: &framework.PathOperation{
+					Callback: b.pathReleasesList,
+					Summary:  pathReleasesListHelpSyn,
+				},
+			},
+
+			HelpSynopsis:    pathReleasesListHelpSyn,
+			HelpDescription: pathReleasesListHelpDesc,
+		},
+		{
+			Pattern: `releases/` + framework.GenericNameRegex(fieldNameVersion) + `$`,
+			Fields: map[string]*framework.FieldSchema{
+				fieldNameVersion: {
+					Type:        framework.TypeString,
+					Description: "Release version",
+					Required:    true,
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathReleaseRead,
+					Summary:  pathReleaseReadHelpSyn,
+				},
+			},
+
+			HelpSynopsis:    pathReleaseReadHelpSyn,
+			HelpDescription: pathReleaseReadHelpDesc,
+		},
+		{
+			Pattern: `channels/?$`,
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathChannelsRead,
+					Summary:  pathChannelsReadHelpSyn,
+				},
+			},
+
+			HelpSynopsis:    pathChannelsReadHelpSyn,
+			HelpDescription: pathChannelsReadHelpDesc,
+		},
+	}
+}
+
+// getPublishedRepository returns the publisher repository without initializing keys.
+// The PGP signing key is not required since release artifacts are not signed through this repository.
+// The nil repository is returned if the repository is not initialized yet.
+func (b *Backend) getPublishedRepository(ctx context.Context, storage logical.Storage, cfg *configuration) (publisher.RepositoryInterface, error) {
+	opts := cfg.RepositoryOptions()
+	opts.InitializeTUFKeys = false
+	opts.InitializePGPSigningKey = false
+	opts.SkipPGPSigningKey = true
+
+	repository, err := b.Publisher.GetRepository(ctx, storage, opts)
+	if err == publisher.ErrUninitializedRepositoryKeys {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error getting publisher repository: %s", err)
+	}
+
+	return repository, nil
+}
+
+func (b *Backend) pathReleasesList(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	cfg, err := getConfiguration(ctx, req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get configuration from storage: %s", err)
+	}
+
+	if cfg == nil {
+		return errorResponseConfigurationNotFound, nil
+	}
+
+	repository, err := b.getPublishedRepository(ctx, req.Storage, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if repository == nil {
+		return logical.ListResponse(nil), nil
+	}
+
+	releases, err := b.Publisher.GetExistingReleases(ctx, repository)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get existing releases: %s", err)
+	}
+
+	sortReleases(releases)
+
+	return logical.ListResponse(releases), nil
+}
+
+func (b *Backend) pathReleaseRead(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
+	cfg, err := getConfiguration(ctx, req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get configuration from storage: %s", err)
+	}
+
+	if cfg == nil {
+		return errorResponseConfigurationNotFound, nil
+	}
+
+	version := fields.Get(fieldNameVersion).(string)
+
+	repository, err := b.getPublishedRepository(ctx, req.Storage, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if repository == nil {
+		return nil, nil
+	}
+
+	targets, err := b.Publisher.GetReleaseTargets(ctx, repository, version)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get release %q targets: %s", version, err)
+	}
+
+	record, err := getReleaseRecord(ctx, req.Storage, version)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get release %q record from storage: %s", version, err)
+	}
+
+	// The targets of the revoked release are removed, but the record is kept
+	isRevoked := record != nil && record.RevokedAt != nil
+	if len(targets) == 0 && !isRevoked {
+		return nil, nil
+	}
+
+	targetsByOsArch := make(map[string][]interface{})
+	for _, target := range targets {
+		targetsByOsArch[target.OsArch] = append(targetsByOsArch[target.OsArch], structs.Map(releaseTarget{
+			Path:          target.Path,
+			Length:        target.Length,
+			Hashes:        target.Hashes,
+			SignaturePath: target.SignaturePath,
+		}))
+	}
+
+	data := map[string]interface{}{
+		"version": version,
+		"targets": targetsByOsArch,
+		"revoked": isRevoked,
+	}
+
+	// The record is absent for the releases published before the records were introduced
+	if record != nil && !record.PublishedAt.IsZero() {
+		data["git_tag"] = record.GitTag
+		data["git_commit"] = record.GitCommit
+		data["published_at"] = record.PublishedAt.Format(time.RFC3339)
+	}
+
+	if isRevoked {
+		data["revoked_at"] = record.RevokedAt.Format(time.RFC3339)
+	}
+
+	return &logical.Response{Data: data}, nil
+}
+
+func (b *Backend) pathChannelsRead(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	cfg, err := getConfiguration(ctx, req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get configuration from storage: %s", err)
+	}
+
+	if cfg == nil {
+		return errorResponseConfigurationNotFound, nil
+	}
+
+	repository, err := b.getPublishedRepository(ctx, req.Storage, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	channels := make(map[string]interface{})
+	if repository != nil {
+		groups, err := b.Publisher.GetChannels(ctx, repository)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get channels: %s", err)
+		}
+
+		for group, groupChannels := range groups {
+			channels[group] = groupChannels
+		}
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"channels": channels,
+		},
+	}, nil
+}
+
+// sortReleases sorts the releases by semver, the releases with invalid versions go last.
+func sortReleases(releases []string) {
+	sort.SliceStable(releases, func(i, j int) bool {
+		vi, errI := semver.NewVersion(releases[i])
+		vj, errJ := semver.NewVersion(releases[j])
+
+		switch {
+		case errI != nil && errJ != nil:
+			return releases[i] < releases[j]
+		case errI != nil:
+			return false
+		case errJ != nil:
+			return true
+		default:
+			return vi.LessThan(vj)
+		}
+	})
+}
+
+func getReleaseRecord(ctx context.Context, storage logical.Storage, version string) (*releaseRecord, error) {
+	raw, err := storage.Get(ctx, storageKeyPrefixRelease+version)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	record := new(releaseRecord)
+	if err := raw.DecodeJSON(record); err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+func putReleaseRecord(ctx context.Context, storage logical.Storage, version string, record *releaseRecord) error {
+	entry, err := logical.StorageEntryJSON(storageKeyPrefixRelease+version, record)
+	if err != nil {
+		return err
+	}
+
+	return storage.Put(ctx, entry)
+}
+
+// markReleaseRecordRevoked sets the revocation time of the release record.
+// The record is created if the release has been published before the records were introduced.
+func markReleaseRecordRevoked(ctx context.Context, storage logical.Storage, version string) error {
+	record, err := getReleaseRecord(ctx, storage, version)
+	if err != nil {
+		return err
+	}
+
+	if record == nil {
+		record = &releaseRecord{}
+	}
+
+	revokedAt := time.Now().UTC()
+	record.RevokedAt = &revokedAt
+
+	return putReleaseRecord(ctx, storage, version, record)
+}
+
+const (
+	pathReleasesListHelpSyn  = "List published releases"
+	pathReleasesListHelpDesc = "List the versions of the releases published into the TUF repository"
+
+	pathReleaseReadHelpSyn  = "Read published release"
+	pathReleaseReadHelpDesc = "Read the release targets per os and arch with their hashes, sizes and PGP signature paths, as well as the source git tag, commit and publish time. The revoked release is read without targets along with the revocation time"
+
+	pathChannelsReadHelpSyn  = "Read channels"
+	pathChannelsReadHelpDesc = "Read the current versions of the release channels grouped by the channel group"
+)
@@ -0,0 +1,366 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Masterminds/semver"
+	"github.com/fatih/structs"
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/werf/logboek"
+
+	"github.com/werf/trdl/server/pkg/publisher"
+	"github.com/werf/trdl/server/pkg/tasks_manager"
+	"github.com/werf/trdl/server/pkg/util"
+)
+
+const (
+	fieldNameKeepReleasesPerMajor = "keep_releases_per_major"
+	fieldNamePeriodicInterval     = "periodic_interval"
+
+	storageKeyReleasesGCConfiguration = "releases_gc_configuration"
+	storageKeyReleasesGCLastRun       = "releases_gc_last_run"
+)
+
+type releasesGCConfiguration struct {
+	KeepReleasesPerMajor int `structs:"keep_releases_per_major" json:"keep_releases_per_major"`
+	PeriodicInterval     int `structs:"periodic_interval" json:"periodic_interval"`
+}
+
+func releasesGCPaths(b *Backend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: `configure/releases_gc/?$`,
+			Fields: map[string]*framework.FieldSchema{
+				fieldNameKeepReleasesPerMajor: {
+					Type:        framework.TypeInt,
+					Description: "The number of the latest releases to keep for each major version. The releases referenced by any channel are always kept",
+					Required:    true,
+				},
+				fieldNamePeriodicInterval: {
+					Type:        framework.TypeDurationSecond,
+					Description: "Run the releases garbage collection periodically with the specified interval. The garbage collection is run only on demand by default",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.CreateOperation: &framework.PathOperation{
+					Description: "Configure the releases garbage collection",
+					Callback:    b.pathConfigureReleasesGCCreateOrUpdate,
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Description: "Configure the releases garbage collection",
+					Callback:    b.pathConfigureReleasesGCCreateOrUpdate,
+				},
+				logical.ReadOperation: &framework.PathOperation{
+					Description: "Read the releases garbage collection configuration",
+					Callback:    b.pathConfigureReleasesGCRead,
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Description: "Reset the releases garbage collection configuration",
+					Callback:    b.pathConfigureReleasesGCDelete,
+				},
+			},
+
+			HelpSynopsis:    pathConfigureReleasesGCHelpSyn,
+			HelpDescription: pathConfigureReleasesGCHelpDesc,
+		},
+		{
+			Pattern: `releases_gc$`,
+			Fields: map[string]*framework.FieldSchema{
+				fieldNameDryRun: {
+					Type:        framework.TypeBool,
+					Description: "Return the releases that would have been removed without changing the TUF repository",
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.CreateOperation: &framework.PathOperation{
+					Callback: b.pathReleasesGC,
+					Summary:  pathReleasesGCHelpSyn,
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.pathReleasesGC,
+					Summary:  pathReleasesGCHelpSyn,
+				},
+			},
+
+			HelpSynopsis:    pathReleasesGCHelpSyn,
+			HelpDescription: pathReleasesGCHelpDesc,
+		},
+	}
+}
+
+func (b *Backend) pathConfigureReleasesGCCreateOrUpdate(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
+	if errResp := util.CheckRequiredFields(req, fields); errResp != nil {
+		return errResp, nil
+	}
+
+	gcCfg := &releasesGCConfiguration{
+		KeepReleasesPerMajor: fields.Get(fieldNameKeepReleasesPerMajor).(int),
+		PeriodicInterval:     fields.Get(fieldNamePeriodicInterval).(int),
+	}
+
+	if gcCfg.KeepReleasesPerMajor < 1 {
+		return logical.ErrorResponse("Invalid field %q: expected a positive number, got %d", fieldNameKeepReleasesPerMajor, gcCfg.KeepReleasesPerMajor), nil
+	}
+
+	if gcCfg.PeriodicInterval < 0 {
+		return logical.ErrorResponse("Invalid field %q: expected a non-negative duration, got %ds", fieldNamePeriodicInterval, gcCfg.PeriodicInterval), nil
+	}
+
+	if err := putReleasesGCConfiguration(ctx, req.Storage, gcCfg); err != nil {
+		return nil, fmt.Errorf("unable to put releases gc configuration into storage: %s", err)
+	}
+
+	return nil, nil
+}
+
+func (b *Backend) pathConfigureReleasesGCRead(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	gcCfg, err := getReleasesGCConfiguration(ctx, req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get releases gc configuration: %s", err)
+	}
+
+	if gcCfg == nil {
+		return errorResponseReleasesGCConfigurationNotFound, nil
+	}
+
+	return &logical.Response{Data: structs.Map(gcCfg)}, nil
+}
+
+func (b *Backend) pathConfigureReleasesGCDelete(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	if err := req.Storage.Delete(ctx, storageKeyReleasesGCConfiguration); err != nil {
+		return nil, fmt.Errorf("unable to delete releases gc configuration: %s", err)
+	}
+
+	return nil, nil
+}
+
+func (b *Backend) pathReleasesGC(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
+	cfg, err := getConfiguration(ctx, req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get configuration from storage: %s", err)
+	}
+
+	if cfg == nil {
+		return errorResponseConfigurationNotFound, nil
+	}
+
+	gcCfg, err := getReleasesGCConfiguration(ctx, req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get releases gc configuration from storage: %s", err)
+	}
+
+	if gcCfg == nil {
+		return errorResponseReleasesGCConfigurationNotFound, nil
+	}
+
+	publisherRepository, err := b.getPublishedRepository(ctx, req.Storage, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if publisherRepository == nil {
+		return logical.ErrorResponse("TUF repository is not initialized: there are no releases to remove"), nil
+	}
+
+	if fields.Get(fieldNameDryRun).(bool) {
+		releases, err := b.getReleasesToRemove(ctx, publisherRepository, gcCfg)
+		if err != nil {
+			return nil, err
+		}
+
+		return &logical.Response{
+			Data: map[string]interface{}{
+				"releases": releases,
+			},
+		}, nil
+	}
+
+	taskUUID, err := b.TasksManager.RunTask(context.Background(), req.Storage, func(ctx context.Context, storage logical.Storage) error {
+		logboek.Context(ctx).Default().LogF("Started task\n")
+		b.Logger().Debug("Started task")
+
+		if err := b.releasesGC(ctx, storage, publisherRepository, gcCfg); err != nil {
+			return err
+		}
+
+		logboek.Context(ctx).Default().LogF("Task finished\n")
+		b.Logger().Debug("Task finished")
+
+		return nil
+	})
+	if err != nil {
+		if err == tasks_manager.ErrBusy {
+			return logical.ErrorResponse("busy"), nil
+		}
+
+		return nil, err
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"task_uuid": taskUUID,
+		},
+	}, nil
+}
+
+// releasesGC removes the releases which are out of the retention policy from the TUF repository and deletes their files.
+func (b *Backend) releasesGC(ctx context.Context, storage logical.Storage, publisherRepository publisher.RepositoryInterface, gcCfg *releasesGCConfiguration) error {
+	logboek.Context(ctx).Default().LogF("Started releases garbage collection\n")
+	b.Logger().Debug("Started releases garbage collection")
+
+	releases, err := b.getReleasesToRemove(ctx, publisherRepository, gcCfg)
+	if err != nil {
+		return err
+	}
+
+	if len(releases) > 0 {
+		logboek.Context(ctx).Default().LogF("Removing releases: %v\n", releases)
+		b.Logger().Debug(fmt.Sprintf("Removing releases: %v", releases))
+
+		if err := b.Publisher.StageReleasesRemoval(ctx, publisherRepository, releases); err != nil {
+			return fmt.Errorf("unable to remove releases: %s", err)
+		}
+
+		logboek.Context(ctx).Default().LogF("Committing TUF repository state\n")
+		b.Logger().Debug("Committing TUF repository state")
+
+		if err := publisherRepository.CommitStaged(ctx); err != nil {
+			return fmt.Errorf("unable to commit new tuf repository state: %s", err)
+		}
+
+		for _, release := range releases {
+			if err := storage.Delete(ctx, storageKeyPrefixRelease+release); err != nil {
+				return fmt.Errorf("unable to delete release %q record from storage: %s", release, err)
+			}
+		}
+	} else {
+		logboek.Context(ctx).Default().LogF("No releases to remove\n")
+		b.Logger().Debug("No releases to remove")
+	}
+
+	entry, err := logical.StorageEntryJSON(storageKeyReleasesGCLastRun, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if err := storage.Put(ctx, entry); err != nil {
+		return fmt.Errorf("unable to put %q into storage: %s", storageKeyReleasesGCLastRun, err)
+	}
+
+	return nil
+}
+
+func (b *Backend) getReleasesToRemove(ctx context.Context, publisherRepository publisher.RepositoryInterface, gcCfg *releasesGCConfiguration) ([]string, error) {
+	releases, err := b.Publisher.GetExistingReleases(ctx, publisherRepository)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get existing releases: %s", err)
+	}
+
+	channels, err := b.Publisher.GetChannels(ctx, publisherRepository)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get channels: %s", err)
+	}
+
+	return selectReleasesToRemove(releases, channels, gcCfg.KeepReleasesPerMajor), nil
+}
+
+// isReleasesGCDue returns true if the periodic releases garbage collection is configured and the interval has passed since the last run.
+func isReleasesGCDue(ctx context.Context, storage logical.Storage, gcCfg *releasesGCConfiguration) (bool, error) {
+	if gcCfg == nil || gcCfg.PeriodicInterval == 0 {
+		return false, nil
+	}
+
+	entry, err := storage.Get(ctx, storageKeyReleasesGCLastRun)
+	if err != nil {
+		return false, err
+	}
+
+	if entry == nil {
+		return true, nil
+	}
+
+	var lastRun time.Time
+	if err := entry.DecodeJSON(&lastRun); err != nil {
+		return false, err
+	}
+
+	return time.Since(lastRun) >= time.Duration(gcCfg.PeriodicInterval)*time.Second, nil
+}
+
+// selectReleasesToRemove keeps the last keepPerMajor releases of each major version and the releases referenced by the channels.
+// The releases with non-semver versions are always kept.
+func selectReleasesToRemove(releases []string, channels map[string]map[string]string, keepPerMajor int) []string {
+	referenced := make(map[string]bool)
+	for _, groupChannels := range channels {
+		for _, release := range groupChannels {
+			referenced[release] = true
+		}
+	}
+
+	versionsByMajor := make(map[int64][]*semver.Version)
+	for _, release := range releases {
+		v, err := semver.NewVersion(release)
+		if err != nil {
+			continue
+		}
+		versionsByMajor[v.Major()] = append(versionsByMajor[v.Major()], v)
+	}
+
+	var toRemove []string
+	for _, versions := range versionsByMajor {
+		sort.Sort(sort.Reverse(semver.Collection(versions)))
+
+		for ind, v := range versions {
+			if ind < keepPerMajor || referenced[v.Original()] {
+				continue
+			}
+			toRemove = append(toRemove, v.Original())
+		}
+	}
+
+	sortReleases(toRemove)
+
+	return toRemove
+}
+
+var errorResponseReleasesGCConfigurationNotFound = logical.ErrorResponse("Releases garbage collection configuration not found")
+
+func getReleasesGCConfiguration(ctx context.Context, storage logical.Storage) (*releasesGCConfiguration, error) {
+	raw, err := storage.Get(ctx, storageKeyReleasesGCConfiguration)
+	if err != nil {
+		return nil, err
+	}
+	if raw == nil {
+		return nil, nil
+	}
+
+	gcCfg := new(releasesGCConfiguration)
+	if err := raw.DecodeJSON(gcCfg); err != nil {
+		return nil, err
+	}
+
+	return gcCfg, nil
+}
+
+func putReleasesGCConfiguration(ctx context.Context, storage logical.Storage, gcCfg *releasesGCConfiguration) error {
+	entry, err := logical.StorageEntryJSON(storageKeyReleasesGCConfiguration, gcCfg)
+	if err != nil {
+		return err
+	}
+
+	return storage.Put(ctx, entry)
+}
+
+const (
+	pathConfigureReleasesGCHelpSyn  = "Configure the releases garbage collection"
+	pathConfigureReleasesGCHelpDesc = "Configure the retention policy of the releases published into the TUF repository"
+
+	pathReleasesGCHelpSyn  = "Run the releases garbage collection"
+	pathReleasesGCHelpDesc = "Remove the releases which are out of the retention policy from the TUF repository and delete their files from the storage"
+)
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type PathReleasesGCCallbacksSuite struct {
+	CommonSuite
+}
+
+func (suite *PathReleasesGCCallbacksSuite) TestConfigure() {
+	suite.req.Path = "configure/releases_gc"
+
+	suite.req.Operation = logical.ReadOperation
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), errorResponseReleasesGCConfigurationNotFound, resp)
+
+	suite.req.Operation = logical.CreateOperation
+	suite.req.Data = map[string]interface{}{fieldNameKeepReleasesPerMajor: 0}
+	resp, err = suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	if assert.NotNil(suite.T(), resp) {
+		assert.True(suite.T(), resp.IsError())
+	}
+
+	suite.req.Data = map[string]interface{}{
+		fieldNameKeepReleasesPerMajor: 3,
+		fieldNamePeriodicInterval:     "24h",
+	}
+	resp, err = suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), resp)
+
+	suite.req.Operation = logical.ReadOperation
+	suite.req.Data = nil
+	resp, err = suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	if assert.NotNil(suite.T(), resp) {
+		assert.Equal(suite.T(), map[string]interface{}{
+			fieldNameKeepReleasesPerMajor: 3,
+			fieldNamePeriodicInterval:     86400,
+		}, resp.Data)
+	}
+
+	suite.req.Operation = logical.DeleteOperation
+	resp, err = suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), resp)
+
+	gcCfg, err := getReleasesGCConfiguration(suite.ctx, suite.storage)
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), gcCfg)
+}
+
+func (suite *PathReleasesGCCallbacksSuite) TestReleasesGCConfigurationNotFound() {
+	err := putConfiguration(suite.ctx, suite.storage, completeConfiguration())
+	assert.Nil(suite.T(), err)
+
+	suite.req.Path = "releases_gc"
+	suite.req.Operation = logical.CreateOperation
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), errorResponseReleasesGCConfigurationNotFound, resp)
+}
+
+func (suite *PathReleasesGCCallbacksSuite) TestIsReleasesGCDue() {
+	due, err := isReleasesGCDue(suite.ctx, suite.storage, nil)
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), due)
+
+	// on demand only
+	due, err = isReleasesGCDue(suite.ctx, suite.storage, &releasesGCConfiguration{KeepReleasesPerMajor: 1})
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), due)
+
+	gcCfg := &releasesGCConfiguration{KeepReleasesPerMajor: 1, PeriodicInterval: 3600}
+
+	due, err = isReleasesGCDue(suite.ctx, suite.storage, gcCfg)
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), due)
+
+	for _, test := range []struct {
+		lastRun  time.Time
+		expected bool
+	}{
+		{lastRun: time.Now().Add(-time.Minute), expected: false},
+		{lastRun: time.Now().Add(-2 * time.Hour), expected: true},
+	} {
+		entry, err := logical.StorageEntryJSON(storageKeyReleasesGCLastRun, test.lastRun)
+		assert.Nil(suite.T(), err)
+		assert.Nil(suite.T(), suite.storage.Put(suite.ctx, entry))
+
+		due, err = isReleasesGCDue(suite.ctx, suite.storage, gcCfg)
+		assert.Nil(suite.T(), err)
+		assert.Equal(suite.T(), test.expected, due)
+	}
+}
+
+func TestBackendPathReleasesGCCallbacks(t *testing.T) {
+	suite.Run(t, new(PathReleasesGCCallbacksSuite))
+}
+
+func TestSelectReleasesToRemove(t *testing.T) {
+	releases := []string{"0.1.0", "0.2.0", "0.3.0", "1.0.0", "1.1.0", "1.1.1", "1.2.0", "2.0.0", "custom"}
+	channels := map[string]map[string]string{
+		"1": {
+			"stable": "1.0.0",
+			"alpha":  "1.2.0",
+		},
+	}
+
+	assert.Equal(t, []string{"0.1.0", "1.1.0"}, selectReleasesToRemove(releases, channels, 2))
+	assert.Equal(t, []string{"0.1.0", "0.2.0", "1.1.0", "1.1.1"}, selectReleasesToRemove(releases, channels, 1))
+	assert.Nil(t, selectReleasesToRemove(releases, channels, 3))
+}
@@ -0,0 +1,207 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/werf/trdl/server/pkg/publisher"
+)
+
+type PathReleasesCallbacksSuite struct {
+	CommonSuite
+}
+
+func (suite *PathReleasesCallbacksSuite) TestConfigurationNotFound() {
+	for _, req := range []*logical.Request{
+		{Path: "releases", Operation: logical.ListOperation},
+		{Path: "releases/1.0.1", Operation: logical.ReadOperation},
+		{Path: "channels", Operation: logical.ReadOperation},
+	} {
+		req.Storage = suite.storage
+
+		resp, err := suite.backend.HandleRequest(suite.ctx, req)
+		assert.Nil(suite.T(), err)
+		assert.Equal(suite.T(), errorResponseConfigurationNotFound, resp)
+	}
+}
+
+func (suite *PathReleasesCallbacksSuite) TestUninitializedRepository() {
+	err := putConfiguration(suite.ctx, suite.storage, completeConfiguration())
+	assert.Nil(suite.T(), err)
+
+	suite.mockedPublisher.On("GetRepository").Return(nil)
+
+	suite.req.Path = "releases"
+	suite.req.Operation = logical.ListOperation
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	if assert.NotNil(suite.T(), resp) {
+		assert.Equal(suite.T(), logical.ListResponse(nil), resp)
+	}
+
+	suite.req.Path = "releases/1.0.1"
+	suite.req.Operation = logical.ReadOperation
+	resp, err = suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), resp)
+
+	suite.req.Path = "channels"
+	suite.req.Operation = logical.ReadOperation
+	resp, err = suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	if assert.NotNil(suite.T(), resp) {
+		assert.Equal(suite.T(), map[string]interface{}{"channels": map[string]interface{}{}}, resp.Data)
+	}
+
+	suite.mockedPublisher.AssertExpectations(suite.T())
+}
+
+func (suite *PathReleasesCallbacksSuite) TestReleaseRecord() {
+	record, err := getReleaseRecord(suite.ctx, suite.storage, "1.0.1")
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), record)
+
+	expected := &releaseRecord{
+		GitTag:      "v1.0.1",
+		GitCommit:   "8a0b7e2bba8f3a1a5c6c4d52a13b8bbd1c0ef6a4",
+		PublishedAt: time.Date(2021, 8, 1, 12, 0, 0, 0, time.UTC),
+	}
+	err = putReleaseRecord(suite.ctx, suite.storage, "1.0.1", expected)
+	assert.Nil(suite.T(), err)
+
+	record, err = getReleaseRecord(suite.ctx, suite.storage, "1.0.1")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), expected, record)
+}
+
+func (suite *PathReleasesCallbacksSuite) TestReleaseRead() {
+	err := putConfiguration(suite.ctx, suite.storage, completeConfiguration())
+	assert.Nil(suite.T(), err)
+
+	suite.mockedPublisher.On("GetRepository").Return(&MockedRepository{})
+	suite.mockedPublisher.On("GetReleaseTargets").Return([]*publisher.ReleaseTarget{
+		{OsArch: "linux-amd64", Path: "releases/1.0.1/linux-amd64/bin/trdl", Length: 10, Hashes: map[string]string{"sha512": "aa"}, SignaturePath: "signatures/1.0.1/linux-amd64/bin/trdl.sig"},
+		{OsArch: "linux-amd64", Path: "releases/1.0.1/linux-amd64/bin/trdl-helper", Length: 20, Hashes: map[string]string{"sha512": "bb"}, SignaturePath: "signatures/1.0.1/linux-amd64/bin/trdl-helper.sig"},
+		{OsArch: "darwin-arm64", Path: "releases/1.0.1/darwin-arm64/bin/trdl", Length: 30, Hashes: map[string]string{"sha512": "cc"}},
+	})
+
+	publishedAt := time.Date(2021, 8, 1, 12, 0, 0, 0, time.UTC)
+	err = putReleaseRecord(suite.ctx, suite.storage, "1.0.1", &releaseRecord{
+		GitTag:      "v1.0.1",
+		GitCommit:   "8a0b7e2bba8f3a1a5c6c4d52a13b8bbd1c0ef6a4",
+		PublishedAt: publishedAt,
+	})
+	assert.Nil(suite.T(), err)
+
+	suite.req.Path = "releases/1.0.1"
+	suite.req.Operation = logical.ReadOperation
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	if assert.NotNil(suite.T(), resp) {
+		assert.Equal(suite.T(), map[string]interface{}{
+			"version": "1.0.1",
+			"revoked": false,
+			"targets": map[string][]interface{}{
+				"linux-amd64": {
+					map[string]interface{}{"path": "releases/1.0.1/linux-amd64/bin/trdl", "length": int64(10), "hashes": map[string]string{"sha512": "aa"}, "signature_path": "signatures/1.0.1/linux-amd64/bin/trdl.sig"},
+					map[string]interface{}{"path": "releases/1.0.1/linux-amd64/bin/trdl-helper", "length": int64(20), "hashes": map[string]string{"sha512": "bb"}, "signature_path": "signatures/1.0.1/linux-amd64/bin/trdl-helper.sig"},
+				},
+				"darwin-arm64": {
+					map[string]interface{}{"path": "releases/1.0.1/darwin-arm64/bin/trdl", "length": int64(30), "hashes": map[string]string{"sha512": "cc"}, "signature_path": ""},
+				},
+			},
+			"git_tag":      "v1.0.1",
+			"git_commit":   "8a0b7e2bba8f3a1a5c6c4d52a13b8bbd1c0ef6a4",
+			"published_at": publishedAt.Format(time.RFC3339),
+		}, resp.Data)
+	}
+
+	// The read paths must not depend on the PGP signing key
+	assert.True(suite.T(), suite.mockedPublisher.RepositoryOptions.SkipPGPSigningKey)
+	assert.False(suite.T(), suite.mockedPublisher.RepositoryOptions.InitializeTUFKeys)
+
+	suite.mockedPublisher.AssertExpectations(suite.T())
+}
+
+func (suite *PathReleasesCallbacksSuite) TestReleaseRead_NoRecord() {
+	err := putConfiguration(suite.ctx, suite.storage, completeConfiguration())
+	assert.Nil(suite.T(), err)
+
+	suite.mockedPublisher.On("GetRepository").Return(&MockedRepository{})
+	suite.mockedPublisher.On("GetReleaseTargets").Return([]*publisher.ReleaseTarget{
+		{OsArch: "any-any", Path: "releases/0.1.0/any-any/bin/script.sh", Length: 5, Hashes: map[string]string{"sha512": "dd"}},
+	})
+
+	suite.req.Path = "releases/0.1.0"
+	suite.req.Operation = logical.ReadOperation
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	if assert.NotNil(suite.T(), resp) {
+		assert.Equal(suite.T(), "0.1.0", resp.Data["version"])
+		assert.Len(suite.T(), resp.Data["targets"], 1)
+		for _, field := range []string{"git_tag", "git_commit", "published_at"} {
+			assert.NotContains(suite.T(), resp.Data, field)
+		}
+	}
+}
+
+func (suite *PathReleasesCallbacksSuite) TestReleaseRead_Revoked() {
+	err := putConfiguration(suite.ctx, suite.storage, completeConfiguration())
+	assert.Nil(suite.T(), err)
+
+	suite.mockedPublisher.On("GetRepository").Return(&MockedRepository{})
+	suite.mockedPublisher.On("GetReleaseTargets").Return(nil)
+
+	publishedAt := time.Date(2021, 8, 1, 12, 0, 0, 0, time.UTC)
+	err = putReleaseRecord(suite.ctx, suite.storage, "1.0.1", &releaseRecord{
+		GitTag:      "v1.0.1",
+		GitCommit:   "8a0b7e2bba8f3a1a5c6c4d52a13b8bbd1c0ef6a4",
+		PublishedAt: publishedAt,
+	})
+	assert.Nil(suite.T(), err)
+
+	// The release without targets is not found until the record is marked as revoked
+	suite.req.Path = "releases/1.0.1"
+	suite.req.Operation = logical.ReadOperation
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), resp)
+
+	err = markReleaseRecordRevoked(suite.ctx, suite.storage, "1.0.1")
+	assert.Nil(suite.T(), err)
+
+	record, err := getReleaseRecord(suite.ctx, suite.storage, "1.0.1")
+	assert.Nil(suite.T(), err)
+	if !assert.NotNil(suite.T(), record) || !assert.NotNil(suite.T(), record.RevokedAt) {
+		suite.T().FailNow()
+	}
+	assert.Equal(suite.T(), "v1.0.1", record.GitTag)
+
+	resp, err = suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	if assert.NotNil(suite.T(), resp) {
+		assert.Equal(suite.T(), map[string]interface{}{
+			"version":      "1.0.1",
+			"revoked":      true,
+			"targets":      map[string][]interface{}{},
+			"git_tag":      "v1.0.1",
+			"git_commit":   "8a0b7e2bba8f3a1a5c6c4d52a13b8bbd1c0ef6a4",
+			"published_at": publishedAt.Format(time.RFC3339),
+			"revoked_at":   record.RevokedAt.Format(time.RFC3339),
+		}, resp.Data)
+	}
+}
+
+func TestBackendPathReleasesCallbacks(t *testing.T) {
+	suite.Run(t, new(PathReleasesCallbacksSuite))
+}
+
+func TestSortReleases(t *testing.T) {
+	releases := []string{"1.10.0", "invalid", "1.2.0", "1.2.0-rc.1", "0.9.3"}
+	sortReleases(releases)
+	assert.Equal(t, []string{"0.9.3", "1.2.0-rc.1", "1.2.0", "1.10.0", "invalid"}, releases)
+}
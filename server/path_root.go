@@ -0,0 +1,309 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/theupdateframework/go-tuf/data"
+	"github.com/werf/logboek"
+
+	"github.com/werf/trdl/server/pkg/publisher"
+	"github.com/werf/trdl/server/pkg/tasks_manager"
+)
+
+const (
+	fieldNameRootKeys          = "root_keys"
+	fieldNameRootKeysThreshold = "root_keys_threshold"
+	fieldNameSignatures        = "signatures"
+)
+
+var errorResponsePendingRootNotFound = logical.ErrorResponse("Pending root.json not found: prepare it with the root/unsigned path")
+
+func rootPaths(b *Backend) []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern: `root/unsigned$`,
+			Fields: map[string]*framework.FieldSchema{
+				fieldNameRootKeys: {
+					Type:        framework.TypeSlice,
+					Description: "The TUF public keys to replace the root keys with, e.g. the offline keys printed by the trdl-server public-keys command. The current root keys are kept by default",
+					Required:    false,
+				},
+				fieldNameRootKeysThreshold: {
+					Type:        framework.TypeInt,
+					Description: "The number of the root keys required to sign root.json (tuf_root_keys_threshold of the configuration is used by default)",
+					Required:    false,
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.CreateOperation: &framework.PathOperation{
+					Callback: b.pathRootUnsignedPrepare,
+					Summary:  pathRootUnsignedPrepareHelpSyn,
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.pathRootUnsignedPrepare,
+					Summary:  pathRootUnsignedPrepareHelpSyn,
+				},
+				logical.ReadOperation: &framework.PathOperation{
+					Callback: b.pathRootUnsignedRead,
+					Summary:  pathRootUnsignedReadHelpSyn,
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Callback: b.pathRootUnsignedDelete,
+					Summary:  pathRootUnsignedDeleteHelpSyn,
+				},
+			},
+
+			HelpSynopsis:    pathRootUnsignedHelpSyn,
+			HelpDescription: pathRootUnsignedHelpDesc,
+		},
+		{
+			Pattern: `root/signatures$`,
+			Fields: map[string]*framework.FieldSchema{
+				fieldNameSignatures: {
+					Type:        framework.TypeSlice,
+					Description: "The signatures of the pending root.json in the TUF format ({\"keyid\": ..., \"sig\": ...}), e.g. printed by the trdl-server sign-root command",
+					Required:    true,
+				},
+			},
+
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.CreateOperation: &framework.PathOperation{
+					Callback: b.pathRootSignaturesAdd,
+					Summary:  pathRootSignaturesHelpSyn,
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Callback: b.pathRootSignaturesAdd,
+					Summary:  pathRootSignaturesHelpSyn,
+				},
+			},
+
+			HelpSynopsis:    pathRootSignaturesHelpSyn,
+			HelpDescription: pathRootSignaturesHelpDesc,
+		},
+	}
+}
+
+func (b *Backend) pathRootUnsignedPrepare(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
+	cfg, err := getConfiguration(ctx, req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get configuration from storage: %s", err)
+	}
+
+	if cfg == nil {
+		return errorResponseConfigurationNotFound, nil
+	}
+
+	var opts publisher.PrepareRootOptions
+	if err := decodeSliceField(fields.Get(fieldNameRootKeys).([]interface{}), &opts.RootKeys); err != nil {
+		return logical.ErrorResponse("Invalid field %q: %s", fieldNameRootKeys, err), nil
+	}
+
+	opts.RootKeysThreshold = fields.Get(fieldNameRootKeysThreshold).(int)
+	if opts.RootKeysThreshold < 0 {
+		return logical.ErrorResponse("Invalid field %q: must be positive", fieldNameRootKeysThreshold), nil
+	}
+
+	publisherRepository, err := b.getPublishedRepository(ctx, req.Storage, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if publisherRepository == nil {
+		return logical.ErrorResponse("TUF repository is not initialized"), nil
+	}
+
+	pending, err := b.Publisher.PreparePendingRoot(ctx, req.Storage, publisherRepository, opts)
+	if err != nil {
+		return logical.ErrorResponse("Unable to prepare root.json: %s", err), nil
+	}
+
+	return b.pendingRootResponse(ctx, req.Storage, publisherRepository, pending, true)
+}
+
+func (b *Backend) pathRootUnsignedRead(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	cfg, err := getConfiguration(ctx, req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get configuration from storage: %s", err)
+	}
+
+	if cfg == nil {
+		return errorResponseConfigurationNotFound, nil
+	}
+
+	pending, err := b.Publisher.GetPendingRoot(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if pending == nil {
+		return nil, nil
+	}
+
+	publisherRepository, err := b.getPublishedRepository(ctx, req.Storage, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if publisherRepository == nil {
+		return logical.ErrorResponse("TUF repository is not initialized"), nil
+	}
+
+	return b.pendingRootResponse(ctx, req.Storage, publisherRepository, pending, false)
+}
+
+func (b *Backend) pathRootUnsignedDelete(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	if err := b.Publisher.DeletePendingRoot(ctx, req.Storage); err != nil {
+		return nil, fmt.Errorf("unable to delete pending root.json: %s", err)
+	}
+
+	return nil, nil
+}
+
+func (b *Backend) pathRootSignaturesAdd(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
+	cfg, err := getConfiguration(ctx, req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get configuration from storage: %s", err)
+	}
+
+	if cfg == nil {
+		return errorResponseConfigurationNotFound, nil
+	}
+
+	var signatures []data.Signature
+	if err := decodeSliceField(fields.Get(fieldNameSignatures).([]interface{}), &signatures); err != nil {
+		return logical.ErrorResponse("Invalid field %q: %s", fieldNameSignatures, err), nil
+	}
+
+	pending, err := b.Publisher.GetPendingRoot(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if pending == nil {
+		return errorResponsePendingRootNotFound, nil
+	}
+
+	publisherRepository, err := b.getPublishedRepository(ctx, req.Storage, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if publisherRepository == nil {
+		return logical.ErrorResponse("TUF repository is not initialized"), nil
+	}
+
+	// The pending root is read again along with the signatures adding, it could be changed in the meantime
+	pending, err = b.Publisher.AddPendingRootSignatures(ctx, req.Storage, publisherRepository, signatures)
+	if err == publisher.ErrPendingRootNotFound {
+		return errorResponsePendingRootNotFound, nil
+	} else if err != nil {
+		return logical.ErrorResponse("Unable to add signatures: %s", err), nil
+	}
+
+	return b.pendingRootResponse(ctx, req.Storage, publisherRepository, pending, true)
+}
+
+// pendingRootResponse describes the pending root.json and its signatures.
+// If publish is set, the publishing of the root.json signed by enough keys is started right away.
+func (b *Backend) pendingRootResponse(ctx context.Context, storage logical.Storage, publisherRepository publisher.RepositoryInterface, pending *publisher.PendingRoot, publish bool) (*logical.Response, error) {
+	status, err := publisherRepository.GetRootSignaturesStatus(pending)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get root.json signatures: %s", err)
+	}
+
+	var root data.Root
+	if err := json.Unmarshal(pending.Signed.Signed, &root); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal pending root.json: %s", err)
+	}
+
+	var signedKeyIDs []string
+	for _, signature := range pending.Signed.Signatures {
+		signedKeyIDs = append(signedKeyIDs, signature.KeyID)
+	}
+
+	respData := map[string]interface{}{
+		"signed":                  string(pending.Signed.Signed),
+		"version":                 root.Version,
+		"expires":                 root.Expires.Format(time.RFC3339),
+		"root_key_ids":            root.Roles["root"].KeyIDs,
+		"signed_key_ids":          signedKeyIDs,
+		"trusted_root_signatures": status.TrustedRootSigned,
+		"trusted_root_threshold":  status.TrustedRootThreshold,
+		"new_root_signatures":     status.NewRootSigned,
+		"new_root_threshold":      status.NewRootThreshold,
+	}
+
+	if !publish || !status.IsThresholdMet() {
+		return &logical.Response{Data: respData}, nil
+	}
+
+	taskUUID, err := b.TasksManager.RunTask(context.Background(), storage, func(ctx context.Context, storage logical.Storage) error {
+		logboek.Context(ctx).Default().LogF("Started task\n")
+		b.Logger().Debug("Started task")
+
+		logboek.Context(ctx).Default().LogF("Publishing root.json version %d\n", root.Version)
+		b.Logger().Debug(fmt.Sprintf("Publishing root.json version %d", root.Version))
+
+		if err := b.Publisher.PublishPendingRoot(ctx, storage, publisherRepository); err != nil {
+			return fmt.Errorf("unable to publish root.json: %s", err)
+		}
+
+		logboek.Context(ctx).Default().LogF("Task finished\n")
+		b.Logger().Debug("Task finished")
+
+		return nil
+	})
+	if err != nil {
+		if err == tasks_manager.ErrBusy {
+			return logical.ErrorResponse("busy"), nil
+		}
+
+		return nil, err
+	}
+
+	respData["task_uuid"] = taskUUID
+
+	return &logical.Response{Data: respData}, nil
+}
+
+// decodeSliceField decodes the list of JSON objects, which are passed either as objects or as JSON strings.
+func decodeSliceField(items []interface{}, v interface{}) error {
+	var rawItems []json.RawMessage
+	for _, item := range items {
+		if s, ok := item.(string); ok {
+			rawItems = append(rawItems, json.RawMessage(s))
+			continue
+		}
+
+		raw, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		rawItems = append(rawItems, raw)
+	}
+
+	raw, err := json.Marshal(rawItems)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(raw, v)
+}
+
+const (
+	pathRootUnsignedHelpSyn  = "Prepare the next root.json to sign offline"
+	pathRootUnsignedHelpDesc = "Prepare the next root.json with the new expiration date, keys thresholds and rotated expired online keys. The root.json is signed by the root keys kept by the plugin, other signatures are added with the root/signatures path. Optionally the root keys are replaced with the specified offline keys, so the plugin stops keeping the root keys once the root.json is published"
+
+	pathRootUnsignedPrepareHelpSyn = "Prepare the next root.json"
+	pathRootUnsignedReadHelpSyn    = "Read the pending root.json and its signatures"
+	pathRootUnsignedDeleteHelpSyn  = "Discard the pending root.json"
+
+	pathRootSignaturesHelpSyn  = "Add the pending root.json signatures"
+	pathRootSignaturesHelpDesc = "Verify and add the detached signatures of the pending root.json. The root.json is published in the background as soon as it is signed by the threshold of both the published and the new root keys"
+)
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+	"github.com/theupdateframework/go-tuf/data"
+
+	"github.com/werf/trdl/server/pkg/publisher"
+)
+
+type PathRootCallbacksSuite struct {
+	CommonSuite
+}
+
+func (suite *PathRootCallbacksSuite) TestConfigurationNotFound() {
+	for _, req := range []*logical.Request{
+		{Path: "root/unsigned", Operation: logical.UpdateOperation},
+		{Path: "root/unsigned", Operation: logical.ReadOperation},
+		{Path: "root/signatures", Operation: logical.UpdateOperation, Data: map[string]interface{}{fieldNameSignatures: []interface{}{}}},
+	} {
+		req.Storage = suite.storage
+
+		resp, err := suite.backend.HandleRequest(suite.ctx, req)
+		assert.Nil(suite.T(), err)
+		assert.Equal(suite.T(), errorResponseConfigurationNotFound, resp)
+	}
+}
+
+func (suite *PathRootCallbacksSuite) TestPendingRootNotFound() {
+	err := putConfiguration(suite.ctx, suite.storage, completeConfiguration())
+	assert.Nil(suite.T(), err)
+
+	suite.backend.Publisher = &pendingRootPublisher{}
+
+	suite.req.Path = "root/unsigned"
+	suite.req.Operation = logical.ReadOperation
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), resp)
+
+	suite.req.Path = "root/signatures"
+	suite.req.Operation = logical.UpdateOperation
+	suite.req.Data = map[string]interface{}{
+		fieldNameSignatures: []interface{}{
+			map[string]interface{}{"keyid": "a1d6bc2bad439d63d1c51ba057df3a455aaae031a023a9414843e2232e8c8925", "sig": "4bf9145e"},
+		},
+	}
+	resp, err = suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), errorResponsePendingRootNotFound, resp)
+
+	suite.mockedTasksManager.AssertNotCalled(suite.T(), "RunTask")
+}
+
+func (suite *PathRootCallbacksSuite) TestPrepare_InvalidRootKeys() {
+	err := putConfiguration(suite.ctx, suite.storage, completeConfiguration())
+	assert.Nil(suite.T(), err)
+
+	suite.req.Path = "root/unsigned"
+	suite.req.Operation = logical.UpdateOperation
+	suite.req.Data = map[string]interface{}{
+		fieldNameRootKeys: []interface{}{"not a key"},
+	}
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	if assert.NotNil(suite.T(), resp) {
+		assert.True(suite.T(), resp.IsError())
+	}
+
+	suite.mockedPublisher.AssertNotCalled(suite.T(), "GetRepository")
+}
+
+func TestBackendPathRootCallbacks(t *testing.T) {
+	suite.Run(t, new(PathRootCallbacksSuite))
+}
+
+type pendingRootPublisher struct {
+	publisher.Interface
+	pending *publisher.PendingRoot
+}
+
+func (p *pendingRootPublisher) GetPendingRoot(_ context.Context, _ logical.Storage) (*publisher.PendingRoot, error) {
+	return p.pending, nil
+}
+
+func TestDecodeSliceField(t *testing.T) {
+	var signatures []data.Signature
+	err := decodeSliceField([]interface{}{
+		map[string]interface{}{"keyid": "key1", "sig": "0102"},
+		`{"keyid": "key2", "sig": "0304"}`,
+	}, &signatures)
+	assert.Nil(t, err)
+	assert.Equal(t, []data.Signature{
+		{KeyID: "key1", Signature: data.HexBytes{0x01, 0x02}},
+		{KeyID: "key2", Signature: data.HexBytes{0x03, 0x04}},
+	}, signatures)
+
+	err = decodeSliceField([]interface{}{"invalid"}, &signatures)
+	assert.NotNil(t, err)
+}
@@ -18,39 +18,19 @@ import (
 var systemClock util.Clock = util.NewSystemClock()
 
 const (
-	// TODO: Do not use global run timestamp to specify repository operations period.
-	// TODO: Instead let publisher.Repository decide whether or not it is appropriate time
-	// TODO: to update timestamp, or to rotate private keys.
-	// TODO: Publisher.RotatePrivKeys may be called every minute, but it will actually rotate keys
-	// TODO: when it is appropriate time to rotate (based on each private key expiration date — which is internal data of publisher package).
-	// TODO: The same with publisher.UpdateTimestamps — let this procedure and internal Repository object decide when to update timestamps
-	// TODO: based on timestamp.json data.
-	// TODO:
-	// TODO: For now timestamps being updated every hour forcefully and private keys are not rotated yet (will be implemented soon).
+	// The repository storage is checked for the expiring metadata at most once per periodicRunPeriod.
+	// The private keys rotation dates are kept in the vault storage, so they are checked on every run.
 	lastPeriodicRunTimestampKey = "last_periodic_run_timestamp"
 	periodicRunPeriod           = 1 * time.Hour
 )
 
 func (b *Backend) Periodic(ctx context.Context, req *logical.Request) error {
-	entry, err := req.Storage.Get(ctx, lastPeriodicRunTimestampKey)
-	if err != nil {
-		return fmt.Errorf("unable to get key %q from storage: %s", lastPeriodicRunTimestampKey, err)
-	}
-
-	if entry != nil {
-		lastRunTimestamp, err := strconv.ParseInt(string(entry.Value), 10, 64)
-		if err == nil && systemClock.Since(time.Unix(lastRunTimestamp, 0)) < periodicRunPeriod {
-			b.Logger().Info("Waiting rotate repository keys period: skipping periodic task")
-			return nil
-		}
-	}
-
 	config, err := getConfiguration(ctx, req.Storage)
 	if err != nil {
 		return fmt.Errorf("unable to get configuration: %s", err)
 	}
 	if config == nil {
-		b.Logger().Info("Configuration not set: skipping periodic task")
+		b.Logger().Debug("Configuration not set: skipping periodic task")
 		return nil
 	}
 
@@ -59,21 +39,95 @@ func (b *Backend) Periodic(ctx context.Context, req *logical.Request) error {
 		b.Logger().Debug(fmt.Sprintf("Got configuration (err=%v):\n%s", err, string(cfgData)))
 	}
 
+	isKeysRotationDue, err := b.Publisher.IsRepositoryKeysRotationDue(ctx, req.Storage)
+	if err != nil {
+		return fmt.Errorf("error checking TUF repository keys rotation dates: %s", err)
+	}
+
+	if !isKeysRotationDue {
+		isPeriodicRunDue, err := isPeriodicRunDue(ctx, req.Storage)
+		if err != nil {
+			return err
+		}
+
+		if !isPeriodicRunDue {
+			b.Logger().Debug("Waiting periodic run period: skipping periodic task")
+			return nil
+		}
+	}
+
 	opts := config.RepositoryOptions()
 	opts.InitializeTUFKeys = false
 	opts.InitializePGPSigningKey = true
 	publisherRepository, err := b.Publisher.GetRepository(ctx, req.Storage, opts)
 	if err == publisher.ErrUninitializedRepositoryKeys {
-		b.Logger().Info("Repository is not initialized: skipping periodic task")
+		b.Logger().Debug("Repository is not initialized: skipping periodic task")
 		return nil
 	}
 	if err != nil {
 		return fmt.Errorf("error getting publisher repository: %s", err)
 	}
 
-	now := systemClock.Now()
+	// The repository decides on its own whether it is appropriate time to rotate private keys or to update metadata,
+	// so the periodic task is added only when there is something to do.
+	expiredPrivKeysRoles := publisherRepository.GetExpiredPrivKeysRoles()
+	expiringMetadataRoles, err := publisherRepository.GetExpiringMetadataRoles(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting expiring TUF repository metadata: %s", err)
+	}
+
+	staleStagingFiles, err := publisherRepository.GetStaleStagingFiles(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting stale TUF repository staging files: %s", err)
+	}
+
+	migrateToConsistentSnapshot := false
+	if config.TufConsistentSnapshot {
+		isConsistentSnapshot, err := publisherRepository.IsConsistentSnapshot()
+		if err != nil {
+			return fmt.Errorf("error checking TUF repository layout: %s", err)
+		}
+
+		isRootOffline, err := publisherRepository.IsRootOffline()
+		if err != nil {
+			return fmt.Errorf("error checking TUF repository root keys: %s", err)
+		}
+
+		// The repository with the offline root is migrated by the offline signed root.json
+		migrateToConsistentSnapshot = !isConsistentSnapshot && !isRootOffline
+	}
+
+	divergedStorageMirrors, err := publisherRepository.GetDivergedStorageMirrors(ctx)
+	if err != nil {
+		return fmt.Errorf("error checking storage mirrors: %s", err)
+	}
+
+	isPGPSigningPublicKeyPublished, err := b.Publisher.IsPGPSigningPublicKeyPublished(ctx, req.Storage, publisherRepository)
+	if err != nil {
+		return fmt.Errorf("error checking pgp signing public key target: %s", err)
+	}
+
+	releasesGCConfig, err := getReleasesGCConfiguration(ctx, req.Storage)
+	if err != nil {
+		return fmt.Errorf("unable to get releases gc configuration: %s", err)
+	}
+
+	releasesGCDue, err := isReleasesGCDue(ctx, req.Storage, releasesGCConfig)
+	if err != nil {
+		return fmt.Errorf("unable to check releases gc last run: %s", err)
+	}
+
+	if len(expiredPrivKeysRoles) == 0 && len(expiringMetadataRoles) == 0 && len(staleStagingFiles) == 0 && !migrateToConsistentSnapshot && len(divergedStorageMirrors) == 0 && isPGPSigningPublicKeyPublished && !releasesGCDue {
+		b.Logger().Debug("TUF repository keys and metadata are up to date: skipping periodic task")
+		return putLastPeriodicRunTimestamp(ctx, req.Storage)
+	}
+
+	if !releasesGCDue {
+		releasesGCConfig = nil
+	}
+
 	uuid, err := b.TasksManager.RunTask(ctx, req.Storage, func(ctx context.Context, storage logical.Storage) error {
-		err := b.periodicTask(ctx, storage, config, publisherRepository)
+		err := b.periodicTask(ctx, storage, config, releasesGCConfig, publisherRepository)
 		if err != nil {
 			b.Logger().Error(fmt.Sprintf("Periodic task failed: %s", err))
 		} else {
@@ -83,7 +137,7 @@ func (b *Backend) Periodic(ctx context.Context, req *logical.Request) error {
 	})
 
 	if err == tasks_manager.ErrBusy {
-		b.Logger().Debug(fmt.Sprintf("Will not add new periodic task: there is currently running task which took more than %s", periodicRunPeriod))
+		b.Logger().Debug("Will not add new periodic task: there is currently running task")
 		return nil
 	}
 
@@ -91,16 +145,86 @@ func (b *Backend) Periodic(ctx context.Context, req *logical.Request) error {
 		return fmt.Errorf("unable to add queue manager periodic task: %s", err)
 	}
 
-	if err := req.Storage.Put(ctx, &logical.StorageEntry{Key: lastPeriodicRunTimestampKey, Value: []byte(fmt.Sprintf("%d", now.Unix()))}); err != nil {
-		return fmt.Errorf("unable to put last periodic task run timestamp in storage by key %q: %s", lastPeriodicRunTimestampKey, err)
+	b.Logger().Debug(fmt.Sprintf("Added new periodic task with uuid %s", uuid))
+
+	return putLastPeriodicRunTimestamp(ctx, req.Storage)
+}
+
+func isPeriodicRunDue(ctx context.Context, storage logical.Storage) (bool, error) {
+	entry, err := storage.Get(ctx, lastPeriodicRunTimestampKey)
+	if err != nil {
+		return false, fmt.Errorf("unable to get key %q from storage: %s", lastPeriodicRunTimestampKey, err)
 	}
 
-	b.Logger().Debug(fmt.Sprintf("Added new periodic task with uuid %s", uuid))
+	if entry == nil {
+		return true, nil
+	}
+
+	lastRunTimestamp, err := strconv.ParseInt(string(entry.Value), 10, 64)
+	if err != nil {
+		return true, nil
+	}
+
+	return systemClock.Since(time.Unix(lastRunTimestamp, 0)) >= periodicRunPeriod, nil
+}
+
+func putLastPeriodicRunTimestamp(ctx context.Context, storage logical.Storage) error {
+	if err := storage.Put(ctx, &logical.StorageEntry{Key: lastPeriodicRunTimestampKey, Value: []byte(fmt.Sprintf("%d", systemClock.Now().Unix()))}); err != nil {
+		return fmt.Errorf("unable to put last periodic task run timestamp in storage by key %q: %s", lastPeriodicRunTimestampKey, err)
+	}
 
 	return nil
 }
 
-func (b *Backend) periodicTask(ctx context.Context, storage logical.Storage, _ *configuration, publisherRepository publisher.RepositoryInterface) error {
+// periodicTask runs the releases garbage collection as well, if releasesGCConfig is passed.
+func (b *Backend) periodicTask(ctx context.Context, storage logical.Storage, config *configuration, releasesGCConfig *releasesGCConfiguration, publisherRepository publisher.RepositoryInterface) error {
+	// The periodic task runs exclusively, so all the staging files are left by the failed tasks
+	if err := publisherRepository.DeleteStaleStagingFiles(ctx); err != nil {
+		return fmt.Errorf("unable to delete stale TUF repository staging files: %s", err)
+	}
+
+	logboek.Context(ctx).Default().LogF("Started storage mirrors sync\n")
+	b.Logger().Debug("Started storage mirrors sync")
+
+	// Mirrors left behind by a failed commit are synced before any new commit
+	if err := b.Publisher.SyncStorageMirrors(ctx, storage, publisherRepository); err != nil {
+		return fmt.Errorf("unable to sync storage mirrors: %s", err)
+	}
+
+	if config.TufConsistentSnapshot {
+		logboek.Context(ctx).Default().LogF("Started TUF repository consistent snapshot migration\n")
+		b.Logger().Debug("Started TUF repository consistent snapshot migration")
+
+		if err := b.Publisher.MigrateToConsistentSnapshot(ctx, storage, publisherRepository); err != nil {
+			return fmt.Errorf("unable to migrate TUF repository to consistent snapshot: %s", err)
+		}
+	}
+
+	if releasesGCConfig != nil {
+		if err := b.releasesGC(ctx, storage, publisherRepository, releasesGCConfig); err != nil {
+			return fmt.Errorf("unable to collect releases garbage: %s", err)
+		}
+	}
+
+	// The retired pgp signing keys are dropped from the public key target once the overlap period is over
+	isPGPSigningPublicKeyPublished, err := b.Publisher.IsPGPSigningPublicKeyPublished(ctx, storage, publisherRepository)
+	if err != nil {
+		return fmt.Errorf("error checking pgp signing public key target: %s", err)
+	}
+
+	if !isPGPSigningPublicKeyPublished {
+		logboek.Context(ctx).Default().LogF("Started pgp signing public key publishing\n")
+		b.Logger().Debug("Started pgp signing public key publishing")
+
+		if err := b.Publisher.StagePGPSigningPublicKey(ctx, storage, publisherRepository); err != nil {
+			return fmt.Errorf("unable to publish pgp signing public key: %s", err)
+		}
+
+		if err := publisherRepository.CommitStaged(ctx); err != nil {
+			return fmt.Errorf("unable to commit new tuf repository state: %s", err)
+		}
+	}
+
 	logboek.Context(ctx).Default().LogF("Started TUF repository keys rotation\n")
 	b.Logger().Debug("Started TUF repository keys rotation")
 
@@ -108,8 +232,8 @@ func (b *Backend) periodicTask(ctx context.Context, storage logical.Storage, _ *
 		return fmt.Errorf("unable to rotate TUF repository private keys: %s", err)
 	}
 
-	logboek.Context(ctx).Default().LogF("Started TUF repository timestamps update\n")
-	b.Logger().Debug("Started TUF repository timestamps update")
+	logboek.Context(ctx).Default().LogF("Started TUF repository expiring metadata update\n")
+	b.Logger().Debug("Started TUF repository expiring metadata update")
 
 	if err := b.Publisher.UpdateTimestamps(ctx, storage, publisherRepository); err != nil {
 		return fmt.Errorf("unable to update TUF repository timestamps: %s", err)
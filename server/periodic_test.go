@@ -0,0 +1,92 @@
+package server
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type PeriodicSuite struct {
+	CommonSuite
+}
+
+func (suite *PeriodicSuite) SetupTest() {
+	suite.CommonSuite.SetupTest()
+
+	err := putConfiguration(suite.ctx, suite.storage, completeConfiguration())
+	assert.Nil(suite.T(), err)
+}
+
+func (suite *PeriodicSuite) TestWaitingPeriod() {
+	err := putLastPeriodicRunTimestamp(suite.ctx, suite.storage)
+	assert.Nil(suite.T(), err)
+
+	// The repository storage is not accessed within the period
+	suite.mockedPublisher.On("IsRepositoryKeysRotationDue").Return(false)
+
+	err = suite.backend.Periodic(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+
+	suite.mockedPublisher.AssertExpectations(suite.T())
+	suite.mockedPublisher.AssertNotCalled(suite.T(), "GetRepository")
+}
+
+func (suite *PeriodicSuite) TestPeriodPassed() {
+	lastRunTimestamp := time.Now().Add(-periodicRunPeriod).Unix()
+	err := suite.storage.Put(suite.ctx, &logical.StorageEntry{Key: lastPeriodicRunTimestampKey, Value: []byte(fmt.Sprintf("%d", lastRunTimestamp))})
+	assert.Nil(suite.T(), err)
+
+	mockedRepository := &MockedRepository{}
+	mockedRepository.On("GetExpiredPrivKeysRoles").Return(nil)
+	mockedRepository.On("GetExpiringMetadataRoles").Return(nil)
+	mockedRepository.On("GetStaleStagingFiles").Return(nil)
+	mockedRepository.On("IsConsistentSnapshot").Return(true)
+	mockedRepository.On("IsRootOffline").Return(false)
+	mockedRepository.On("GetDivergedStorageMirrors").Return(nil)
+	suite.mockedPublisher.On("IsRepositoryKeysRotationDue").Return(false)
+	suite.mockedPublisher.On("IsPGPSigningPublicKeyPublished").Return(true)
+	suite.mockedPublisher.On("GetRepository").Return(mockedRepository)
+
+	err = suite.backend.Periodic(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+
+	suite.mockedPublisher.AssertExpectations(suite.T())
+	mockedRepository.AssertExpectations(suite.T())
+
+	// Nothing to do, but the next check is postponed anyway
+	isDue, err := isPeriodicRunDue(suite.ctx, suite.storage)
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), isDue)
+}
+
+func (suite *PeriodicSuite) TestKeysRotationDue() {
+	err := putLastPeriodicRunTimestamp(suite.ctx, suite.storage)
+	assert.Nil(suite.T(), err)
+
+	// The expired keys are rotated regardless of the period
+	mockedRepository := &MockedRepository{}
+	mockedRepository.On("GetExpiredPrivKeysRoles").Return([]string{"timestamp"})
+	mockedRepository.On("GetExpiringMetadataRoles").Return(nil)
+	mockedRepository.On("GetStaleStagingFiles").Return(nil)
+	mockedRepository.On("IsConsistentSnapshot").Return(true)
+	mockedRepository.On("IsRootOffline").Return(false)
+	mockedRepository.On("GetDivergedStorageMirrors").Return(nil)
+	suite.mockedPublisher.On("IsRepositoryKeysRotationDue").Return(true)
+	suite.mockedPublisher.On("IsPGPSigningPublicKeyPublished").Return(true)
+	suite.mockedPublisher.On("GetRepository").Return(mockedRepository)
+	suite.mockedTasksManager.On("RunTask").Return("UUID", nil)
+
+	err = suite.backend.Periodic(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+
+	suite.mockedPublisher.AssertExpectations(suite.T())
+	suite.mockedTasksManager.AssertExpectations(suite.T())
+}
+
+func TestBackendPeriodic(t *testing.T) {
+	suite.Run(t, new(PeriodicSuite))
+}
@@ -0,0 +1,180 @@
+package git
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-billy/v5/osfs"
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/cache"
+	"github.com/go-git/go-git/v5/plumbing/format/index"
+	"github.com/go-git/go-git/v5/storage/filesystem"
+)
+
+var cacheMutexes sync.Map
+
+// CacheRepositoryPath returns the path of the bare repository cache for the url.
+func CacheRepositoryPath(cacheDir, url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(cacheDir, hex.EncodeToString(sum[:]))
+}
+
+// CloneWithCache fetches the tag or the branch incrementally into the persistent bare repository cache in the cacheDir
+// and checks out the worktree in memory.
+// The cache is locked until the returned release function is called, the repository must not be used after that.
+func CloneWithCache(cacheDir, url string, opts CloneOptions) (*git.Repository, func(), error) {
+	repoPath := CacheRepositoryPath(cacheDir, url)
+
+	unlock, err := lockCache(repoPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to lock git cache %q: %s", repoPath, err)
+	}
+
+	repo, err := cloneWithCache(repoPath, url, opts)
+	if err != nil {
+		unlock()
+		return nil, nil, err
+	}
+
+	return repo, unlock, nil
+}
+
+func cloneWithCache(repoPath, url string, opts CloneOptions) (*git.Repository, error) {
+	var referenceName plumbing.ReferenceName
+	switch {
+	case opts.TagName != "":
+		referenceName = plumbing.ReferenceName(fmt.Sprintf("refs/tags/%s", opts.TagName))
+	case opts.BranchName != "":
+		referenceName = plumbing.ReferenceName(fmt.Sprintf("refs/heads/%s", opts.BranchName))
+	case opts.ReferenceName != "":
+		referenceName = plumbing.ReferenceName(opts.ReferenceName)
+	default:
+		return nil, fmt.Errorf("tag, branch or reference name required")
+	}
+
+	auth := opts.Auth
+	credential := opts.Credential
+	if credential != nil {
+		var err error
+		if auth, err = credential.AuthMethod(url); err != nil {
+			return nil, err
+		}
+	} else {
+		credential = &GitCredential{}
+	}
+
+	storage := filesystem.NewStorage(osfs.New(repoPath), cache.NewObjectLRUDefault())
+
+	repo, err := git.Open(storage, memfs.New())
+	if err == git.ErrRepositoryNotExists {
+		if _, err := git.Init(storage, nil); err != nil {
+			return nil, fmt.Errorf("unable to init git cache repository: %s", err)
+		}
+
+		repo, err = git.Open(storage, memfs.New())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to open git cache repository: %s", err)
+	}
+
+	if _, err := repo.Remote(git.DefaultRemoteName); err == git.ErrRemoteNotFound {
+		if _, err := repo.CreateRemote(&config.RemoteConfig{Name: git.DefaultRemoteName, URLs: []string{url}}); err != nil {
+			return nil, fmt.Errorf("unable to create git cache repository remote: %s", err)
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to get git cache repository remote: %s", err)
+	}
+
+	// Only the required reference is fetched, all tags are fetched to get the signatures
+	if err := repo.Fetch(&git.FetchOptions{
+		RemoteName: git.DefaultRemoteName,
+		RefSpecs:   []config.RefSpec{config.RefSpec(fmt.Sprintf("+%s:%s", referenceName, referenceName))},
+		Tags:       git.AllTags,
+		Auth:       auth,
+		Force:      true,
+	}); err != nil && err != git.NoErrAlreadyUpToDate {
+		return nil, fmt.Errorf("unable to fetch %q: %s", referenceName, err)
+	}
+
+	hash, err := repo.ResolveRevision(plumbing.Revision(referenceName))
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve %q: %s", referenceName, err)
+	}
+
+	// The index of the previous checkout does not match the new in-memory worktree
+	if err := storage.SetIndex(&index.Index{Version: 2}); err != nil {
+		return nil, fmt.Errorf("unable to reset git cache repository index: %s", err)
+	}
+
+	w, err := repo.Worktree()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get git repository worktree: %s", err)
+	}
+
+	if err := w.Checkout(&git.CheckoutOptions{Hash: *hash, Force: true}); err != nil {
+		return nil, fmt.Errorf("unable to checkout %q: %s", referenceName, err)
+	}
+
+	if opts.RecurseSubmodules != git.NoRecurseSubmodules {
+		if err := updateSubmodules(repo, url, credential, opts.RecurseSubmodules); err != nil {
+			return nil, fmt.Errorf("unable to update submodules: %s", err)
+		}
+	}
+
+	return repo, nil
+}
+
+// PruneCache removes the repository cache for the url.
+func PruneCache(cacheDir, url string) error {
+	repoPath := CacheRepositoryPath(cacheDir, url)
+
+	unlock, err := lockCache(repoPath)
+	if err != nil {
+		return fmt.Errorf("unable to lock git cache %q: %s", repoPath, err)
+	}
+	defer unlock()
+
+	if err := os.RemoveAll(repoPath); err != nil {
+		return fmt.Errorf("unable to remove git cache %q: %s", repoPath, err)
+	}
+
+	return nil
+}
+
+// lockCache guards the repository cache against concurrent use both inside the process and by other plugin processes sharing the cache directory.
+func lockCache(repoPath string) (func(), error) {
+	m, _ := cacheMutexes.LoadOrStore(repoPath, &sync.Mutex{})
+	mu := m.(*sync.Mutex)
+	mu.Lock()
+
+	if err := os.MkdirAll(filepath.Dir(repoPath), 0o700); err != nil {
+		mu.Unlock()
+		return nil, err
+	}
+
+	f, err := os.OpenFile(repoPath+".lock", os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		mu.Unlock()
+		return nil, err
+	}
+
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		f.Close()
+		mu.Unlock()
+		return nil, err
+	}
+
+	return func() {
+		_ = syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		_ = f.Close()
+		mu.Unlock()
+	}, nil
+}
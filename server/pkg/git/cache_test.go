@@ -0,0 +1,93 @@
+package git
+
+import (
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCloneWithCache(t *testing.T) {
+	root := t.TempDir()
+	cacheDir := filepath.Join(t.TempDir(), "cache")
+
+	mainRepo := filepath.Join(root, "main")
+	subRepo := filepath.Join(root, "sub")
+	for _, dir := range []string{mainRepo, subRepo} {
+		runGit(t, root, "init", "-q", dir)
+		runGit(t, dir, "commit", "-q", "--allow-empty", "-m", "init")
+	}
+
+	runGit(t, mainRepo, "submodule", "add", "-q", "../sub", "sub")
+	runGit(t, mainRepo, "commit", "-q", "-m", "Add submodule")
+	runGit(t, mainRepo, "tag", "-a", "v1.0.0", "-m", "v1.0.0")
+
+	clone := func(opts CloneOptions, expectedFile string) {
+		opts.RecurseSubmodules = git.DefaultSubmoduleRecursionDepth
+
+		repo, release, err := CloneWithCache(cacheDir, mainRepo, opts)
+		if !assert.Nil(t, err) {
+			t.FailNow()
+		}
+		defer release()
+
+		_, err = ReadWorktreeFile(repo, expectedFile)
+		assert.Nil(t, err)
+
+		if opts.TagName != "" {
+			_, err = repo.Tag(opts.TagName)
+			assert.Nil(t, err)
+		}
+
+		submodules, err := worktreeSubmodules(repo)
+		assert.Nil(t, err)
+		assert.Len(t, submodules, 1)
+	}
+
+	clone(CloneOptions{TagName: "v1.0.0"}, ".gitmodules")
+
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(mainRepo, "file"), []byte("data"), 0o644))
+	runGit(t, mainRepo, "add", "file")
+	runGit(t, mainRepo, "commit", "-q", "-m", "Add file")
+	runGit(t, mainRepo, "tag", "-a", "v1.1.0", "-m", "v1.1.0")
+
+	// The cache is fetched incrementally
+	clone(CloneOptions{TagName: "v1.1.0"}, "file")
+	clone(CloneOptions{BranchName: "master"}, "file")
+	clone(CloneOptions{TagName: "v1.0.0"}, ".gitmodules")
+
+	_, err := os.Stat(CacheRepositoryPath(cacheDir, mainRepo))
+	assert.Nil(t, err)
+
+	assert.Nil(t, PruneCache(cacheDir, mainRepo))
+
+	_, err = os.Stat(CacheRepositoryPath(cacheDir, mainRepo))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func worktreeSubmodules(repo *git.Repository) (git.Submodules, error) {
+	w, err := repo.Worktree()
+	if err != nil {
+		return nil, err
+	}
+
+	return w.Submodules()
+}
+
+func runGit(t *testing.T, dir string, args ...string) {
+	cmd := exec.Command("git", append([]string{
+		"-c", "user.name=trdl",
+		"-c", "user.email=trdl@example.com",
+		"-c", "init.defaultBranch=master",
+		"-c", "protocol.file.allow=always",
+	}, args...)...)
+	cmd.Dir = dir
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %s\n%s", args, err, output)
+	}
+}
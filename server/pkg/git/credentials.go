@@ -3,21 +3,108 @@ package git
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
+	"os"
 
+	"github.com/go-git/go-git/v5/plumbing/transport"
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
+	cryptossh "golang.org/x/crypto/ssh"
 )
 
 const (
-	FieldNameGitCredentialUsername = "username"
-	FieldNameGitCredentialPassword = "password"
+	FieldNameGitCredentialUsername                = "username"
+	FieldNameGitCredentialPassword                = "password"
+	FieldNameGitCredentialSSHPrivateKey           = "ssh_private_key"
+	FieldNameGitCredentialSSHPrivateKeyPassphrase = "ssh_private_key_passphrase"
+	FieldNameGitCredentialSSHKnownHosts           = "ssh_known_hosts"
 
 	StorageKeyConfigurationGitCredential = "configuration_git_credential"
 )
 
+const defaultSSHUser = "git"
+
 type GitCredential struct {
-	Username string `structs:"username" json:"username"`
-	Password string `structs:"password" json:"password"`
+	Username                string `structs:"username" json:"username"`
+	Password                string `structs:"password" json:"password"`
+	SSHPrivateKey           string `structs:"ssh_private_key" json:"ssh_private_key,omitempty"`
+	SSHPrivateKeyPassphrase string `structs:"ssh_private_key_passphrase" json:"ssh_private_key_passphrase,omitempty"`
+	SSHKnownHosts           string `structs:"ssh_known_hosts" json:"ssh_known_hosts,omitempty"`
+}
+
+// AuthMethod returns the auth method for the repository url: the ssh key for ssh:// and git@ urls, the username and password for http(s) urls.
+// The auth method is nil if the credential has nothing for the url protocol.
+func (c *GitCredential) AuthMethod(url string) (transport.AuthMethod, error) {
+	endpoint, err := transport.NewEndpoint(url)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse git url %q: %s", url, err)
+	}
+
+	switch endpoint.Protocol {
+	case "ssh":
+		if c.SSHPrivateKey == "" {
+			return nil, nil
+		}
+
+		user := endpoint.User
+		if user == "" {
+			user = defaultSSHUser
+		}
+
+		return c.sshAuthMethod(user)
+	case "http", "https":
+		if c.Username == "" || c.Password == "" {
+			return nil, nil
+		}
+
+		return &http.BasicAuth{
+			Username: c.Username,
+			Password: c.Password,
+		}, nil
+	default:
+		return nil, nil
+	}
+}
+
+func (c *GitCredential) sshAuthMethod(user string) (*ssh.PublicKeys, error) {
+	publicKeys, err := ssh.NewPublicKeys(user, []byte(c.SSHPrivateKey), c.SSHPrivateKeyPassphrase)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load ssh private key: %s", err)
+	}
+
+	// The known_hosts files from the SSH_KNOWN_HOSTS environment variable or the home directory are used otherwise
+	if c.SSHKnownHosts != "" {
+		callback, err := newKnownHostsCallback(c.SSHKnownHosts)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load ssh known hosts: %s", err)
+		}
+
+		publicKeys.HostKeyCallback = callback
+	}
+
+	return publicKeys, nil
+}
+
+func newKnownHostsCallback(knownHosts string) (cryptossh.HostKeyCallback, error) {
+	f, err := ioutil.TempFile("", "trdl-known-hosts-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.WriteString(knownHosts); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+
+	// The file is read right away, so it can be removed after the callback is created
+	return ssh.NewKnownHostsCallback(f.Name())
 }
 
 func CredentialsPaths() []*framework.Path {
@@ -25,16 +112,28 @@ func CredentialsPaths() []*framework.Path {
 		{
 			Pattern:         "^configure/git_credential/?$",
 			HelpSynopsis:    "Configure Git credentials",
-			HelpDescription: "Configure Git credentials to perform clone operation",
+			HelpDescription: "Configure Git credentials to perform clone operation. The username and password are used for http(s) urls, the ssh private key is used for ssh:// and git@ urls, including submodules",
 
 			Fields: map[string]*framework.FieldSchema{
 				FieldNameGitCredentialUsername: {
 					Type:        framework.TypeString,
-					Description: "A Git username; Required for CREATE, UPDATE.",
+					Description: "A Git username; Required for CREATE, UPDATE unless ssh_private_key is specified.",
 				},
 				FieldNameGitCredentialPassword: {
 					Type:        framework.TypeString,
-					Description: "A Git password; Required for CREATE, UPDATE.",
+					Description: "A Git password; Required for CREATE, UPDATE unless ssh_private_key is specified.",
+				},
+				FieldNameGitCredentialSSHPrivateKey: {
+					Type:        framework.TypeString,
+					Description: "A PEM encoded SSH private key to clone ssh:// and git@ urls",
+				},
+				FieldNameGitCredentialSSHPrivateKeyPassphrase: {
+					Type:        framework.TypeString,
+					Description: "A passphrase of the SSH private key",
+				},
+				FieldNameGitCredentialSSHKnownHosts: {
+					Type:        framework.TypeString,
+					Description: "The known_hosts entries to verify the SSH host keys (the known_hosts files from the SSH_KNOWN_HOSTS environment variable or ~/.ssh/known_hosts are used by default)",
 				},
 			},
 
@@ -60,13 +159,27 @@ func pathConfigureGitCredentialCreateOrUpdate(ctx context.Context, req *logical.
 	gitCredential := GitCredential{
 		Username: fields.Get(FieldNameGitCredentialUsername).(string),
 		Password: fields.Get(FieldNameGitCredentialPassword).(string),
+
+		SSHPrivateKey:           fields.Get(FieldNameGitCredentialSSHPrivateKey).(string),
+		SSHPrivateKeyPassphrase: fields.Get(FieldNameGitCredentialSSHPrivateKeyPassphrase).(string),
+		SSHKnownHosts:           fields.Get(FieldNameGitCredentialSSHKnownHosts).(string),
 	}
 
-	if gitCredential.Username == "" {
-		return logical.ErrorResponse("%q field value should not be empty", FieldNameGitCredentialUsername), nil
+	if gitCredential.SSHPrivateKey == "" || gitCredential.Username != "" || gitCredential.Password != "" {
+		if gitCredential.Username == "" {
+			return logical.ErrorResponse("%q field value should not be empty", FieldNameGitCredentialUsername), nil
+		}
+		if gitCredential.Password == "" {
+			return logical.ErrorResponse("%q field value should not be empty", FieldNameGitCredentialPassword), nil
+		}
 	}
-	if gitCredential.Password == "" {
-		return logical.ErrorResponse("%q field value should not be empty", FieldNameGitCredentialPassword), nil
+
+	if gitCredential.SSHPrivateKey == "" {
+		if gitCredential.SSHPrivateKeyPassphrase != "" || gitCredential.SSHKnownHosts != "" {
+			return logical.ErrorResponse("%q field value should not be empty", FieldNameGitCredentialSSHPrivateKey), nil
+		}
+	} else if _, err := gitCredential.sshAuthMethod(defaultSSHUser); err != nil {
+		return logical.ErrorResponse("invalid SSH credential: %s", err), nil
 	}
 
 	if err := PutGitCredential(ctx, req.Storage, gitCredential); err != nil {
@@ -2,8 +2,14 @@ package git
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
 	"testing"
 
+	"github.com/go-git/go-git/v5/plumbing/transport/http"
+	"github.com/go-git/go-git/v5/plumbing/transport/ssh"
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
 	"github.com/stretchr/testify/assert"
@@ -93,6 +99,63 @@ func (suite *PathConfigureGitCredentialsCallbacksSuite) Test_CreateOrUpdate_Full
 	)
 }
 
+func (suite *PathConfigureGitCredentialsCallbacksSuite) Test_CreateOrUpdate_SSHPrivateKey() {
+	assert := assert.New(suite.T())
+
+	privateKey := generateSSHPrivateKey(suite.T())
+	knownHosts := "github.com ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOMqqnkVzrm0SdG6UOoqKLsabgH5C9okWi0dh2l9GKJl"
+
+	suite.req.Operation = logical.CreateOperation
+	suite.req.Data = map[string]interface{}{
+		FieldNameGitCredentialSSHPrivateKey: privateKey,
+		FieldNameGitCredentialSSHKnownHosts: knownHosts,
+	}
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(err)
+	assert.Nil(resp)
+
+	cfg, err := GetGitCredential(suite.ctx, suite.storage)
+	assert.Nil(err)
+	assert.Equal(
+		&GitCredential{
+			SSHPrivateKey: privateKey,
+			SSHKnownHosts: knownHosts,
+		},
+		cfg,
+	)
+}
+
+func (suite *PathConfigureGitCredentialsCallbacksSuite) Test_CreateOrUpdate_InvalidSSHPrivateKey() {
+	assert := assert.New(suite.T())
+
+	suite.req.Operation = logical.CreateOperation
+	suite.req.Data = map[string]interface{}{
+		FieldNameGitCredentialSSHPrivateKey: "invalid",
+	}
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(err)
+	if assert.NotNil(resp) {
+		assert.True(resp.IsError())
+	}
+}
+
+func (suite *PathConfigureGitCredentialsCallbacksSuite) Test_CreateOrUpdate_SSHPrivateKeyPassphraseWithoutKey() {
+	assert := assert.New(suite.T())
+
+	suite.req.Operation = logical.CreateOperation
+	suite.req.Data = map[string]interface{}{
+		FieldNameGitCredentialUsername:                "user",
+		FieldNameGitCredentialPassword:                "password",
+		FieldNameGitCredentialSSHPrivateKeyPassphrase: "passphrase",
+	}
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(err)
+	assert.Equal(logical.ErrorResponse("%q field value should not be empty", FieldNameGitCredentialSSHPrivateKey), resp)
+}
+
 func (suite *PathConfigureGitCredentialsCallbacksSuite) Test_Delete_NoConfig() {
 	assert := assert.New(suite.T())
 
@@ -123,6 +186,48 @@ func (suite *PathConfigureGitCredentialsCallbacksSuite) Test_Delete_HasConfig()
 	assert.Nil(cfg)
 }
 
+func TestGitCredentialAuthMethod(t *testing.T) {
+	credential := &GitCredential{
+		Username:      "user",
+		Password:      "password",
+		SSHPrivateKey: generateSSHPrivateKey(t),
+	}
+
+	for _, url := range []string{"https://github.com/werf/trdl.git", "http://github.com/werf/trdl.git"} {
+		auth, err := credential.AuthMethod(url)
+		assert.Nil(t, err)
+		assert.Equal(t, &http.BasicAuth{Username: "user", Password: "password"}, auth)
+	}
+
+	for url, user := range map[string]string{
+		"git@github.com:werf/trdl.git":               "git",
+		"ssh://github.com/werf/trdl.git":             "git",
+		"ssh://deploy@github.com:2222/werf/trdl.git": "deploy",
+	} {
+		auth, err := credential.AuthMethod(url)
+		assert.Nil(t, err)
+		if assert.IsType(t, &ssh.PublicKeys{}, auth) {
+			assert.Equal(t, user, auth.(*ssh.PublicKeys).User)
+		}
+	}
+
+	auth, err := (&GitCredential{Username: "user", Password: "password"}).AuthMethod("git@github.com:werf/trdl.git")
+	assert.Nil(t, err)
+	assert.Nil(t, auth)
+}
+
+func generateSSHPrivateKey(t *testing.T) string {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(key),
+	}))
+}
+
 func TestGitCredentials(t *testing.T) {
 	suite.Run(t, new(PathConfigureGitCredentialsCallbacksSuite))
 }
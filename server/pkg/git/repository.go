@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	neturl "net/url"
 	"os"
 	"path"
+	"strings"
 	"time"
 
 	"github.com/go-git/go-billy/v5/memfs"
@@ -22,6 +24,9 @@ type CloneOptions struct {
 	ReferenceName     string
 	RecurseSubmodules git.SubmoduleRescursivity
 	Auth              transport.AuthMethod
+
+	// Credential selects the auth method by the url of the repository and of each submodule, Auth is ignored.
+	Credential *GitCredential
 }
 
 func CloneInMemory(url string, opts CloneOptions) (*git.Repository, error) {
@@ -48,9 +53,114 @@ func CloneInMemory(url string, opts CloneOptions) (*git.Repository, error) {
 		if opts.Auth != nil {
 			cloneOptions.Auth = opts.Auth
 		}
+
+		if opts.Credential != nil {
+			auth, err := opts.Credential.AuthMethod(url)
+			if err != nil {
+				return nil, err
+			}
+			cloneOptions.Auth = auth
+
+			// The submodules may use other protocols than the superproject, so they are updated separately
+			cloneOptions.RecurseSubmodules = git.NoRecurseSubmodules
+		}
+	}
+
+	repo, err := git.Clone(storage, fs, cloneOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.Credential != nil && opts.RecurseSubmodules != git.NoRecurseSubmodules {
+		if err := updateSubmodules(repo, url, opts.Credential, opts.RecurseSubmodules); err != nil {
+			return nil, fmt.Errorf("unable to update submodules: %s", err)
+		}
+	}
+
+	return repo, nil
+}
+
+func updateSubmodules(repo *git.Repository, repoURL string, credential *GitCredential, depth git.SubmoduleRescursivity) error {
+	w, err := repo.Worktree()
+	if err != nil {
+		return fmt.Errorf("unable to get git repository worktree: %s", err)
+	}
+
+	submodules, err := w.Submodules()
+	if err != nil {
+		return fmt.Errorf("unable to get submodules: %s", err)
+	}
+
+	for _, submodule := range submodules {
+		cfg := submodule.Config()
+
+		submoduleURL, err := resolveSubmoduleURL(repoURL, cfg.URL)
+		if err != nil {
+			return fmt.Errorf("unable to resolve submodule %q url: %s", cfg.Name, err)
+		}
+		cfg.URL = submoduleURL
+
+		auth, err := credential.AuthMethod(submoduleURL)
+		if err != nil {
+			return err
+		}
+
+		if err := submodule.Update(&git.SubmoduleUpdateOptions{
+			Init:              true,
+			RecurseSubmodules: git.NoRecurseSubmodules,
+			Auth:              auth,
+		}); err != nil {
+			return fmt.Errorf("unable to update submodule %q: %s", cfg.Name, err)
+		}
+
+		if depth > 1 {
+			submoduleRepo, err := submodule.Repository()
+			if err != nil {
+				return fmt.Errorf("unable to open submodule %q repository: %s", cfg.Name, err)
+			}
+
+			if err := updateSubmodules(submoduleRepo, submoduleURL, credential, depth-1); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// resolveSubmoduleURL resolves the submodule url relative to the superproject url.
+// The scp-like urls are converted, because go-git cannot parse them in submodules.
+func resolveSubmoduleURL(repoURL, submoduleURL string) (string, error) {
+	if !strings.HasPrefix(submoduleURL, "./") && !strings.HasPrefix(submoduleURL, "../") {
+		return normalizeURL(submoduleURL)
+	}
+
+	base, err := normalizeURL(repoURL)
+	if err != nil {
+		return "", err
+	}
+
+	u, err := neturl.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	u.Path = path.Join(u.Path, submoduleURL)
+
+	return u.String(), nil
+}
+
+// normalizeURL converts the scp-like url (git@host:path) to the ssh:// url.
+func normalizeURL(url string) (string, error) {
+	endpoint, err := transport.NewEndpoint(url)
+	if err != nil {
+		return "", err
+	}
+
+	if endpoint.Protocol != "ssh" {
+		return url, nil
 	}
 
-	return git.Clone(storage, fs, cloneOptions)
+	return endpoint.String(), nil
 }
 
 func AddWorktreeFilesToTar(tw *tar.Writer, gitRepo *git.Repository) error {
@@ -0,0 +1,25 @@
+package git
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveSubmoduleURL(t *testing.T) {
+	for _, tc := range []struct {
+		repoURL      string
+		submoduleURL string
+		expected     string
+	}{
+		{"https://github.com/werf/trdl.git", "https://github.com/werf/werf.git", "https://github.com/werf/werf.git"},
+		{"https://github.com/werf/trdl.git", "../werf.git", "https://github.com/werf/werf.git"},
+		{"https://github.com/werf/trdl.git", "git@github.com:werf/werf.git", "ssh://git@github.com/werf/werf.git"},
+		{"git@github.com:werf/trdl.git", "../werf.git", "ssh://git@github.com/werf/werf.git"},
+		{"ssh://git@github.com:2222/werf/trdl.git", "./werf.git", "ssh://git@github.com:2222/werf/trdl.git/werf.git"},
+	} {
+		url, err := resolveSubmoduleURL(tc.repoURL, tc.submoduleURL)
+		assert.Nil(t, err)
+		assert.Equal(t, tc.expected, url, "%s + %s", tc.repoURL, tc.submoduleURL)
+	}
+}
@@ -11,7 +11,9 @@ import (
 	"github.com/go-git/go-git/v5/plumbing/object"
 	"github.com/hashicorp/go-hclog"
 
+	"github.com/werf/trdl/server/pkg/gitsign"
 	"github.com/werf/trdl/server/pkg/pgp"
+	"github.com/werf/trdl/server/pkg/sshsig"
 )
 
 type NotEnoughVerifiedPGPSignaturesError struct {
@@ -19,14 +21,16 @@ type NotEnoughVerifiedPGPSignaturesError struct {
 }
 
 func (r *NotEnoughVerifiedPGPSignaturesError) Error() string {
-	return fmt.Sprintf("not enough verified PGP signatures: %d verified signature(s) required", r.Number)
+	return fmt.Sprintf("not enough verified signatures: %d verified signature(s) required", r.Number)
 }
 
 func NewNotEnoughVerifiedPGPSignaturesError(number int) error {
 	return &NotEnoughVerifiedPGPSignaturesError{Number: number}
 }
 
-func VerifyTagSignatures(repo *git.Repository, tagName string, trustedPGPPublicKeys []string, requiredNumberOfVerifiedSignatures int, logger hclog.Logger) error {
+// VerifyTagSignatures verifies PGP, SSH and gitsign signatures of the tag and its signatures stored in notes.
+// Signatures of all kinds count toward the requiredNumberOfVerifiedSignatures, gitsign signatures are verified only if the gitsignPolicy is set.
+func VerifyTagSignatures(repo *git.Repository, tagName string, trustedPGPPublicKeys, trustedSSHPublicKeys []string, gitsignPolicy *gitsign.TrustPolicy, requiredNumberOfVerifiedSignatures int, logger hclog.Logger) error {
 	tr, err := repo.Tag(tagName)
 	if err != nil {
 		return fmt.Errorf("unable to get tag: %s", err)
@@ -40,19 +44,36 @@ func VerifyTagSignatures(repo *git.Repository, tagName string, trustedPGPPublicK
 				return fmt.Errorf("resolve revision %s failed: %s", tr.Hash(), err)
 			}
 
-			return VerifyCommitSignatures(repo, revHash.String(), trustedPGPPublicKeys, requiredNumberOfVerifiedSignatures, logger)
+			return VerifyCommitSignatures(repo, revHash.String(), trustedPGPPublicKeys, trustedSSHPublicKeys, gitsignPolicy, requiredNumberOfVerifiedSignatures, logger)
 		}
 
 		return fmt.Errorf("unable to get tag object: %s", err)
 	}
 
-	if to.PGPSignature != "" {
+	tagSignature := to.PGPSignature
+	signedTag := to
+
+	// go-git recognizes only PGP tag signatures, SSH and gitsign signatures remain at the end of the message
+	if tagSignature == "" {
+		for _, signatureBegin := range []string{sshsig.SignatureBegin, gitsign.SignatureBegin} {
+			if ind := strings.Index(to.Message, signatureBegin); ind != -1 {
+				tagWithoutSignature := *to
+				tagWithoutSignature.Message = to.Message[:ind]
+
+				tagSignature = to.Message[ind:]
+				signedTag = &tagWithoutSignature
+				break
+			}
+		}
+	}
+
+	if tagSignature != "" {
 		encoded := &plumbing.MemoryObject{}
-		if err := to.EncodeWithoutSignature(encoded); err != nil {
+		if err := signedTag.EncodeWithoutSignature(encoded); err != nil {
 			return fmt.Errorf("unable to encode tag object: %s", err)
 		}
 
-		trustedPGPPublicKeys, requiredNumberOfVerifiedSignatures, err = pgp.VerifyPGPSignatures([]string{to.PGPSignature}, func() (io.Reader, error) { return encoded.Reader() }, trustedPGPPublicKeys, requiredNumberOfVerifiedSignatures, logger)
+		trustedPGPPublicKeys, trustedSSHPublicKeys, gitsignPolicy, requiredNumberOfVerifiedSignatures, err = verifySignatures([]string{tagSignature}, func() (io.Reader, error) { return encoded.Reader() }, trustedPGPPublicKeys, trustedSSHPublicKeys, gitsignPolicy, requiredNumberOfVerifiedSignatures, logger)
 		if err != nil {
 			return err
 		}
@@ -62,10 +83,12 @@ func VerifyTagSignatures(repo *git.Repository, tagName string, trustedPGPPublicK
 		return nil
 	}
 
-	return verifyObjectSignatures(repo, to.Hash.String(), trustedPGPPublicKeys, requiredNumberOfVerifiedSignatures, logger)
+	return verifyObjectSignatures(repo, to.Hash.String(), trustedPGPPublicKeys, trustedSSHPublicKeys, gitsignPolicy, requiredNumberOfVerifiedSignatures, logger)
 }
 
-func VerifyCommitSignatures(repo *git.Repository, commit string, trustedPGPPublicKeys []string, requiredNumberOfVerifiedSignatures int, logger hclog.Logger) error {
+// VerifyCommitSignatures verifies PGP, SSH and gitsign signatures of the commit and its signatures stored in notes.
+// Signatures of all kinds count toward the requiredNumberOfVerifiedSignatures, gitsign signatures are verified only if the gitsignPolicy is set.
+func VerifyCommitSignatures(repo *git.Repository, commit string, trustedPGPPublicKeys, trustedSSHPublicKeys []string, gitsignPolicy *gitsign.TrustPolicy, requiredNumberOfVerifiedSignatures int, logger hclog.Logger) error {
 	co, err := repo.CommitObject(plumbing.NewHash(commit))
 	if err != nil {
 		return fmt.Errorf("unable to get commit %q: %s", commit, err)
@@ -77,7 +100,7 @@ func VerifyCommitSignatures(repo *git.Repository, commit string, trustedPGPPubli
 			return err
 		}
 
-		trustedPGPPublicKeys, requiredNumberOfVerifiedSignatures, err = pgp.VerifyPGPSignatures([]string{co.PGPSignature}, func() (io.Reader, error) { return encoded.Reader() }, trustedPGPPublicKeys, requiredNumberOfVerifiedSignatures, logger)
+		trustedPGPPublicKeys, trustedSSHPublicKeys, gitsignPolicy, requiredNumberOfVerifiedSignatures, err = verifySignatures([]string{co.PGPSignature}, func() (io.Reader, error) { return encoded.Reader() }, trustedPGPPublicKeys, trustedSSHPublicKeys, gitsignPolicy, requiredNumberOfVerifiedSignatures, logger)
 		if err != nil {
 			return err
 		}
@@ -87,10 +110,55 @@ func VerifyCommitSignatures(repo *git.Repository, commit string, trustedPGPPubli
 		return nil
 	}
 
-	return verifyObjectSignatures(repo, commit, trustedPGPPublicKeys, requiredNumberOfVerifiedSignatures, logger)
+	return verifyObjectSignatures(repo, commit, trustedPGPPublicKeys, trustedSSHPublicKeys, gitsignPolicy, requiredNumberOfVerifiedSignatures, logger)
+}
+
+func verifySignatures(signatures []string, signedReaderFunc func() (io.Reader, error), trustedPGPPublicKeys, trustedSSHPublicKeys []string, gitsignPolicy *gitsign.TrustPolicy, requiredNumberOfVerifiedSignatures int, logger hclog.Logger) ([]string, []string, *gitsign.TrustPolicy, int, error) {
+	var pgpSignatures, sshSignatures, gitsignSignatures []string
+	for _, signature := range signatures {
+		if sshsig.IsSSHSignature(signature) {
+			sshSignatures = append(sshSignatures, signature)
+		} else if gitsign.IsGitsignSignature(signature) {
+			gitsignSignatures = append(gitsignSignatures, signature)
+		} else {
+			pgpSignatures = append(pgpSignatures, signature)
+		}
+	}
+
+	var err error
+	if len(pgpSignatures) != 0 {
+		trustedPGPPublicKeys, requiredNumberOfVerifiedSignatures, err = pgp.VerifyPGPSignatures(pgpSignatures, signedReaderFunc, trustedPGPPublicKeys, requiredNumberOfVerifiedSignatures, logger)
+		if err != nil {
+			return nil, nil, nil, 0, err
+		}
+	}
+
+	if len(sshSignatures) != 0 {
+		trustedSSHPublicKeys, requiredNumberOfVerifiedSignatures, err = sshsig.VerifySSHSignatures(sshSignatures, signedReaderFunc, trustedSSHPublicKeys, requiredNumberOfVerifiedSignatures, logger)
+		if err != nil {
+			return nil, nil, nil, 0, err
+		}
+	}
+
+	if len(gitsignSignatures) != 0 {
+		if gitsignPolicy == nil {
+			if logger != nil {
+				logger.Debug("[DEBUG-SIGNATURES] gitsign is not configured: will skip gitsign signatures")
+			}
+		} else {
+			remainingPolicy := *gitsignPolicy
+			remainingPolicy.Identities, requiredNumberOfVerifiedSignatures, err = gitsign.VerifyGitsignSignatures(gitsignSignatures, signedReaderFunc, *gitsignPolicy, requiredNumberOfVerifiedSignatures, logger)
+			if err != nil {
+				return nil, nil, nil, 0, err
+			}
+			gitsignPolicy = &remainingPolicy
+		}
+	}
+
+	return trustedPGPPublicKeys, trustedSSHPublicKeys, gitsignPolicy, requiredNumberOfVerifiedSignatures, nil
 }
 
-func verifyObjectSignatures(repo *git.Repository, objectID string, trustedPGPPublicKeys []string, requiredNumberOfVerifiedSignatures int, logger hclog.Logger) error {
+func verifyObjectSignatures(repo *git.Repository, objectID string, trustedPGPPublicKeys, trustedSSHPublicKeys []string, gitsignPolicy *gitsign.TrustPolicy, requiredNumberOfVerifiedSignatures int, logger hclog.Logger) error {
 	signatures, err := objectSignaturesFromNotes(repo, objectID)
 	if err != nil {
 		if strings.HasSuffix(err.Error(), plumbing.ErrObjectNotFound.Error()) {
@@ -112,7 +180,7 @@ func verifyObjectSignatures(repo *git.Repository, objectID string, trustedPGPPub
 		return NewNotEnoughVerifiedPGPSignaturesError(requiredNumberOfVerifiedSignatures)
 	}
 
-	trustedPGPPublicKeys, requiredNumberOfVerifiedSignatures, err = pgp.VerifyPGPSignatures(signatures, func() (io.Reader, error) { return strings.NewReader(objectID), nil }, trustedPGPPublicKeys, requiredNumberOfVerifiedSignatures, logger)
+	_, _, _, requiredNumberOfVerifiedSignatures, err = verifySignatures(signatures, func() (io.Reader, error) { return strings.NewReader(objectID), nil }, trustedPGPPublicKeys, trustedSSHPublicKeys, gitsignPolicy, requiredNumberOfVerifiedSignatures, logger)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,126 @@
+package git
+
+import (
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/werf/trdl/server/pkg/gitsign"
+	"github.com/werf/trdl/server/pkg/gitsign/testutil"
+)
+
+func TestVerifyGitsignSignatures(t *testing.T) {
+	sigstore := testutil.NewSigstore(t)
+	trustedIdentity := gitsign.Identity{Subject: "alice@example.com", Issuer: "https://accounts.example.com"}
+	untrustedIdentity := gitsign.Identity{Subject: "mallory@example.com", Issuer: "https://accounts.example.com"}
+
+	signWith := func(identity gitsign.Identity) func(data []byte) string {
+		return func(data []byte) string {
+			return sigstore.Sign(data, testutil.SignOptions{Subject: identity.Subject, Issuer: identity.Issuer})
+		}
+	}
+
+	repo, err := git.Init(memory.NewStorage(), nil)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	commitHash := storeGitsignSignedCommit(t, repo, signWith(trustedIdentity))
+	storeGitsignSignedTag(t, repo, "signed", commitHash, signWith(trustedIdentity))
+	storeGitsignSignedTag(t, repo, "untrusted", commitHash, signWith(untrustedIdentity))
+
+	policy := &gitsign.TrustPolicy{
+		FulcioRoots:    sigstore.FulcioRoot,
+		RekorPublicKey: sigstore.RekorPublicKey,
+		Identities:     []gitsign.Identity{trustedIdentity},
+	}
+
+	assert.Nil(t, VerifyCommitSignatures(repo, commitHash.String(), nil, nil, policy, 1, nil))
+	assert.Equal(t, NewNotEnoughVerifiedPGPSignaturesError(1), VerifyCommitSignatures(repo, commitHash.String(), nil, nil, nil, 1, nil))
+
+	assert.Nil(t, VerifyTagSignatures(repo, "signed", nil, nil, policy, 1, nil))
+	assert.Equal(t, NewNotEnoughVerifiedPGPSignaturesError(1), VerifyTagSignatures(repo, "untrusted", nil, nil, policy, 1, nil))
+	assert.Equal(t, NewNotEnoughVerifiedPGPSignaturesError(1), VerifyTagSignatures(repo, "signed", nil, nil, policy, 2, nil))
+}
+
+func storeGitsignSignedCommit(t *testing.T, repo *git.Repository, sign func(data []byte) string) plumbing.Hash {
+	treeHash := storeObject(t, repo, &object.Tree{})
+
+	commit := &object.Commit{
+		Author:    testSignature(),
+		Committer: testSignature(),
+		Message:   "signed\n",
+		TreeHash:  treeHash,
+	}
+
+	encoded := &plumbing.MemoryObject{}
+	if err := commit.EncodeWithoutSignature(encoded); err != nil {
+		t.Fatal(err)
+	}
+	commit.PGPSignature = sign(readObject(t, encoded))
+
+	return storeObject(t, repo, commit)
+}
+
+func storeGitsignSignedTag(t *testing.T, repo *git.Repository, name string, target plumbing.Hash, sign func(data []byte) string) {
+	tag := &object.Tag{
+		Name:       name,
+		Tagger:     testSignature(),
+		Message:    name + "\n",
+		TargetType: plumbing.CommitObject,
+		Target:     target,
+	}
+
+	encoded := &plumbing.MemoryObject{}
+	if err := tag.Encode(encoded); err != nil {
+		t.Fatal(err)
+	}
+
+	// gitsign appends the tag signature to the message the same way as git does for SSH signatures
+	tag.Message += sign(readObject(t, encoded))
+
+	tagHash := storeObject(t, repo, tag)
+	if err := repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewTagReferenceName(name), tagHash)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func storeObject(t *testing.T, repo *git.Repository, obj interface {
+	Encode(plumbing.EncodedObject) error
+}) plumbing.Hash {
+	encoded := repo.Storer.NewEncodedObject()
+	if err := obj.Encode(encoded); err != nil {
+		t.Fatal(err)
+	}
+
+	hash, err := repo.Storer.SetEncodedObject(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return hash
+}
+
+func readObject(t *testing.T, obj plumbing.EncodedObject) []byte {
+	r, err := obj.Reader()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+func testSignature() object.Signature {
+	return object.Signature{Name: "trdl", Email: "trdl@example.com", When: time.Unix(1600000000, 0).UTC()}
+}
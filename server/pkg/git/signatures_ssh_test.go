@@ -0,0 +1,59 @@
+package git
+
+import (
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifySSHSignatures(t *testing.T) {
+	root := t.TempDir()
+	repoDir := filepath.Join(root, "repo")
+
+	trustedKey := generateSSHSigningKey(t, filepath.Join(root, "trusted"))
+	untrustedKey := generateSSHSigningKey(t, filepath.Join(root, "untrusted"))
+
+	signWith := func(keyPath string) []string {
+		return []string{"-c", "gpg.format=ssh", "-c", "user.signingkey=" + keyPath}
+	}
+
+	runGit(t, root, "init", "-q", repoDir)
+	runGit(t, repoDir, append(signWith(filepath.Join(root, "trusted")), "commit", "-q", "--allow-empty", "-S", "-m", "signed")...)
+	runGit(t, repoDir, append(signWith(filepath.Join(root, "trusted")), "tag", "-s", "signed", "-m", "signed")...)
+	runGit(t, repoDir, append(signWith(filepath.Join(root, "untrusted")), "tag", "-s", "untrusted", "-m", "untrusted")...)
+
+	repo, err := CloneInMemory(repoDir, CloneOptions{})
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	head, err := repo.Head()
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	assert.Nil(t, VerifyCommitSignatures(repo, head.Hash().String(), nil, []string{trustedKey}, nil, 1, nil))
+	assert.Equal(t, NewNotEnoughVerifiedPGPSignaturesError(1), VerifyCommitSignatures(repo, head.Hash().String(), nil, []string{untrustedKey}, nil, 1, nil))
+
+	assert.Nil(t, VerifyTagSignatures(repo, "signed", nil, []string{trustedKey}, nil, 1, nil))
+	assert.Nil(t, VerifyTagSignatures(repo, "untrusted", nil, []string{trustedKey, untrustedKey}, nil, 1, nil))
+	assert.Equal(t, NewNotEnoughVerifiedPGPSignaturesError(1), VerifyTagSignatures(repo, "untrusted", nil, []string{trustedKey}, nil, 1, nil))
+	assert.Equal(t, NewNotEnoughVerifiedPGPSignaturesError(1), VerifyTagSignatures(repo, "signed", nil, []string{trustedKey}, nil, 2, nil))
+}
+
+func generateSSHSigningKey(t *testing.T, keyPath string) string {
+	if output, err := exec.Command("ssh-keygen", "-q", "-t", "ed25519", "-N", "", "-f", keyPath).CombinedOutput(); err != nil {
+		t.Fatalf("ssh-keygen failed: %s\n%s", err, output)
+	}
+
+	publicKey, err := ioutil.ReadFile(keyPath + ".pub")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return strings.TrimSpace(string(publicKey))
+}
@@ -128,6 +128,8 @@ var _ = Describe("VerifyTagSignatures and VerifyCommitSignatures", func() {
 			repo,
 			tagName,
 			entry.trustedPGPPublicKeys,
+			nil,
+			nil,
 			entry.requiredNumberOfVerifiedSignatures,
 			nil,
 		)
@@ -152,6 +154,8 @@ var _ = Describe("VerifyTagSignatures and VerifyCommitSignatures", func() {
 			repo,
 			headCommit.String(),
 			entry.trustedPGPPublicKeys,
+			nil,
+			nil,
 			entry.requiredNumberOfVerifiedSignatures,
 			nil,
 		)
@@ -0,0 +1,87 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/hashicorp/go-hclog"
+
+	"github.com/werf/trdl/server/pkg/gitsign"
+)
+
+type SignerGroup struct {
+	Name                               string
+	TrustedPGPPublicKeys               []string
+	TrustedSSHPublicKeys               []string
+	GitsignPolicy                      *gitsign.TrustPolicy
+	RequiredNumberOfVerifiedSignatures int
+}
+
+type SignerGroupVerificationResult struct {
+	Name                               string
+	RequiredNumberOfVerifiedSignatures int
+	MissingNumberOfVerifiedSignatures  int
+}
+
+func (r *SignerGroupVerificationResult) Satisfied() bool {
+	return r.MissingNumberOfVerifiedSignatures == 0
+}
+
+type NotSatisfiedSignerGroupsError struct {
+	Results []*SignerGroupVerificationResult
+}
+
+func (r *NotSatisfiedSignerGroupsError) Error() string {
+	var groups []string
+	for _, result := range r.Results {
+		if !result.Satisfied() {
+			groups = append(groups, fmt.Sprintf("%q (%d more verified signature(s) required)", result.Name, result.MissingNumberOfVerifiedSignatures))
+		}
+	}
+
+	return fmt.Sprintf("signer groups not satisfied: %s", strings.Join(groups, ", "))
+}
+
+// VerifyTagSignerGroups verifies the tag signatures against each signer group separately: every group must be satisfied by its own keys.
+func VerifyTagSignerGroups(repo *git.Repository, tagName string, signerGroups []*SignerGroup, logger hclog.Logger) ([]*SignerGroupVerificationResult, error) {
+	return verifySignerGroups(signerGroups, func(group *SignerGroup) error {
+		return VerifyTagSignatures(repo, tagName, group.TrustedPGPPublicKeys, group.TrustedSSHPublicKeys, group.GitsignPolicy, group.RequiredNumberOfVerifiedSignatures, logger)
+	})
+}
+
+// VerifyCommitSignerGroups verifies the commit signatures against each signer group separately: every group must be satisfied by its own keys.
+func VerifyCommitSignerGroups(repo *git.Repository, commit string, signerGroups []*SignerGroup, logger hclog.Logger) ([]*SignerGroupVerificationResult, error) {
+	return verifySignerGroups(signerGroups, func(group *SignerGroup) error {
+		return VerifyCommitSignatures(repo, commit, group.TrustedPGPPublicKeys, group.TrustedSSHPublicKeys, group.GitsignPolicy, group.RequiredNumberOfVerifiedSignatures, logger)
+	})
+}
+
+func verifySignerGroups(signerGroups []*SignerGroup, verifyFunc func(group *SignerGroup) error) ([]*SignerGroupVerificationResult, error) {
+	var results []*SignerGroupVerificationResult
+	var notSatisfied bool
+	for _, group := range signerGroups {
+		result := &SignerGroupVerificationResult{
+			Name:                               group.Name,
+			RequiredNumberOfVerifiedSignatures: group.RequiredNumberOfVerifiedSignatures,
+		}
+
+		if err := verifyFunc(group); err != nil {
+			notEnoughErr, ok := err.(*NotEnoughVerifiedPGPSignaturesError)
+			if !ok {
+				return nil, fmt.Errorf("unable to verify signer group %q: %s", group.Name, err)
+			}
+
+			result.MissingNumberOfVerifiedSignatures = notEnoughErr.Number
+			notSatisfied = true
+		}
+
+		results = append(results, result)
+	}
+
+	if notSatisfied {
+		return results, &NotSatisfiedSignerGroupsError{Results: results}
+	}
+
+	return results, nil
+}
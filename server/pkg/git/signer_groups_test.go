@@ -0,0 +1,56 @@
+package git
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyCommitSignerGroups(t *testing.T) {
+	root := t.TempDir()
+	repoDir := filepath.Join(root, "repo")
+
+	releaseManagerKey1 := generateSSHSigningKey(t, filepath.Join(root, "release-manager-1"))
+	releaseManagerKey2 := generateSSHSigningKey(t, filepath.Join(root, "release-manager-2"))
+	securityKey := generateSSHSigningKey(t, filepath.Join(root, "security"))
+
+	runGit(t, root, "init", "-q", repoDir)
+	runGit(t, repoDir, "-c", "gpg.format=ssh", "-c", "user.signingkey="+filepath.Join(root, "release-manager-1"), "commit", "-q", "--allow-empty", "-S", "-m", "signed")
+
+	repo, err := CloneInMemory(repoDir, CloneOptions{})
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	head, err := repo.Head()
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	releaseManagers := &SignerGroup{
+		Name:                               "release-managers",
+		TrustedSSHPublicKeys:               []string{releaseManagerKey1, releaseManagerKey2},
+		RequiredNumberOfVerifiedSignatures: 1,
+	}
+	security := &SignerGroup{
+		Name:                               "security",
+		TrustedSSHPublicKeys:               []string{securityKey},
+		RequiredNumberOfVerifiedSignatures: 1,
+	}
+
+	results, err := VerifyCommitSignerGroups(repo, head.Hash().String(), []*SignerGroup{releaseManagers}, nil)
+	assert.Nil(t, err)
+	if assert.Len(t, results, 1) {
+		assert.True(t, results[0].Satisfied())
+	}
+
+	results, err = VerifyCommitSignerGroups(repo, head.Hash().String(), []*SignerGroup{releaseManagers, security}, nil)
+	assert.Equal(t, &NotSatisfiedSignerGroupsError{Results: results}, err)
+	assert.EqualError(t, err, `signer groups not satisfied: "security" (1 more verified signature(s) required)`)
+	if assert.Len(t, results, 2) {
+		assert.True(t, results[0].Satisfied())
+		assert.False(t, results[1].Satisfied())
+		assert.Equal(t, 1, results[1].MissingNumberOfVerifiedSignatures)
+	}
+}
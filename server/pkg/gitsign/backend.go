@@ -0,0 +1,236 @@
+package gitsign
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+
+	"github.com/werf/trdl/server/pkg/util"
+)
+
+const (
+	fieldNameFulcioRoot     = "fulcio_root"
+	fieldNameRekorPublicKey = "rekor_public_key"
+
+	fieldNameTrustedIdentityName    = "name"
+	fieldNameTrustedIdentitySubject = "subject"
+	fieldNameTrustedIdentityIssuer  = "issuer"
+)
+
+func Paths() []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern:         "configure/gitsign/?$",
+			HelpSynopsis:    "Configure gitsign signatures verification",
+			HelpDescription: "Configure the pinned Fulcio root certificate and the Rekor public key to check git repository commit and tag signatures made with gitsign (gpg.format=x509). The signatures are verified offline with the transparency log inclusion proof bundled into the signature",
+			Fields: map[string]*framework.FieldSchema{
+				fieldNameFulcioRoot: {
+					Type:        framework.TypeString,
+					Description: "PEM encoded Fulcio root certificate, the intermediate certificates may be appended",
+					Required:    true,
+				},
+				fieldNameRekorPublicKey: {
+					Type:        framework.TypeString,
+					Description: "PEM encoded Rekor transparency log public key",
+					Required:    true,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.CreateOperation: &framework.PathOperation{
+					Description: "Configure gitsign signatures verification",
+					Callback:    pathConfigureGitsignCreateOrUpdate,
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Description: "Configure gitsign signatures verification",
+					Callback:    pathConfigureGitsignCreateOrUpdate,
+				},
+				logical.ReadOperation: &framework.PathOperation{
+					Description: "Get the gitsign signatures verification configuration",
+					Callback:    pathConfigureGitsignRead,
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Description: "Delete the gitsign signatures verification configuration",
+					Callback:    pathConfigureGitsignDelete,
+				},
+			},
+		},
+		{
+			Pattern:         "configure/gitsign/trusted_identity/?",
+			HelpSynopsis:    "Configure trusted gitsign identities",
+			HelpDescription: "Configure the OIDC identities trusted to sign git repository commits and tags with gitsign: the email or the URI of the Fulcio certificate and the OIDC issuer",
+			Fields: map[string]*framework.FieldSchema{
+				fieldNameTrustedIdentityName: {
+					Type:        framework.TypeNameString,
+					Description: "Identity name",
+					Required:    true,
+				},
+				fieldNameTrustedIdentitySubject: {
+					Type:        framework.TypeString,
+					Description: "The email or the URI the Fulcio certificate is issued for",
+					Required:    true,
+				},
+				fieldNameTrustedIdentityIssuer: {
+					Type:        framework.TypeString,
+					Description: "The OIDC issuer URL, e.g. https://accounts.google.com or https://token.actions.githubusercontent.com",
+					Required:    true,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.CreateOperation: &framework.PathOperation{
+					Description: "Add a trusted gitsign identity",
+					Callback:    pathConfigureTrustedIdentityCreateOrUpdate,
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Description: "Add a trusted gitsign identity",
+					Callback:    pathConfigureTrustedIdentityCreateOrUpdate,
+				},
+				logical.ReadOperation: &framework.PathOperation{
+					Description: "Get the list of trusted gitsign identities",
+					Callback:    pathConfigureTrustedIdentityReadOrList,
+				},
+				logical.ListOperation: &framework.PathOperation{
+					Description: "Get the list of trusted gitsign identities",
+					Callback:    pathConfigureTrustedIdentityReadOrList,
+				},
+			},
+		},
+		{
+			Pattern:         "configure/gitsign/trusted_identity/" + framework.GenericNameRegex(fieldNameTrustedIdentityName) + "$",
+			HelpSynopsis:    "Read or delete the configured trusted gitsign identity",
+			HelpDescription: "Read or delete the configured trusted gitsign identity",
+			Fields: map[string]*framework.FieldSchema{
+				fieldNameTrustedIdentityName: {
+					Type:        framework.TypeNameString,
+					Description: "Identity name",
+					Required:    true,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Description: "Get the trusted gitsign identity",
+					Callback:    pathConfigureTrustedIdentityRead,
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Description: "Delete the trusted gitsign identity",
+					Callback:    pathConfigureTrustedIdentityDelete,
+				},
+			},
+		},
+	}
+}
+
+func pathConfigureGitsignCreateOrUpdate(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
+	if errResp := util.CheckRequiredFields(req, fields); errResp != nil {
+		return errResp, nil
+	}
+
+	cfg := configuration{
+		FulcioRoot:     fields.Get(fieldNameFulcioRoot).(string),
+		RekorPublicKey: fields.Get(fieldNameRekorPublicKey).(string),
+	}
+
+	if _, err := parseTrustRoots(TrustPolicy{FulcioRoots: cfg.FulcioRoot, RekorPublicKey: cfg.RekorPublicKey}); err != nil {
+		return logical.ErrorResponse("Invalid gitsign configuration: %s", err), nil
+	}
+
+	e, err := logical.StorageEntryJSON(storageKeyConfiguration, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("error creating storage json entry by key %q: %s", storageKeyConfiguration, err)
+	}
+
+	if err := req.Storage.Put(ctx, e); err != nil {
+		return nil, fmt.Errorf("unable to put gitsign configuration: %s", err)
+	}
+
+	return nil, nil
+}
+
+func pathConfigureGitsignRead(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	cfg, err := getConfiguration(ctx, req.Storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg == nil {
+		return logical.ErrorResponse("gitsign configuration not found"), nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			fieldNameFulcioRoot:     cfg.FulcioRoot,
+			fieldNameRekorPublicKey: cfg.RekorPublicKey,
+		},
+	}, nil
+}
+
+func pathConfigureGitsignDelete(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	if err := req.Storage.Delete(ctx, storageKeyConfiguration); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+func pathConfigureTrustedIdentityCreateOrUpdate(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
+	if errResp := util.CheckRequiredFields(req, fields); errResp != nil {
+		return errResp, nil
+	}
+
+	name := fields.Get(fieldNameTrustedIdentityName).(string)
+	identity := Identity{
+		Subject: fields.Get(fieldNameTrustedIdentitySubject).(string),
+		Issuer:  fields.Get(fieldNameTrustedIdentityIssuer).(string),
+	}
+
+	e, err := logical.StorageEntryJSON(trustedIdentityStorageKey(name), identity)
+	if err != nil {
+		return nil, fmt.Errorf("error creating storage json entry by key %q: %s", trustedIdentityStorageKey(name), err)
+	}
+
+	if err := req.Storage.Put(ctx, e); err != nil {
+		return nil, fmt.Errorf("unable to put gitsign trusted identity: %s", err)
+	}
+
+	return nil, nil
+}
+
+func pathConfigureTrustedIdentityReadOrList(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	list, err := req.Storage.List(ctx, storageKeyPrefixTrustedIdentity)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list %q in storage: %s", storageKeyPrefixTrustedIdentity, err)
+	}
+
+	return logical.ListResponse(list), nil
+}
+
+func pathConfigureTrustedIdentityRead(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
+	name := fields.Get(fieldNameTrustedIdentityName).(string)
+
+	identity, err := GetTrustedIdentity(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if identity == nil {
+		return logical.ErrorResponse("gitsign identity %q not found in storage", name), nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			fieldNameTrustedIdentityName:    name,
+			fieldNameTrustedIdentitySubject: identity.Subject,
+			fieldNameTrustedIdentityIssuer:  identity.Issuer,
+		},
+	}, nil
+}
+
+func pathConfigureTrustedIdentityDelete(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
+	name := fields.Get(fieldNameTrustedIdentityName).(string)
+	if err := req.Storage.Delete(ctx, trustedIdentityStorageKey(name)); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
@@ -0,0 +1,214 @@
+package gitsign
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	"github.com/werf/trdl/server/pkg/gitsign/testutil"
+)
+
+type pathConfigureGitsignCallbacksSuite struct {
+	suite.Suite
+	ctx      context.Context
+	backend  logical.Backend
+	req      *logical.Request
+	storage  logical.Storage
+	sigstore *testutil.Sigstore
+}
+
+func (suite *pathConfigureGitsignCallbacksSuite) SetupTest() {
+	ctx := context.Background()
+	b := &framework.Backend{}
+	b.Paths = Paths()
+	storage := &logical.InmemStorage{}
+	config := logical.TestBackendConfig()
+	config.StorageView = storage
+	err := b.Setup(ctx, config)
+	assert.Nil(suite.T(), err)
+
+	suite.ctx = ctx
+	suite.backend = b
+	suite.req = &logical.Request{Storage: storage}
+	suite.storage = storage
+	suite.sigstore = testutil.NewSigstore(suite.T())
+}
+
+func (suite *pathConfigureGitsignCallbacksSuite) TestConfigure() {
+	policy, err := GetTrustPolicy(suite.ctx, suite.storage)
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), policy)
+
+	suite.req.Path = "configure/gitsign"
+	suite.req.Operation = logical.CreateOperation
+	suite.req.Data = suite.dataConfiguration()
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), resp)
+
+	suite.req.Operation = logical.ReadOperation
+	suite.req.Data = nil
+
+	resp, err = suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	if assert.NotNil(suite.T(), resp) {
+		assert.Equal(suite.T(), suite.dataConfiguration(), resp.Data)
+	}
+
+	policy, err = GetTrustPolicy(suite.ctx, suite.storage)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), &TrustPolicy{FulcioRoots: suite.sigstore.FulcioRoot, RekorPublicKey: suite.sigstore.RekorPublicKey}, policy)
+
+	suite.req.Operation = logical.DeleteOperation
+
+	resp, err = suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), resp)
+
+	policy, err = GetTrustPolicy(suite.ctx, suite.storage)
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), policy)
+}
+
+func (suite *pathConfigureGitsignCallbacksSuite) TestConfigure_RequiredFields() {
+	suite.req.Path = "configure/gitsign"
+	suite.req.Operation = logical.CreateOperation
+
+	for _, fieldName := range []string{fieldNameFulcioRoot, fieldNameRekorPublicKey} {
+		suite.Run(fieldName, func() {
+			data := suite.dataConfiguration()
+			delete(data, fieldName)
+
+			suite.req.Data = data
+
+			resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+			assert.Nil(suite.T(), err)
+			assert.Equal(suite.T(), logical.ErrorResponse("Required field %q must be set", fieldName), resp)
+		})
+	}
+}
+
+func (suite *pathConfigureGitsignCallbacksSuite) TestConfigure_Invalid() {
+	suite.req.Path = "configure/gitsign"
+	suite.req.Operation = logical.CreateOperation
+
+	for _, fieldName := range []string{fieldNameFulcioRoot, fieldNameRekorPublicKey} {
+		suite.Run(fieldName, func() {
+			data := suite.dataConfiguration()
+			data[fieldName] = "invalid"
+
+			suite.req.Data = data
+
+			resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+			assert.Nil(suite.T(), err)
+			if assert.NotNil(suite.T(), resp) {
+				assert.True(suite.T(), resp.IsError())
+			}
+		})
+	}
+}
+
+func (suite *pathConfigureGitsignCallbacksSuite) TestTrustedIdentities() {
+	suite.req.Path = "configure/gitsign"
+	suite.req.Operation = logical.CreateOperation
+	suite.req.Data = suite.dataConfiguration()
+
+	_, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+
+	suite.req.Path = "configure/gitsign/trusted_identity"
+	for _, data := range []map[string]interface{}{dataTrustedIdentity1(), dataTrustedIdentity2()} {
+		suite.req.Data = data
+
+		resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+		assert.Nil(suite.T(), err)
+		assert.Nil(suite.T(), resp)
+	}
+
+	suite.req.Operation = logical.ListOperation
+	suite.req.Data = nil
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), logical.ListResponse([]string{"identity_1", "identity_2"}), resp)
+
+	suite.req.Path = "configure/gitsign/trusted_identity/identity_1"
+	suite.req.Operation = logical.ReadOperation
+
+	resp, err = suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	if assert.NotNil(suite.T(), resp) {
+		assert.Equal(suite.T(), dataTrustedIdentity1(), resp.Data)
+	}
+
+	policy, err := GetTrustPolicy(suite.ctx, suite.storage)
+	assert.Nil(suite.T(), err)
+	if assert.NotNil(suite.T(), policy) {
+		assert.Equal(suite.T(), []Identity{
+			{Subject: "alice@example.com", Issuer: "https://accounts.example.com"},
+			{Subject: "bob@example.com", Issuer: "https://accounts.example.com"},
+		}, policy.Identities)
+	}
+
+	suite.req.Operation = logical.DeleteOperation
+
+	resp, err = suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), resp)
+
+	suite.req.Operation = logical.ReadOperation
+
+	resp, err = suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), logical.ErrorResponse("gitsign identity %q not found in storage", "identity_1"), resp)
+}
+
+func (suite *pathConfigureGitsignCallbacksSuite) TestTrustedIdentity_RequiredFields() {
+	suite.req.Path = "configure/gitsign/trusted_identity"
+	suite.req.Operation = logical.CreateOperation
+
+	for _, fieldName := range []string{fieldNameTrustedIdentityName, fieldNameTrustedIdentitySubject, fieldNameTrustedIdentityIssuer} {
+		suite.Run(fieldName, func() {
+			data := dataTrustedIdentity1()
+			delete(data, fieldName)
+
+			suite.req.Data = data
+
+			resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+			assert.Nil(suite.T(), err)
+			assert.Equal(suite.T(), logical.ErrorResponse("Required field %q must be set", fieldName), resp)
+		})
+	}
+}
+
+func TestBackendPathConfigureGitsignCallbacks(t *testing.T) {
+	suite.Run(t, new(pathConfigureGitsignCallbacksSuite))
+}
+
+func (suite *pathConfigureGitsignCallbacksSuite) dataConfiguration() map[string]interface{} {
+	return map[string]interface{}{
+		fieldNameFulcioRoot:     suite.sigstore.FulcioRoot,
+		fieldNameRekorPublicKey: suite.sigstore.RekorPublicKey,
+	}
+}
+
+func dataTrustedIdentity1() map[string]interface{} {
+	return map[string]interface{}{
+		fieldNameTrustedIdentityName:    "identity_1",
+		fieldNameTrustedIdentitySubject: "alice@example.com",
+		fieldNameTrustedIdentityIssuer:  "https://accounts.example.com",
+	}
+}
+
+func dataTrustedIdentity2() map[string]interface{} {
+	return map[string]interface{}{
+		fieldNameTrustedIdentityName:    "identity_2",
+		fieldNameTrustedIdentitySubject: "bob@example.com",
+		fieldNameTrustedIdentityIssuer:  "https://accounts.example.com",
+	}
+}
@@ -0,0 +1,271 @@
+package gitsign
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+var (
+	oidSignedData                = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidData                      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidAttributeContentType      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidAttributeMessageDigest    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidDigestAlgorithmSHA256     = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidRekorTransparencyLogEntry = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 3, 1}
+)
+
+// The CMS structures described in https://datatracker.ietf.org/doc/html/rfc5652, only the parts used by gitsign are parsed.
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,tag:0"`
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	EncapContentInfo encapsulatedContentInfo
+	Certificates     asn1.RawValue `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue `asn1:"optional,tag:1"`
+	SignerInfos      []signerInfo  `asn1:"set"`
+}
+
+type encapsulatedContentInfo struct {
+	EContentType asn1.ObjectIdentifier
+	EContent     asn1.RawValue `asn1:"optional,explicit,tag:0"`
+}
+
+type signerInfo struct {
+	Version            int
+	SID                asn1.RawValue
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        asn1.RawValue `asn1:"optional,tag:0"`
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+	UnsignedAttrs      asn1.RawValue `asn1:"optional,tag:1"`
+}
+
+type issuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue
+}
+
+// signature is the detached CMS signature made by gitsign.
+type signature struct {
+	Signer       *x509.Certificate
+	Certificates []*x509.Certificate
+
+	// SignedAttributes is the DER encoded SET of the signed attributes, which is the data the Signature is made for.
+	SignedAttributes []byte
+	MessageDigest    []byte
+	Signature        []byte
+
+	// TransparencyLogEntry is the protobuf encoded Rekor entry of the signature, see tlogEntry.
+	TransparencyLogEntry []byte
+}
+
+func parseSignature(armored string) (*signature, error) {
+	block, _ := pem.Decode([]byte(strings.TrimSpace(armored)))
+	if block == nil || block.Type != signatureBlockType {
+		return nil, fmt.Errorf("armored gitsign signature expected")
+	}
+
+	var ci contentInfo
+	if rest, err := asn1.Unmarshal(block.Bytes, &ci); err != nil {
+		return nil, fmt.Errorf("unable to parse cms content info: %s", err)
+	} else if len(rest) != 0 {
+		return nil, fmt.Errorf("unexpected trailing data after cms content info")
+	}
+
+	if !ci.ContentType.Equal(oidSignedData) {
+		return nil, fmt.Errorf("unexpected cms content type %s: signed data expected", ci.ContentType)
+	}
+
+	var sd signedData
+	if _, err := asn1.Unmarshal(ci.Content.Bytes, &sd); err != nil {
+		return nil, fmt.Errorf("unable to parse cms signed data: %s", err)
+	}
+
+	if len(sd.EncapContentInfo.EContent.Bytes) != 0 {
+		return nil, fmt.Errorf("detached cms signature expected")
+	}
+
+	if len(sd.SignerInfos) != 1 {
+		return nil, fmt.Errorf("cms signature with exactly one signer expected, got %d", len(sd.SignerInfos))
+	}
+	si := sd.SignerInfos[0]
+
+	if !si.DigestAlgorithm.Algorithm.Equal(oidDigestAlgorithmSHA256) {
+		return nil, fmt.Errorf("unsupported cms digest algorithm %s", si.DigestAlgorithm.Algorithm)
+	}
+
+	certificates, err := x509.ParseCertificates(sd.Certificates.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse cms certificates: %s", err)
+	}
+
+	var sid issuerAndSerialNumber
+	if _, err := asn1.Unmarshal(si.SID.FullBytes, &sid); err != nil {
+		return nil, fmt.Errorf("unsupported cms signer identifier: issuer and serial number expected")
+	}
+
+	sig := &signature{
+		Certificates: certificates,
+		Signature:    si.Signature,
+	}
+
+	for _, cert := range certificates {
+		if bytes.Equal(cert.RawIssuer, sid.Issuer.FullBytes) && cert.SerialNumber.Cmp(sid.SerialNumber) == 0 {
+			sig.Signer = cert
+			break
+		}
+	}
+
+	if sig.Signer == nil {
+		return nil, fmt.Errorf("cms signer certificate not found")
+	}
+
+	if len(si.SignedAttrs.FullBytes) == 0 {
+		return nil, fmt.Errorf("cms signed attributes not found")
+	}
+
+	// The signature is made for the DER encoding of the attributes with the SET tag instead of the IMPLICIT [0] one
+	sig.SignedAttributes, err = attributesForVerification(si.SignedAttrs)
+	if err != nil {
+		return nil, err
+	}
+
+	signedAttrs, err := parseAttributes(sig.SignedAttributes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse cms signed attributes: %s", err)
+	}
+
+	var contentType asn1.ObjectIdentifier
+	if err := signedAttrs.unmarshal(oidAttributeContentType, &contentType); err != nil {
+		return nil, err
+	}
+
+	if !contentType.Equal(oidData) {
+		return nil, fmt.Errorf("unexpected cms signed content type %s", contentType)
+	}
+
+	if err := signedAttrs.unmarshal(oidAttributeMessageDigest, &sig.MessageDigest); err != nil {
+		return nil, err
+	}
+
+	if len(si.UnsignedAttrs.FullBytes) != 0 {
+		rawUnsignedAttrs, err := attributesForVerification(si.UnsignedAttrs)
+		if err != nil {
+			return nil, err
+		}
+
+		unsignedAttrs, err := parseAttributes(rawUnsignedAttrs)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse cms unsigned attributes: %s", err)
+		}
+
+		if unsignedAttrs.has(oidRekorTransparencyLogEntry) {
+			if err := unsignedAttrs.unmarshal(oidRekorTransparencyLogEntry, &sig.TransparencyLogEntry); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return sig, nil
+}
+
+func attributesForVerification(raw asn1.RawValue) ([]byte, error) {
+	if len(raw.FullBytes) == 0 {
+		return nil, fmt.Errorf("empty cms attributes")
+	}
+
+	res := append([]byte{}, raw.FullBytes...)
+	res[0] = 0x31 // SET OF, constructed
+
+	return res, nil
+}
+
+type attributes []attribute
+
+func parseAttributes(der []byte) (attributes, error) {
+	var attrs attributes
+	if rest, err := asn1.UnmarshalWithParams(der, &attrs, "set"); err != nil {
+		return nil, err
+	} else if len(rest) != 0 {
+		return nil, fmt.Errorf("unexpected trailing data")
+	}
+
+	return attrs, nil
+}
+
+func (attrs attributes) has(oid asn1.ObjectIdentifier) bool {
+	for _, attr := range attrs {
+		if attr.Type.Equal(oid) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// unmarshal parses the single value of the attribute, which must be present exactly once.
+func (attrs attributes) unmarshal(oid asn1.ObjectIdentifier, out interface{}) error {
+	var found *attribute
+	for i := range attrs {
+		if attrs[i].Type.Equal(oid) {
+			if found != nil {
+				return fmt.Errorf("duplicate cms attribute %s", oid)
+			}
+			found = &attrs[i]
+		}
+	}
+
+	if found == nil {
+		return fmt.Errorf("cms attribute %s not found", oid)
+	}
+
+	rest, err := asn1.Unmarshal(found.Values.Bytes, out)
+	if err != nil {
+		return fmt.Errorf("unable to parse cms attribute %s: %s", oid, err)
+	}
+
+	if len(rest) != 0 {
+		return fmt.Errorf("cms attribute %s with exactly one value expected", oid)
+	}
+
+	return nil
+}
+
+// verify checks the digest of the signed data and the signature of the signed attributes made by the signer certificate.
+func (sig *signature) verify(digest []byte) error {
+	if !bytes.Equal(sig.MessageDigest, digest) {
+		return fmt.Errorf("message digest does not match the signed data")
+	}
+
+	var algorithm x509.SignatureAlgorithm
+	switch sig.Signer.PublicKey.(type) {
+	case *ecdsa.PublicKey:
+		algorithm = x509.ECDSAWithSHA256
+	case *rsa.PublicKey:
+		algorithm = x509.SHA256WithRSA
+	case ed25519.PublicKey:
+		algorithm = x509.PureEd25519
+	default:
+		return fmt.Errorf("unsupported signer public key type %T", sig.Signer.PublicKey)
+	}
+
+	return sig.Signer.CheckSignature(algorithm, sig.SignedAttributes, sig.Signature)
+}
@@ -0,0 +1,260 @@
+package gitsign
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+const (
+	SignatureBegin = "-----BEGIN SIGNED MESSAGE-----"
+
+	signatureBlockType = "SIGNED MESSAGE"
+)
+
+var (
+	// The OIDC issuer extensions of the Fulcio certificates, see https://github.com/sigstore/fulcio/blob/main/docs/oid-info.md.
+	oidFulcioIssuer   = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 1}
+	oidFulcioIssuerV2 = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// IsGitsignSignature checks whether the armored signature is a gitsign keyless signature (gpg.format=x509) rather than a PGP signature.
+func IsGitsignSignature(signature string) bool {
+	return strings.HasPrefix(strings.TrimSpace(signature), SignatureBegin)
+}
+
+// Identity is the OIDC identity the Fulcio certificate is issued for: the email or the URI of the certificate and the OIDC issuer.
+type Identity struct {
+	Subject string `json:"subject"`
+	Issuer  string `json:"issuer"`
+}
+
+// TrustPolicy is the trust configuration of the gitsign signatures.
+type TrustPolicy struct {
+	// FulcioRoots are the PEM encoded Fulcio root certificates, the intermediate certificates may be added as well.
+	FulcioRoots string
+	// RekorPublicKey is the PEM encoded public key of the Rekor transparency log.
+	RekorPublicKey string
+	Identities     []Identity
+}
+
+type trustRoots struct {
+	roots          *x509.CertPool
+	intermediates  []*x509.Certificate
+	rekorPublicKey crypto.PublicKey
+}
+
+func parseTrustRoots(policy TrustPolicy) (*trustRoots, error) {
+	certificates, err := parseCertificates(policy.FulcioRoots)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse fulcio certificates: %s", err)
+	}
+
+	res := &trustRoots{roots: x509.NewCertPool()}
+	hasRoot := false
+	for _, cert := range certificates {
+		if bytes.Equal(cert.RawIssuer, cert.RawSubject) && cert.CheckSignatureFrom(cert) == nil {
+			res.roots.AddCert(cert)
+			hasRoot = true
+		} else {
+			res.intermediates = append(res.intermediates, cert)
+		}
+	}
+
+	if !hasRoot {
+		return nil, fmt.Errorf("fulcio root certificate not found")
+	}
+
+	res.rekorPublicKey, err = parsePublicKey(policy.RekorPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse rekor public key: %s", err)
+	}
+
+	return res, nil
+}
+
+func parseCertificates(data string) ([]*x509.Certificate, error) {
+	var certificates []*x509.Certificate
+	rest := []byte(data)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+
+		if block.Type != "CERTIFICATE" {
+			return nil, fmt.Errorf("unexpected pem block %q", block.Type)
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+
+		certificates = append(certificates, cert)
+	}
+
+	if len(certificates) == 0 {
+		return nil, fmt.Errorf("pem encoded certificates expected")
+	}
+
+	return certificates, nil
+}
+
+func parsePublicKey(data string) (crypto.PublicKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil || block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("pem encoded public key expected")
+	}
+
+	return x509.ParsePKIXPublicKey(block.Bytes)
+}
+
+// verifySignature verifies the signature offline and returns the verified signer certificate:
+// the signature must be made for the signed data by the certificate issued by the trusted Fulcio root
+// and must be included into the trusted Rekor transparency log while the short-lived certificate is valid.
+func verifySignature(sig *signature, signed io.Reader, roots *trustRoots) (*x509.Certificate, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, signed); err != nil {
+		return nil, fmt.Errorf("unable to hash signed data: %s", err)
+	}
+
+	if err := sig.verify(h.Sum(nil)); err != nil {
+		return nil, err
+	}
+
+	integratedTime, err := verifyTlogEntry(sig, roots.rekorPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range append(append([]*x509.Certificate{}, roots.intermediates...), sig.Certificates...) {
+		intermediates.AddCert(cert)
+	}
+
+	if _, err := sig.Signer.Verify(x509.VerifyOptions{
+		Roots:         roots.roots,
+		Intermediates: intermediates,
+		CurrentTime:   integratedTime,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+	}); err != nil {
+		return nil, fmt.Errorf("unable to verify certificate chain: %s", err)
+	}
+
+	return sig.Signer, nil
+}
+
+func certificateIssuer(cert *x509.Certificate) (string, error) {
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidFulcioIssuerV2) {
+			var issuer string
+			if _, err := asn1.UnmarshalWithParams(ext.Value, &issuer, "utf8"); err != nil {
+				return "", fmt.Errorf("unable to parse certificate oidc issuer: %s", err)
+			}
+
+			return issuer, nil
+		}
+	}
+
+	// The deprecated extension contains the raw issuer string
+	for _, ext := range cert.Extensions {
+		if ext.Id.Equal(oidFulcioIssuer) {
+			return string(ext.Value), nil
+		}
+	}
+
+	return "", fmt.Errorf("certificate oidc issuer not found")
+}
+
+func certificateSubjects(cert *x509.Certificate) []string {
+	subjects := append([]string{}, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		subjects = append(subjects, uri.String())
+	}
+
+	return subjects
+}
+
+func matchIdentity(cert *x509.Certificate, identity Identity) bool {
+	issuer, err := certificateIssuer(cert)
+	if err != nil || issuer != identity.Issuer {
+		return false
+	}
+
+	for _, subject := range certificateSubjects(cert) {
+		if subject == identity.Subject {
+			return true
+		}
+	}
+
+	return false
+}
+
+// VerifyGitsignSignatures verifies the armored gitsign signatures with the trust policy the same way as pgp.VerifyPGPSignatures does:
+// each identity is counted once, the identities which have not verified any signature and the remaining number of the required signatures are returned.
+func VerifyGitsignSignatures(gitsignSignatures []string, signedReaderFunc func() (io.Reader, error), policy TrustPolicy, requiredNumberOfVerifiedSignatures int, logger hclog.Logger) ([]Identity, int, error) {
+	identities := policy.Identities
+	if requiredNumberOfVerifiedSignatures == 0 || len(identities) == 0 {
+		return identities, requiredNumberOfVerifiedSignatures, nil
+	}
+
+	roots, err := parseTrustRoots(policy)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	for _, gitsignSignature := range gitsignSignatures {
+		sig, err := parseSignature(gitsignSignature)
+		if err != nil {
+			if logger != nil {
+				logger.Debug(fmt.Sprintf("[DEBUG-SIGNATURES] VerifyGitsignSignatures -- will skip signature due to error: %s", err))
+			}
+			continue
+		}
+
+		signedReader, err := signedReaderFunc()
+		if err != nil {
+			return nil, 0, err
+		}
+
+		cert, err := verifySignature(sig, signedReader, roots)
+		if err != nil {
+			if logger != nil {
+				logger.Debug(fmt.Sprintf("[DEBUG-SIGNATURES] VerifyGitsignSignatures -- will skip signature due to error: %s", err))
+			}
+			continue
+		}
+
+		matched := false
+		for i, identity := range identities {
+			if !matchIdentity(cert, identity) {
+				continue
+			}
+			matched = true
+
+			requiredNumberOfVerifiedSignatures--
+			if requiredNumberOfVerifiedSignatures == 0 {
+				return identities, 0, nil
+			}
+
+			identities = append(append([]Identity{}, identities[:i]...), identities[i+1:]...)
+			break
+		}
+
+		if !matched && logger != nil {
+			logger.Debug(fmt.Sprintf("[DEBUG-SIGNATURES] VerifyGitsignSignatures -- will skip signature of the untrusted identity %v", certificateSubjects(cert)))
+		}
+	}
+
+	return identities, requiredNumberOfVerifiedSignatures, nil
+}
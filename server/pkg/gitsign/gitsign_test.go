@@ -0,0 +1,135 @@
+package gitsign
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/werf/trdl/server/pkg/gitsign/testutil"
+)
+
+const testIssuer = "https://accounts.example.com"
+
+func TestVerifyGitsignSignatures(t *testing.T) {
+	data := []byte("signed data")
+	signedReaderFunc := func() (io.Reader, error) { return bytes.NewReader(data), nil }
+
+	sigstore := testutil.NewSigstore(t)
+	identity1 := Identity{Subject: "alice@example.com", Issuer: testIssuer}
+	identity2 := Identity{Subject: "bob@example.com", Issuer: testIssuer}
+	identity3 := Identity{Subject: "carol@example.com", Issuer: testIssuer}
+
+	signature1 := sigstore.Sign(data, testutil.SignOptions{Subject: identity1.Subject, Issuer: identity1.Issuer})
+	signature2 := sigstore.Sign(data, testutil.SignOptions{Subject: identity2.Subject, Issuer: identity2.Issuer})
+
+	assert.True(t, IsGitsignSignature(signature1))
+
+	policy := func(identities ...Identity) TrustPolicy {
+		return TrustPolicy{FulcioRoots: sigstore.FulcioRoot, RekorPublicKey: sigstore.RekorPublicKey, Identities: identities}
+	}
+
+	t.Run("verified", func(t *testing.T) {
+		_, number, err := VerifyGitsignSignatures([]string{signature1, signature2}, signedReaderFunc, policy(identity1, identity2, identity3), 2, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, number)
+	})
+
+	t.Run("not enough signatures", func(t *testing.T) {
+		identities, number, err := VerifyGitsignSignatures([]string{signature1}, signedReaderFunc, policy(identity1, identity2, identity3), 2, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, number)
+		assert.Equal(t, []Identity{identity2, identity3}, identities)
+	})
+
+	t.Run("the same identity is counted once", func(t *testing.T) {
+		_, number, err := VerifyGitsignSignatures([]string{signature1, signature1}, signedReaderFunc, policy(identity1, identity2), 2, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, number)
+	})
+
+	t.Run("untrusted identity", func(t *testing.T) {
+		_, number, err := VerifyGitsignSignatures([]string{signature1, signature2}, signedReaderFunc, policy(identity3), 1, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, number)
+	})
+
+	t.Run("untrusted issuer", func(t *testing.T) {
+		_, number, err := VerifyGitsignSignatures([]string{signature1}, signedReaderFunc, policy(Identity{Subject: identity1.Subject, Issuer: "https://other.example.com"}), 1, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, number)
+	})
+
+	t.Run("modified data", func(t *testing.T) {
+		modifiedReaderFunc := func() (io.Reader, error) { return strings.NewReader("modified data"), nil }
+		_, number, err := VerifyGitsignSignatures([]string{signature1}, modifiedReaderFunc, policy(identity1), 1, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, number)
+	})
+
+	t.Run("untrusted fulcio root", func(t *testing.T) {
+		other := testutil.NewSigstore(t)
+		p := policy(identity1)
+		p.FulcioRoots = other.FulcioRoot
+
+		_, number, err := VerifyGitsignSignatures([]string{signature1}, signedReaderFunc, p, 1, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, number)
+	})
+
+	t.Run("untrusted rekor key", func(t *testing.T) {
+		other := testutil.NewSigstore(t)
+		p := policy(identity1)
+		p.RekorPublicKey = other.RekorPublicKey
+
+		_, number, err := VerifyGitsignSignatures([]string{signature1}, signedReaderFunc, p, 1, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, number)
+	})
+
+	t.Run("not a gitsign signature", func(t *testing.T) {
+		_, number, err := VerifyGitsignSignatures([]string{"-----BEGIN SIGNED MESSAGE-----\ninvalid\n-----END SIGNED MESSAGE-----"}, signedReaderFunc, policy(identity1), 1, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, number)
+	})
+}
+
+func TestVerifySignature_TransparencyLog(t *testing.T) {
+	data := []byte("signed data")
+	sigstore := testutil.NewSigstore(t)
+
+	roots, err := parseTrustRoots(TrustPolicy{FulcioRoots: sigstore.FulcioRoot, RekorPublicKey: sigstore.RekorPublicKey})
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	verify := func(opts testutil.SignOptions) error {
+		opts.Subject = "alice@example.com"
+		opts.Issuer = testIssuer
+
+		sig, err := parseSignature(sigstore.Sign(data, opts))
+		if err != nil {
+			return err
+		}
+
+		_, err = verifySignature(sig, bytes.NewReader(data), roots)
+		return err
+	}
+
+	assert.Nil(t, verify(testutil.SignOptions{}))
+
+	err = verify(testutil.SignOptions{WithoutTransparencyLogEntry: true})
+	assert.EqualError(t, err, "transparency log entry not found in the signature")
+
+	err = verify(testutil.SignOptions{InvalidInclusionProof: true})
+	assert.EqualError(t, err, "invalid transparency log inclusion proof: calculated root hash does not match the proof root hash")
+
+	// The short-lived certificate must be valid when the entry is integrated into the log rather than at the verification time
+	err = verify(testutil.SignOptions{IntegratedTime: time.Now().Add(time.Hour)})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "unable to verify certificate chain")
+	}
+}
@@ -0,0 +1,383 @@
+package gitsign
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// tlogEntry is the dev.sigstore.rekor.v1.TransparencyLogEntry message gitsign bundles into the signature,
+// see https://github.com/sigstore/protobuf-specs/blob/main/protos/sigstore_rekor.proto.
+type tlogEntry struct {
+	LogIndex             int64
+	LogID                []byte
+	IntegratedTime       int64
+	SignedEntryTimestamp []byte
+	InclusionProof       *inclusionProof
+	CanonicalizedBody    []byte
+}
+
+type inclusionProof struct {
+	LogIndex   int64
+	RootHash   []byte
+	TreeSize   int64
+	Hashes     [][]byte
+	Checkpoint string
+}
+
+func parseTlogEntry(b []byte) (*tlogEntry, error) {
+	entry := &tlogEntry{}
+	err := consumeMessage(b, func(num protowire.Number, v uint64, b []byte) error {
+		switch num {
+		case 1:
+			entry.LogIndex = int64(v)
+		case 2:
+			return consumeMessage(b, func(num protowire.Number, _ uint64, b []byte) error {
+				if num == 1 {
+					entry.LogID = b
+				}
+				return nil
+			})
+		case 4:
+			entry.IntegratedTime = int64(v)
+		case 5:
+			return consumeMessage(b, func(num protowire.Number, _ uint64, b []byte) error {
+				if num == 1 {
+					entry.SignedEntryTimestamp = b
+				}
+				return nil
+			})
+		case 6:
+			proof, err := parseInclusionProof(b)
+			if err != nil {
+				return err
+			}
+			entry.InclusionProof = proof
+		case 7:
+			entry.CanonicalizedBody = b
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse transparency log entry: %s", err)
+	}
+
+	return entry, nil
+}
+
+func parseInclusionProof(b []byte) (*inclusionProof, error) {
+	proof := &inclusionProof{}
+	err := consumeMessage(b, func(num protowire.Number, v uint64, b []byte) error {
+		switch num {
+		case 1:
+			proof.LogIndex = int64(v)
+		case 2:
+			proof.RootHash = b
+		case 3:
+			proof.TreeSize = int64(v)
+		case 4:
+			proof.Hashes = append(proof.Hashes, b)
+		case 5:
+			return consumeMessage(b, func(num protowire.Number, _ uint64, b []byte) error {
+				if num == 1 {
+					proof.Checkpoint = string(b)
+				}
+				return nil
+			})
+		}
+
+		return nil
+	})
+
+	return proof, err
+}
+
+// consumeMessage calls the function for each varint and length-delimited field of the protobuf message, other fields are skipped.
+func consumeMessage(b []byte, f func(num protowire.Number, v uint64, b []byte) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+		b = b[n:]
+
+		var err error
+		switch typ {
+		case protowire.VarintType:
+			var v uint64
+			v, n = protowire.ConsumeVarint(b)
+			if n >= 0 {
+				err = f(num, v, nil)
+			}
+		case protowire.BytesType:
+			var v []byte
+			v, n = protowire.ConsumeBytes(b)
+			if n >= 0 {
+				err = f(num, 0, v)
+			}
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+
+		if n < 0 {
+			return protowire.ParseError(n)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		b = b[n:]
+	}
+
+	return nil
+}
+
+// hashedRekord is the body of the hashedrekord Rekor entry gitsign uploads for the signed attributes of the signature.
+type hashedRekord struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Spec       struct {
+		Data struct {
+			Hash struct {
+				Algorithm string `json:"algorithm"`
+				Value     string `json:"value"`
+			} `json:"hash"`
+		} `json:"data"`
+		Signature struct {
+			Content   []byte `json:"content"`
+			PublicKey struct {
+				Content []byte `json:"content"`
+			} `json:"publicKey"`
+		} `json:"signature"`
+	} `json:"spec"`
+}
+
+// verifyTlogEntry verifies the bundled Rekor entry of the signature offline and returns the time the entry was integrated into the log:
+// the entry must match the signature, be promised by the signed entry timestamp
+// and be included into the log tree with the root hash of the checkpoint signed by the Rekor key.
+func verifyTlogEntry(sig *signature, rekorPublicKey crypto.PublicKey) (time.Time, error) {
+	if len(sig.TransparencyLogEntry) == 0 {
+		return time.Time{}, fmt.Errorf("transparency log entry not found in the signature")
+	}
+
+	entry, err := parseTlogEntry(sig.TransparencyLogEntry)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if err := verifyTlogEntryBody(entry.CanonicalizedBody, sig); err != nil {
+		return time.Time{}, err
+	}
+
+	rekorPublicKeyDER, err := x509.MarshalPKIXPublicKey(rekorPublicKey)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unable to marshal rekor public key: %s", err)
+	}
+	rekorKeyID := sha256.Sum256(rekorPublicKeyDER)
+
+	if !bytes.Equal(entry.LogID, rekorKeyID[:]) {
+		return time.Time{}, fmt.Errorf("transparency log entry is made by another log")
+	}
+
+	// The payload of the signed entry timestamp is the canonical JSON with the keys sorted
+	payload, err := json.Marshal(struct {
+		Body           string `json:"body"`
+		IntegratedTime int64  `json:"integratedTime"`
+		LogID          string `json:"logID"`
+		LogIndex       int64  `json:"logIndex"`
+	}{
+		Body:           base64.StdEncoding.EncodeToString(entry.CanonicalizedBody),
+		IntegratedTime: entry.IntegratedTime,
+		LogID:          hex.EncodeToString(entry.LogID),
+		LogIndex:       entry.LogIndex,
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if err := verifyRekorSignature(rekorPublicKey, payload, entry.SignedEntryTimestamp); err != nil {
+		return time.Time{}, fmt.Errorf("invalid signed entry timestamp: %s", err)
+	}
+
+	if entry.InclusionProof == nil {
+		return time.Time{}, fmt.Errorf("transparency log inclusion proof not found")
+	}
+
+	if err := verifyInclusionProof(entry.InclusionProof, entry.CanonicalizedBody); err != nil {
+		return time.Time{}, fmt.Errorf("invalid transparency log inclusion proof: %s", err)
+	}
+
+	if err := verifyCheckpoint(entry.InclusionProof, rekorPublicKey, rekorKeyID[:4]); err != nil {
+		return time.Time{}, fmt.Errorf("invalid transparency log checkpoint: %s", err)
+	}
+
+	return time.Unix(entry.IntegratedTime, 0), nil
+}
+
+func verifyTlogEntryBody(body []byte, sig *signature) error {
+	var rekord hashedRekord
+	if err := json.Unmarshal(body, &rekord); err != nil {
+		return fmt.Errorf("unable to parse transparency log entry body: %s", err)
+	}
+
+	if rekord.Kind != "hashedrekord" {
+		return fmt.Errorf("unsupported transparency log entry kind %q", rekord.Kind)
+	}
+
+	if rekord.Spec.Data.Hash.Algorithm != "sha256" {
+		return fmt.Errorf("unsupported transparency log entry hash algorithm %q", rekord.Spec.Data.Hash.Algorithm)
+	}
+
+	digest := sha256.Sum256(sig.SignedAttributes)
+	if rekord.Spec.Data.Hash.Value != hex.EncodeToString(digest[:]) {
+		return fmt.Errorf("transparency log entry is made for another signature")
+	}
+
+	if !bytes.Equal(rekord.Spec.Signature.Content, sig.Signature) {
+		return fmt.Errorf("transparency log entry is made for another signature")
+	}
+
+	block, _ := pem.Decode(rekord.Spec.Signature.PublicKey.Content)
+	if block == nil || !bytes.Equal(block.Bytes, sig.Signer.Raw) {
+		return fmt.Errorf("transparency log entry is made for another certificate")
+	}
+
+	return nil
+}
+
+// verifyInclusionProof verifies the inclusion of the entry into the log tree as described in https://datatracker.ietf.org/doc/html/rfc9162#section-2.1.3.2.
+func verifyInclusionProof(proof *inclusionProof, body []byte) error {
+	if proof.LogIndex < 0 || proof.LogIndex >= proof.TreeSize {
+		return fmt.Errorf("log index %d is out of the tree size %d", proof.LogIndex, proof.TreeSize)
+	}
+
+	fn, sn := uint64(proof.LogIndex), uint64(proof.TreeSize-1)
+	r := hashMerkleLeaf(body)
+	for _, p := range proof.Hashes {
+		if sn == 0 {
+			return fmt.Errorf("unexpected proof length")
+		}
+
+		if fn&1 == 1 || fn == sn {
+			r = hashMerkleChildren(p, r)
+			for fn&1 == 0 && fn != 0 {
+				fn >>= 1
+				sn >>= 1
+			}
+		} else {
+			r = hashMerkleChildren(r, p)
+		}
+
+		fn >>= 1
+		sn >>= 1
+	}
+
+	if sn != 0 {
+		return fmt.Errorf("unexpected proof length")
+	}
+
+	if !bytes.Equal(r, proof.RootHash) {
+		return fmt.Errorf("calculated root hash does not match the proof root hash")
+	}
+
+	return nil
+}
+
+func hashMerkleLeaf(data []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{0x00})
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func hashMerkleChildren(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{0x01})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// verifyCheckpoint verifies the checkpoint of the proof is a signed note (https://pkg.go.dev/golang.org/x/mod/sumdb/note) signed by the Rekor key,
+// which commits to the tree size and the root hash of the proof.
+func verifyCheckpoint(proof *inclusionProof, rekorPublicKey crypto.PublicKey, keyHint []byte) error {
+	ind := strings.Index(proof.Checkpoint, "\n\n")
+	if ind == -1 {
+		return fmt.Errorf("signed note expected")
+	}
+
+	text := proof.Checkpoint[:ind+1]
+	lines := strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	if len(lines) < 3 {
+		return fmt.Errorf("origin, tree size and root hash expected")
+	}
+
+	if treeSize, err := strconv.ParseInt(lines[1], 10, 64); err != nil || treeSize != proof.TreeSize {
+		return fmt.Errorf("tree size does not match the proof tree size")
+	}
+
+	if rootHash, err := base64.StdEncoding.DecodeString(lines[2]); err != nil || !bytes.Equal(rootHash, proof.RootHash) {
+		return fmt.Errorf("root hash does not match the proof root hash")
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(proof.Checkpoint[ind+2:], "\n"), "\n") {
+		if !strings.HasPrefix(line, "— ") {
+			return fmt.Errorf("malformed signature line %q", line)
+		}
+
+		fields := strings.Fields(strings.TrimPrefix(line, "— "))
+		if len(fields) != 2 {
+			return fmt.Errorf("malformed signature line %q", line)
+		}
+
+		sig, err := base64.StdEncoding.DecodeString(fields[1])
+		if err != nil || len(sig) < 5 {
+			return fmt.Errorf("malformed signature line %q", line)
+		}
+
+		if !bytes.Equal(sig[:4], keyHint) {
+			continue
+		}
+
+		if err := verifyRekorSignature(rekorPublicKey, []byte(text), sig[4:]); err != nil {
+			return err
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("signature of the rekor key not found")
+}
+
+func verifyRekorSignature(publicKey crypto.PublicKey, data, sig []byte) error {
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(data)
+		if !ecdsa.VerifyASN1(key, digest[:], sig) {
+			return fmt.Errorf("ecdsa signature verification failed")
+		}
+	case ed25519.PublicKey:
+		if !ed25519.Verify(key, data, sig) {
+			return fmt.Errorf("ed25519 signature verification failed")
+		}
+	default:
+		return fmt.Errorf("unsupported rekor public key type %T", publicKey)
+	}
+
+	return nil
+}
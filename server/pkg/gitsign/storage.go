@@ -0,0 +1,95 @@
+package gitsign
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	storageKeyConfiguration         = "gitsign/configuration"
+	storageKeyPrefixTrustedIdentity = "gitsign/trusted_identity/"
+)
+
+type configuration struct {
+	FulcioRoot     string `json:"fulcio_root"`
+	RekorPublicKey string `json:"rekor_public_key"`
+}
+
+// GetTrustPolicy returns the trust policy with the configured Fulcio root, Rekor public key and trusted identities
+// or nil if gitsign signatures verification is not configured.
+func GetTrustPolicy(ctx context.Context, storage logical.Storage) (*TrustPolicy, error) {
+	cfg, err := getConfiguration(ctx, storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg == nil {
+		return nil, nil
+	}
+
+	list, err := storage.List(ctx, storageKeyPrefixTrustedIdentity)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := &TrustPolicy{
+		FulcioRoots:    cfg.FulcioRoot,
+		RekorPublicKey: cfg.RekorPublicKey,
+	}
+
+	for _, name := range list {
+		identity, err := GetTrustedIdentity(ctx, storage, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if identity != nil {
+			policy.Identities = append(policy.Identities, *identity)
+		}
+	}
+
+	return policy, nil
+}
+
+func getConfiguration(ctx context.Context, storage logical.Storage) (*configuration, error) {
+	e, err := storage.Get(ctx, storageKeyConfiguration)
+	if err != nil {
+		return nil, err
+	}
+
+	if e == nil {
+		return nil, nil
+	}
+
+	var cfg configuration
+	if err := e.DecodeJSON(&cfg); err != nil {
+		return nil, fmt.Errorf("unable to decode gitsign configuration: %s", err)
+	}
+
+	return &cfg, nil
+}
+
+// GetTrustedIdentity returns the trusted identity by name or nil if the identity does not exist.
+func GetTrustedIdentity(ctx context.Context, storage logical.Storage, name string) (*Identity, error) {
+	e, err := storage.Get(ctx, trustedIdentityStorageKey(name))
+	if err != nil {
+		return nil, err
+	}
+
+	if e == nil {
+		return nil, nil
+	}
+
+	var identity Identity
+	if err := e.DecodeJSON(&identity); err != nil {
+		return nil, fmt.Errorf("unable to decode gitsign trusted identity %q: %s", name, err)
+	}
+
+	return &identity, nil
+}
+
+func trustedIdentityStorageKey(name string) string {
+	return storageKeyPrefixTrustedIdentity + name
+}
@@ -0,0 +1,405 @@
+package testutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+var (
+	oidSignedData                = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+	oidData                      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 1}
+	oidAttributeContentType      = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 3}
+	oidAttributeMessageDigest    = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 9, 4}
+	oidDigestAlgorithmSHA256     = asn1.ObjectIdentifier{2, 16, 840, 1, 101, 3, 4, 2, 1}
+	oidSignatureECDSAWithSHA256  = asn1.ObjectIdentifier{1, 2, 840, 10045, 4, 3, 2}
+	oidRekorTransparencyLogEntry = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 3, 1}
+	oidFulcioIssuerV2            = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 1, 8}
+)
+
+// Sigstore is the test Fulcio CA and Rekor transparency log,
+// the signatures are made the same way gitsign makes them in the offline Rekor mode.
+type Sigstore struct {
+	FulcioRoot     string
+	RekorPublicKey string
+
+	t               testing.TB
+	intermediate    *x509.Certificate
+	intermediateKey *ecdsa.PrivateKey
+	rekorKey        *ecdsa.PrivateKey
+}
+
+type SignOptions struct {
+	Subject string
+	Issuer  string
+
+	// IntegratedTime is the time the entry is integrated into the log, the current time by default.
+	// The certificate is valid for 10 minutes since the current time.
+	IntegratedTime time.Time
+
+	WithoutTransparencyLogEntry bool
+	InvalidInclusionProof       bool
+}
+
+func NewSigstore(t testing.TB) *Sigstore {
+	rootKey := generateKey(t)
+	root := createCertificate(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "sigstore"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}, nil, rootKey, rootKey)
+
+	intermediateKey := generateKey(t)
+	intermediate := createCertificate(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "sigstore-intermediate"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}, root, intermediateKey, rootKey)
+
+	rekorKey := generateKey(t)
+	rekorPublicKey, err := x509.MarshalPKIXPublicKey(rekorKey.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Sigstore{
+		FulcioRoot:     string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: root.Raw})),
+		RekorPublicKey: string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: rekorPublicKey})),
+
+		t:               t,
+		intermediate:    intermediate,
+		intermediateKey: intermediateKey,
+		rekorKey:        rekorKey,
+	}
+}
+
+// Sign returns the armored detached signature of the data made with the short-lived certificate issued for the identity.
+func (s *Sigstore) Sign(data []byte, opts SignOptions) string {
+	issuer, err := asn1.MarshalWithParams(opts.Issuer, "utf8")
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	key := generateKey(s.t)
+	cert := createCertificate(s.t, &x509.Certificate{
+		NotBefore:       time.Now().Add(-time.Minute),
+		NotAfter:        time.Now().Add(10 * time.Minute),
+		KeyUsage:        x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:     []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		EmailAddresses:  []string{opts.Subject},
+		ExtraExtensions: []pkix.Extension{{Id: oidFulcioIssuerV2, Value: issuer}},
+	}, s.intermediate, key, s.intermediateKey)
+
+	digest := sha256.Sum256(data)
+	signedAttrs := marshalAttributes(s.t, []attribute{
+		newAttribute(s.t, oidAttributeContentType, oidData),
+		newAttribute(s.t, oidAttributeMessageDigest, digest[:]),
+	})
+
+	signedAttrsDigest := sha256.Sum256(signedAttrs)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, signedAttrsDigest[:])
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	si := signerInfo{
+		Version:            1,
+		SID:                asn1.RawValue{FullBytes: mustMarshal(s.t, issuerAndSerialNumber{Issuer: asn1.RawValue{FullBytes: cert.RawIssuer}, SerialNumber: cert.SerialNumber})},
+		DigestAlgorithm:    pkix.AlgorithmIdentifier{Algorithm: oidDigestAlgorithmSHA256},
+		SignedAttrs:        asn1.RawValue{FullBytes: withTag(signedAttrs, 0xa0)},
+		SignatureAlgorithm: pkix.AlgorithmIdentifier{Algorithm: oidSignatureECDSAWithSHA256},
+		Signature:          sig,
+	}
+
+	if !opts.WithoutTransparencyLogEntry {
+		entry := s.transparencyLogEntry(signedAttrs, sig, cert, opts)
+		si.UnsignedAttrs = asn1.RawValue{FullBytes: withTag(marshalAttributes(s.t, []attribute{newAttribute(s.t, oidRekorTransparencyLogEntry, entry)}), 0xa1)}
+	}
+
+	sd := signedData{
+		Version:          1,
+		DigestAlgorithms: []pkix.AlgorithmIdentifier{{Algorithm: oidDigestAlgorithmSHA256}},
+		EncapContentInfo: encapsulatedContentInfo{EContentType: oidData},
+		Certificates:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: append(append([]byte{}, cert.Raw...), s.intermediate.Raw...)},
+		SignerInfos:      []signerInfo{si},
+	}
+
+	ci := contentInfo{
+		ContentType: oidSignedData,
+		Content:     asn1.RawValue{Class: asn1.ClassContextSpecific, Tag: 0, IsCompound: true, Bytes: mustMarshal(s.t, sd)},
+	}
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "SIGNED MESSAGE", Bytes: mustMarshal(s.t, ci)}))
+}
+
+// transparencyLogEntry returns the protobuf encoded dev.sigstore.rekor.v1.TransparencyLogEntry of the hashedrekord entry
+// included into the log tree with several other entries.
+func (s *Sigstore) transparencyLogEntry(signedAttrs, sig []byte, cert *x509.Certificate, opts SignOptions) []byte {
+	signedAttrsDigest := sha256.Sum256(signedAttrs)
+	body := mustJSON(s.t, map[string]interface{}{
+		"apiVersion": "0.0.1",
+		"kind":       "hashedrekord",
+		"spec": map[string]interface{}{
+			"data": map[string]interface{}{
+				"hash": map[string]interface{}{"algorithm": "sha256", "value": hex.EncodeToString(signedAttrsDigest[:])},
+			},
+			"signature": map[string]interface{}{
+				"content":   sig,
+				"publicKey": map[string]interface{}{"content": pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})},
+			},
+		},
+	})
+
+	integratedTime := opts.IntegratedTime
+	if integratedTime.IsZero() {
+		integratedTime = time.Now()
+	}
+
+	rekorPublicKey, err := x509.MarshalPKIXPublicKey(s.rekorKey.Public())
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	logID := sha256.Sum256(rekorPublicKey)
+
+	const treeIndex, treeSize, logIndex = 5, 7, 1005
+	leaves := make([][]byte, treeSize)
+	for i := range leaves {
+		leaves[i] = hashLeaf([]byte(fmt.Sprintf("entry %d", i)))
+	}
+	leaves[treeIndex] = hashLeaf(body)
+
+	rootHash := merkleRoot(leaves)
+	hashes := merklePath(treeIndex, leaves)
+	if opts.InvalidInclusionProof {
+		hashes[0] = hashLeaf([]byte("invalid"))
+	}
+
+	set := s.rekorSign(mustJSON(s.t, map[string]interface{}{
+		"body":           base64.StdEncoding.EncodeToString(body),
+		"integratedTime": integratedTime.Unix(),
+		"logID":          hex.EncodeToString(logID[:]),
+		"logIndex":       logIndex,
+	}))
+
+	checkpointText := fmt.Sprintf("rekor.test - 1\n%d\n%s\n", treeSize, base64.StdEncoding.EncodeToString(rootHash))
+	checkpointSignature := append(append([]byte{}, logID[:4]...), s.rekorSign([]byte(checkpointText))...)
+	checkpoint := fmt.Sprintf("%s\n— rekor.test %s\n", checkpointText, base64.StdEncoding.EncodeToString(checkpointSignature))
+
+	var proof []byte
+	proof = appendVarintField(proof, 1, treeIndex)
+	proof = appendBytesField(proof, 2, rootHash)
+	proof = appendVarintField(proof, 3, treeSize)
+	for _, h := range hashes {
+		proof = appendBytesField(proof, 4, h)
+	}
+	proof = appendBytesField(proof, 5, appendBytesField(nil, 1, []byte(checkpoint)))
+
+	var entry []byte
+	entry = appendVarintField(entry, 1, logIndex)
+	entry = appendBytesField(entry, 2, appendBytesField(nil, 1, logID[:]))
+	entry = appendBytesField(entry, 3, appendBytesField(appendBytesField(nil, 1, []byte("hashedrekord")), 2, []byte("0.0.1")))
+	entry = appendVarintField(entry, 4, uint64(integratedTime.Unix()))
+	entry = appendBytesField(entry, 5, appendBytesField(nil, 1, set))
+	entry = appendBytesField(entry, 6, proof)
+	entry = appendBytesField(entry, 7, body)
+
+	return entry
+}
+
+func (s *Sigstore) rekorSign(data []byte) []byte {
+	digest := sha256.Sum256(data)
+	sig, err := ecdsa.SignASN1(rand.Reader, s.rekorKey, digest[:])
+	if err != nil {
+		s.t.Fatal(err)
+	}
+
+	return sig
+}
+
+type contentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue
+}
+
+type signedData struct {
+	Version          int
+	DigestAlgorithms []pkix.AlgorithmIdentifier `asn1:"set"`
+	EncapContentInfo encapsulatedContentInfo
+	Certificates     asn1.RawValue
+	SignerInfos      []signerInfo `asn1:"set"`
+}
+
+type encapsulatedContentInfo struct {
+	EContentType asn1.ObjectIdentifier
+}
+
+type signerInfo struct {
+	Version            int
+	SID                asn1.RawValue
+	DigestAlgorithm    pkix.AlgorithmIdentifier
+	SignedAttrs        asn1.RawValue
+	SignatureAlgorithm pkix.AlgorithmIdentifier
+	Signature          []byte
+	UnsignedAttrs      asn1.RawValue `asn1:"optional"`
+}
+
+type issuerAndSerialNumber struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+type attribute struct {
+	Type   asn1.ObjectIdentifier
+	Values asn1.RawValue
+}
+
+func newAttribute(t testing.TB, oid asn1.ObjectIdentifier, value interface{}) attribute {
+	return attribute{
+		Type:   oid,
+		Values: asn1.RawValue{Class: asn1.ClassUniversal, Tag: asn1.TagSet, IsCompound: true, Bytes: mustMarshal(t, value)},
+	}
+}
+
+func marshalAttributes(t testing.TB, attrs []attribute) []byte {
+	res, err := asn1.MarshalWithParams(attrs, "set")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res
+}
+
+func withTag(der []byte, tag byte) []byte {
+	res := append([]byte{}, der...)
+	res[0] = tag
+	return res
+}
+
+func generateKey(t testing.TB) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key
+}
+
+func createCertificate(t testing.TB, template, parent *x509.Certificate, key, parentKey *ecdsa.PrivateKey) *x509.Certificate {
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.SerialNumber = serialNumber
+
+	if parent == nil {
+		parent = template
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, key.Public(), parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cert
+}
+
+func mustMarshal(t testing.TB, v interface{}) []byte {
+	res, err := asn1.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res
+}
+
+func mustJSON(t testing.TB, v interface{}) []byte {
+	res, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return res
+}
+
+func appendVarintField(b []byte, num protowire.Number, v uint64) []byte {
+	b = protowire.AppendTag(b, num, protowire.VarintType)
+	return protowire.AppendVarint(b, v)
+}
+
+func appendBytesField(b []byte, num protowire.Number, v []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendBytes(b, v)
+}
+
+// merkleRoot and merklePath implement the Merkle tree hash and the audit path described in https://datatracker.ietf.org/doc/html/rfc6962#section-2.1.
+func merkleRoot(leaves [][]byte) []byte {
+	if len(leaves) == 1 {
+		return leaves[0]
+	}
+
+	k := splitPoint(len(leaves))
+	return hashChildren(merkleRoot(leaves[:k]), merkleRoot(leaves[k:]))
+}
+
+func merklePath(m int, leaves [][]byte) [][]byte {
+	if len(leaves) == 1 {
+		return nil
+	}
+
+	k := splitPoint(len(leaves))
+	if m < k {
+		return append(merklePath(m, leaves[:k]), merkleRoot(leaves[k:]))
+	}
+
+	return append(merklePath(m-k, leaves[k:]), merkleRoot(leaves[:k]))
+}
+
+func splitPoint(n int) int {
+	k := 1
+	for k*2 < n {
+		k *= 2
+	}
+
+	return k
+}
+
+func hashLeaf(data []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{0x00})
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func hashChildren(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{0x01})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
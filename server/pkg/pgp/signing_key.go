@@ -0,0 +1,212 @@
+package pgp
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+)
+
+const (
+	SigningKeyAlgorithmRSA       = "rsa"
+	SigningKeyAlgorithmEd25519   = "ed25519"
+	SigningKeyAlgorithmECDSAP256 = "ecdsa-p256"
+
+	DefaultSigningKeyAlgorithm = SigningKeyAlgorithmRSA
+)
+
+var SigningKeyAlgorithms = []string{SigningKeyAlgorithmRSA, SigningKeyAlgorithmEd25519, SigningKeyAlgorithmECDSAP256}
+
+const (
+	signingKeyName    = "trdl"
+	signingKeyComment = "trdl server auto signer"
+)
+
+type SigningKey struct {
+	Entity *openpgp.Entity
+}
+
+// Algorithm returns the algorithm of the primary key, or an empty string if the algorithm is not one of SigningKeyAlgorithms.
+func (key *SigningKey) Algorithm() string {
+	switch pub := key.Entity.PrimaryKey.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return SigningKeyAlgorithmRSA
+	case *ed25519.PublicKey:
+		return SigningKeyAlgorithmEd25519
+	case *ecdsa.PublicKey:
+		if pub.Curve == elliptic.P256() {
+			return SigningKeyAlgorithmECDSAP256
+		}
+	}
+
+	return ""
+}
+
+func (key *SigningKey) SerializePublicKey(out io.Writer) error {
+	armoredOut, err := armor.Encode(out, openpgp.PublicKeyType, nil)
+	if err != nil {
+		return fmt.Errorf("unable to prepare armored writer: %s", err)
+	}
+
+	if err := key.Entity.Serialize(armoredOut); err != nil {
+		return err
+	}
+
+	if err := armoredOut.Close(); err != nil {
+		return fmt.Errorf("unable to close armored writer: %s", err)
+	}
+
+	return nil
+}
+
+// SerializePublicKeys writes the public keys of the entities as a single armored keyring.
+func SerializePublicKeys(out io.Writer, entities openpgp.EntityList) error {
+	armoredOut, err := armor.Encode(out, openpgp.PublicKeyType, nil)
+	if err != nil {
+		return fmt.Errorf("unable to prepare armored writer: %s", err)
+	}
+
+	for _, entity := range entities {
+		if err := entity.Serialize(armoredOut); err != nil {
+			return err
+		}
+	}
+
+	if err := armoredOut.Close(); err != nil {
+		return fmt.Errorf("unable to close armored writer: %s", err)
+	}
+
+	return nil
+}
+
+func (key *SigningKey) SerializeFull(out io.Writer) error {
+	return key.SerializePrivateKey(out)
+}
+
+func (key *SigningKey) SerializePrivateKey(out io.Writer) error {
+	armoredOut, err := armor.Encode(out, openpgp.PrivateKeyType, nil)
+	if err != nil {
+		return fmt.Errorf("unable to prepare armored writer: %s", err)
+	}
+
+	if err := key.Entity.SerializePrivate(armoredOut, nil); err != nil {
+		return err
+	}
+
+	if err := armoredOut.Close(); err != nil {
+		return fmt.Errorf("unable to close armored writer: %s", err)
+	}
+
+	return nil
+}
+
+func IsValidSigningKeyAlgorithm(algorithm string) bool {
+	for _, a := range SigningKeyAlgorithms {
+		if a == algorithm {
+			return true
+		}
+	}
+
+	return false
+}
+
+func GenerateSigningKey(algorithm string) (*SigningKey, error) {
+	config := &packet.Config{
+		Time:          time.Now,
+		Rand:          rand.Reader,
+		DefaultHash:   crypto.SHA256,
+		DefaultCipher: packet.CipherAES128,
+		RSABits:       4096,
+	}
+
+	var entity *openpgp.Entity
+	var err error
+	switch algorithm {
+	case SigningKeyAlgorithmRSA:
+		entity, err = openpgp.NewEntity(signingKeyName, signingKeyComment, "", config)
+	case SigningKeyAlgorithmEd25519:
+		var priv ed25519.PrivateKey
+		if _, priv, err = ed25519.GenerateKey(config.Random()); err == nil {
+			entity, err = newSigningEntity(packet.NewEdDSAPrivateKey(config.Now(), &priv), config)
+		}
+	case SigningKeyAlgorithmECDSAP256:
+		var priv *ecdsa.PrivateKey
+		if priv, err = ecdsa.GenerateKey(elliptic.P256(), config.Random()); err == nil {
+			entity, err = newSigningEntity(packet.NewECDSAPrivateKey(config.Now(), priv), config)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported signing key algorithm %q", algorithm)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate openpgp entity: %s", err)
+	}
+
+	return &SigningKey{Entity: entity}, nil
+}
+
+// newSigningEntity returns the entity with the single sign-only primary key,
+// the encryption subkey is not needed to sign the release artifacts.
+func newSigningEntity(primary *packet.PrivateKey, config *packet.Config) (*openpgp.Entity, error) {
+	uid := packet.NewUserId(signingKeyName, signingKeyComment, "")
+
+	isPrimaryID := true
+	selfSignature := &packet.Signature{
+		Version:            primary.PublicKey.Version,
+		SigType:            packet.SigTypePositiveCert,
+		PubKeyAlgo:         primary.PublicKey.PubKeyAlgo,
+		Hash:               config.Hash(),
+		CreationTime:       primary.PublicKey.CreationTime,
+		IssuerKeyId:        &primary.PublicKey.KeyId,
+		IssuerFingerprint:  primary.PublicKey.Fingerprint,
+		IsPrimaryId:        &isPrimaryID,
+		FlagsValid:         true,
+		FlagSign:           true,
+		FlagCertify:        true,
+		PreferredHash:      []uint8{8}, // SHA256
+		PreferredSymmetric: []uint8{uint8(config.Cipher())},
+	}
+
+	if err := selfSignature.SignUserId(uid.Id, &primary.PublicKey, primary, config); err != nil {
+		return nil, err
+	}
+
+	return &openpgp.Entity{
+		PrimaryKey: &primary.PublicKey,
+		PrivateKey: primary,
+		Identities: map[string]*openpgp.Identity{
+			uid.Id: {
+				Name:          uid.Id,
+				UserId:        uid,
+				SelfSignature: selfSignature,
+				Signatures:    []*packet.Signature{selfSignature},
+			},
+		},
+	}, nil
+}
+
+// ParseSigningKey parses the armored private key of any of SigningKeyAlgorithms.
+func ParseSigningKey(in io.Reader) (*SigningKey, error) {
+	el, err := openpgp.ReadArmoredKeyRing(in)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(el) == 0 {
+		return nil, fmt.Errorf("no private PGP signing key entities found")
+	}
+
+	return &SigningKey{Entity: el[0]}, nil
+}
+
+func SignDataStream(detachedSignatureOut io.Writer, dataStream io.Reader, key *SigningKey) error {
+	return openpgp.DetachSign(detachedSignatureOut, key.Entity, dataStream, nil)
+}
@@ -30,6 +30,20 @@ func GetTrustedPGPPublicKeys(ctx context.Context, storage logical.Storage) ([]st
 	return trustedPGPPublicKeys, nil
 }
 
+// GetTrustedPGPPublicKey returns the trusted key by name or an empty string if the key does not exist.
+func GetTrustedPGPPublicKey(ctx context.Context, storage logical.Storage, name string) (string, error) {
+	e, err := storage.Get(ctx, trustedPGPPublicKeyStorageKey(name))
+	if err != nil {
+		return "", err
+	}
+
+	if e == nil {
+		return "", nil
+	}
+
+	return string(e.Value), nil
+}
+
 func trustedPGPPublicKeyStorageKey(name string) string {
 	return storageKeyPrefixTrustedPGPPublicKey + name
 }
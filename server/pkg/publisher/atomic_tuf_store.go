@@ -0,0 +1,183 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/theupdateframework/go-tuf/data"
+	"github.com/theupdateframework/go-tuf/util"
+)
+
+// AtomicTufStore is the NonAtomicTufStore which commits the staged changes in the order safe for the TUF clients:
+// target files are staged aside of the published ones, verified and moved to the final paths first,
+// then the versioned metadata is uploaded, and timestamp.json, which references everything else, is written last.
+// A failure in the middle of the commit leaves the previously published timestamp.json untouched.
+type AtomicTufStore struct {
+	*NonAtomicTufStore
+}
+
+// AtomicStagingDir is the directory the staged target files are stored in until the commit,
+// so the published targets are not overwritten before the verification.
+// The files are deleted by the commit; the files left by the failed tasks are deleted by the periodic task.
+const AtomicStagingDir = "staging"
+
+func NewAtomicTufStore(privKeys TufRepoPrivKeys, filesystem Filesystem, logger hclog.Logger) *AtomicTufStore {
+	store := NewNonAtomicTufStore(privKeys, filesystem, logger)
+	store.stagingDir = AtomicStagingDir
+
+	return &AtomicTufStore{NonAtomicTufStore: store}
+}
+
+// atomicCommitOrder defines the order of writing the top-level metadata.
+// Each file is written only when all the files it references are already in place.
+var atomicCommitOrder = []string{
+	"root.json",
+	"targets.json",
+	"snapshot.json",
+	"timestamp.json",
+}
+
+func (store *AtomicTufStore) Commit(consistentSnapshot bool, versions map[string]int, hashes map[string]data.Hashes) error {
+	store.logger.Debug("-- AtomicTufStore.Commit")
+
+	ctx := context.Background()
+	hashes = store.targetsHashes(hashes)
+
+	// The staging files are not needed either after the successful commit or after the failed one
+	defer store.deleteStagingFiles(ctx)
+
+	// All the staged files are verified before any of them is published
+	for _, targetPath := range store.stagedFiles {
+		expectedHashes, hasKey := hashes[path.Join("targets", targetPath)]
+		if !hasKey {
+			return fmt.Errorf("staged target %q is not found in the targets metadata", targetPath)
+		}
+
+		if err := store.verifyFile(ctx, store.stagedFilePath(targetPath), expectedHashes); err != nil {
+			return fmt.Errorf("error verifying staged target %q: %s", targetPath, err)
+		}
+	}
+
+	for _, targetPath := range store.stagedFiles {
+		filePath := path.Join("targets", targetPath)
+		stagedFilePath := store.stagedFilePath(targetPath)
+
+		var publishedPaths []string
+		if consistentSnapshot {
+			publishedPaths = append(publishedPaths, computeTargetPaths(consistentSnapshot, filePath, hashes)...)
+		}
+		if stagedFilePath != filePath {
+			publishedPaths = append(publishedPaths, filePath)
+		}
+
+		for _, publishedPath := range publishedPaths {
+			if err := store.copyFile(ctx, stagedFilePath, publishedPath); err != nil {
+				return fmt.Errorf("error copying target %q into the filesystem: %s", publishedPath, err)
+			}
+
+			if err := store.verifyFile(ctx, publishedPath, hashes[filePath]); err != nil {
+				return fmt.Errorf("error verifying target %q: %s", publishedPath, err)
+			}
+		}
+	}
+
+	for _, name := range atomicCommitOrder {
+		data, hasKey := store.stagedMeta[name]
+		if !hasKey {
+			continue
+		}
+
+		metadataPaths := computeMetadataPaths(consistentSnapshot, name, versions)
+
+		// Versioned copies go first, the file with the well-known name is swapped last
+		for i := len(metadataPaths) - 1; i >= 0; i-- {
+			if err := store.writeMetadataFile(ctx, metadataPaths[i], data); err != nil {
+				return err
+			}
+		}
+	}
+
+	store.stagedMeta = make(map[string]json.RawMessage)
+	store.delegatedTargets = nil
+	store.rotatedRootKeys = nil
+
+	return nil
+}
+
+// deleteStagingFiles deletes the staging files of the staged targets and unstages the targets.
+// The leftover staging files do not affect the published repository, so the failed deletion is not an error.
+func (store *AtomicTufStore) deleteStagingFiles(ctx context.Context) {
+	for _, targetPath := range store.stagedFiles {
+		if store.restagedFiles[targetPath] {
+			continue
+		}
+
+		stagingPath := store.stagedFilePath(targetPath)
+		if err := store.Filesystem.DeleteFile(ctx, stagingPath); err != nil {
+			store.logger.Error(fmt.Sprintf("Unable to delete staging file %q: %s", stagingPath, err))
+		}
+	}
+
+	store.stagedFiles = nil
+	store.restagedFiles = nil
+}
+
+func (store *AtomicTufStore) writeMetadataFile(ctx context.Context, metadataPath string, data json.RawMessage) error {
+	store.logger.Debug(fmt.Sprintf("-- AtomicTufStore.Commit storing metadata path %q into the filesystem", metadataPath))
+
+	if err := store.Filesystem.WriteFileBytes(ctx, metadataPath, data); err != nil {
+		return fmt.Errorf("error writing metadata path %q into the filesystem: %s", metadataPath, err)
+	}
+
+	expectedMeta, err := util.GenerateFileMeta(bytes.NewReader(data), "sha512")
+	if err != nil {
+		return fmt.Errorf("error generating metadata path %q hashes: %s", metadataPath, err)
+	}
+
+	if err := store.verifyFile(ctx, metadataPath, expectedMeta.Hashes); err != nil {
+		return fmt.Errorf("error verifying metadata path %q: %s", metadataPath, err)
+	}
+
+	return nil
+}
+
+// verifyFile reads the file back from the filesystem and compares its hashes with the expected ones.
+func (store *AtomicTufStore) verifyFile(ctx context.Context, filePath string, expectedHashes data.Hashes) error {
+	var hashAlgorithms []string
+	for alg := range expectedHashes {
+		hashAlgorithms = append(hashAlgorithms, alg)
+	}
+
+	if len(hashAlgorithms) == 0 {
+		return fmt.Errorf("no expected hashes")
+	}
+
+	reader, writer := io.Pipe()
+	defer reader.Close()
+
+	go func() {
+		if err := store.Filesystem.ReadFileStream(ctx, filePath, writer); err != nil {
+			writer.CloseWithError(fmt.Errorf("error reading file: %s", err))
+			return
+		}
+		writer.Close()
+	}()
+
+	actualMeta, err := util.GenerateFileMeta(reader, hashAlgorithms...)
+	if err != nil {
+		return fmt.Errorf("error generating file hashes: %s", err)
+	}
+
+	for alg, expected := range expectedHashes {
+		if !bytes.Equal(actualMeta.Hashes[alg], expected) {
+			return fmt.Errorf("%s hash mismatch: expected %s, got %s", alg, expected, actualMeta.Hashes[alg])
+		}
+	}
+
+	return nil
+}
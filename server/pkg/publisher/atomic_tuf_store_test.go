@@ -0,0 +1,97 @@
+package publisher
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAtomicTufStoreCommit_WriteOrder(t *testing.T) {
+	ctx := context.Background()
+	filesystem := &recordingFilesystem{memoryFilesystem: &memoryFilesystem{files: make(map[string][]byte)}}
+	repository := newTestRepositoryWithFilesystem(t, filesystem)
+
+	filesystem.writes = nil
+	if !assert.Nil(t, repository.StageTarget(ctx, "any-any/file", strings.NewReader("new data"))) {
+		t.FailNow()
+	}
+
+	// The published target is not touched until the commit
+	assert.Equal(t, []string{"staging/targets/any-any/file"}, filesystem.writes)
+	assert.Equal(t, "data", string(filesystem.files["targets/any-any/file"]))
+
+	if !assert.Nil(t, repository.CommitStaged(ctx)) {
+		t.FailNow()
+	}
+
+	assert.Equal(t, "new data", string(filesystem.files["targets/any-any/file"]))
+	assert.NotContains(t, filesystem.files, "staging/targets/any-any/file")
+
+	targetWrite := indexOfWrite(filesystem.writes, "targets/any-any/file")
+	targetsWrite := indexOfWrite(filesystem.writes, "targets.json")
+	snapshotWrite := indexOfWrite(filesystem.writes, "snapshot.json")
+	timestampWrite := indexOfWrite(filesystem.writes, "timestamp.json")
+
+	assert.NotEqual(t, -1, targetWrite)
+	assert.Less(t, targetWrite, targetsWrite)
+	assert.Less(t, targetsWrite, snapshotWrite)
+	assert.Less(t, snapshotWrite, timestampWrite)
+	assert.Equal(t, len(filesystem.writes)-1, timestampWrite)
+}
+
+func TestAtomicTufStoreCommit_HashMismatch(t *testing.T) {
+	ctx := context.Background()
+	repository, filesystem := newTestRepository(t)
+
+	targetsJSON := filesystem.files["targets.json"]
+	timestampJSON := filesystem.files["timestamp.json"]
+
+	if !assert.Nil(t, repository.StageTarget(ctx, "any-any/file", strings.NewReader("new data"))) {
+		t.FailNow()
+	}
+
+	// The staged file is changed after its hashes are put into targets.json
+	filesystem.files["staging/targets/any-any/file"] = []byte("tampered")
+
+	err := repository.CommitStaged(ctx)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "hash mismatch")
+	}
+
+	assert.Equal(t, "data", string(filesystem.files["targets/any-any/file"]))
+	assert.Equal(t, targetsJSON, filesystem.files["targets.json"])
+	assert.Equal(t, timestampJSON, filesystem.files["timestamp.json"])
+
+	// The staging files are deleted after the failed commit as well
+	assert.NotContains(t, filesystem.files, "staging/targets/any-any/file")
+}
+
+// recordingFilesystem records the paths of the written files in the order of writing.
+type recordingFilesystem struct {
+	*memoryFilesystem
+	writes []string
+}
+
+func (fs *recordingFilesystem) WriteFileBytes(ctx context.Context, filePath string, data []byte) error {
+	fs.writes = append(fs.writes, filePath)
+	return fs.memoryFilesystem.WriteFileBytes(ctx, filePath, data)
+}
+
+func (fs *recordingFilesystem) WriteFileStream(ctx context.Context, filePath string, data io.Reader) error {
+	fs.writes = append(fs.writes, filePath)
+	return fs.memoryFilesystem.WriteFileStream(ctx, filePath, data)
+}
+
+func indexOfWrite(writes []string, filePath string) int {
+	index := -1
+	for i, p := range writes {
+		if p == filePath {
+			index = i
+		}
+	}
+
+	return index
+}
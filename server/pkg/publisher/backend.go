@@ -4,20 +4,43 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/vault/sdk/framework"
 	"github.com/hashicorp/vault/sdk/logical"
+
+	"github.com/werf/trdl/server/pkg/pgp"
 )
 
+const fieldNamePGPSigningKeyAlgorithm = "algorithm"
+
 func (publisher *Publisher) Paths() []*framework.Path {
 	return []*framework.Path{
 		{
 			Pattern:      "configure/pgp_signing_key",
 			HelpSynopsis: "Configure a PGP key for signing release artifacts",
-			Fields:       map[string]*framework.FieldSchema{},
+			Fields: map[string]*framework.FieldSchema{
+				fieldNamePGPSigningKeyAlgorithm: {
+					Type:          framework.TypeString,
+					Description:   "The algorithm of the PGP signing key: " + strings.Join(pgp.SigningKeyAlgorithms, ", "),
+					Default:       pgp.DefaultSigningKeyAlgorithm,
+					AllowedValues: signingKeyAlgorithmsAllowedValues(),
+				},
+			},
 			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.CreateOperation: &framework.PathOperation{
+					Summary:     "Initialize the PGP signing key",
+					Description: "Generate the PGP signing key of the specified algorithm (the rsa key is generated automatically on demand otherwise)",
+					Callback:    publisher.pathConfigurePGPSigningKeyCreateOrUpdate,
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Summary:     "Initialize the PGP signing key",
+					Description: "Generate the PGP signing key of the specified algorithm (the rsa key is generated automatically on demand otherwise)",
+					Callback:    publisher.pathConfigurePGPSigningKeyCreateOrUpdate,
+				},
 				logical.ReadOperation: &framework.PathOperation{
-					Description: "Get the public part of the current PGP signing key",
+					Description: "Get the public part of the current PGP signing key and the retired keys which are still trusted during the overlap period",
 					Callback:    publisher.pathConfigurePGPSigningKeyRead,
 				},
 				logical.DeleteOperation: &framework.PathOperation{
@@ -27,9 +50,81 @@ func (publisher *Publisher) Paths() []*framework.Path {
 				},
 			},
 		},
+		{
+			Pattern:         "pgp_signing_key$",
+			HelpSynopsis:    "Get the public keys to verify the release artifacts signatures",
+			HelpDescription: "Get the armored keyring with the public parts of the current PGP signing key and the retired keys which are still trusted. The same keyring is published as the " + PGPSigningPublicKeyTarget + " TUF target",
+			Fields:          map[string]*framework.FieldSchema{},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Description: "Get the public keys to verify the release artifacts signatures",
+					Callback:    publisher.pathPGPSigningKeyRead,
+				},
+			},
+		},
 	}
 }
 
+func (publisher *Publisher) pathPGPSigningKeyRead(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	publicKeys, err := publisher.GetPGPSigningPublicKeys(ctx, req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("error getting pgp signing public keys: %s", err)
+	}
+
+	if publicKeys == nil {
+		return logical.ErrorResponse("PGP signing key is not initialized"), nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"public_keys": string(publicKeys),
+			"target":      PGPSigningPublicKeyTarget,
+		},
+	}, nil
+}
+
+func signingKeyAlgorithmsAllowedValues() []interface{} {
+	var res []interface{}
+	for _, algorithm := range pgp.SigningKeyAlgorithms {
+		res = append(res, algorithm)
+	}
+	return res
+}
+
+func (publisher *Publisher) pathConfigurePGPSigningKeyCreateOrUpdate(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
+	algorithm := fields.Get(fieldNamePGPSigningKeyAlgorithm).(string)
+	if !pgp.IsValidSigningKeyAlgorithm(algorithm) {
+		return logical.ErrorResponse("Unsupported PGP signing key algorithm %q: expected one of %s", algorithm, strings.Join(pgp.SigningKeyAlgorithms, ", ")), nil
+	}
+
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+
+	_, err := publisher.fetchPGPSigningKey(ctx, req.Storage, false)
+	if err == nil {
+		return logical.ErrorResponse("PGP signing key already exists: use the pgp_signing_key/rotate path to replace it"), nil
+	} else if err != ErrUninitializedPGPSigningKey {
+		return nil, fmt.Errorf("error fetching pgp signing key: %s", err)
+	}
+
+	key, err := publisher.initializePGPSigningKey(ctx, req.Storage, algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	pk := bytes.NewBuffer(nil)
+	if err := key.SerializePublicKey(pk); err != nil {
+		return nil, fmt.Errorf("unable to get public key text: %s", err)
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"algorithm":  key.Algorithm(),
+			"public_key": pk.String(),
+		},
+	}, nil
+}
+
 func (publisher *Publisher) pathConfigurePGPSigningKeyRead(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
 	key, err := publisher.fetchPGPSigningKey(ctx, req.Storage, true)
 	if err != nil {
@@ -41,9 +136,24 @@ func (publisher *Publisher) pathConfigurePGPSigningKeyRead(ctx context.Context,
 		return nil, fmt.Errorf("unable to get public key text: %s", err)
 	}
 
+	retiredKeys, err := publisher.getRetiredPGPSigningKeys(ctx, req.Storage)
+	if err != nil {
+		return nil, fmt.Errorf("error getting retired pgp signing keys: %s", err)
+	}
+
+	var retiredPublicKeys []map[string]interface{}
+	for _, retiredKey := range retiredKeys {
+		retiredPublicKeys = append(retiredPublicKeys, map[string]interface{}{
+			"public_key": retiredKey.PublicKey,
+			"expires":    retiredKey.Expires.Format(time.RFC3339),
+		})
+	}
+
 	return &logical.Response{
 		Data: map[string]interface{}{
-			"public_key": pk.String(),
+			"algorithm":           key.Algorithm(),
+			"public_key":          pk.String(),
+			"retired_public_keys": retiredPublicKeys,
 		},
 	}, nil
 }
@@ -0,0 +1,71 @@
+package publisher
+
+import (
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/theupdateframework/go-tuf/data"
+)
+
+// RootBootstrap describes the published root.json the trdl clients are bootstrapped with.
+type RootBootstrap struct {
+	Version int
+
+	// Sha512 is the checksum of the root.json file, which is the same for the <version>.root.json file.
+	Sha512 string
+
+	// KeyIDs are the key IDs of each role trusted by the root.json.
+	KeyIDs map[string][]string
+}
+
+// GetRootBootstrap returns the bootstrap information of the published root.json or nil if the repository is not initialized.
+func (publisher *Publisher) GetRootBootstrap(ctx context.Context, storage logical.Storage, options RepositoryOptions) (*RootBootstrap, error) {
+	repository, err := publisher.newRepository(storage, options)
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := repository.Filesystem.IsFileExist(ctx, "root.json")
+	if err != nil {
+		return nil, fmt.Errorf("error checking %q existence: %s", "root.json", err)
+	}
+
+	if !exists {
+		return nil, nil
+	}
+
+	rootJSON, err := repository.Filesystem.ReadFileBytes(ctx, "root.json")
+	if err != nil {
+		return nil, fmt.Errorf("error reading %q: %s", "root.json", err)
+	}
+
+	signed := &data.Signed{}
+	if err := json.Unmarshal(rootJSON, signed); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal root.json: %s", err)
+	}
+
+	root, err := unmarshalRoot(signed)
+	if err != nil {
+		return nil, err
+	}
+
+	checksum := sha512.Sum512(rootJSON)
+
+	bootstrap := &RootBootstrap{
+		Version: root.Version,
+		Sha512:  hex.EncodeToString(checksum[:]),
+		KeyIDs:  make(map[string][]string),
+	}
+
+	for _, role := range tufRoles {
+		if r, hasKey := root.Roles[role]; hasKey {
+			bootstrap.KeyIDs[role] = r.KeyIDs
+		}
+	}
+
+	return bootstrap, nil
+}
@@ -0,0 +1,332 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/theupdateframework/go-tuf/data"
+	"github.com/theupdateframework/go-tuf/sign"
+)
+
+// DelegationsDir is the targets directory the delegated targets metadata is published in.
+//
+// The TUF library does not support delegations, so the metadata of the delegated role is published
+// as the regular target delegations/<role>.json. The target is pinned by its hashes in targets.json,
+// and its custom data contains the delegated role keys and the target paths the role is trusted for.
+// Thus the delegated metadata needs neither the expiration date nor the snapshot.json entry:
+// its freshness is guaranteed by targets.json.
+const DelegationsDir = "delegations"
+
+// DelegatedTargets is the signed metadata of the delegated targets role.
+type DelegatedTargets struct {
+	Type    string           `json:"_type"`
+	Role    string           `json:"role"`
+	Version int              `json:"version"`
+	Targets data.TargetFiles `json:"targets"`
+}
+
+// Delegation is the custom data of the delegated targets metadata target in targets.json.
+type Delegation struct {
+	Role         string               `json:"role"`
+	Keys         map[string]*data.Key `json:"keys"`
+	Threshold    int                  `json:"threshold"`
+	PathPrefixes []string             `json:"path_prefixes"`
+}
+
+type delegationCustom struct {
+	Delegation *Delegation `json:"delegation"`
+}
+
+// delegatedRole returns the delegated role the target belongs to: the release targets and signatures
+// are grouped by the major version of the release, and the channels have the role of their own.
+// The other targets are listed in targets.json, so the empty role is returned.
+func delegatedRole(pathInsideTargets string) string {
+	parts := strings.SplitN(pathInsideTargets, "/", 3)
+	if len(parts) < 2 {
+		return ""
+	}
+
+	switch parts[0] {
+	case "releases", "signatures":
+		versionParts := strings.SplitN(parts[1], ".", 2)
+		if len(versionParts) != 2 {
+			return ""
+		}
+
+		return "releases-" + versionParts[0]
+	case "channels":
+		return "channels"
+	default:
+		return ""
+	}
+}
+
+// delegatedRolePathPrefixes returns the target paths prefixes of the role, which are consistent with delegatedRole.
+func delegatedRolePathPrefixes(role string) []string {
+	if major := strings.TrimPrefix(role, "releases-"); major != role {
+		return []string{fmt.Sprintf("releases/%s.", major), fmt.Sprintf("signatures/%s.", major)}
+	}
+
+	return []string{role + "/"}
+}
+
+func delegationTargetPath(role string) string {
+	return path.Join(DelegationsDir, role+".json")
+}
+
+// targets returns the targets listed in targets.json along with the targets of all the delegated roles.
+func (repository *S3Repository) targets(ctx context.Context) (data.TargetFiles, error) {
+	topLevelTargets, err := repository.TufRepo.Targets()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get TUF-repo targets metadata: %s", err)
+	}
+
+	res := make(data.TargetFiles)
+	for pathInsideTargets := range topLevelTargets {
+		if !strings.HasPrefix(pathInsideTargets, DelegationsDir+"/") {
+			continue
+		}
+
+		role := strings.TrimSuffix(strings.TrimPrefix(pathInsideTargets, DelegationsDir+"/"), ".json")
+		delegatedTargets, err := repository.getDelegatedTargets(ctx, role, topLevelTargets)
+		if err != nil {
+			return nil, err
+		}
+
+		for delegatedPath, delegatedMeta := range delegatedTargets.Targets {
+			res[delegatedPath] = delegatedMeta
+		}
+	}
+
+	// The target staged into targets.json replaces the delegated one
+	for pathInsideTargets, targetMeta := range topLevelTargets {
+		res[pathInsideTargets] = targetMeta
+	}
+
+	return res, nil
+}
+
+// getDelegatedTargets returns the delegated role metadata including the changes which have not been committed yet.
+// The role which is not listed in targets.json gets the empty metadata.
+func (repository *S3Repository) getDelegatedTargets(ctx context.Context, role string, topLevelTargets data.TargetFiles) (*DelegatedTargets, error) {
+	if delegatedTargets, hasKey := repository.delegatedTargets[role]; hasKey {
+		return delegatedTargets, nil
+	}
+
+	delegatedTargets := &DelegatedTargets{Type: "delegated-targets", Role: role, Targets: make(data.TargetFiles)}
+	if targetMeta, hasKey := topLevelTargets[delegationTargetPath(role)]; hasKey {
+		metaJSON, err := repository.readTargetFile(ctx, delegationTargetPath(role), targetMeta)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read delegated role %q metadata: %s", role, err)
+		}
+
+		if err := unmarshalSignedMeta(metaJSON, delegatedTargets); err != nil {
+			return nil, fmt.Errorf("unable to parse delegated role %q metadata: %s", role, err)
+		}
+
+		if delegatedTargets.Targets == nil {
+			delegatedTargets.Targets = make(data.TargetFiles)
+		}
+	}
+
+	if repository.delegatedTargets == nil {
+		repository.delegatedTargets = make(map[string]*DelegatedTargets)
+	}
+	repository.delegatedTargets[role] = delegatedTargets
+
+	return delegatedTargets, nil
+}
+
+// removeDelegatedTargets removes the targets from the delegated roles metadata.
+// Returns the targets which are not listed in the delegated roles.
+func (repository *S3Repository) removeDelegatedTargets(ctx context.Context, pathsInsideTargets []string) ([]string, error) {
+	topLevelTargets, err := repository.TufRepo.Targets()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get TUF-repo targets metadata: %s", err)
+	}
+
+	var rest []string
+	for _, pathInsideTargets := range pathsInsideTargets {
+		role := delegatedRole(pathInsideTargets)
+		_, hasDelegation := topLevelTargets[delegationTargetPath(role)]
+		if _, isTopLevel := topLevelTargets[pathInsideTargets]; isTopLevel || role == "" || !hasDelegation {
+			rest = append(rest, pathInsideTargets)
+			continue
+		}
+
+		delegatedTargets, err := repository.getDelegatedTargets(ctx, role, topLevelTargets)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, hasKey := delegatedTargets.Targets[pathInsideTargets]; hasKey {
+			delete(delegatedTargets.Targets, pathInsideTargets)
+			repository.setDelegatedRoleChanged(role)
+		}
+	}
+
+	return rest, nil
+}
+
+func (repository *S3Repository) setDelegatedRoleChanged(role string) {
+	if repository.changedDelegatedRoles == nil {
+		repository.changedDelegatedRoles = make(map[string]bool)
+	}
+	repository.changedDelegatedRoles[role] = true
+}
+
+// stageDelegations moves the targets of the delegated roles from targets.json into the delegated roles metadata,
+// if the delegations are enabled, and stages the changed delegated roles metadata.
+// With the delegations disabled the targets staged into targets.json are removed from the delegated roles.
+func (repository *S3Repository) stageDelegations(ctx context.Context) error {
+	topLevelTargets, err := repository.TufRepo.Targets()
+	if err != nil {
+		return fmt.Errorf("unable to get TUF-repo targets metadata: %s", err)
+	}
+
+	var movedTargets []string
+	for pathInsideTargets, targetMeta := range topLevelTargets {
+		role := delegatedRole(pathInsideTargets)
+		if role == "" {
+			continue
+		}
+
+		_, hasDelegation := topLevelTargets[delegationTargetPath(role)]
+		if !repository.Delegations && !hasDelegation {
+			continue
+		}
+
+		delegatedTargets, err := repository.getDelegatedTargets(ctx, role, topLevelTargets)
+		if err != nil {
+			return err
+		}
+
+		if repository.Delegations {
+			delegatedTargets.Targets[pathInsideTargets] = targetMeta
+			movedTargets = append(movedTargets, pathInsideTargets)
+			repository.setDelegatedRoleChanged(role)
+		} else if _, hasKey := delegatedTargets.Targets[pathInsideTargets]; hasKey {
+			delete(delegatedTargets.Targets, pathInsideTargets)
+			repository.setDelegatedRoleChanged(role)
+		}
+	}
+
+	if len(movedTargets) > 0 {
+		if err := repository.TufRepo.RemoveTargetsWithExpires(movedTargets, repository.expires("targets")); err != nil {
+			return fmt.Errorf("unable to move targets into the delegated roles: %s", err)
+		}
+	}
+
+	var changedRoles []string
+	for role := range repository.changedDelegatedRoles {
+		changedRoles = append(changedRoles, role)
+	}
+	sort.Strings(changedRoles)
+
+	privKeysGenerated := false
+	for _, role := range changedRoles {
+		generated, err := repository.stageDelegatedRole(ctx, role)
+		if err != nil {
+			return fmt.Errorf("unable to stage delegated role %q metadata: %s", role, err)
+		}
+
+		privKeysGenerated = privKeysGenerated || generated
+	}
+	repository.changedDelegatedRoles = nil
+
+	// The staged files of the delegated targets are published along with the ones listed in targets.json
+	delegatedTargetFiles := make(data.TargetFiles)
+	for _, delegatedTargets := range repository.delegatedTargets {
+		for pathInsideTargets, targetMeta := range delegatedTargets.Targets {
+			delegatedTargetFiles[pathInsideTargets] = targetMeta
+		}
+	}
+	repository.TufStore.SetDelegatedTargets(delegatedTargetFiles)
+
+	// The new key is stored before the metadata signed by the key is published
+	if privKeysGenerated && repository.SavePrivKeys != nil {
+		if err := repository.SavePrivKeys(ctx, repository.GetPrivKeys()); err != nil {
+			return fmt.Errorf("unable to save delegated roles private keys: %s", err)
+		}
+	}
+
+	return nil
+}
+
+// stageDelegatedRole signs the delegated role metadata and stages it into targets.json along with the role keys.
+// Returns true when the private key of the role has been generated.
+func (repository *S3Repository) stageDelegatedRole(ctx context.Context, role string) (bool, error) {
+	key, generated, err := repository.delegatedRoleKey(role)
+	if err != nil {
+		return false, err
+	}
+
+	delegatedTargets := repository.delegatedTargets[role]
+	delegatedTargets.Version++
+
+	signed, err := sign.Marshal(delegatedTargets, key.Signer())
+	if err != nil {
+		return false, fmt.Errorf("unable to sign metadata: %s", err)
+	}
+
+	metaJSON, err := json.Marshal(signed)
+	if err != nil {
+		return false, fmt.Errorf("unable to marshal metadata: %s", err)
+	}
+
+	keys := make(map[string]*data.Key)
+	for _, id := range key.PublicData().IDs() {
+		keys[id] = key.PublicData()
+	}
+
+	custom, err := json.Marshal(delegationCustom{Delegation: &Delegation{
+		Role:         role,
+		Keys:         keys,
+		Threshold:    1,
+		PathPrefixes: delegatedRolePathPrefixes(role),
+	}})
+	if err != nil {
+		return false, fmt.Errorf("unable to marshal delegation: %s", err)
+	}
+
+	targetPath := delegationTargetPath(role)
+	if err := repository.TufStore.StageTargetFile(ctx, targetPath, bytes.NewReader(metaJSON)); err != nil {
+		return false, fmt.Errorf("unable to add staged file %q: %s", targetPath, err)
+	}
+
+	if err := repository.TufRepo.AddTargetWithExpires(targetPath, custom, repository.expires("targets")); err != nil {
+		return false, fmt.Errorf("unable to register target file %q in the tuf repo: %s", targetPath, err)
+	}
+
+	return generated, nil
+}
+
+// delegatedRoleKey returns the private key of the delegated role generating the new one for the new role.
+func (repository *S3Repository) delegatedRoleKey(role string) (*sign.PrivateKey, bool, error) {
+	privKeys := repository.TufStore.GetPrivKeys()
+	if key, hasKey := privKeys.Delegations[role]; hasKey && key != nil {
+		return key, false, nil
+	}
+
+	key, err := sign.GenerateEd25519Key()
+	if err != nil {
+		return nil, false, fmt.Errorf("error generating key: %s", err)
+	}
+
+	delegationsKeys := make(map[string]*sign.PrivateKey)
+	for r, k := range privKeys.Delegations {
+		delegationsKeys[r] = k
+	}
+	delegationsKeys[role] = key
+	privKeys.Delegations = delegationsKeys
+	repository.TufStore.SetPrivKeys(privKeys)
+
+	repository.logger.Info(fmt.Sprintf("Generated TUF repository private key of delegated role %q", role))
+
+	return key, true, nil
+}
@@ -12,4 +12,6 @@ type Filesystem interface {
 	ReadFileBytes(ctx context.Context, path string) ([]byte, error)
 	WriteFileBytes(ctx context.Context, path string, data []byte) error
 	WriteFileStream(ctx context.Context, path string, reader io.Reader) error
+	DeleteFile(ctx context.Context, path string) error
+	ListFiles(ctx context.Context, prefix string) ([]string, error)
 }
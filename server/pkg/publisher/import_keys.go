@@ -0,0 +1,116 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/theupdateframework/go-tuf/data"
+)
+
+var ErrRepositoryKeysAlreadyExist = errors.New("repository keys already exist")
+
+// ImportRepositoryKeys saves the existing repository private keys, e.g. created with the go-tuf CLI, into the storage.
+// The keys are validated against the published root.json: each key must belong to the role
+// and every online role must have enough keys to meet its threshold.
+// The root keys are optional, the root is kept offline if not enough root keys are imported.
+func (publisher *Publisher) ImportRepositoryKeys(ctx context.Context, storage logical.Storage, options RepositoryOptions, privKeys TufRepoPrivKeys) error {
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+
+	existingPrivKeys, err := getRepositoryKeys(ctx, storage)
+	if err != nil {
+		return err
+	}
+
+	if existingPrivKeys != nil {
+		return ErrRepositoryKeysAlreadyExist
+	}
+
+	repository, err := publisher.newRepository(storage, options)
+	if err != nil {
+		return err
+	}
+
+	exists, err := repository.Filesystem.IsFileExist(ctx, "root.json")
+	if err != nil {
+		return fmt.Errorf("error checking %q existence: %s", "root.json", err)
+	}
+
+	if !exists {
+		return fmt.Errorf("%q not found in the repository", "root.json")
+	}
+
+	root, err := repository.getPublishedRoot()
+	if err != nil {
+		return err
+	}
+
+	if err := validateRepositoryPrivKeys(root, privKeys); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	privKeys.Expires = make(map[string]time.Time)
+	for _, role := range tufRoles {
+		if len(*privKeys.roleKeys(role)) != 0 {
+			privKeys.Expires[role] = now.Add(privKeysLifetime[role]).UTC().Round(time.Second)
+		}
+	}
+
+	if err := putRepositoryKeys(ctx, storage, privKeys); err != nil {
+		return err
+	}
+
+	publisher.logger.Info("Imported repository private keys")
+
+	return nil
+}
+
+func validateRepositoryPrivKeys(root *data.Root, privKeys TufRepoPrivKeys) error {
+	for _, role := range tufRoles {
+		roleKeys := *privKeys.roleKeys(role)
+
+		keyIDs := make(map[string]bool)
+		for _, key := range roleKeys {
+			if key.Type != data.KeyTypeEd25519 {
+				return fmt.Errorf("%s key type %q is not supported", role, key.Type)
+			}
+
+			if len(key.Value.Private) != ed25519.PrivateKeySize {
+				return fmt.Errorf("%s key is invalid: unexpected private key size", role)
+			}
+
+			publicKey := ed25519.PrivateKey(key.Value.Private).Public().(ed25519.PublicKey)
+			if !bytes.Equal(publicKey, key.Value.Public) {
+				return fmt.Errorf("%s key is invalid: the public key does not match the private key", role)
+			}
+
+			publicData := key.PublicData()
+			if !isRoleKey(root, role, publicData) {
+				return fmt.Errorf("%s key %q is not a %s key of root.json", role, publicData.IDs()[0], role)
+			}
+
+			keyIDs[publicData.IDs()[0]] = true
+		}
+
+		if role == "root" {
+			continue
+		}
+
+		r, hasKey := root.Roles[role]
+		if !hasKey {
+			return fmt.Errorf("%s role not found in root.json", role)
+		}
+
+		if len(keyIDs) < r.Threshold {
+			return fmt.Errorf("%s keys required: %d of %d imported", role, len(keyIDs), r.Threshold)
+		}
+	}
+
+	return nil
+}
@@ -3,8 +3,12 @@ package publisher
 import (
 	"context"
 	"io"
+	"time"
 
 	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/theupdateframework/go-tuf"
+	"github.com/theupdateframework/go-tuf/data"
+	"github.com/theupdateframework/go-tuf/sign"
 
 	"github.com/werf/trdl/server/pkg/config"
 )
@@ -12,11 +16,32 @@ import (
 type Interface interface {
 	GetRepository(ctx context.Context, storage logical.Storage, options RepositoryOptions) (RepositoryInterface, error)
 	RotateRepositoryKeys(ctx context.Context, storage logical.Storage, repository RepositoryInterface) error
+	IsRepositoryKeysRotationDue(ctx context.Context, storage logical.Storage) (bool, error)
 	UpdateTimestamps(ctx context.Context, storage logical.Storage, repository RepositoryInterface) error
+	MigrateToConsistentSnapshot(ctx context.Context, storage logical.Storage, repository RepositoryInterface) error
+	SyncStorageMirrors(ctx context.Context, storage logical.Storage, repository RepositoryInterface) error
 	StageReleaseTarget(ctx context.Context, repository RepositoryInterface, releaseName, path string, data io.Reader) error
 	StageChannelsConfig(ctx context.Context, repository RepositoryInterface, trdlChannelsConfig *config.TrdlChannels) error
 	StageInMemoryFiles(ctx context.Context, repository RepositoryInterface, files []*InMemoryFile) error
 	GetExistingReleases(ctx context.Context, repository RepositoryInterface) ([]string, error)
+	GetChannels(ctx context.Context, repository RepositoryInterface) (map[string]map[string]string, error)
+	GetReleaseTargets(ctx context.Context, repository RepositoryInterface, releaseName string) ([]*ReleaseTarget, error)
+	StageReleaseRevocation(ctx context.Context, repository RepositoryInterface, releaseName string) error
+	StageReleasesRemoval(ctx context.Context, repository RepositoryInterface, releases []string) error
+	GetRevokedReleases(ctx context.Context, repository RepositoryInterface) ([]string, error)
+	PreparePendingRoot(ctx context.Context, storage logical.Storage, repository RepositoryInterface, opts PrepareRootOptions) (*PendingRoot, error)
+	GetPendingRoot(ctx context.Context, storage logical.Storage) (*PendingRoot, error)
+	DeletePendingRoot(ctx context.Context, storage logical.Storage) error
+	AddPendingRootSignatures(ctx context.Context, storage logical.Storage, repository RepositoryInterface, signatures []data.Signature) (*PendingRoot, error)
+	PublishPendingRoot(ctx context.Context, storage logical.Storage, repository RepositoryInterface) error
+	ImportRepositoryKeys(ctx context.Context, storage logical.Storage, options RepositoryOptions, privKeys TufRepoPrivKeys) error
+	GetRootBootstrap(ctx context.Context, storage logical.Storage, options RepositoryOptions) (*RootBootstrap, error)
+	RotatePGPSigningKey(ctx context.Context, storage logical.Storage, algorithm string, overlapPeriod time.Duration) error
+	GetRetiredPGPSigningKeys(ctx context.Context, storage logical.Storage) ([]RetiredPGPSigningKey, error)
+	StageReleaseSignatures(ctx context.Context, repository RepositoryInterface) error
+	GetPGPSigningPublicKeys(ctx context.Context, storage logical.Storage) ([]byte, error)
+	IsPGPSigningPublicKeyPublished(ctx context.Context, storage logical.Storage, repository RepositoryInterface) (bool, error)
+	StagePGPSigningPublicKey(ctx context.Context, storage logical.Storage, repository RepositoryInterface) error
 }
 
 type RepositoryInterface interface {
@@ -25,8 +50,36 @@ type RepositoryInterface interface {
 	GetPrivKeys() TufRepoPrivKeys
 	GenPrivKeys() error
 	RotatePrivKeys(ctx context.Context) (bool, TufRepoPrivKeys, error)
+	GetExpiredPrivKeysRoles() []string
 	UpdateTimestamps(ctx context.Context) error
+	GetExpiringMetadataRoles(ctx context.Context) ([]string, error)
 	StageTarget(ctx context.Context, pathInsideTargets string, data io.Reader) error
 	CommitStaged(ctx context.Context) error
+	GetStaleStagingFiles(ctx context.Context) ([]string, error)
+	DeleteStaleStagingFiles(ctx context.Context) error
 	GetTargets(ctx context.Context) ([]string, error)
+	RemoveTargets(ctx context.Context, pathsInsideTargets []string) error
+	GetTargetsMeta(ctx context.Context) (map[string]TargetMeta, error)
+	GetTargetFile(ctx context.Context, pathInsideTargets string) ([]byte, error)
+	IsRootOffline() (bool, error)
+	PrepareRoot(ctx context.Context, opts PrepareRootOptions) (*PendingRoot, error)
+	AddRootSignatures(pending *PendingRoot, signatures []data.Signature) error
+	GetRootSignaturesStatus(pending *PendingRoot) (RootSignaturesStatus, error)
+	PublishRoot(ctx context.Context, pending *PendingRoot) error
+	IsConsistentSnapshot() (bool, error)
+	MigrateToConsistentSnapshot(ctx context.Context) error
+	GetDivergedStorageMirrors(ctx context.Context) ([]string, error)
+	SyncStorageMirrors(ctx context.Context) error
+}
+
+type TufStore interface {
+	tuf.LocalStore
+	StageTargetFile(ctx context.Context, targetPath string, data io.Reader) error
+	RestageTargetFile(targetPath string)
+	SetDelegatedTargets(targets data.TargetFiles)
+	GetPrivKeys() TufRepoPrivKeys
+	SetPrivKeys(privKeys TufRepoPrivKeys)
+	GetPrivateKeys(role string) []*sign.PrivateKey
+	RemovePrivateKey(role string, key *sign.PrivateKey)
+	AddRotatedRootKey(key *sign.PrivateKey)
 }
@@ -0,0 +1,196 @@
+package publisher
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+// LocalFilesystem stores the repository in the local directory, which can be served by any static web server.
+type LocalFilesystem struct {
+	RootDir string
+
+	logger hclog.Logger
+}
+
+func NewLocalFilesystem(rootDir string, logger hclog.Logger) *LocalFilesystem {
+	return &LocalFilesystem{RootDir: rootDir, logger: logger}
+}
+
+// fullPath converts the slash-separated repository path into the path inside the root directory.
+// The path cannot point outside the root directory.
+func (fs *LocalFilesystem) fullPath(filePath string) string {
+	return filepath.Join(fs.RootDir, filepath.FromSlash(path.Clean("/"+filePath)))
+}
+
+func (fs *LocalFilesystem) IsFileExist(_ context.Context, filePath string) (bool, error) {
+	_, err := os.Stat(fs.fullPath(filePath))
+	fs.logger.Debug(fmt.Sprintf("-- LocalFilesystem.IsFileExist %q err=%v", filePath, err))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error accessing file %q: %s", filePath, err)
+	}
+
+	return true, nil
+}
+
+func (fs *LocalFilesystem) ReadFile(_ context.Context, filePath string, writerAt io.WriterAt) error {
+	f, err := os.Open(fs.fullPath(filePath))
+	if err != nil {
+		return fmt.Errorf("unable to open file %q: %s", filePath, err)
+	}
+	defer f.Close()
+
+	buf := make([]byte, 1024*1024)
+	var offset int64
+	for {
+		n, err := f.Read(buf)
+		if n > 0 {
+			if _, err := writerAt.WriteAt(buf[:n], offset); err != nil {
+				return fmt.Errorf("unable to write file %q data: %s", filePath, err)
+			}
+			offset += int64(n)
+		}
+
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("unable to read file %q: %s", filePath, err)
+		}
+	}
+
+	fs.logger.Debug(fmt.Sprintf("Read %q %d bytes", filePath, offset))
+
+	return nil
+}
+
+func (fs *LocalFilesystem) ReadFileStream(_ context.Context, filePath string, writer io.Writer) error {
+	f, err := os.Open(fs.fullPath(filePath))
+	if err != nil {
+		return fmt.Errorf("unable to open file %q: %s", filePath, err)
+	}
+	defer f.Close()
+
+	numBytes, err := io.Copy(writer, f)
+	if err != nil {
+		return fmt.Errorf("unable to read file %q: %s", filePath, err)
+	}
+
+	fs.logger.Debug(fmt.Sprintf("-- LocalFilesystem.ReadFileStream read %q %d bytes", filePath, numBytes))
+
+	return nil
+}
+
+func (fs *LocalFilesystem) ReadFileBytes(_ context.Context, filePath string) ([]byte, error) {
+	data, err := ioutil.ReadFile(fs.fullPath(filePath))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read file %q: %s", filePath, err)
+	}
+
+	return data, nil
+}
+
+func (fs *LocalFilesystem) WriteFileBytes(ctx context.Context, filePath string, data []byte) error {
+	return fs.writeFile(filePath, func(f *os.File) error {
+		_, err := f.Write(data)
+		return err
+	})
+}
+
+func (fs *LocalFilesystem) WriteFileStream(ctx context.Context, filePath string, data io.Reader) error {
+	return fs.writeFile(filePath, func(f *os.File) error {
+		_, err := io.Copy(f, data)
+		return err
+	})
+}
+
+func (fs *LocalFilesystem) DeleteFile(_ context.Context, filePath string) error {
+	err := os.Remove(fs.fullPath(filePath))
+	fs.logger.Debug(fmt.Sprintf("-- LocalFilesystem.DeleteFile %q err=%v", filePath, err))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("unable to delete file %q: %s", filePath, err)
+	}
+
+	return nil
+}
+
+func (fs *LocalFilesystem) ListFiles(_ context.Context, prefix string) ([]string, error) {
+	var filePaths []string
+	err := filepath.Walk(fs.RootDir, func(fullPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(fs.RootDir, fullPath)
+		if err != nil {
+			return err
+		}
+
+		filePath := filepath.ToSlash(relPath)
+		if strings.HasPrefix(filePath, prefix) {
+			filePaths = append(filePaths, filePath)
+		}
+
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to list files with prefix %q: %s", prefix, err)
+	}
+
+	return filePaths, nil
+}
+
+// writeFile writes the data into the temporary file and renames it afterwards,
+// so the web server never serves partially written files.
+func (fs *LocalFilesystem) writeFile(filePath string, writeFunc func(f *os.File) error) error {
+	fullPath := fs.fullPath(filePath)
+
+	if err := os.MkdirAll(filepath.Dir(fullPath), 0o755); err != nil {
+		return fmt.Errorf("unable to create directory for %q: %s", filePath, err)
+	}
+
+	tmpFile, err := ioutil.TempFile(filepath.Dir(fullPath), "."+filepath.Base(fullPath)+".tmp")
+	if err != nil {
+		return fmt.Errorf("unable to create temporary file for %q: %s", filePath, err)
+	}
+	defer os.Remove(tmpFile.Name())
+
+	if err := writeFunc(tmpFile); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("error writing %q: %s", filePath, err)
+	}
+
+	if err := tmpFile.Chmod(0o644); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("unable to change %q permissions: %s", filePath, err)
+	}
+
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("error writing %q: %s", filePath, err)
+	}
+
+	if err := os.Rename(tmpFile.Name(), fullPath); err != nil {
+		return fmt.Errorf("unable to rename temporary file to %q: %s", filePath, err)
+	}
+
+	fs.logger.Debug(fmt.Sprintf("-- LocalFilesystem.writeFile stored %q", fullPath))
+
+	return nil
+}
@@ -0,0 +1,192 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/go-hclog"
+)
+
+type FilesystemMirror struct {
+	Name       string
+	Filesystem Filesystem
+}
+
+type MirrorStatus struct {
+	Name         string
+	WrittenFiles int
+	Err          error
+}
+
+// MirroredFilesystem reads from the primary filesystem and writes into the primary filesystem and all the mirrors.
+// The write fails if any of the mirrors fails, so the TUF store does not proceed to the next metadata file
+// and the mirrors are left with the previously published timestamp.json.
+// The rest of the mirrors are written anyway and the errors of all the failed mirrors are returned.
+type MirroredFilesystem struct {
+	Primary FilesystemMirror
+	Mirrors []FilesystemMirror
+
+	mu     sync.Mutex
+	status map[string]*MirrorStatus
+	logger hclog.Logger
+}
+
+func NewMirroredFilesystem(primary FilesystemMirror, mirrors []FilesystemMirror, logger hclog.Logger) *MirroredFilesystem {
+	return &MirroredFilesystem{
+		Primary: primary,
+		Mirrors: mirrors,
+		status:  make(map[string]*MirrorStatus),
+		logger:  logger,
+	}
+}
+
+func (fs *MirroredFilesystem) IsFileExist(ctx context.Context, path string) (bool, error) {
+	return fs.Primary.Filesystem.IsFileExist(ctx, path)
+}
+
+func (fs *MirroredFilesystem) ReadFile(ctx context.Context, path string, writer io.WriterAt) error {
+	return fs.Primary.Filesystem.ReadFile(ctx, path, writer)
+}
+
+func (fs *MirroredFilesystem) ReadFileStream(ctx context.Context, path string, writer io.Writer) error {
+	return fs.Primary.Filesystem.ReadFileStream(ctx, path, writer)
+}
+
+func (fs *MirroredFilesystem) ReadFileBytes(ctx context.Context, path string) ([]byte, error) {
+	return fs.Primary.Filesystem.ReadFileBytes(ctx, path)
+}
+
+func (fs *MirroredFilesystem) WriteFileBytes(ctx context.Context, path string, data []byte) error {
+	if err := fs.Primary.Filesystem.WriteFileBytes(ctx, path, data); err != nil {
+		fs.setStatus(fs.Primary.Name, err)
+		return err
+	}
+	fs.setStatus(fs.Primary.Name, nil)
+
+	return fs.forEachMirror(func(mirror FilesystemMirror) error {
+		err := mirror.Filesystem.WriteFileBytes(ctx, path, data)
+		fs.setStatus(mirror.Name, err)
+		return err
+	})
+}
+
+// WriteFileStream writes the stream into the primary filesystem
+// and then copies the written file from the primary filesystem into the mirrors.
+func (fs *MirroredFilesystem) WriteFileStream(ctx context.Context, path string, data io.Reader) error {
+	if err := fs.Primary.Filesystem.WriteFileStream(ctx, path, data); err != nil {
+		fs.setStatus(fs.Primary.Name, err)
+		return err
+	}
+	fs.setStatus(fs.Primary.Name, nil)
+
+	return fs.forEachMirror(func(mirror FilesystemMirror) error {
+		err := CopyFile(ctx, fs.Primary.Filesystem, mirror.Filesystem, path)
+		fs.setStatus(mirror.Name, err)
+		return err
+	})
+}
+
+func (fs *MirroredFilesystem) DeleteFile(ctx context.Context, path string) error {
+	if err := fs.Primary.Filesystem.DeleteFile(ctx, path); err != nil {
+		return err
+	}
+
+	return fs.forEachMirror(func(mirror FilesystemMirror) error {
+		return mirror.Filesystem.DeleteFile(ctx, path)
+	})
+}
+
+// ListFiles returns the files found either in the primary filesystem or in any of the mirrors.
+func (fs *MirroredFilesystem) ListFiles(ctx context.Context, prefix string) ([]string, error) {
+	var res []string
+	seen := make(map[string]bool)
+
+	for _, mirror := range append([]FilesystemMirror{fs.Primary}, fs.Mirrors...) {
+		paths, err := mirror.Filesystem.ListFiles(ctx, prefix)
+		if err != nil {
+			return nil, fmt.Errorf("storage %s: %s", mirror.Name, err)
+		}
+
+		for _, path := range paths {
+			if !seen[path] {
+				seen[path] = true
+				res = append(res, path)
+			}
+		}
+	}
+
+	return res, nil
+}
+
+// forEachMirror calls the function for all the mirrors, so the failed mirror does not prevent the others from being updated.
+// The errors of all the failed mirrors are returned together.
+func (fs *MirroredFilesystem) forEachMirror(f func(mirror FilesystemMirror) error) error {
+	var errs []string
+	for _, mirror := range fs.Mirrors {
+		if err := f(mirror); err != nil {
+			errs = append(errs, fmt.Sprintf("storage mirror %s: %s", mirror.Name, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+// TakeStatus returns the write status of the primary filesystem and each mirror since the previous call.
+func (fs *MirroredFilesystem) TakeStatus() []*MirrorStatus {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	var res []*MirrorStatus
+	for _, mirror := range append([]FilesystemMirror{fs.Primary}, fs.Mirrors...) {
+		if status, hasKey := fs.status[mirror.Name]; hasKey {
+			res = append(res, status)
+		} else {
+			res = append(res, &MirrorStatus{Name: mirror.Name})
+		}
+	}
+	fs.status = make(map[string]*MirrorStatus)
+
+	return res
+}
+
+func (fs *MirroredFilesystem) setStatus(name string, err error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	status, hasKey := fs.status[name]
+	if !hasKey {
+		status = &MirrorStatus{Name: name}
+		fs.status[name] = status
+	}
+
+	if err != nil {
+		status.Err = err
+		fs.logger.Error(fmt.Sprintf("Storage %s write failed: %s", name, err))
+	} else {
+		status.WrittenFiles++
+	}
+}
+
+// CopyFile copies the file from one filesystem into another.
+func CopyFile(ctx context.Context, src, dst Filesystem, path string) error {
+	reader, writer := io.Pipe()
+	defer reader.Close()
+
+	go func() {
+		if err := src.ReadFileStream(ctx, path, writer); err != nil {
+			writer.CloseWithError(fmt.Errorf("error reading file %q: %s", path, err))
+			return
+		}
+		writer.Close()
+	}()
+
+	return dst.WriteFileStream(ctx, path, reader)
+}
@@ -1,11 +1,13 @@
 package publisher
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"path"
+	"time"
 
 	"github.com/djherbis/buffer"
 	"github.com/djherbis/nio/v3"
@@ -17,19 +19,102 @@ import (
 )
 
 type TufRepoPrivKeys struct {
-	Root      *sign.PrivateKey `json:"root"`
-	Snapshot  *sign.PrivateKey `json:"snapshot"`
-	Targets   *sign.PrivateKey `json:"targets"`
-	Timestamp *sign.PrivateKey `json:"timestamp"`
+	Root      TufRolePrivKeys `json:"root"`
+	Snapshot  TufRolePrivKeys `json:"snapshot"`
+	Targets   TufRolePrivKeys `json:"targets"`
+	Timestamp TufRolePrivKeys `json:"timestamp"`
+
+	// Delegations contains the private key for each delegated role, see DelegationsDir.
+	Delegations map[string]*sign.PrivateKey `json:"delegations,omitempty"`
+
+	// Expires contains the rotation date of the private key for each role.
+	// A key without the rotation date gets it on the next RotatePrivKeys call.
+	Expires map[string]time.Time `json:"expires,omitempty"`
+}
+
+// ExpiredRoles returns the roles which private keys should be rotated or have no rotation date yet.
+func (privKeys TufRepoPrivKeys) ExpiredRoles(now time.Time) []string {
+	var roles []string
+	for _, role := range tufRoles {
+		if expires, hasKey := privKeys.Expires[role]; hasKey && now.Before(expires) {
+			continue
+		}
+		roles = append(roles, role)
+	}
+
+	return roles
+}
+
+// roleKeys returns the pointer to the private keys of the role.
+func (privKeys *TufRepoPrivKeys) roleKeys(role string) *TufRolePrivKeys {
+	switch role {
+	case "root":
+		return &privKeys.Root
+
+	case "targets":
+		return &privKeys.Targets
+
+	case "snapshot":
+		return &privKeys.Snapshot
+
+	case "timestamp":
+		return &privKeys.Timestamp
+
+	default:
+		panic(fmt.Sprintf("unknown role %q", role))
+	}
+}
+
+// TufRolePrivKeys contains the private keys of the role, the role metadata is signed by all the keys.
+type TufRolePrivKeys []*sign.PrivateKey
+
+// UnmarshalJSON also accepts the single key object, which is stored by the previous versions.
+func (keys *TufRolePrivKeys) UnmarshalJSON(data []byte) error {
+	trimmed := bytes.TrimSpace(data)
+
+	switch {
+	case bytes.Equal(trimmed, []byte("null")):
+		*keys = nil
+		return nil
+
+	case bytes.HasPrefix(trimmed, []byte("[")):
+		var list []*sign.PrivateKey
+		if err := json.Unmarshal(trimmed, &list); err != nil {
+			return err
+		}
+		*keys = list
+		return nil
+
+	default:
+		key := &sign.PrivateKey{}
+		if err := json.Unmarshal(trimmed, key); err != nil {
+			return err
+		}
+		*keys = TufRolePrivKeys{key}
+		return nil
+	}
 }
 
 type NonAtomicTufStore struct {
 	PrivKeys   TufRepoPrivKeys
 	Filesystem Filesystem
 
+	// Root keys replaced during the current commit.
+	// Such keys should sign the new root.json along with the actual root key,
+	// so clients trusting the previous root.json are able to verify the new one.
+	rotatedRootKeys []*sign.PrivateKey
+
+	// stagingDir is the directory the staged target files are stored in until the commit.
+	// The files are written right to the final paths if the directory is not set.
+	stagingDir string
+
 	stagedMeta  map[string]json.RawMessage
 	stagedFiles []string
-	logger      hclog.Logger
+	// restagedFiles are the staged target files which are already stored at the final paths.
+	restagedFiles map[string]bool
+	// delegatedTargets are the targets listed in the delegated roles metadata rather than in targets.json.
+	delegatedTargets data.TargetFiles
+	logger           hclog.Logger
 }
 
 func NewNonAtomicTufStore(privKeys TufRepoPrivKeys, filesystem Filesystem, logger hclog.Logger) *NonAtomicTufStore {
@@ -117,7 +202,7 @@ func (store *NonAtomicTufStore) WalkStagedTargets(targetPathList []string, targe
 
 	if len(targetPathList) == 0 {
 		for _, filePath := range store.stagedFiles {
-			if err := targetsFn(filePath, runPipedFileReader(path.Join("targets", filePath))); err != nil {
+			if err := targetsFn(filePath, runPipedFileReader(store.stagedFilePath(filePath))); err != nil {
 				return err
 			}
 		}
@@ -129,7 +214,7 @@ FilterStagedPaths:
 	for _, targetPath := range targetPathList {
 		for _, stagedPath := range store.stagedFiles {
 			if stagedPath == targetPath {
-				if err := targetsFn(targetPath, runPipedFileReader(path.Join("targets", targetPath))); err != nil {
+				if err := targetsFn(targetPath, runPipedFileReader(store.stagedFilePath(targetPath))); err != nil {
 					return err
 				}
 
@@ -146,9 +231,10 @@ FilterStagedPaths:
 func (store *NonAtomicTufStore) StageTargetFile(ctx context.Context, targetPath string, data io.Reader) error {
 	store.logger.Debug(fmt.Sprintf("-- NonAtomicTufStore.StageTargetFile %q", targetPath))
 
-	// NOTE: consistenSnapshot cannot be supported when adding staged files before commit stage
+	// NOTE: target hashes are not known before commit stage,
+	// NOTE: so the consistent snapshot copies of the target are made by the commit
 
-	if err := store.Filesystem.WriteFileStream(ctx, path.Join("targets", targetPath), data); err != nil {
+	if err := store.Filesystem.WriteFileStream(ctx, store.stagedFilePath(targetPath), data); err != nil {
 		return fmt.Errorf("error writing %q into the store filesystem: %s", targetPath, err)
 	}
 
@@ -157,13 +243,62 @@ func (store *NonAtomicTufStore) StageTargetFile(ctx context.Context, targetPath
 	return nil
 }
 
-func (store *NonAtomicTufStore) Commit(consistentSnapshot bool, versions map[string]int, _ map[string]data.Hashes) error {
-	store.logger.Debug("-- NonAtomicTufStore.Commit")
-	if consistentSnapshot {
-		panic("not supported")
+// RestageTargetFile marks the already stored target file as staged, so it is published again by the next commit.
+func (store *NonAtomicTufStore) RestageTargetFile(targetPath string) {
+	if store.restagedFiles == nil {
+		store.restagedFiles = make(map[string]bool)
+	}
+	store.restagedFiles[targetPath] = true
+
+	store.stagedFiles = append(store.stagedFiles, targetPath)
+}
+
+// SetDelegatedTargets sets the targets listed in the delegated roles metadata,
+// so the staged files of such targets are published by the commit as well as the ones listed in targets.json.
+func (store *NonAtomicTufStore) SetDelegatedTargets(targets data.TargetFiles) {
+	store.delegatedTargets = targets
+}
+
+// targetsHashes adds the hashes of the delegated targets to the hashes of the files listed in the top-level metadata.
+func (store *NonAtomicTufStore) targetsHashes(hashes map[string]data.Hashes) map[string]data.Hashes {
+	res := make(map[string]data.Hashes)
+	for name, fileHashes := range hashes {
+		res[name] = fileHashes
+	}
+
+	for targetPath, targetMeta := range store.delegatedTargets {
+		res[path.Join("targets", targetPath)] = targetMeta.Hashes
+	}
+
+	return res
+}
+
+// stagedFilePath returns the path the staged target file is stored at until the commit.
+func (store *NonAtomicTufStore) stagedFilePath(targetPath string) string {
+	if store.stagingDir == "" || store.restagedFiles[targetPath] {
+		return path.Join("targets", targetPath)
 	}
 
+	return path.Join(store.stagingDir, "targets", targetPath)
+}
+
+func (store *NonAtomicTufStore) Commit(consistentSnapshot bool, versions map[string]int, hashes map[string]data.Hashes) error {
+	store.logger.Debug("-- NonAtomicTufStore.Commit")
+
 	ctx := context.Background()
+	hashes = store.targetsHashes(hashes)
+
+	if consistentSnapshot {
+		for _, targetPath := range store.stagedFiles {
+			filePath := path.Join("targets", targetPath)
+
+			for _, hashedPath := range computeTargetPaths(consistentSnapshot, filePath, hashes) {
+				if err := store.copyFile(ctx, filePath, hashedPath); err != nil {
+					return fmt.Errorf("error copying target %q into the filesystem: %s", hashedPath, err)
+				}
+			}
+		}
+	}
 
 	for name, data := range store.stagedMeta {
 		// TODO: perms 0644
@@ -178,7 +313,10 @@ func (store *NonAtomicTufStore) Commit(consistentSnapshot bool, versions map[str
 	}
 
 	store.stagedFiles = nil
+	store.restagedFiles = nil
+	store.delegatedTargets = nil
 	store.stagedMeta = make(map[string]json.RawMessage)
+	store.rotatedRootKeys = nil
 
 	return nil
 }
@@ -186,69 +324,98 @@ func (store *NonAtomicTufStore) Commit(consistentSnapshot bool, versions map[str
 func (store *NonAtomicTufStore) GetSigningKeys(role string) ([]sign.Signer, error) {
 	store.logger.Debug(fmt.Sprintf("-- NonAtomicTufStore.GetSigningKeys(%q) store.PrivKeys=%#v", role, store.PrivKeys))
 
-	toSigners := func(key *sign.PrivateKey) []sign.Signer {
-		if key != nil {
-			return []sign.Signer{key.Signer()}
+	var signers []sign.Signer
+	for _, key := range *store.PrivKeys.roleKeys(role) {
+		signers = append(signers, key.Signer())
+	}
+
+	if role == "root" {
+		for _, key := range store.rotatedRootKeys {
+			signers = append(signers, key.Signer())
 		}
-		return nil
 	}
 
-	switch role {
-	case "root":
-		return toSigners(store.PrivKeys.Root), nil
+	return signers, nil
+}
 
-	case "targets":
-		return toSigners(store.PrivKeys.Targets), nil
+func (store *NonAtomicTufStore) GetPrivKeys() TufRepoPrivKeys {
+	return store.PrivKeys
+}
 
-	case "snapshot":
-		return toSigners(store.PrivKeys.Snapshot), nil
+func (store *NonAtomicTufStore) SetPrivKeys(privKeys TufRepoPrivKeys) {
+	store.PrivKeys = privKeys
+}
 
-	case "timestamp":
-		return toSigners(store.PrivKeys.Timestamp), nil
+func (store *NonAtomicTufStore) GetPrivateKeys(role string) []*sign.PrivateKey {
+	return *store.PrivKeys.roleKeys(role)
+}
 
-	default:
-		panic(fmt.Sprintf("unknown role %q", role))
-	}
+func (store *NonAtomicTufStore) AddRotatedRootKey(key *sign.PrivateKey) {
+	store.rotatedRootKeys = append(store.rotatedRootKeys, key)
 }
 
+// SavePrivateKey adds the key to the role keys, the already saved key is skipped.
 func (store *NonAtomicTufStore) SavePrivateKey(role string, key *sign.PrivateKey) error {
-	switch role {
-	case "root":
-		store.PrivKeys.Root = key
+	keys := store.PrivKeys.roleKeys(role)
 
-	case "targets":
-		store.PrivKeys.Targets = key
+	keyID := key.PublicData().IDs()[0]
+	for _, k := range *keys {
+		if k.PublicData().IDs()[0] == keyID {
+			return nil
+		}
+	}
 
-	case "snapshot":
-		store.PrivKeys.Snapshot = key
+	*keys = append(*keys, key)
 
-	case "timestamp":
-		store.PrivKeys.Timestamp = key
+	return nil
+}
 
-	default:
-		panic(fmt.Sprintf("unknown role %q", role))
+// RemovePrivateKey removes the key from the role keys.
+func (store *NonAtomicTufStore) RemovePrivateKey(role string, key *sign.PrivateKey) {
+	keys := store.PrivKeys.roleKeys(role)
+
+	keyID := key.PublicData().IDs()[0]
+
+	var res TufRolePrivKeys
+	for _, k := range *keys {
+		if k.PublicData().IDs()[0] != keyID {
+			res = append(res, k)
+		}
 	}
 
-	return nil
+	*keys = res
+}
+
+func (store *NonAtomicTufStore) copyFile(ctx context.Context, srcPath, dstPath string) error {
+	store.logger.Debug(fmt.Sprintf("-- NonAtomicTufStore.copyFile %q -> %q", srcPath, dstPath))
+
+	reader, writer := io.Pipe()
+	defer reader.Close()
+
+	go func() {
+		if err := store.Filesystem.ReadFileStream(ctx, srcPath, writer); err != nil {
+			writer.CloseWithError(fmt.Errorf("error reading file %q: %s", srcPath, err))
+			return
+		}
+		writer.Close()
+	}()
+
+	return store.Filesystem.WriteFileStream(ctx, dstPath, reader)
 }
 
 func (m *NonAtomicTufStore) Clean() error {
 	panic("not supported")
 }
 
-func computeTargetPaths(consistentSnapshot bool, name string, _ map[string]data.Hashes) []string {
+func computeTargetPaths(consistentSnapshot bool, name string, hashes map[string]data.Hashes) []string {
 	if consistentSnapshot {
-		panic("not supported")
+		return util.HashedPaths(name, hashes[name])
 	}
 
 	return []string{name}
 }
 
 func computeMetadataPaths(consistentSnapshot bool, name string, versions map[string]int) []string {
-	if consistentSnapshot {
-		panic("not supported")
-	}
-
 	copyVersion := false
 
 	switch name {
@@ -257,7 +424,7 @@ func computeMetadataPaths(consistentSnapshot bool, name string, versions map[str
 	case "timestamp.json":
 		copyVersion = false
 	default:
-		copyVersion = false
+		copyVersion = consistentSnapshot
 	}
 
 	paths := []string{name}
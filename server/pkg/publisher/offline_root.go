@@ -0,0 +1,664 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/theupdateframework/go-tuf"
+	"github.com/theupdateframework/go-tuf/data"
+	"github.com/theupdateframework/go-tuf/sign"
+	"github.com/theupdateframework/go-tuf/verify"
+)
+
+// PendingRoot is the next root.json waiting for the offline signatures.
+type PendingRoot struct {
+	// Signed contains the canonical root.json and the signatures collected so far.
+	Signed *data.Signed `json:"signed"`
+
+	// PrivKeys are the repository private keys to use once the root.json is published.
+	// The root keys which are not in the new root.json are dropped, so the plugin does not keep the offline root keys.
+	PrivKeys TufRepoPrivKeys `json:"priv_keys"`
+
+	// BaseKeysChecksum is the checksum of the stored repository private keys the root.json is prepared with.
+	// The root.json is not published if the stored keys have been changed since then.
+	BaseKeysChecksum string `json:"base_keys_checksum"`
+
+	// Activating is set right before the root.json is published. If the plugin stops before the PrivKeys are saved,
+	// the keys are activated on the next repository loading as long as the published root.json is the pending one.
+	Activating bool `json:"activating,omitempty"`
+}
+
+type PrepareRootOptions struct {
+	// RootKeys replace the root role keys, the current root keys are kept if not specified.
+	RootKeys []*data.Key
+
+	// RootKeysThreshold overrides the configured root keys threshold.
+	RootKeysThreshold int
+}
+
+// RootSignaturesStatus describes how many root keys signed the pending root.json.
+type RootSignaturesStatus struct {
+	// TrustedRootSigned and TrustedRootThreshold are related to the keys of the published root.json,
+	// which clients use to verify the new one.
+	TrustedRootSigned    int
+	TrustedRootThreshold int
+
+	// NewRootSigned and NewRootThreshold are related to the keys of the pending root.json.
+	NewRootSigned    int
+	NewRootThreshold int
+}
+
+func (status RootSignaturesStatus) IsThresholdMet() bool {
+	return status.TrustedRootSigned >= status.TrustedRootThreshold && status.NewRootSigned >= status.NewRootThreshold
+}
+
+// IsRootOffline returns true if the repository does not keep enough root keys to sign root.json.
+// The root.json of such repository is changed only by the offline signed PendingRoot.
+func (repository *S3Repository) IsRootOffline() (bool, error) {
+	root, err := repository.getRoot()
+	if err != nil {
+		return false, err
+	}
+
+	role, hasKey := root.Roles["root"]
+	if !hasKey {
+		return false, nil
+	}
+
+	held := 0
+	for _, key := range repository.TufStore.GetPrivateKeys("root") {
+		for _, keyID := range role.KeyIDs {
+			if key.PublicData().ContainsID(keyID) {
+				held++
+				break
+			}
+		}
+	}
+
+	return held < role.Threshold, nil
+}
+
+// PrepareRoot builds the next root.json with the new expiration date and the configured keys settings.
+// The expired online keys are replaced with the new ones, which are activated when the root.json is published.
+// The root.json is signed with the root keys held by the repository, other signatures should be added offline.
+func (repository *S3Repository) PrepareRoot(_ context.Context, opts PrepareRootOptions) (*PendingRoot, error) {
+	root, err := repository.getRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	publishedVersion, err := repository.getPublishedRootVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	privKeys := repository.copyPrivKeys()
+
+	root.Version = publishedVersion + 1
+	root.Expires = repository.expires("root")
+	root.ConsistentSnapshot = root.ConsistentSnapshot || repository.ConsistentSnapshot
+
+	if len(opts.RootKeys) > 0 {
+		replaceRoleKeys(root, "root", opts.RootKeys)
+	}
+
+	expiredRoles := make(map[string]bool)
+	for _, role := range repository.expiredPrivKeysRoles() {
+		expiredRoles[role] = true
+	}
+
+	for _, role := range tufRoles {
+		if role == "root" || !expiredRoles[role] {
+			continue
+		}
+
+		var newKeys TufRolePrivKeys
+		var newPublicKeys []*data.Key
+		for i := 0; i < repository.keysNumber(role); i++ {
+			key, err := sign.GenerateEd25519Key()
+			if err != nil {
+				return nil, fmt.Errorf("error generating %s key: %s", role, err)
+			}
+
+			newKeys = append(newKeys, key)
+			newPublicKeys = append(newPublicKeys, key.PublicData())
+		}
+
+		replaceRoleKeys(root, role, newPublicKeys)
+		*privKeys.roleKeys(role) = newKeys
+		privKeys.Expires[role] = now.Add(privKeysLifetime[role]).UTC().Round(time.Second)
+	}
+
+	for _, role := range tufRoles {
+		r, hasKey := root.Roles[role]
+		if !hasKey {
+			return nil, fmt.Errorf("%s role not found in root.json", role)
+		}
+
+		r.Threshold = repository.keysThreshold(role)
+		if role == "root" && opts.RootKeysThreshold != 0 {
+			r.Threshold = opts.RootKeysThreshold
+		}
+
+		if r.Threshold > len(r.KeyIDs) {
+			return nil, fmt.Errorf("%s keys threshold %d exceeds the number of the role keys %d", role, r.Threshold, len(r.KeyIDs))
+		}
+	}
+
+	var heldRootKeys TufRolePrivKeys
+	var signers []sign.Signer
+	for _, key := range privKeys.Root {
+		if isRoleKey(root, "root", key.PublicData()) {
+			heldRootKeys = append(heldRootKeys, key)
+		}
+		signers = append(signers, key.Signer())
+	}
+	privKeys.Root = heldRootKeys
+
+	signed, err := sign.Marshal(root, signers...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to sign root.json: %s", err)
+	}
+
+	return &PendingRoot{Signed: signed, PrivKeys: privKeys}, nil
+}
+
+// AddRootSignatures verifies the signatures of the pending root.json and adds them to the pending root.
+// The signature is accepted if it is made by the root key of either the published or the pending root.json.
+func (repository *S3Repository) AddRootSignatures(pending *PendingRoot, signatures []data.Signature) error {
+	trustedDB, newDB, err := repository.rootDBs(pending)
+	if err != nil {
+		return err
+	}
+
+	for _, signature := range signatures {
+		key := newDB.GetKey(signature.KeyID)
+		if key == nil || !newDB.GetRole("root").ValidKey(signature.KeyID) {
+			key = trustedDB.GetKey(signature.KeyID)
+			if key == nil || !trustedDB.GetRole("root").ValidKey(signature.KeyID) {
+				return fmt.Errorf("key %q is not the root key", signature.KeyID)
+			}
+		}
+
+		if err := verifySignature(pending.Signed, key, signature); err != nil {
+			return fmt.Errorf("invalid signature of key %q: %s", signature.KeyID, err)
+		}
+
+		signatures := make([]data.Signature, 0, len(pending.Signed.Signatures)+1)
+		for _, s := range pending.Signed.Signatures {
+			if s.KeyID != signature.KeyID {
+				signatures = append(signatures, s)
+			}
+		}
+		pending.Signed.Signatures = append(signatures, signature)
+	}
+
+	return nil
+}
+
+// GetRootSignaturesStatus counts the valid signatures of the pending root.json.
+func (repository *S3Repository) GetRootSignaturesStatus(pending *PendingRoot) (RootSignaturesStatus, error) {
+	trustedDB, newDB, err := repository.rootDBs(pending)
+	if err != nil {
+		return RootSignaturesStatus{}, err
+	}
+
+	return RootSignaturesStatus{
+		TrustedRootSigned:    countValidSignatures(pending.Signed, trustedDB),
+		TrustedRootThreshold: trustedDB.GetRole("root").Threshold,
+		NewRootSigned:        countValidSignatures(pending.Signed, newDB),
+		NewRootThreshold:     newDB.GetRole("root").Threshold,
+	}, nil
+}
+
+// PublishRoot commits the pending root.json along with the metadata re-signed by the new online keys.
+func (repository *S3Repository) PublishRoot(ctx context.Context, pending *PendingRoot) error {
+	publishedVersion, err := repository.getPublishedRootVersion()
+	if err != nil {
+		return err
+	}
+
+	pendingRoot, err := unmarshalRoot(pending.Signed)
+	if err != nil {
+		return err
+	}
+
+	if pendingRoot.Version != publishedVersion+1 {
+		return fmt.Errorf("pending root.json version %d does not follow the published version %d: the root.json should be prepared again", pendingRoot.Version, publishedVersion)
+	}
+
+	status, err := repository.GetRootSignaturesStatus(pending)
+	if err != nil {
+		return err
+	}
+
+	if !status.IsThresholdMet() {
+		return fmt.Errorf("pending root.json is not signed by enough keys: %d of %d trusted root keys, %d of %d new root keys", status.TrustedRootSigned, status.TrustedRootThreshold, status.NewRootSigned, status.NewRootThreshold)
+	}
+
+	isConsistentSnapshot, err := repository.IsConsistentSnapshot()
+	if err != nil {
+		return err
+	}
+
+	rootJSON, err := json.Marshal(pending.Signed)
+	if err != nil {
+		return fmt.Errorf("unable to marshal root.json: %s", err)
+	}
+
+	if err := repository.TufStore.SetMeta("root.json", rootJSON); err != nil {
+		return err
+	}
+	repository.TufStore.SetPrivKeys(pending.PrivKeys)
+
+	tufRepo, err := tuf.NewRepo(repository.TufStore)
+	if err != nil {
+		return fmt.Errorf("error reloading tuf repo: %s", err)
+	}
+	repository.TufRepo = tufRepo
+
+	// The targets are published under the hash-prefixed paths as in MigrateToConsistentSnapshot
+	if pendingRoot.ConsistentSnapshot && !isConsistentSnapshot {
+		targets, err := repository.TufRepo.Targets()
+		if err != nil {
+			return fmt.Errorf("unable to get tuf repository targets: %s", err)
+		}
+
+		for targetPath := range targets {
+			repository.TufStore.RestageTargetFile(targetPath)
+		}
+	}
+
+	if err := repository.TufRepo.AddTargetsWithExpires([]string{}, nil, repository.expires("targets")); err != nil {
+		return fmt.Errorf("unable to re-sign tuf repository targets: %s", err)
+	}
+
+	return repository.CommitStaged(ctx)
+}
+
+// PreparePendingRoot prepares the next root.json and saves it into the storage replacing the previous pending root.
+func (publisher *Publisher) PreparePendingRoot(ctx context.Context, storage logical.Storage, repository RepositoryInterface, opts PrepareRootOptions) (*PendingRoot, error) {
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+
+	pending, err := repository.PrepareRoot(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to prepare root.json: %s", err)
+	}
+
+	privKeys, err := getRepositoryKeys(ctx, storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if privKeys == nil {
+		return nil, ErrUninitializedRepositoryKeys
+	}
+
+	if pending.BaseKeysChecksum, err = privKeysChecksum(*privKeys); err != nil {
+		return nil, err
+	}
+
+	if err := putPendingRoot(ctx, storage, pending); err != nil {
+		return nil, err
+	}
+
+	return pending, nil
+}
+
+func (publisher *Publisher) GetPendingRoot(ctx context.Context, storage logical.Storage) (*PendingRoot, error) {
+	return getPendingRoot(ctx, storage)
+}
+
+func getPendingRoot(ctx context.Context, storage logical.Storage) (*PendingRoot, error) {
+	entry, err := storage.Get(ctx, storageKeyTufPendingRoot)
+	if err != nil {
+		return nil, fmt.Errorf("error getting pending root json entry by the key %q: %s", storageKeyTufPendingRoot, err)
+	}
+
+	if entry == nil {
+		return nil, nil
+	}
+
+	pending := &PendingRoot{}
+	if err := entry.DecodeJSON(pending); err != nil {
+		return nil, fmt.Errorf("unable to decode pending root json by the %q storage key: %s", storageKeyTufPendingRoot, err)
+	}
+
+	return pending, nil
+}
+
+func (publisher *Publisher) DeletePendingRoot(ctx context.Context, storage logical.Storage) error {
+	return storage.Delete(ctx, storageKeyTufPendingRoot)
+}
+
+// AddPendingRootSignatures adds the signatures to the stored pending root.json and returns the updated pending root.
+func (publisher *Publisher) AddPendingRootSignatures(ctx context.Context, storage logical.Storage, repository RepositoryInterface, signatures []data.Signature) (*PendingRoot, error) {
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+
+	pending, err := getPendingRoot(ctx, storage)
+	if err != nil {
+		return nil, err
+	}
+
+	if pending == nil {
+		return nil, ErrPendingRootNotFound
+	}
+
+	if err := repository.AddRootSignatures(pending, signatures); err != nil {
+		return nil, err
+	}
+
+	if err := putPendingRoot(ctx, storage, pending); err != nil {
+		return nil, err
+	}
+
+	return pending, nil
+}
+
+// PublishPendingRoot publishes the pending root.json and saves the repository private keys activated by it.
+// The pending root is marked as activating beforehand, so the keys are not lost if the plugin stops in between
+// (see reconcilePendingRoot).
+func (publisher *Publisher) PublishPendingRoot(ctx context.Context, storage logical.Storage, repository RepositoryInterface) error {
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+
+	pending, err := getPendingRoot(ctx, storage)
+	if err != nil {
+		return err
+	}
+
+	if pending == nil {
+		return ErrPendingRootNotFound
+	}
+
+	privKeys, err := getRepositoryKeys(ctx, storage)
+	if err != nil {
+		return err
+	}
+
+	if privKeys == nil {
+		return ErrUninitializedRepositoryKeys
+	}
+
+	checksum, err := privKeysChecksum(*privKeys)
+	if err != nil {
+		return err
+	}
+
+	if checksum != pending.BaseKeysChecksum {
+		return fmt.Errorf("repository private keys have been changed since the root.json was prepared: the root.json should be prepared again")
+	}
+
+	// The delegated roles keys are not changed by the root.json, but might be generated after it was prepared
+	pending.PrivKeys.Delegations = privKeys.Delegations
+
+	pending.Activating = true
+	if err := putPendingRoot(ctx, storage, pending); err != nil {
+		return err
+	}
+
+	// The pending root stays activating on failure: it is reconciled with the published root.json on the next loading
+	if err := repository.PublishRoot(ctx, pending); err != nil {
+		return fmt.Errorf("unable to publish root.json: %s", err)
+	}
+
+	if err := publisher.activatePendingRoot(ctx, storage, pending); err != nil {
+		return err
+	}
+
+	publisher.logger.Info("Successfully published the new root.json")
+
+	return nil
+}
+
+// reconcilePendingRoot completes the activation of the pending root keys interrupted by the plugin stop.
+// If the activating root.json has not been published, the pending root can be published again.
+func (publisher *Publisher) reconcilePendingRoot(ctx context.Context, storage logical.Storage, repository *S3Repository) error {
+	pending, err := getPendingRoot(ctx, storage)
+	if err != nil {
+		return err
+	}
+
+	if pending == nil || !pending.Activating {
+		return nil
+	}
+
+	publishedRootJSON, err := repository.Filesystem.ReadFileBytes(ctx, "root.json")
+	if err != nil {
+		return fmt.Errorf("error reading %q: %s", "root.json", err)
+	}
+
+	publishedRoot := &data.Signed{}
+	if err := json.Unmarshal(publishedRootJSON, publishedRoot); err != nil {
+		return fmt.Errorf("unable to unmarshal root.json: %s", err)
+	}
+
+	if !bytes.Equal(publishedRoot.Signed, pending.Signed.Signed) {
+		pending.Activating = false
+		return putPendingRoot(ctx, storage, pending)
+	}
+
+	if err := repository.SetPrivKeys(pending.PrivKeys); err != nil {
+		return fmt.Errorf("unable to set private keys into repository: %s", err)
+	}
+
+	if err := publisher.activatePendingRoot(ctx, storage, pending); err != nil {
+		return err
+	}
+
+	publisher.logger.Info("Activated repository private keys of the published root.json")
+
+	return nil
+}
+
+// activatePendingRoot saves the private keys of the published root.json and deletes the pending root.
+func (publisher *Publisher) activatePendingRoot(ctx context.Context, storage logical.Storage, pending *PendingRoot) error {
+	if err := putRepositoryKeys(ctx, storage, pending.PrivKeys); err != nil {
+		return err
+	}
+
+	if err := publisher.DeletePendingRoot(ctx, storage); err != nil {
+		return fmt.Errorf("unable to delete pending root.json: %s", err)
+	}
+
+	return nil
+}
+
+func putPendingRoot(ctx context.Context, storage logical.Storage, pending *PendingRoot) error {
+	entry, err := logical.StorageEntryJSON(storageKeyTufPendingRoot, pending)
+	if err != nil {
+		return fmt.Errorf("error creating storage json entry by key %q: %s", storageKeyTufPendingRoot, err)
+	}
+
+	if err := storage.Put(ctx, entry); err != nil {
+		return fmt.Errorf("error putting pending root json entry by key %q into the storage: %s", storageKeyTufPendingRoot, err)
+	}
+
+	return nil
+}
+
+// rootDBs returns the verification databases of the published and the pending root.json.
+func (repository *S3Repository) rootDBs(pending *PendingRoot) (*verify.DB, *verify.DB, error) {
+	trustedRoot, err := repository.getPublishedRoot()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pendingRoot, err := unmarshalRoot(pending.Signed)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	trustedDB, err := rootDB(trustedRoot)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid published root.json: %s", err)
+	}
+
+	newDB, err := rootDB(pendingRoot)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid pending root.json: %s", err)
+	}
+
+	return trustedDB, newDB, nil
+}
+
+func (repository *S3Repository) getPublishedRoot() (*data.Root, error) {
+	rootJSON, err := repository.Filesystem.ReadFileBytes(context.Background(), "root.json")
+	if err != nil {
+		return nil, fmt.Errorf("error reading %q: %s", "root.json", err)
+	}
+
+	signed := &data.Signed{}
+	if err := json.Unmarshal(rootJSON, signed); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal root.json: %s", err)
+	}
+
+	return unmarshalRoot(signed)
+}
+
+// privKeysChecksum returns the checksum of the top-level roles private keys and their rotation dates.
+func privKeysChecksum(privKeys TufRepoPrivKeys) (string, error) {
+	privKeys.Delegations = nil
+
+	privKeysJSON, err := json.Marshal(privKeys)
+	if err != nil {
+		return "", fmt.Errorf("unable to marshal private keys: %s", err)
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(privKeysJSON)), nil
+}
+
+// copyPrivKeys returns the copy of the repository private keys, which can be changed without affecting the repository.
+func (repository *S3Repository) copyPrivKeys() TufRepoPrivKeys {
+	privKeys := repository.TufStore.GetPrivKeys()
+
+	res := TufRepoPrivKeys{Expires: make(map[string]time.Time)}
+	for _, role := range tufRoles {
+		*res.roleKeys(role) = append(TufRolePrivKeys{}, *privKeys.roleKeys(role)...)
+	}
+	for role, expires := range privKeys.Expires {
+		res.Expires[role] = expires
+	}
+	res.Delegations = privKeys.Delegations
+
+	return res
+}
+
+func unmarshalRoot(signed *data.Signed) (*data.Root, error) {
+	root := &data.Root{}
+	if err := json.Unmarshal(signed.Signed, root); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal root.json signed data: %s", err)
+	}
+
+	return root, nil
+}
+
+func rootDB(root *data.Root) (*verify.DB, error) {
+	db := verify.NewDB()
+	for id, key := range root.Keys {
+		if err := db.AddKey(id, key); err != nil {
+			return nil, err
+		}
+	}
+
+	role, hasKey := root.Roles["root"]
+	if !hasKey {
+		return nil, fmt.Errorf("root role not found")
+	}
+
+	if err := db.AddRole("root", role); err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// replaceRoleKeys sets the keys of the role, the keys not used by other roles are removed from root.json.
+func replaceRoleKeys(root *data.Root, role string, keys []*data.Key) {
+	r, hasKey := root.Roles[role]
+	if !hasKey {
+		r = &data.Role{Threshold: 1}
+		root.Roles[role] = r
+	}
+
+	oldKeyIDs := r.KeyIDs
+	r.KeyIDs = []string{}
+	for _, key := range keys {
+		r.AddKeyIDs(key.IDs())
+		root.AddKey(key)
+	}
+
+	for _, keyID := range oldKeyIDs {
+		isUsed := false
+		for _, other := range root.Roles {
+			for _, id := range other.KeyIDs {
+				if id == keyID {
+					isUsed = true
+				}
+			}
+		}
+
+		if !isUsed {
+			delete(root.Keys, keyID)
+		}
+	}
+}
+
+func isRoleKey(root *data.Root, role string, key *data.Key) bool {
+	r, hasKey := root.Roles[role]
+	if !hasKey {
+		return false
+	}
+
+	for _, keyID := range r.KeyIDs {
+		if key.ContainsID(keyID) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func verifySignature(signed *data.Signed, key *data.Key, signature data.Signature) error {
+	db := verify.NewDB()
+	if err := db.AddKey(signature.KeyID, key); err != nil {
+		return err
+	}
+
+	if err := db.AddRole("root", &data.Role{KeyIDs: []string{signature.KeyID}, Threshold: 1}); err != nil {
+		return err
+	}
+
+	return db.VerifySignatures(&data.Signed{Signed: signed.Signed, Signatures: []data.Signature{signature}}, "root")
+}
+
+// countValidSignatures returns the number of the role keys with the valid signature.
+func countValidSignatures(signed *data.Signed, db *verify.DB) int {
+	role := db.GetRole("root")
+
+	count := 0
+	for _, signature := range signed.Signatures {
+		if !role.ValidKey(signature.KeyID) {
+			continue
+		}
+
+		key := db.GetKey(signature.KeyID)
+		if key == nil {
+			continue
+		}
+
+		if verifySignature(signed, key, signature) == nil {
+			count++
+		}
+	}
+
+	return count
+}
@@ -0,0 +1,165 @@
+package publisher
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublishPendingRoot_Interrupted(t *testing.T) {
+	ctx := context.Background()
+	storage := &logical.InmemStorage{}
+	publisher, options := newTestPublisher(t)
+
+	repository := newTestPublishedRepository(t, publisher, storage, options)
+	expirePrivKeys(t, ctx, storage, "timestamp")
+	repository = getTestPublisherRepository(t, publisher, storage, options)
+	timestampKeys := repository.GetPrivKeys().Timestamp
+
+	pending, err := publisher.PreparePendingRoot(ctx, storage, repository, PrepareRootOptions{})
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	assert.NotEqual(t, timestampKeys, pending.PrivKeys.Timestamp)
+
+	// The plugin stops after root.json is published, but before the new keys are saved
+	pending.Activating = true
+	if !assert.Nil(t, putPendingRoot(ctx, storage, pending)) {
+		t.FailNow()
+	}
+
+	if !assert.Nil(t, repository.PublishRoot(ctx, pending)) {
+		t.FailNow()
+	}
+
+	// The keys of the published root.json are activated on the next loading
+	repository = getTestPublisherRepository(t, publisher, storage, options)
+	assert.Equal(t, pending.PrivKeys.Timestamp, repository.GetPrivKeys().Timestamp)
+
+	privKeys, err := getRepositoryKeys(ctx, storage)
+	if assert.Nil(t, err) {
+		assert.Equal(t, pending.PrivKeys.Timestamp, privKeys.Timestamp)
+	}
+
+	pending, err = publisher.GetPendingRoot(ctx, storage)
+	assert.Nil(t, err)
+	assert.Nil(t, pending)
+}
+
+func TestPublishPendingRoot_NotPublished(t *testing.T) {
+	ctx := context.Background()
+	storage := &logical.InmemStorage{}
+	publisher, options := newTestPublisher(t)
+
+	repository := newTestPublishedRepository(t, publisher, storage, options)
+	privKeys := repository.GetPrivKeys()
+
+	pending, err := publisher.PreparePendingRoot(ctx, storage, repository, PrepareRootOptions{})
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	// The plugin stops before root.json is published: the keys are kept
+	pending.Activating = true
+	if !assert.Nil(t, putPendingRoot(ctx, storage, pending)) {
+		t.FailNow()
+	}
+
+	repository = getTestPublisherRepository(t, publisher, storage, options)
+	assert.Equal(t, privKeys, repository.GetPrivKeys())
+
+	pending, err = publisher.GetPendingRoot(ctx, storage)
+	if assert.Nil(t, err) && assert.NotNil(t, pending) {
+		assert.False(t, pending.Activating)
+	}
+
+	// The pending root.json can be published again
+	if assert.Nil(t, publisher.PublishPendingRoot(ctx, storage, repository)) {
+		pending, err = publisher.GetPendingRoot(ctx, storage)
+		assert.Nil(t, err)
+		assert.Nil(t, pending)
+	}
+}
+
+func TestPublishPendingRoot_KeysChanged(t *testing.T) {
+	ctx := context.Background()
+	storage := &logical.InmemStorage{}
+	publisher, options := newTestPublisher(t)
+
+	repository := newTestPublishedRepository(t, publisher, storage, options)
+
+	if _, err := publisher.PreparePendingRoot(ctx, storage, repository, PrepareRootOptions{}); !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	// The keys are rotated after the root.json is prepared
+	expirePrivKeys(t, ctx, storage, "timestamp")
+	repository = getTestPublisherRepository(t, publisher, storage, options)
+	if !assert.Nil(t, publisher.RotateRepositoryKeys(ctx, storage, repository)) {
+		t.FailNow()
+	}
+
+	err := publisher.PublishPendingRoot(ctx, storage, repository)
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "should be prepared again")
+	}
+
+	pending, err := publisher.GetPendingRoot(ctx, storage)
+	if assert.Nil(t, err) && assert.NotNil(t, pending) {
+		assert.False(t, pending.Activating)
+	}
+}
+
+func newTestPublisher(t *testing.T) (*Publisher, RepositoryOptions) {
+	return NewPublisher(hclog.NewNullLogger()), RepositoryOptions{
+		StorageOptions:    StorageOptions{StorageType: StorageTypeLocal, LocalPath: t.TempDir()},
+		InitializeTUFKeys: true,
+		SkipPGPSigningKey: true,
+	}
+}
+
+// newTestPublishedRepository returns the repository with the committed target "any-any/file".
+func newTestPublishedRepository(t *testing.T, publisher *Publisher, storage logical.Storage, options RepositoryOptions) RepositoryInterface {
+	ctx := context.Background()
+	repository := getTestPublisherRepository(t, publisher, storage, options)
+
+	if err := repository.StageTarget(ctx, "any-any/file", strings.NewReader("data")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repository.CommitStaged(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	return repository
+}
+
+func getTestPublisherRepository(t *testing.T, publisher *Publisher, storage logical.Storage, options RepositoryOptions) RepositoryInterface {
+	repository, err := publisher.GetRepository(context.Background(), storage, options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return repository
+}
+
+// expirePrivKeys moves the rotation date of the stored role keys into the past.
+func expirePrivKeys(t *testing.T, ctx context.Context, storage logical.Storage, roles ...string) {
+	privKeys, err := getRepositoryKeys(ctx, storage)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, role := range roles {
+		privKeys.Expires[role] = time.Now().Add(-time.Hour)
+	}
+
+	if err := putRepositoryKeys(ctx, storage, *privKeys); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,253 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/theupdateframework/go-tuf"
+
+	"github.com/werf/trdl/server/pkg/pgp"
+)
+
+// PGPSigningPublicKeyTarget is the TUF target with the public keys to verify the release artifacts signatures.
+const PGPSigningPublicKeyTarget = "pgp/signing_key.asc"
+
+// RetiredPGPSigningKey is the public part of the rotated PGP signing key,
+// which is still trusted during the overlap period, so the signatures made by the key remain valid.
+type RetiredPGPSigningKey struct {
+	PublicKey string    `json:"public_key"`
+	Expires   time.Time `json:"expires"`
+}
+
+// RotatePGPSigningKey generates the new PGP signing key, the public part of the current key is retired for the overlap period.
+// The algorithm of the current key is kept if the algorithm is not specified.
+// The current key stays in place until the new key is generated and the retired keys are saved,
+// so a failure during the rotation never leaves the repository without a signing key.
+func (publisher *Publisher) RotatePGPSigningKey(ctx context.Context, storage logical.Storage, algorithm string, overlapPeriod time.Duration) error {
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+
+	retiredKeys, err := publisher.getRetiredPGPSigningKeys(ctx, storage)
+	if err != nil {
+		return err
+	}
+
+	currentKey, err := publisher.fetchPGPSigningKey(ctx, storage, false)
+	if err != nil && err != ErrUninitializedPGPSigningKey {
+		return fmt.Errorf("error fetching pgp signing key: %s", err)
+	}
+
+	if algorithm == "" && currentKey != nil {
+		algorithm = currentKey.Algorithm()
+	}
+	if algorithm == "" {
+		algorithm = pgp.DefaultSigningKeyAlgorithm
+	}
+
+	newKey, err := generatePGPSigningKey(algorithm)
+	if err != nil {
+		return err
+	}
+
+	if currentKey != nil && overlapPeriod > 0 {
+		publicKey := bytes.NewBuffer(nil)
+		if err := currentKey.SerializePublicKey(publicKey); err != nil {
+			return fmt.Errorf("unable to serialize pgp signing public key: %s", err)
+		}
+
+		retiredKeys = append(retiredKeys, RetiredPGPSigningKey{
+			PublicKey: publicKey.String(),
+			Expires:   time.Now().Add(overlapPeriod).UTC().Round(time.Second),
+		})
+	}
+
+	if err := putRetiredPGPSigningKeys(ctx, storage, retiredKeys); err != nil {
+		return err
+	}
+
+	if err := putPGPSigningKey(ctx, storage, newKey); err != nil {
+		return err
+	}
+	publisher.PGPSigningKey = newKey
+
+	return nil
+}
+
+// GetRetiredPGPSigningKeys returns the retired PGP signing keys which overlap period is not over yet.
+func (publisher *Publisher) GetRetiredPGPSigningKeys(ctx context.Context, storage logical.Storage) ([]RetiredPGPSigningKey, error) {
+	return publisher.getRetiredPGPSigningKeys(ctx, storage)
+}
+
+func (publisher *Publisher) getRetiredPGPSigningKeys(ctx context.Context, storage logical.Storage) ([]RetiredPGPSigningKey, error) {
+	entry, err := storage.Get(ctx, storageKeyPGPRetiredSigningKeys)
+	if err != nil {
+		return nil, fmt.Errorf("error getting storage retired pgp signing keys json entry by the key %q: %s", storageKeyPGPRetiredSigningKeys, err)
+	}
+
+	if entry == nil {
+		return nil, nil
+	}
+
+	var retiredKeys []RetiredPGPSigningKey
+	if err := entry.DecodeJSON(&retiredKeys); err != nil {
+		return nil, fmt.Errorf("unable to decode retired pgp signing keys json by the %q storage key:\n%s---\n%s", storageKeyPGPRetiredSigningKeys, entry.Value, err)
+	}
+
+	return withoutExpiredPGPSigningKeys(retiredKeys), nil
+}
+
+// putRetiredPGPSigningKeys saves the retired keys, the keys which overlap period is over are dropped.
+func putRetiredPGPSigningKeys(ctx context.Context, storage logical.Storage, retiredKeys []RetiredPGPSigningKey) error {
+	entry, err := logical.StorageEntryJSON(storageKeyPGPRetiredSigningKeys, withoutExpiredPGPSigningKeys(retiredKeys))
+	if err != nil {
+		return fmt.Errorf("error creating storage json entry by key %q: %s", storageKeyPGPRetiredSigningKeys, err)
+	}
+
+	if err := storage.Put(ctx, entry); err != nil {
+		return fmt.Errorf("error putting retired pgp signing keys json entry by key %q into the storage: %s", storageKeyPGPRetiredSigningKeys, err)
+	}
+
+	return nil
+}
+
+func withoutExpiredPGPSigningKeys(retiredKeys []RetiredPGPSigningKey) []RetiredPGPSigningKey {
+	var res []RetiredPGPSigningKey
+	now := time.Now()
+	for _, key := range retiredKeys {
+		if key.Expires.After(now) {
+			res = append(res, key)
+		}
+	}
+
+	return res
+}
+
+// StageReleaseSignatures re-signs the targets of all releases with the current PGP signing key.
+func (publisher *Publisher) StageReleaseSignatures(ctx context.Context, repository RepositoryInterface) error {
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+
+	existingTargets, err := repository.GetTargets(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting existing targets: %s", err)
+	}
+
+	for _, target := range existingTargets {
+		if !strings.HasPrefix(target, "releases/") {
+			continue
+		}
+
+		data, err := repository.GetTargetFile(ctx, target)
+		if err != nil {
+			return fmt.Errorf("unable to get release target %q: %s", target, err)
+		}
+
+		signature := bytes.NewBuffer(nil)
+		if err := pgp.SignDataStream(signature, bytes.NewReader(data), publisher.PGPSigningKey); err != nil {
+			return fmt.Errorf("unable to sign %q: %s", target, err)
+		}
+
+		signaturePath := path.Join("signatures", fmt.Sprintf("%s.sig", strings.TrimPrefix(target, "releases/")))
+		hclog.L().Debug(fmt.Sprintf("Stage release target signature %q ...\n", signaturePath))
+		if err := repository.StageTarget(ctx, signaturePath, signature); err != nil {
+			return fmt.Errorf("unable to stage release target signature %q into the repository: %s", signaturePath, err)
+		}
+	}
+
+	return nil
+}
+
+// GetPGPSigningPublicKeys returns the armored keyring with the public parts of the current PGP signing key
+// and the retired keys which are still trusted.
+func (publisher *Publisher) GetPGPSigningPublicKeys(ctx context.Context, storage logical.Storage) ([]byte, error) {
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+
+	return publisher.getPGPSigningPublicKeys(ctx, storage)
+}
+
+func (publisher *Publisher) getPGPSigningPublicKeys(ctx context.Context, storage logical.Storage) ([]byte, error) {
+	currentKey, err := publisher.fetchPGPSigningKey(ctx, storage, false)
+	if err == ErrUninitializedPGPSigningKey {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("error fetching pgp signing key: %s", err)
+	}
+
+	entities := openpgp.EntityList{currentKey.Entity}
+
+	retiredKeys, err := publisher.getRetiredPGPSigningKeys(ctx, storage)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, retiredKey := range retiredKeys {
+		el, err := openpgp.ReadArmoredKeyRing(strings.NewReader(retiredKey.PublicKey))
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse retired pgp signing key: %s", err)
+		}
+
+		entities = append(entities, el...)
+	}
+
+	publicKeys := bytes.NewBuffer(nil)
+	if err := pgp.SerializePublicKeys(publicKeys, entities); err != nil {
+		return nil, fmt.Errorf("unable to serialize pgp signing public keys: %s", err)
+	}
+
+	return publicKeys.Bytes(), nil
+}
+
+// IsPGPSigningPublicKeyPublished checks whether the PGPSigningPublicKeyTarget target matches the current PGP signing public keys.
+func (publisher *Publisher) IsPGPSigningPublicKeyPublished(ctx context.Context, storage logical.Storage, repository RepositoryInterface) (bool, error) {
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+
+	publicKeys, err := publisher.getPGPSigningPublicKeys(ctx, storage)
+	if err != nil {
+		return false, err
+	}
+
+	if publicKeys == nil {
+		return true, nil
+	}
+
+	data, err := repository.GetTargetFile(ctx, PGPSigningPublicKeyTarget)
+	if _, isNotFound := err.(tuf.ErrFileNotFound); isNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("unable to get target %q: %s", PGPSigningPublicKeyTarget, err)
+	}
+
+	return bytes.Equal(data, publicKeys), nil
+}
+
+// StagePGPSigningPublicKey stages the PGPSigningPublicKeyTarget target with the current PGP signing public keys,
+// so the release artifacts signatures can be verified outside of trdl.
+func (publisher *Publisher) StagePGPSigningPublicKey(ctx context.Context, storage logical.Storage, repository RepositoryInterface) error {
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+
+	publicKeys, err := publisher.getPGPSigningPublicKeys(ctx, storage)
+	if err != nil {
+		return err
+	}
+
+	if publicKeys == nil {
+		return nil
+	}
+
+	hclog.L().Debug(fmt.Sprintf("Stage pgp signing public key %q ...\n", PGPSigningPublicKeyTarget))
+	if err := repository.StageTarget(ctx, PGPSigningPublicKeyTarget, bytes.NewReader(publicKeys)); err != nil {
+		return fmt.Errorf("error publishing %q: %s", PGPSigningPublicKeyTarget, err)
+	}
+
+	return nil
+}
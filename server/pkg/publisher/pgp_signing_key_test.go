@@ -0,0 +1,129 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/werf/trdl/server/pkg/pgp"
+)
+
+func TestRotatePGPSigningKey(t *testing.T) {
+	ctx := context.Background()
+	storage := &logical.InmemStorage{}
+	publisher := NewPublisher(hclog.NewNullLogger())
+
+	// The key is initialized if there is no current key
+	assert.Nil(t, publisher.RotatePGPSigningKey(ctx, storage, pgp.SigningKeyAlgorithmEd25519, time.Hour))
+
+	firstKey, err := publisher.fetchPGPSigningKey(ctx, storage, false)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, pgp.SigningKeyAlgorithmEd25519, firstKey.Algorithm())
+
+	retiredKeys, err := publisher.GetRetiredPGPSigningKeys(ctx, storage)
+	assert.Nil(t, err)
+	assert.Empty(t, retiredKeys)
+
+	// The current key is retired for the overlap period, the algorithm is kept
+	assert.Nil(t, publisher.RotatePGPSigningKey(ctx, storage, "", time.Hour))
+
+	secondKey, err := publisher.fetchPGPSigningKey(ctx, storage, false)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, pgp.SigningKeyAlgorithmEd25519, secondKey.Algorithm())
+	assert.NotEqual(t, firstKey.Entity.PrimaryKey.Fingerprint, secondKey.Entity.PrimaryKey.Fingerprint)
+	assert.Equal(t, secondKey.Entity.PrimaryKey.Fingerprint, publisher.PGPSigningKey.Entity.PrimaryKey.Fingerprint)
+
+	retiredKeys, err = publisher.GetRetiredPGPSigningKeys(ctx, storage)
+	assert.Nil(t, err)
+	if assert.Len(t, retiredKeys, 1) {
+		assert.Equal(t, serializePGPSigningPublicKey(t, firstKey), retiredKeys[0].PublicKey)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), retiredKeys[0].Expires, time.Minute)
+	}
+
+	// The key is not retired without the overlap period
+	assert.Nil(t, publisher.RotatePGPSigningKey(ctx, storage, "", 0))
+
+	retiredKeys, err = publisher.GetRetiredPGPSigningKeys(ctx, storage)
+	assert.Nil(t, err)
+	assert.Len(t, retiredKeys, 1)
+}
+
+func TestRotatePGPSigningKey_PruneExpiredRetiredKeys(t *testing.T) {
+	ctx := context.Background()
+	storage := &logical.InmemStorage{}
+	publisher := NewPublisher(hclog.NewNullLogger())
+
+	entry, err := logical.StorageEntryJSON(storageKeyPGPRetiredSigningKeys, []RetiredPGPSigningKey{
+		{PublicKey: "expired", Expires: time.Now().Add(-time.Hour)},
+		{PublicKey: "active", Expires: time.Now().Add(time.Hour)},
+	})
+	assert.Nil(t, err)
+	assert.Nil(t, storage.Put(ctx, entry))
+
+	assert.Nil(t, publisher.RotatePGPSigningKey(ctx, storage, pgp.SigningKeyAlgorithmEd25519, time.Hour))
+
+	entry, err = storage.Get(ctx, storageKeyPGPRetiredSigningKeys)
+	if !assert.Nil(t, err) || !assert.NotNil(t, entry) {
+		t.FailNow()
+	}
+
+	var storedKeys []RetiredPGPSigningKey
+	assert.Nil(t, entry.DecodeJSON(&storedKeys))
+	if assert.Len(t, storedKeys, 1) {
+		assert.Equal(t, "active", storedKeys[0].PublicKey)
+	}
+}
+
+func TestRotatePGPSigningKey_KeepCurrentKeyOnFailure(t *testing.T) {
+	ctx := context.Background()
+	storage := &failingPutStorage{Storage: &logical.InmemStorage{}}
+	publisher := NewPublisher(hclog.NewNullLogger())
+
+	currentKey, err := publisher.initializePGPSigningKey(ctx, storage, pgp.SigningKeyAlgorithmEd25519)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	for _, failKey := range []string{storageKeyPGPRetiredSigningKeys, storageKeyPGPSigningKey} {
+		storage.failKey = failKey
+		assert.NotNil(t, publisher.RotatePGPSigningKey(ctx, storage, "", time.Hour), failKey)
+
+		storage.failKey = ""
+		key, err := publisher.fetchPGPSigningKey(ctx, storage, false)
+		if assert.Nil(t, err, failKey) {
+			assert.Equal(t, currentKey.Entity.PrimaryKey.Fingerprint, key.Entity.PrimaryKey.Fingerprint, failKey)
+		}
+	}
+}
+
+type failingPutStorage struct {
+	logical.Storage
+	failKey string
+}
+
+func (s *failingPutStorage) Put(ctx context.Context, entry *logical.StorageEntry) error {
+	if entry.Key == s.failKey {
+		return errors.New("put failed")
+	}
+
+	return s.Storage.Put(ctx, entry)
+}
+
+func serializePGPSigningPublicKey(t *testing.T, key *pgp.SigningKey) string {
+	buf := bytes.NewBuffer(nil)
+	if err := key.SerializePublicKey(buf); err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.String()
+}
@@ -9,8 +9,10 @@ import (
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 	"unicode/utf8"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -24,24 +26,82 @@ import (
 )
 
 const (
-	storageKeyTufRepositoryKeys = "tuf_repository_keys"
-	storageKeyPGPSigningKey     = "pgp_signing_key"
+	storageKeyTufRepositoryKeys     = "tuf_repository_keys"
+	storageKeyPGPSigningKey         = "pgp_signing_key"
+	storageKeyTufPendingRoot        = "tuf_pending_root"
+	storageKeyPGPRetiredSigningKeys = "pgp_retired_signing_keys"
 )
 
 var (
 	ErrUninitializedRepositoryKeys = errors.New("uninitialized repository keys")
 	ErrUninitializedPGPSigningKey  = errors.New("uninitialized pgp signing key")
+	ErrPendingRootNotFound         = errors.New("pending root.json not found")
 )
 
-type RepositoryOptions struct {
+const (
+	StorageTypeS3    = "s3"
+	StorageTypeLocal = "local"
+)
+
+type StorageOptions struct {
+	// StorageType selects the repository filesystem: StorageTypeS3 (by default) or StorageTypeLocal.
+	StorageType string
+	LocalPath   string
+
 	S3Endpoint        string
 	S3Region          string
 	S3AccessKeyID     string
 	S3SecretAccessKey string
 	S3BucketName      string
+}
+
+// Name returns the storage name for the logs.
+func (options StorageOptions) Name() string {
+	switch options.StorageType {
+	case StorageTypeLocal:
+		return fmt.Sprintf("local:%s", options.LocalPath)
+	default:
+		return fmt.Sprintf("s3:%s/%s", options.S3Endpoint, options.S3BucketName)
+	}
+}
+
+type RepositoryOptions struct {
+	StorageOptions
+
+	// StorageMirrors receive the same content as the primary storage.
+	StorageMirrors []StorageOptions
+
+	// TufExpirationPeriods overrides the default expiration period of the TUF role metadata.
+	TufExpirationPeriods map[string]time.Duration
+	// TufExpirationThresholds overrides the default expiration threshold of the TUF role metadata.
+	TufExpirationThresholds map[string]time.Duration
+	// TufConsistentSnapshot enables the consistent snapshot layout for the new TUF repository.
+	TufConsistentSnapshot bool
+	// TufDelegations enables publishing the release and channel targets in the delegated roles metadata.
+	TufDelegations bool
+	// TufKeysNumbers defines the number of the private keys of the TUF role.
+	TufKeysNumbers map[string]int
+	// TufKeysThresholds defines the number of the keys required to sign the TUF role metadata.
+	TufKeysThresholds map[string]int
 
 	InitializeTUFKeys       bool
 	InitializePGPSigningKey bool
+	// SkipPGPSigningKey is used to get the repository for the access which does not sign release artifacts,
+	// so the repository is available even if the PGP signing key has not been initialized yet.
+	SkipPGPSigningKey bool
+}
+
+type TargetMeta struct {
+	Length int64
+	Hashes map[string]string
+}
+
+type ReleaseTarget struct {
+	OsArch        string
+	Path          string
+	Length        int64
+	Hashes        map[string]string
+	SignaturePath string
 }
 
 type InMemoryFile struct {
@@ -57,7 +117,7 @@ type Publisher struct {
 	mu     sync.Mutex
 	logger hclog.Logger
 
-	PGPSigningKey *pgp.RSASigningKey
+	PGPSigningKey *pgp.SigningKey
 }
 
 func NewPublisher(logger hclog.Logger) *Publisher {
@@ -71,36 +131,85 @@ func (publisher *Publisher) RotateRepositoryKeys(ctx context.Context, storage lo
 	}
 
 	if updated {
-		entry, err := logical.StorageEntryJSON(storageKeyTufRepositoryKeys, updatedPrivKeys)
-		if err != nil {
-			return fmt.Errorf("error creating storage json entry by key %q: %s", storageKeyTufRepositoryKeys, err)
+		if err := putRepositoryKeys(ctx, storage, updatedPrivKeys); err != nil {
+			return err
 		}
 
-		if err := storage.Put(ctx, entry); err != nil {
-			return fmt.Errorf("error putting private keys json entry by key %q into the storage: %s", storageKeyTufRepositoryKeys, err)
-		}
+		publisher.logger.Info("Successfully updated repository private keys")
+	}
 
-		publisher.logger.Info("Successfully rotated repository private keys")
+	return nil
+}
+
+func putRepositoryKeys(ctx context.Context, storage logical.Storage, privKeys TufRepoPrivKeys) error {
+	entry, err := logical.StorageEntryJSON(storageKeyTufRepositoryKeys, privKeys)
+	if err != nil {
+		return fmt.Errorf("error creating storage json entry by key %q: %s", storageKeyTufRepositoryKeys, err)
+	}
+
+	if err := storage.Put(ctx, entry); err != nil {
+		return fmt.Errorf("error putting private keys json entry by key %q into the storage: %s", storageKeyTufRepositoryKeys, err)
 	}
 
 	return nil
 }
 
+// IsRepositoryKeysRotationDue checks the rotation dates of the stored private keys without accessing the repository storage.
+func (publisher *Publisher) IsRepositoryKeysRotationDue(ctx context.Context, storage logical.Storage) (bool, error) {
+	privKeys, err := getRepositoryKeys(ctx, storage)
+	if err != nil {
+		return false, err
+	}
+
+	if privKeys == nil {
+		return false, nil
+	}
+
+	return len(privKeys.ExpiredRoles(time.Now())) > 0, nil
+}
+
+// getRepositoryKeys returns the stored repository private keys or nil, if the keys have not been generated yet.
+func getRepositoryKeys(ctx context.Context, storage logical.Storage) (*TufRepoPrivKeys, error) {
+	entry, err := storage.Get(ctx, storageKeyTufRepositoryKeys)
+	if err != nil {
+		return nil, fmt.Errorf("error getting storage private keys json entry by the key %q: %s", storageKeyTufRepositoryKeys, err)
+	}
+
+	if entry == nil {
+		return nil, nil
+	}
+
+	privKeys := &TufRepoPrivKeys{}
+	if err := entry.DecodeJSON(privKeys); err != nil {
+		return nil, fmt.Errorf("unable to decode keys json by the %q storage key: %s", storageKeyTufRepositoryKeys, err)
+	}
+
+	return privKeys, nil
+}
+
 func (publisher *Publisher) UpdateTimestamps(ctx context.Context, storage logical.Storage, repository RepositoryInterface) error {
 	return repository.UpdateTimestamps(ctx)
 }
 
+func (publisher *Publisher) MigrateToConsistentSnapshot(ctx context.Context, storage logical.Storage, repository RepositoryInterface) error {
+	return repository.MigrateToConsistentSnapshot(ctx)
+}
+
+func (publisher *Publisher) SyncStorageMirrors(ctx context.Context, storage logical.Storage, repository RepositoryInterface) error {
+	return repository.SyncStorageMirrors(ctx)
+}
+
 type setRepositoryKeysOptions struct {
 	InitializeKeys bool
 }
 
 func (publisher *Publisher) setRepositoryKeys(ctx context.Context, storage logical.Storage, repository RepositoryInterface, opts setRepositoryKeysOptions) error {
-	entry, err := storage.Get(ctx, storageKeyTufRepositoryKeys)
+	privKeys, err := getRepositoryKeys(ctx, storage)
 	if err != nil {
-		return fmt.Errorf("error getting storage private keys json entry by the key %q: %s", storageKeyTufRepositoryKeys, err)
+		return err
 	}
 
-	if entry == nil {
+	if privKeys == nil {
 		if !opts.InitializeKeys {
 			return ErrUninitializedRepositoryKeys
 		}
@@ -111,15 +220,8 @@ func (publisher *Publisher) setRepositoryKeys(ctx context.Context, storage logic
 			return fmt.Errorf("error generating repository private keys: %s", err)
 		}
 
-		privKeys := repository.GetPrivKeys()
-
-		entry, err := logical.StorageEntryJSON(storageKeyTufRepositoryKeys, privKeys)
-		if err != nil {
-			return fmt.Errorf("error creating storage json entry by key %q: %s", storageKeyTufRepositoryKeys, err)
-		}
-
-		if err := storage.Put(ctx, entry); err != nil {
-			return fmt.Errorf("error putting private keys json entry by key %q into the storage: %s", storageKeyTufRepositoryKeys, err)
+		if err := putRepositoryKeys(ctx, storage, repository.GetPrivKeys()); err != nil {
+			return err
 		}
 
 		publisher.logger.Info("Generated new repository private keys")
@@ -127,12 +229,7 @@ func (publisher *Publisher) setRepositoryKeys(ctx context.Context, storage logic
 		return nil
 	}
 
-	var privKeys TufRepoPrivKeys
-	if err := entry.DecodeJSON(&privKeys); err != nil {
-		return fmt.Errorf("unable to decode keys json by the %q storage key:\n%s---\n%s", storageKeyTufRepositoryKeys, entry.Value, err)
-	}
-
-	if err := repository.SetPrivKeys(privKeys); err != nil {
+	if err := repository.SetPrivKeys(*privKeys); err != nil {
 		return fmt.Errorf("unable to set private keys into repository: %s", err)
 	}
 
@@ -145,7 +242,7 @@ func (pulisher *Publisher) deletePGPSigningKey(ctx context.Context, storage logi
 	return storage.Delete(ctx, storageKeyPGPSigningKey)
 }
 
-func (publisher *Publisher) fetchPGPSigningKey(ctx context.Context, storage logical.Storage, initializeKey bool) (*pgp.RSASigningKey, error) {
+func (publisher *Publisher) fetchPGPSigningKey(ctx context.Context, storage logical.Storage, initializeKey bool) (*pgp.SigningKey, error) {
 	entry, err := storage.Get(ctx, storageKeyPGPSigningKey)
 	if err != nil {
 		return nil, fmt.Errorf("error getting storage pgp signing key json entry by storage key %q: %s", storageKeyPGPSigningKey, err)
@@ -156,56 +253,67 @@ func (publisher *Publisher) fetchPGPSigningKey(ctx context.Context, storage logi
 			return nil, ErrUninitializedPGPSigningKey
 		}
 
-		hclog.L().Debug("Will generate a new pgp signing key")
-
-		key, err := pgp.GenerateRSASigningKey()
-		if err != nil {
-			return nil, fmt.Errorf("unable to generate new rsa pgp signing key: %s", err)
-		}
+		return publisher.initializePGPSigningKey(ctx, storage, pgp.DefaultSigningKeyAlgorithm)
+	}
 
-		serializedKey := bytes.NewBuffer(nil)
-		if err := key.SerializeFull(serializedKey); err != nil {
-			return nil, fmt.Errorf("unable to serialize pgp signing key: %s", err)
-		}
+	key, err := pgp.ParseSigningKey(bytes.NewReader(entry.Value))
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse pgp signing key by the %q storage key:\n%s\n---%s", storageKeyPGPSigningKey, entry.Value, err)
+	}
+	return key, nil
+}
 
-		entry := &logical.StorageEntry{
-			Key:   storageKeyPGPSigningKey,
-			Value: serializedKey.Bytes(),
-		}
+func (publisher *Publisher) initializePGPSigningKey(ctx context.Context, storage logical.Storage, algorithm string) (*pgp.SigningKey, error) {
+	key, err := generatePGPSigningKey(algorithm)
+	if err != nil {
+		return nil, err
+	}
 
-		if err := storage.Put(ctx, entry); err != nil {
-			return nil, fmt.Errorf("error putting pgp signing key by storage key %q: %s", storageKeyPGPSigningKey, err)
-		}
+	if err := putPGPSigningKey(ctx, storage, key); err != nil {
+		return nil, err
+	}
 
-		hclog.L().Info("Generated new PGP signing key")
+	return key, nil
+}
 
-		return key, nil
-	}
+func generatePGPSigningKey(algorithm string) (*pgp.SigningKey, error) {
+	hclog.L().Debug(fmt.Sprintf("Will generate a new %s pgp signing key", algorithm))
 
-	key, err := pgp.ParseRSASigningKey(bytes.NewReader(entry.Value))
+	key, err := pgp.GenerateSigningKey(algorithm)
 	if err != nil {
-		return nil, fmt.Errorf("unable to parse pgp signing key by the %q storage key:\n%s\n---%s", storageKeyPGPSigningKey, entry.Value, err)
+		return nil, fmt.Errorf("unable to generate new %s pgp signing key: %s", algorithm, err)
 	}
+
+	hclog.L().Info(fmt.Sprintf("Generated new %s PGP signing key", algorithm))
+
 	return key, nil
 }
 
+func putPGPSigningKey(ctx context.Context, storage logical.Storage, key *pgp.SigningKey) error {
+	serializedKey := bytes.NewBuffer(nil)
+	if err := key.SerializeFull(serializedKey); err != nil {
+		return fmt.Errorf("unable to serialize pgp signing key: %s", err)
+	}
+
+	entry := &logical.StorageEntry{
+		Key:   storageKeyPGPSigningKey,
+		Value: serializedKey.Bytes(),
+	}
+
+	if err := storage.Put(ctx, entry); err != nil {
+		return fmt.Errorf("error putting pgp signing key by storage key %q: %s", storageKeyPGPSigningKey, err)
+	}
+
+	return nil
+}
+
 func (publisher *Publisher) GetRepository(ctx context.Context, storage logical.Storage, options RepositoryOptions) (RepositoryInterface, error) {
 	publisher.mu.Lock()
 	defer publisher.mu.Unlock()
 
-	awsConfig := &aws.Config{
-		Endpoint:    aws.String(options.S3Endpoint),
-		Region:      aws.String(options.S3Region),
-		Credentials: credentials.NewStaticCredentials(options.S3AccessKeyID, options.S3SecretAccessKey, ""),
-	}
-
-	repository, err := NewRepositoryWithOptions(
-		S3Options{AwsConfig: awsConfig, BucketName: options.S3BucketName},
-		TufRepoOptions{},
-		publisher.logger,
-	)
+	repository, err := publisher.newRepository(storage, options)
 	if err != nil {
-		return nil, fmt.Errorf("error initializing publisher repository handle: %s", err)
+		return nil, err
 	}
 
 	if err := repository.Init(); err != nil {
@@ -218,6 +326,14 @@ func (publisher *Publisher) GetRepository(ctx context.Context, storage logical.S
 		return nil, fmt.Errorf("error initializing repository keys: %s", err)
 	}
 
+	if err := publisher.reconcilePendingRoot(ctx, storage, repository); err != nil {
+		return nil, fmt.Errorf("error reconciling pending root.json: %s", err)
+	}
+
+	if options.SkipPGPSigningKey {
+		return repository, nil
+	}
+
 	pgpSigningKey, err := publisher.fetchPGPSigningKey(ctx, storage, options.InitializePGPSigningKey)
 	if err != nil {
 		return nil, fmt.Errorf("error fetching pgp signing key: %s", err)
@@ -227,16 +343,63 @@ func (publisher *Publisher) GetRepository(ctx context.Context, storage logical.S
 	return repository, nil
 }
 
-func (publisher *Publisher) StageReleaseTarget(ctx context.Context, repository RepositoryInterface, releaseName, releaseFilePath string, data io.Reader) error {
-	publisher.mu.Lock()
-	defer publisher.mu.Unlock()
+func (publisher *Publisher) newRepository(storage logical.Storage, options RepositoryOptions) (*S3Repository, error) {
+	filesystem := publisher.newFilesystem(options.StorageOptions)
+	if len(options.StorageMirrors) > 0 {
+		var mirrors []FilesystemMirror
+		for _, mirrorOptions := range options.StorageMirrors {
+			mirrors = append(mirrors, FilesystemMirror{Name: mirrorOptions.Name(), Filesystem: publisher.newFilesystem(mirrorOptions)})
+		}
+		filesystem = NewMirroredFilesystem(FilesystemMirror{Name: options.StorageOptions.Name(), Filesystem: filesystem}, mirrors, publisher.logger)
+	}
 
+	repository, err := NewRepositoryWithFilesystem(
+		filesystem,
+		TufRepoOptions{
+			ExpirationPeriods:    options.TufExpirationPeriods,
+			ExpirationThresholds: options.TufExpirationThresholds,
+			ConsistentSnapshot:   options.TufConsistentSnapshot,
+			Delegations:          options.TufDelegations,
+			KeysNumbers:          options.TufKeysNumbers,
+			KeysThresholds:       options.TufKeysThresholds,
+		},
+		publisher.logger,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error initializing publisher repository handle: %s", err)
+	}
+	repository.SavePrivKeys = func(ctx context.Context, privKeys TufRepoPrivKeys) error {
+		return putRepositoryKeys(ctx, storage, privKeys)
+	}
+
+	return repository, nil
+}
+
+func (publisher *Publisher) newFilesystem(options StorageOptions) Filesystem {
+	switch options.StorageType {
+	case StorageTypeLocal:
+		return NewLocalFilesystem(options.LocalPath, publisher.logger)
+	default:
+		awsConfig := &aws.Config{
+			Endpoint:    aws.String(options.S3Endpoint),
+			Region:      aws.String(options.S3Region),
+			Credentials: credentials.NewStaticCredentials(options.S3AccessKeyID, options.S3SecretAccessKey, ""),
+		}
+		return NewS3Filesystem(awsConfig, options.S3BucketName, publisher.logger)
+	}
+}
+
+// ValidateReleaseTargetPath checks the release artifact path has the <os>-<arch>/... format.
+func ValidateReleaseTargetPath(releaseFilePath string) error {
 	pathParts := SplitFilepath(filepath.Clean(releaseFilePath))
 	if len(pathParts) == 0 {
 		return NewErrIncorrectTargetPath(releaseFilePath)
 	}
 
 	osAndArchParts := strings.SplitN(pathParts[0], "-", 2)
+	if len(osAndArchParts) != 2 {
+		return NewErrIncorrectTargetPath(releaseFilePath)
+	}
 
 	switch osAndArchParts[0] {
 	case "any", "linux", "darwin", "windows":
@@ -250,6 +413,17 @@ func (publisher *Publisher) StageReleaseTarget(ctx context.Context, repository R
 		return NewErrIncorrectTargetPath(releaseFilePath)
 	}
 
+	return nil
+}
+
+func (publisher *Publisher) StageReleaseTarget(ctx context.Context, repository RepositoryInterface, releaseName, releaseFilePath string, data io.Reader) error {
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+
+	if err := ValidateReleaseTargetPath(releaseFilePath); err != nil {
+		return err
+	}
+
 	gpgSignErrCh := make(chan error)
 	gpgSignDoneCh := make(chan struct{})
 	gpgSignBuf := bytes.NewBuffer(nil)
@@ -349,6 +523,148 @@ ScanTargets:
 	return releases, nil
 }
 
+// StageReleaseRevocation removes the release targets and signatures from the targets metadata
+// and stages the revoked/VERSION target, so the clients can tell the revoked release from the non-existing one.
+func (publisher *Publisher) StageReleaseRevocation(ctx context.Context, repository RepositoryInterface, releaseName string) error {
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+
+	hclog.L().Debug(fmt.Sprintf("Revoke release %q ...\n", releaseName))
+	if err := publisher.removeReleasesTargets(ctx, repository, []string{releaseName}); err != nil {
+		return err
+	}
+
+	revokedPath := path.Join("revoked", releaseName)
+	if err := repository.StageTarget(ctx, revokedPath, bytes.NewBufferString(releaseName+"\n")); err != nil {
+		return fmt.Errorf("error publishing %q: %s", revokedPath, err)
+	}
+
+	return nil
+}
+
+// StageReleasesRemoval removes the releases targets and signatures from the targets metadata.
+// The files are deleted from the filesystem on commit.
+func (publisher *Publisher) StageReleasesRemoval(ctx context.Context, repository RepositoryInterface, releases []string) error {
+	publisher.mu.Lock()
+	defer publisher.mu.Unlock()
+
+	hclog.L().Debug(fmt.Sprintf("Remove releases %v ...\n", releases))
+	return publisher.removeReleasesTargets(ctx, repository, releases)
+}
+
+func (publisher *Publisher) removeReleasesTargets(ctx context.Context, repository RepositoryInterface, releases []string) error {
+	existingTargets, err := repository.GetTargets(ctx)
+	if err != nil {
+		return fmt.Errorf("error getting existing targets: %s", err)
+	}
+
+	var removedTargets []string
+	for _, target := range existingTargets {
+		for _, releaseName := range releases {
+			for _, prefix := range []string{path.Join("releases", releaseName), path.Join("signatures", releaseName)} {
+				if strings.HasPrefix(target, prefix+"/") {
+					removedTargets = append(removedTargets, target)
+				}
+			}
+		}
+	}
+
+	if err := repository.RemoveTargets(ctx, removedTargets); err != nil {
+		return fmt.Errorf("unable to remove releases %v targets: %s", releases, err)
+	}
+
+	return nil
+}
+
+// GetRevokedReleases returns the releases revoked by StageReleaseRevocation.
+func (publisher *Publisher) GetRevokedReleases(ctx context.Context, repository RepositoryInterface) ([]string, error) {
+	existingTargets, err := repository.GetTargets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting existing targets: %s", err)
+	}
+
+	var releases []string
+	for _, target := range existingTargets {
+		if strings.HasPrefix(target, "revoked/") {
+			releases = append(releases, strings.TrimPrefix(target, "revoked/"))
+		}
+	}
+
+	return releases, nil
+}
+
+// GetReleaseTargets returns the published targets of the release.
+func (publisher *Publisher) GetReleaseTargets(ctx context.Context, repository RepositoryInterface, releaseName string) ([]*ReleaseTarget, error) {
+	targetsMeta, err := repository.GetTargetsMeta(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting existing targets: %s", err)
+	}
+
+	var releaseTargets []*ReleaseTarget
+
+	releasePrefix := path.Join("releases", releaseName) + "/"
+	for target, meta := range targetsMeta {
+		if !strings.HasPrefix(target, releasePrefix) {
+			continue
+		}
+
+		releaseFilePath := strings.TrimPrefix(target, releasePrefix)
+		releaseTarget := &ReleaseTarget{
+			OsArch: strings.SplitN(releaseFilePath, "/", 2)[0],
+			Path:   target,
+			Length: meta.Length,
+			Hashes: meta.Hashes,
+		}
+
+		signaturePath := path.Join("signatures", releaseName, fmt.Sprintf("%s.sig", releaseFilePath))
+		if _, hasKey := targetsMeta[signaturePath]; hasKey {
+			releaseTarget.SignaturePath = signaturePath
+		}
+
+		releaseTargets = append(releaseTargets, releaseTarget)
+	}
+
+	sort.Slice(releaseTargets, func(i, j int) bool {
+		return releaseTargets[i].Path < releaseTargets[j].Path
+	})
+
+	return releaseTargets, nil
+}
+
+// GetChannels returns the published channels versions by group and channel name.
+func (publisher *Publisher) GetChannels(ctx context.Context, repository RepositoryInterface) (map[string]map[string]string, error) {
+	existingTargets, err := repository.GetTargets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error getting existing targets: %s", err)
+	}
+
+	channels := make(map[string]map[string]string)
+
+	for _, target := range existingTargets {
+		if !strings.HasPrefix(target, "channels/") {
+			continue
+		}
+
+		pathParts := strings.Split(strings.TrimPrefix(target, "channels/"), "/")
+		if len(pathParts) != 2 {
+			continue
+		}
+		groupName, channelName := pathParts[0], pathParts[1]
+
+		data, err := repository.GetTargetFile(ctx, target)
+		if err != nil {
+			return nil, fmt.Errorf("error getting channel %q: %s", target, err)
+		}
+
+		if _, hasKey := channels[groupName]; !hasKey {
+			channels[groupName] = make(map[string]string)
+		}
+		channels[groupName][channelName] = strings.TrimSpace(string(data))
+	}
+
+	return channels, nil
+}
+
 // TODO: move this to the separate project in github.com/werf
 func SplitFilepath(path string) (result []string) {
 	path = filepath.FromSlash(path)
@@ -1,16 +1,49 @@
 package publisher
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"path"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
 	"github.com/hashicorp/go-hclog"
 	"github.com/theupdateframework/go-tuf"
 	"github.com/theupdateframework/go-tuf/data"
 	"github.com/theupdateframework/go-tuf/sign"
+	"github.com/theupdateframework/go-tuf/util"
+)
+
+var (
+	tufRoles = []string{"root", "targets", "snapshot", "timestamp"}
+
+	// privKeysLifetime defines how long the private key of the role is used before rotation.
+	privKeysLifetime = map[string]time.Duration{
+		"root":      365 * 24 * time.Hour,
+		"targets":   180 * 24 * time.Hour,
+		"snapshot":  90 * 24 * time.Hour,
+		"timestamp": 30 * 24 * time.Hour,
+	}
+
+	// DefaultExpirationPeriods defines how long the signed metadata of the role is valid.
+	DefaultExpirationPeriods = map[string]time.Duration{
+		"root":      365 * 24 * time.Hour,
+		"targets":   90 * 24 * time.Hour,
+		"snapshot":  7 * 24 * time.Hour,
+		"timestamp": 24 * time.Hour,
+	}
+
+	// DefaultExpirationThresholds defines how long before the expiration date the metadata of the role is re-signed.
+	DefaultExpirationThresholds = map[string]time.Duration{
+		"root":      60 * 24 * time.Hour,
+		"targets":   30 * 24 * time.Hour,
+		"snapshot":  3 * 24 * time.Hour,
+		"timestamp": 12 * time.Hour,
+	}
 )
 
 type S3Options struct {
@@ -20,93 +53,315 @@ type S3Options struct {
 
 type TufRepoOptions struct {
 	PrivKeys TufRepoPrivKeys
+
+	// ExpirationPeriods overrides DefaultExpirationPeriods for the specified roles.
+	ExpirationPeriods map[string]time.Duration
+
+	// ExpirationThresholds overrides DefaultExpirationThresholds for the specified roles.
+	ExpirationThresholds map[string]time.Duration
+
+	// ConsistentSnapshot enables the consistent snapshot layout on the repository initialization.
+	ConsistentSnapshot bool
+
+	// Delegations enables publishing the release and channel targets in the delegated roles metadata.
+	Delegations bool
+
+	// KeysNumbers defines the number of the private keys of the role, one key by default.
+	KeysNumbers map[string]int
+
+	// KeysThresholds defines the number of the role keys required to sign the role metadata, one key by default.
+	KeysThresholds map[string]int
 }
 
 func NewRepositoryWithOptions(s3Options S3Options, tufRepoOptions TufRepoOptions, logger hclog.Logger) (*S3Repository, error) {
 	s3fs := NewS3Filesystem(s3Options.AwsConfig, s3Options.BucketName, logger)
-	tufStore := NewNonAtomicTufStore(tufRepoOptions.PrivKeys, s3fs, logger)
+	return NewRepositoryWithFilesystem(s3fs, tufRepoOptions, logger)
+}
+
+func NewRepositoryWithFilesystem(filesystem Filesystem, tufRepoOptions TufRepoOptions, logger hclog.Logger) (*S3Repository, error) {
+	tufStore := NewAtomicTufStore(tufRepoOptions.PrivKeys, filesystem, logger)
 	tufRepo, err := tuf.NewRepo(tufStore)
 	if err != nil {
 		return nil, fmt.Errorf("error initializing tuf repo: %s", err)
 	}
 
-	return NewRepository(s3fs, tufStore, tufRepo, logger), nil
+	repository := NewRepository(filesystem, tufStore, tufRepo, logger)
+	repository.ExpirationPeriods = tufRepoOptions.ExpirationPeriods
+	repository.ExpirationThresholds = tufRepoOptions.ExpirationThresholds
+	repository.ConsistentSnapshot = tufRepoOptions.ConsistentSnapshot
+	repository.Delegations = tufRepoOptions.Delegations
+	repository.KeysNumbers = tufRepoOptions.KeysNumbers
+	repository.KeysThresholds = tufRepoOptions.KeysThresholds
+
+	return repository, nil
 }
 
 type S3Repository struct {
-	S3Filesystem *S3Filesystem
-	TufStore     *NonAtomicTufStore
-	TufRepo      *tuf.Repo
+	Filesystem Filesystem
+	TufStore   TufStore
+	TufRepo    *tuf.Repo
+
+	ExpirationPeriods    map[string]time.Duration
+	ExpirationThresholds map[string]time.Duration
+	ConsistentSnapshot   bool
+	Delegations          bool
+	KeysNumbers          map[string]int
+	KeysThresholds       map[string]int
+
+	// SavePrivKeys stores the private keys when the key of the new delegated role is generated.
+	SavePrivKeys func(ctx context.Context, privKeys TufRepoPrivKeys) error
+
+	// delegatedTargets are the loaded delegated roles metadata, see DelegationsDir
+	delegatedTargets      map[string]*DelegatedTargets
+	changedDelegatedRoles map[string]bool
+
+	// removedTargets are the targets removed from the targets metadata, which files are deleted on commit
+	removedTargets map[string]data.Hashes
 
 	logger hclog.Logger
 }
 
-func NewRepository(s3Filesystem *S3Filesystem, tufStore *NonAtomicTufStore, tufRepo *tuf.Repo, logger hclog.Logger) *S3Repository {
+func NewRepository(filesystem Filesystem, tufStore TufStore, tufRepo *tuf.Repo, logger hclog.Logger) *S3Repository {
 	return &S3Repository{
-		S3Filesystem: s3Filesystem,
-		TufStore:     tufStore,
-		TufRepo:      tufRepo,
-		logger:       logger,
+		Filesystem: filesystem,
+		TufStore:   tufStore,
+		TufRepo:    tufRepo,
+		logger:     logger,
 	}
 }
 
 func (repository *S3Repository) SetPrivKeys(privKeys TufRepoPrivKeys) error {
 	repository.logger.Debug("-- S3Repository.SetPrivKeys")
 
-	repository.TufStore.PrivKeys = privKeys
-	repository.logger.Debug(fmt.Sprintf("-- S3Repository.SetPrivKeys BEFORE AddPrivateKeyWithExpires: %#v\n", repository.TufStore.PrivKeys))
+	repository.TufStore.SetPrivKeys(privKeys)
+	repository.logger.Debug(fmt.Sprintf("-- S3Repository.SetPrivKeys BEFORE AddPrivateKeyWithExpires: %#v\n", repository.TufStore.GetPrivKeys()))
 
-	for _, desc := range []struct {
-		role string
-		key  *sign.PrivateKey
-	}{
-		{"root", privKeys.Root},
-		{"targets", privKeys.Targets},
-		{"snapshot", privKeys.Snapshot},
-		{"timestamp", privKeys.Timestamp},
-	} {
-		if err := repository.TufRepo.AddPrivateKeyWithExpires(desc.role, desc.key, data.DefaultExpires("root")); err != nil {
-			return fmt.Errorf("unable to add tuf repository private key for role %s: %s", desc.role, err)
+	for _, role := range tufRoles {
+		for _, key := range *privKeys.roleKeys(role) {
+			if err := repository.TufRepo.AddPrivateKeyWithExpires(role, key, repository.expires("root")); err != nil {
+				return fmt.Errorf("unable to add tuf repository private key for role %s: %s", role, err)
+			}
 		}
 	}
 
-	repository.logger.Debug(fmt.Sprintf("-- S3Repository.SetPrivKeys AFTER AddPrivateKeyWithExpires: %#v\n", repository.TufStore.PrivKeys))
+	repository.logger.Debug(fmt.Sprintf("-- S3Repository.SetPrivKeys AFTER AddPrivateKeyWithExpires: %#v\n", repository.TufStore.GetPrivKeys()))
 
 	return nil
 }
 
 func (repository *S3Repository) GetPrivKeys() TufRepoPrivKeys {
-	return repository.TufStore.PrivKeys
+	return repository.TufStore.GetPrivKeys()
 }
 
 func (repository *S3Repository) GenPrivKeys() error {
-	if _, err := repository.TufRepo.GenKey("root"); err != nil {
-		return fmt.Errorf("error generating tuf repository root key: %s", err)
+	now := time.Now()
+
+	for _, role := range tufRoles {
+		for i := 0; i < repository.keysNumber(role); i++ {
+			if _, err := repository.TufRepo.GenKeyWithExpires(role, repository.expires("root")); err != nil {
+				return fmt.Errorf("error generating tuf repository %s key: %s", role, err)
+			}
+		}
+
+		repository.setPrivKeyExpires(role, now)
+	}
+
+	if err := repository.updateKeysThresholds(); err != nil {
+		return fmt.Errorf("unable to set tuf repository keys thresholds: %s", err)
+	}
+
+	return nil
+}
+
+// RotatePrivKeys replaces the expired private keys with the new ones and commits the re-signed metadata.
+// The roles which keys number or threshold do not match the configured ones are rotated as well.
+// The new root.json is signed by both the previous and the new root keys,
+// so clients are able to verify the chain of root versions.
+// Returns true when the private keys have been changed and should be saved.
+func (repository *S3Repository) RotatePrivKeys(ctx context.Context) (bool, TufRepoPrivKeys, error) {
+	now := time.Now()
+
+	privKeysUpdated := false
+	var rotatedRoles []string
+	for _, role := range repository.GetExpiredPrivKeysRoles() {
+		if _, hasExpires := repository.TufStore.GetPrivKeys().Expires[role]; !hasExpires {
+			// The key has been generated before the rotation was introduced: start its lifetime now.
+			repository.setPrivKeyExpires(role, now)
+			privKeysUpdated = true
+			continue
+		}
+
+		if err := repository.rotatePrivKey(role); err != nil {
+			return false, TufRepoPrivKeys{}, fmt.Errorf("unable to rotate %s key: %s", role, err)
+		}
+
+		repository.setPrivKeyExpires(role, now)
+		rotatedRoles = append(rotatedRoles, role)
+	}
+
+	if len(rotatedRoles) == 0 {
+		return privKeysUpdated, repository.GetPrivKeys(), nil
 	}
 
-	if _, err := repository.TufRepo.GenKey("targets"); err != nil {
-		return fmt.Errorf("error generating tuf repository targets key: %s", err)
+	repository.logger.Info(fmt.Sprintf("Rotated TUF repository private keys of roles %v", rotatedRoles))
+
+	// The thresholds are changed last: the go-tuf repo handle is reloaded by the root.json update.
+	if err := repository.updateKeysThresholds(); err != nil {
+		return false, TufRepoPrivKeys{}, fmt.Errorf("unable to update tuf repository keys thresholds: %s", err)
+	}
+
+	// Re-sign targets.json with the actual targets key.
+	if err := repository.TufRepo.AddTargetsWithExpires([]string{}, nil, repository.expires("targets")); err != nil {
+		return false, TufRepoPrivKeys{}, fmt.Errorf("unable to re-sign tuf repository targets: %s", err)
+	}
+
+	if err := repository.CommitStaged(ctx); err != nil {
+		return false, TufRepoPrivKeys{}, err
+	}
+
+	return true, repository.GetPrivKeys(), nil
+}
+
+// GetExpiredPrivKeysRoles returns the roles which private keys should be rotated or have no rotation date yet,
+// and the roles which keys number or threshold do not match the configured ones.
+// The keys of the repository with the offline root are rotated by the offline signed root.json (see PrepareRoot).
+func (repository *S3Repository) GetExpiredPrivKeysRoles() []string {
+	isRootOffline, err := repository.IsRootOffline()
+	if err != nil {
+		repository.logger.Error(fmt.Sprintf("Unable to check TUF repository root keys: %s", err))
+		return nil
+	}
+
+	if isRootOffline {
+		return nil
+	}
+
+	return repository.expiredPrivKeysRoles()
+}
+
+func (repository *S3Repository) expiredPrivKeysRoles() []string {
+	expiredRoles := make(map[string]bool)
+	for _, role := range repository.TufStore.GetPrivKeys().ExpiredRoles(time.Now()) {
+		expiredRoles[role] = true
+	}
+
+	root, err := repository.getRoot()
+	if err != nil {
+		repository.logger.Error(fmt.Sprintf("Unable to check TUF repository keys thresholds: %s", err))
 	}
 
-	if _, err := repository.TufRepo.GenKey("snapshot"); err != nil {
-		return fmt.Errorf("error generating tuf repository snapshot key: %s", err)
+	var roles []string
+	for _, role := range tufRoles {
+		isKeysNumberChanged := len(repository.TufStore.GetPrivateKeys(role)) != repository.keysNumber(role)
+
+		isKeysThresholdChanged := false
+		if root != nil {
+			if r, hasKey := root.Roles[role]; hasKey {
+				isKeysThresholdChanged = r.Threshold != repository.keysThreshold(role)
+			}
+		}
+
+		if expiredRoles[role] || isKeysNumberChanged || isKeysThresholdChanged {
+			roles = append(roles, role)
+		}
 	}
 
-	if _, err := repository.TufRepo.GenKey("timestamp"); err != nil {
-		return fmt.Errorf("error generating tuf repository timestamp key: %s", err)
+	return roles
+}
+
+// rotatePrivKey replaces all the keys of the role with the configured number of the new keys.
+func (repository *S3Repository) rotatePrivKey(role string) error {
+	oldKeys := append([]*sign.PrivateKey{}, repository.TufStore.GetPrivateKeys(role)...)
+
+	for i := 0; i < repository.keysNumber(role); i++ {
+		newKey, err := sign.GenerateEd25519Key()
+		if err != nil {
+			return fmt.Errorf("error generating key: %s", err)
+		}
+
+		if err := repository.TufRepo.AddPrivateKeyWithExpires(role, newKey, repository.expires("root")); err != nil {
+			return fmt.Errorf("unable to add new key: %s", err)
+		}
+	}
+
+	for _, oldKey := range oldKeys {
+		if role == "root" {
+			repository.TufStore.AddRotatedRootKey(oldKey)
+		}
+
+		repository.TufStore.RemovePrivateKey(role, oldKey)
+
+		if err := repository.TufRepo.RevokeKeyWithExpires(role, oldKey.PublicData().IDs()[0], repository.expires("root")); err != nil {
+			return fmt.Errorf("unable to revoke old key: %s", err)
+		}
 	}
 
 	return nil
 }
 
-func (repository *S3Repository) RotatePrivKeys(ctx context.Context) (bool, TufRepoPrivKeys, error) {
-	// TODO: Check priv keys expiration and generate new keys when necessary.
+func (repository *S3Repository) keysNumber(role string) int {
+	if number, hasKey := repository.KeysNumbers[role]; hasKey && number != 0 {
+		return number
+	}
+	return 1
+}
 
-	return false, TufRepoPrivKeys{}, nil
+func (repository *S3Repository) keysThreshold(role string) int {
+	if threshold, hasKey := repository.KeysThresholds[role]; hasKey && threshold != 0 {
+		return threshold
+	}
+	return 1
+}
+
+// updateKeysThresholds sets the configured keys thresholds of the roles into root.json.
+func (repository *S3Repository) updateKeysThresholds() error {
+	root, err := repository.getRoot()
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	for _, role := range tufRoles {
+		r, hasKey := root.Roles[role]
+		if !hasKey {
+			continue
+		}
+
+		threshold := repository.keysThreshold(role)
+		if threshold > len(r.KeyIDs) {
+			return fmt.Errorf("%s keys threshold %d exceeds the number of the role keys %d", role, threshold, len(r.KeyIDs))
+		}
+
+		if r.Threshold != threshold {
+			changed = true
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+
+	return repository.updateRoot(func(root *data.Root) {
+		for _, role := range tufRoles {
+			if r, hasKey := root.Roles[role]; hasKey {
+				r.Threshold = repository.keysThreshold(role)
+			}
+		}
+		root.Expires = repository.expires("root")
+	})
+}
+
+func (repository *S3Repository) setPrivKeyExpires(role string, now time.Time) {
+	privKeys := repository.TufStore.GetPrivKeys()
+	if privKeys.Expires == nil {
+		privKeys.Expires = make(map[string]time.Time)
+	}
+	privKeys.Expires[role] = now.Add(privKeysLifetime[role]).UTC().Round(time.Second)
+	repository.TufStore.SetPrivKeys(privKeys)
 }
 
 func (repository *S3Repository) Init() error {
-	err := repository.TufRepo.Init(false)
+	err := repository.TufRepo.Init(repository.ConsistentSnapshot)
 
 	if err == tuf.ErrInitNotAllowed {
 		repository.logger.Info("Tuf repository already initialized: skip initialization")
@@ -122,21 +377,122 @@ func (repository *S3Repository) StageTarget(ctx context.Context, pathInsideTarge
 		return fmt.Errorf("unable to add staged file %q: %s", pathInsideTargets, err)
 	}
 
-	if err := repository.TufRepo.AddTarget(pathInsideTargets, json.RawMessage("")); err != nil {
+	if err := repository.TufRepo.AddTargetWithExpires(pathInsideTargets, json.RawMessage(""), repository.expires("targets")); err != nil {
 		return fmt.Errorf("unable to register target file %q in the tuf repo: %s", pathInsideTargets, err)
 	}
 
 	return nil
 }
 
-func (repository *S3Repository) UpdateTimestamps(_ context.Context) error {
-	// TODO: Update only timestamp, or timestamp with snapshot, or timestamp with snapshot with root
-	// TODO: based on expiration dates.
+// RemoveTargets removes the targets from the targets metadata.
+// The target files are deleted from the filesystem by CommitStaged when the new metadata is published.
+func (repository *S3Repository) RemoveTargets(ctx context.Context, pathsInsideTargets []string) error {
+	// An empty list means all targets for the TUF repo
+	if len(pathsInsideTargets) == 0 {
+		return nil
+	}
 
-	if err := repository.TufRepo.Snapshot(tuf.CompressionTypeNone); err != nil {
-		return fmt.Errorf("tuf repo timestamp failed: %s", err)
+	targetsMeta, err := repository.targets(ctx)
+	if err != nil {
+		return err
+	}
+
+	topLevelPaths, err := repository.removeDelegatedTargets(ctx, pathsInsideTargets)
+	if err != nil {
+		return fmt.Errorf("unable to remove targets from the delegated roles: %s", err)
 	}
-	if err := repository.TufRepo.Timestamp(); err != nil {
+
+	if len(topLevelPaths) > 0 {
+		if err := repository.TufRepo.RemoveTargetsWithExpires(topLevelPaths, repository.expires("targets")); err != nil {
+			return fmt.Errorf("unable to remove targets from the tuf repo: %s", err)
+		}
+	}
+
+	if repository.removedTargets == nil {
+		repository.removedTargets = make(map[string]data.Hashes)
+	}
+
+	for _, pathInsideTargets := range pathsInsideTargets {
+		if targetMeta, hasKey := targetsMeta[pathInsideTargets]; hasKey {
+			repository.removedTargets[pathInsideTargets] = targetMeta.Hashes
+		}
+	}
+
+	return nil
+}
+
+// deleteRemovedTargets deletes the files of the removed targets including the hashed copies of the consistent snapshot.
+// The files are deleted only after the commit, so the published metadata never references the deleted files.
+func (repository *S3Repository) deleteRemovedTargets(ctx context.Context) error {
+	targetsMeta, err := repository.targets(ctx)
+	if err != nil {
+		return err
+	}
+
+	var failed []string
+	for pathInsideTargets, hashes := range repository.removedTargets {
+		// The target has been staged again after the removal
+		if _, hasKey := targetsMeta[pathInsideTargets]; hasKey {
+			continue
+		}
+
+		filePath := path.Join("targets", pathInsideTargets)
+		for _, p := range append([]string{filePath}, util.HashedPaths(filePath, hashes)...) {
+			if err := repository.Filesystem.DeleteFile(ctx, p); err != nil {
+				repository.logger.Error(fmt.Sprintf("Unable to delete removed target file %q: %s", p, err))
+				failed = append(failed, p)
+			}
+		}
+	}
+	repository.removedTargets = nil
+
+	if len(failed) > 0 {
+		return fmt.Errorf("unable to delete removed targets files: %s", strings.Join(failed, ", "))
+	}
+
+	return nil
+}
+
+// UpdateTimestamps re-signs the metadata of the roles nearing the expiration date.
+// Updated root.json or targets.json entails the update of snapshot.json, which in turn entails the update of timestamp.json.
+func (repository *S3Repository) UpdateTimestamps(ctx context.Context) error {
+	expiringRoles, err := repository.GetExpiringMetadataRoles(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(expiringRoles) == 0 {
+		repository.logger.Debug("-- S3Repository.UpdateTimestamps: no expiring metadata")
+		return nil
+	}
+
+	update := make(map[string]bool)
+	for _, role := range expiringRoles {
+		update[role] = true
+	}
+	update["snapshot"] = update["snapshot"] || update["root"] || update["targets"]
+	update["timestamp"] = true
+
+	repository.logger.Info(fmt.Sprintf("Updating TUF repository metadata of expiring roles %v", expiringRoles))
+
+	defer repository.reportStorageStatus(ctx)
+
+	if update["root"] {
+		if err := repository.resignRoot(repository.expires("root")); err != nil {
+			return fmt.Errorf("tuf repo root failed: %s", err)
+		}
+	}
+	if update["targets"] {
+		if err := repository.TufRepo.AddTargetsWithExpires([]string{}, nil, repository.expires("targets")); err != nil {
+			return fmt.Errorf("tuf repo targets failed: %s", err)
+		}
+	}
+	if update["snapshot"] {
+		if err := repository.TufRepo.SnapshotWithExpires(tuf.CompressionTypeNone, repository.expires("snapshot")); err != nil {
+			return fmt.Errorf("tuf repo snapshot failed: %s", err)
+		}
+	}
+	if err := repository.TufRepo.TimestampWithExpires(repository.expires("timestamp")); err != nil {
 		return fmt.Errorf("tuf repo timestamp failed: %s", err)
 	}
 	if err := repository.TufRepo.Commit(); err != nil {
@@ -145,23 +501,361 @@ func (repository *S3Repository) UpdateTimestamps(_ context.Context) error {
 	return nil
 }
 
-func (repository *S3Repository) CommitStaged(_ context.Context) error {
-	if err := repository.TufRepo.Snapshot(tuf.CompressionTypeNone); err != nil {
+// GetExpiringMetadataRoles returns the roles which metadata expires within the expiration threshold of the role.
+// The root.json of the repository with the offline root is skipped, it is re-signed offline.
+func (repository *S3Repository) GetExpiringMetadataRoles(_ context.Context) ([]string, error) {
+	meta, err := repository.TufStore.GetMeta()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get tuf repository metadata: %s", err)
+	}
+
+	isRootOffline, err := repository.IsRootOffline()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+
+	var roles []string
+	for _, role := range tufRoles {
+		if role == "root" && isRootOffline {
+			continue
+		}
+
+		name := role + ".json"
+
+		expires, err := metaExpires(meta[name])
+		if err != nil {
+			return nil, fmt.Errorf("unable to get %q expiration date: %s", name, err)
+		}
+
+		if now.Add(repository.expirationThreshold(role)).After(expires) {
+			roles = append(roles, role)
+		}
+	}
+
+	return roles, nil
+}
+
+func (repository *S3Repository) expirationThreshold(role string) time.Duration {
+	if threshold, hasKey := repository.ExpirationThresholds[role]; hasKey && threshold != 0 {
+		return threshold
+	}
+	return DefaultExpirationThresholds[role]
+}
+
+// expires returns the expiration date for the metadata of the role signed now.
+func (repository *S3Repository) expires(role string) time.Time {
+	period := DefaultExpirationPeriods[role]
+	if p, hasKey := repository.ExpirationPeriods[role]; hasKey && p != 0 {
+		period = p
+	}
+	return time.Now().Add(period).UTC().Round(time.Second)
+}
+
+// resignRoot bumps root.json version with the new expiration date.
+func (repository *S3Repository) resignRoot(expires time.Time) error {
+	return repository.updateRoot(func(root *data.Root) {
+		root.Expires = expires.Round(time.Second)
+	})
+}
+
+// updateRoot bumps root.json version with the changes made by the updateFunc.
+// The go-tuf repo changes root.json only along with the keys, so the metadata is signed here
+// and the repo handle is reloaded from the store.
+// The version already bumped by the staged changes is kept, so the published root versions have no gaps.
+func (repository *S3Repository) updateRoot(updateFunc func(root *data.Root)) error {
+	root, err := repository.getRoot()
+	if err != nil {
+		return err
+	}
+	updateFunc(root)
+
+	publishedVersion, err := repository.getPublishedRootVersion()
+	if err != nil {
+		return err
+	}
+	if root.Version <= publishedVersion {
+		root.Version++
+	}
+
+	signers, err := repository.TufStore.GetSigningKeys("root")
+	if err != nil {
+		return fmt.Errorf("unable to get root signing keys: %s", err)
+	}
+
+	newSigned, err := sign.Marshal(root, signers...)
+	if err != nil {
+		return fmt.Errorf("unable to sign root.json: %s", err)
+	}
+
+	rootJSON, err := json.Marshal(newSigned)
+	if err != nil {
+		return fmt.Errorf("unable to marshal root.json: %s", err)
+	}
+
+	if err := repository.TufStore.SetMeta("root.json", rootJSON); err != nil {
+		return err
+	}
+
+	tufRepo, err := tuf.NewRepo(repository.TufStore)
+	if err != nil {
+		return fmt.Errorf("error reloading tuf repo: %s", err)
+	}
+	repository.TufRepo = tufRepo
+
+	return nil
+}
+
+func (repository *S3Repository) getRoot() (*data.Root, error) {
+	meta, err := repository.TufStore.GetMeta()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get tuf repository metadata: %s", err)
+	}
+
+	signed := &data.Signed{}
+	if err := json.Unmarshal(meta["root.json"], signed); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal root.json: %s", err)
+	}
+
+	root := &data.Root{}
+	if err := json.Unmarshal(signed.Signed, root); err != nil {
+		return nil, fmt.Errorf("unable to unmarshal root.json signed data: %s", err)
+	}
+
+	return root, nil
+}
+
+// getPublishedRootVersion returns the version of the committed root.json, zero if root.json is not committed yet.
+func (repository *S3Repository) getPublishedRootVersion() (int, error) {
+	ctx := context.Background()
+
+	exists, err := repository.Filesystem.IsFileExist(ctx, "root.json")
+	if err != nil {
+		return 0, fmt.Errorf("error checking existance of %q: %s", "root.json", err)
+	}
+	if !exists {
+		return 0, nil
+	}
+
+	rootJSON, err := repository.Filesystem.ReadFileBytes(ctx, "root.json")
+	if err != nil {
+		return 0, fmt.Errorf("error reading %q: %s", "root.json", err)
+	}
+
+	signed := &data.Signed{}
+	if err := json.Unmarshal(rootJSON, signed); err != nil {
+		return 0, fmt.Errorf("unable to unmarshal root.json: %s", err)
+	}
+
+	var signedData struct {
+		Version int `json:"version"`
+	}
+	if err := json.Unmarshal(signed.Signed, &signedData); err != nil {
+		return 0, fmt.Errorf("unable to unmarshal root.json signed data: %s", err)
+	}
+
+	return signedData.Version, nil
+}
+
+func (repository *S3Repository) IsConsistentSnapshot() (bool, error) {
+	root, err := repository.getRoot()
+	if err != nil {
+		return false, err
+	}
+
+	return root.ConsistentSnapshot, nil
+}
+
+// MigrateToConsistentSnapshot switches the existing repository to the consistent snapshot layout.
+// The new root.json enables consistent snapshots, and all the existing targets are published
+// under the hash-prefixed paths along with the version-prefixed metadata.
+// The regular paths are kept, so clients that have not updated root.json yet continue to work.
+func (repository *S3Repository) MigrateToConsistentSnapshot(ctx context.Context) error {
+	isConsistentSnapshot, err := repository.IsConsistentSnapshot()
+	if err != nil {
+		return err
+	}
+
+	if isConsistentSnapshot {
+		repository.logger.Debug("-- S3Repository.MigrateToConsistentSnapshot: already migrated")
+		return nil
+	}
+
+	isRootOffline, err := repository.IsRootOffline()
+	if err != nil {
+		return err
+	}
+
+	if isRootOffline {
+		repository.logger.Info("Skipping TUF repository consistent snapshot migration: the root keys are offline, the consistent snapshot is enabled by the offline signed root.json")
+		return nil
+	}
+
+	repository.logger.Info("Migrating TUF repository to the consistent snapshot layout")
+
+	targets, err := repository.targets(ctx)
+	if err != nil {
+		return err
+	}
+
+	for targetPath := range targets {
+		repository.TufStore.RestageTargetFile(targetPath)
+	}
+
+	if err := repository.updateRoot(func(root *data.Root) {
+		root.Expires = repository.expires("root")
+		root.ConsistentSnapshot = true
+	}); err != nil {
+		return fmt.Errorf("tuf repo root failed: %s", err)
+	}
+
+	if err := repository.TufRepo.AddTargetsWithExpires([]string{}, nil, repository.expires("targets")); err != nil {
+		return fmt.Errorf("tuf repo targets failed: %s", err)
+	}
+
+	return repository.CommitStaged(ctx)
+}
+
+func metaExpires(metaJSON json.RawMessage) (time.Time, error) {
+	if metaJSON == nil {
+		return time.Time{}, nil
+	}
+
+	signed := &data.Signed{}
+	if err := json.Unmarshal(metaJSON, signed); err != nil {
+		return time.Time{}, err
+	}
+
+	var signedData struct {
+		Expires time.Time `json:"expires"`
+	}
+	if err := json.Unmarshal(signed.Signed, &signedData); err != nil {
+		return time.Time{}, err
+	}
+
+	return signedData.Expires, nil
+}
+
+func (repository *S3Repository) CommitStaged(ctx context.Context) error {
+	defer repository.reportStorageStatus(ctx)
+
+	if err := repository.stageDelegations(ctx); err != nil {
+		return err
+	}
+
+	if err := repository.TufRepo.SnapshotWithExpires(tuf.CompressionTypeNone, repository.expires("snapshot")); err != nil {
 		return fmt.Errorf("tuf repo snapshot failed: %s", err)
 	}
-	if err := repository.TufRepo.Timestamp(); err != nil {
+	if err := repository.TufRepo.TimestampWithExpires(repository.expires("timestamp")); err != nil {
 		return fmt.Errorf("tuf repo timestamp failed: %s", err)
 	}
 	if err := repository.TufRepo.Commit(); err != nil {
 		return fmt.Errorf("unable to commit staged changes into the repo: %s", err)
 	}
+
+	if len(repository.removedTargets) > 0 {
+		if err := repository.deleteRemovedTargets(ctx); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// GetStaleStagingFiles returns the staging files of the targets which have not been committed.
+// Such files are left by the failed tasks, so the result is reliable only when no other task stages the targets.
+func (repository *S3Repository) GetStaleStagingFiles(ctx context.Context) ([]string, error) {
+	files, err := repository.Filesystem.ListFiles(ctx, AtomicStagingDir+"/")
+	if err != nil {
+		return nil, fmt.Errorf("unable to list staging files: %s", err)
+	}
+
+	return files, nil
+}
+
+// DeleteStaleStagingFiles deletes the staging files of the targets which have not been committed.
+func (repository *S3Repository) DeleteStaleStagingFiles(ctx context.Context) error {
+	files, err := repository.GetStaleStagingFiles(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, filePath := range files {
+		if err := repository.Filesystem.DeleteFile(ctx, filePath); err != nil {
+			return fmt.Errorf("unable to delete staging file: %s", err)
+		}
+	}
+
+	if len(files) > 0 {
+		repository.logger.Info(fmt.Sprintf("Deleted %d stale staging files", len(files)))
+	}
+
+	return nil
+}
+
+// GetTargetFile reads the published target file and verifies it against the targets metadata.
+func (repository *S3Repository) GetTargetFile(ctx context.Context, pathInsideTargets string) ([]byte, error) {
+	targetsMeta, err := repository.targets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	targetMeta, hasKey := targetsMeta[pathInsideTargets]
+	if !hasKey {
+		return nil, tuf.ErrFileNotFound{Path: pathInsideTargets}
+	}
+
+	return repository.readTargetFile(ctx, pathInsideTargets, targetMeta)
+}
+
+// readTargetFile reads the published target file and verifies it against the target metadata.
+func (repository *S3Repository) readTargetFile(ctx context.Context, pathInsideTargets string, targetMeta data.TargetFileMeta) ([]byte, error) {
+	data, err := repository.Filesystem.ReadFileBytes(ctx, path.Join("targets", pathInsideTargets))
+	if err != nil {
+		return nil, fmt.Errorf("unable to read target %q: %s", pathInsideTargets, err)
+	}
+
+	var hashAlgorithms []string
+	for alg := range targetMeta.Hashes {
+		hashAlgorithms = append(hashAlgorithms, alg)
+	}
+
+	actualMeta, err := util.GenerateTargetFileMeta(bytes.NewReader(data), hashAlgorithms...)
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate target %q hashes: %s", pathInsideTargets, err)
+	}
+
+	if err := util.TargetFileMetaEqual(actualMeta, targetMeta); err != nil {
+		return nil, fmt.Errorf("target %q does not match the targets metadata: %s", pathInsideTargets, err)
+	}
+
+	return data, nil
+}
+
+func (repository *S3Repository) GetTargetsMeta(ctx context.Context) (map[string]TargetMeta, error) {
+	targetsMeta, err := repository.targets(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make(map[string]TargetMeta)
+	for path, meta := range targetsMeta {
+		hashes := make(map[string]string)
+		for alg, hash := range meta.Hashes {
+			hashes[alg] = hash.String()
+		}
+
+		res[path] = TargetMeta{Length: meta.Length, Hashes: hashes}
+	}
+
+	return res, nil
+}
+
 func (repository *S3Repository) GetTargets(ctx context.Context) ([]string, error) {
-	targetsMeta, err := repository.TufRepo.Targets()
+	targetsMeta, err := repository.targets(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("unable to get TUF-repo targets metadata: %s", err)
+		return nil, err
 	}
 
 	var res []string
@@ -0,0 +1,374 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+	"github.com/theupdateframework/go-tuf"
+	"github.com/theupdateframework/go-tuf/data"
+	"github.com/theupdateframework/go-tuf/util"
+	"github.com/theupdateframework/go-tuf/verify"
+)
+
+func TestRotatePrivKeys_Root(t *testing.T) {
+	ctx := context.Background()
+	repository, filesystem := newTestRepository(t)
+
+	rootKeyID := repository.GetPrivKeys().Root[0].PublicData().IDs()[0]
+	privKeys := repository.GetPrivKeys()
+	privKeys.Expires["root"] = time.Now().Add(-time.Hour)
+
+	updated, privKeys, err := repository.RotatePrivKeys(ctx)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	assert.True(t, updated)
+	assert.NotEqual(t, rootKeyID, privKeys.Root[0].PublicData().IDs()[0])
+	assert.True(t, time.Now().Before(privKeys.Expires["root"]))
+
+	prevRoot := readTestRoot(t, filesystem, "1.root.json")
+	newRootJSON := filesystem.files["2.root.json"]
+	assert.Equal(t, newRootJSON, filesystem.files["root.json"])
+
+	// The new root.json is trusted by both the previous and the new root keys
+	signed := &data.Signed{}
+	if err := json.Unmarshal(newRootJSON, signed); err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, newTestRootDB(t, prevRoot).VerifySignatures(signed, "root"))
+	assert.Nil(t, newTestRootDB(t, readTestRoot(t, filesystem, "2.root.json")).VerifySignatures(signed, "root"))
+}
+
+func TestRotatePrivKeys_MissingExpires(t *testing.T) {
+	ctx := context.Background()
+	repository, filesystem := newTestRepository(t)
+
+	privKeys := repository.GetPrivKeys()
+	privKeys.Expires = nil
+	repository.TufStore.SetPrivKeys(privKeys)
+	rootJSON := filesystem.files["root.json"]
+
+	// The keys get the rotation dates without the rotation itself
+	updated, updatedPrivKeys, err := repository.RotatePrivKeys(ctx)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	assert.True(t, updated)
+	assert.Equal(t, privKeys.Root, updatedPrivKeys.Root)
+	assert.Equal(t, privKeys.Timestamp, updatedPrivKeys.Timestamp)
+	for _, role := range tufRoles {
+		assert.WithinDuration(t, time.Now().Add(privKeysLifetime[role]), updatedPrivKeys.Expires[role], time.Minute, role)
+	}
+	assert.Equal(t, rootJSON, filesystem.files["root.json"])
+
+	updated, _, err = repository.RotatePrivKeys(ctx)
+	assert.Nil(t, err)
+	assert.False(t, updated)
+}
+
+func TestMigrateToConsistentSnapshot(t *testing.T) {
+	ctx := context.Background()
+	repository, filesystem := newTestRepository(t)
+
+	if !assert.Nil(t, repository.MigrateToConsistentSnapshot(ctx)) {
+		t.FailNow()
+	}
+
+	isConsistentSnapshot, err := repository.IsConsistentSnapshot()
+	assert.Nil(t, err)
+	assert.True(t, isConsistentSnapshot)
+
+	// The already published target is kept and gets the hash-prefixed copies
+	assert.Equal(t, "data", string(filesystem.files["targets/any-any/file"]))
+
+	targets, err := repository.TufRepo.Targets()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hashedPaths := util.HashedPaths("targets/any-any/file", targets["any-any/file"].Hashes)
+	assert.NotEmpty(t, hashedPaths)
+	for _, hashedPath := range hashedPaths {
+		assert.Equal(t, "data", string(filesystem.files[hashedPath]), hashedPath)
+	}
+
+	assert.Contains(t, filesystem.files, "2.root.json")
+	assert.Contains(t, filesystem.files, "2.targets.json")
+}
+
+func TestDeleteStaleStagingFiles(t *testing.T) {
+	ctx := context.Background()
+	filesystem := NewLocalFilesystem(t.TempDir(), hclog.NewNullLogger())
+	repository := newTestRepositoryWithFilesystem(t, filesystem)
+
+	// The target is staged by the task which has failed before the commit
+	if !assert.Nil(t, repository.StageTarget(ctx, "any-any/file", strings.NewReader("new data"))) {
+		t.FailNow()
+	}
+
+	staleFiles, err := repository.GetStaleStagingFiles(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"staging/targets/any-any/file"}, staleFiles)
+
+	if !assert.Nil(t, repository.DeleteStaleStagingFiles(ctx)) {
+		t.FailNow()
+	}
+
+	staleFiles, err = repository.GetStaleStagingFiles(ctx)
+	assert.Nil(t, err)
+	assert.Empty(t, staleFiles)
+
+	data, err := filesystem.ReadFileBytes(ctx, "targets/any-any/file")
+	assert.Nil(t, err)
+	assert.Equal(t, "data", string(data))
+}
+
+func TestDelegations(t *testing.T) {
+	ctx := context.Background()
+	repository, filesystem := newTestRepository(t)
+
+	// The channel published before the delegations are enabled is moved into the delegated role
+	if !assert.Nil(t, repository.StageTarget(ctx, "channels/0/stable", strings.NewReader("1.0.0\n"))) {
+		t.FailNow()
+	}
+	if !assert.Nil(t, repository.CommitStaged(ctx)) {
+		t.FailNow()
+	}
+
+	var savedPrivKeys TufRepoPrivKeys
+	repository.Delegations = true
+	repository.SavePrivKeys = func(_ context.Context, privKeys TufRepoPrivKeys) error {
+		savedPrivKeys = privKeys
+		return nil
+	}
+
+	for _, targetPath := range []string{"releases/1.0.0/any-any/bin/app", "signatures/1.0.0/any-any/bin/app.sig", "releases/2.0.0/any-any/bin/app"} {
+		if !assert.Nil(t, repository.StageTarget(ctx, targetPath, strings.NewReader(targetPath))) {
+			t.FailNow()
+		}
+	}
+	if !assert.Nil(t, repository.CommitStaged(ctx)) {
+		t.FailNow()
+	}
+
+	topLevelTargets, err := repository.TufRepo.Targets()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var topLevelPaths []string
+	for targetPath := range topLevelTargets {
+		topLevelPaths = append(topLevelPaths, targetPath)
+	}
+	assert.ElementsMatch(t, []string{"any-any/file", "delegations/channels.json", "delegations/releases-1.json", "delegations/releases-2.json"}, topLevelPaths)
+	assert.Len(t, savedPrivKeys.Delegations, 3)
+
+	// The delegated metadata is signed by the role key listed in targets.json
+	custom := &delegationCustom{}
+	if err := json.Unmarshal(*topLevelTargets["delegations/releases-1.json"].Custom, custom); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, []string{"releases/1.", "signatures/1."}, custom.Delegation.PathPrefixes)
+
+	signed := &data.Signed{}
+	if err := json.Unmarshal(filesystem.files["targets/delegations/releases-1.json"], signed); err != nil {
+		t.Fatal(err)
+	}
+
+	db := verify.NewDB()
+	role := &data.Role{Threshold: custom.Delegation.Threshold}
+	for id, key := range custom.Delegation.Keys {
+		if err := db.AddKey(id, key); err != nil {
+			t.Fatal(err)
+		}
+		role.KeyIDs = append(role.KeyIDs, id)
+	}
+	if err := db.AddRole("targets", role); err != nil {
+		t.Fatal(err)
+	}
+	assert.Nil(t, db.VerifySignatures(signed, "targets"))
+	assert.Contains(t, custom.Delegation.Keys, savedPrivKeys.Delegations["releases-1"].PublicData().IDs()[0])
+
+	// The new repository handle reads the delegated targets from the storage
+	tufStore := NewAtomicTufStore(savedPrivKeys, filesystem, hclog.NewNullLogger())
+	tufRepo, err := tuf.NewRepo(tufStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repository = NewRepository(filesystem, tufStore, tufRepo, hclog.NewNullLogger())
+
+	targets, err := repository.GetTargets(ctx)
+	assert.Nil(t, err)
+	assert.Subset(t, targets, []string{"channels/0/stable", "releases/1.0.0/any-any/bin/app", "signatures/1.0.0/any-any/bin/app.sig", "releases/2.0.0/any-any/bin/app"})
+
+	data, err := repository.GetTargetFile(ctx, "channels/0/stable")
+	assert.Nil(t, err)
+	assert.Equal(t, "1.0.0\n", string(data))
+
+	// The removed delegated targets are deleted along with their files
+	if !assert.Nil(t, repository.RemoveTargets(ctx, []string{"releases/1.0.0/any-any/bin/app", "signatures/1.0.0/any-any/bin/app.sig"})) {
+		t.FailNow()
+	}
+	if !assert.Nil(t, repository.CommitStaged(ctx)) {
+		t.FailNow()
+	}
+
+	targets, err = repository.GetTargets(ctx)
+	assert.Nil(t, err)
+	assert.NotContains(t, targets, "releases/1.0.0/any-any/bin/app")
+	assert.Contains(t, targets, "releases/2.0.0/any-any/bin/app")
+	assert.NotContains(t, filesystem.files, "targets/releases/1.0.0/any-any/bin/app")
+
+	// The delegated targets get the hash-prefixed copies as well
+	if !assert.Nil(t, repository.MigrateToConsistentSnapshot(ctx)) {
+		t.FailNow()
+	}
+
+	targetsMeta, err := repository.targets(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, hashedPath := range util.HashedPaths("targets/releases/2.0.0/any-any/bin/app", targetsMeta["releases/2.0.0/any-any/bin/app"].Hashes) {
+		assert.Contains(t, filesystem.files, hashedPath)
+	}
+}
+
+func newTestRepository(t *testing.T) (*S3Repository, *memoryFilesystem) {
+	filesystem := &memoryFilesystem{files: make(map[string][]byte)}
+	return newTestRepositoryWithFilesystem(t, filesystem), filesystem
+}
+
+// newTestRepositoryWithFilesystem returns the initialized repository with the committed target "any-any/file".
+func newTestRepositoryWithFilesystem(t *testing.T, filesystem Filesystem) *S3Repository {
+	ctx := context.Background()
+	logger := hclog.NewNullLogger()
+	tufStore := NewAtomicTufStore(TufRepoPrivKeys{}, filesystem, logger)
+	tufRepo, err := tuf.NewRepo(tufStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repository := NewRepository(filesystem, tufStore, tufRepo, logger)
+	if err := repository.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repository.GenPrivKeys(); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repository.StageTarget(ctx, "any-any/file", bytes.NewReader([]byte("data"))); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repository.CommitStaged(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	return repository
+}
+
+func readTestRoot(t *testing.T, filesystem *memoryFilesystem, name string) *data.Root {
+	signed := &data.Signed{}
+	if err := json.Unmarshal(filesystem.files[name], signed); err != nil {
+		t.Fatal(err)
+	}
+
+	root := &data.Root{}
+	if err := json.Unmarshal(signed.Signed, root); err != nil {
+		t.Fatal(err)
+	}
+
+	return root
+}
+
+func newTestRootDB(t *testing.T, root *data.Root) *verify.DB {
+	db := verify.NewDB()
+	for id, key := range root.Keys {
+		if err := db.AddKey(id, key); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := db.AddRole("root", root.Roles["root"]); err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+type memoryFilesystem struct {
+	files map[string][]byte
+}
+
+func (fs *memoryFilesystem) IsFileExist(_ context.Context, path string) (bool, error) {
+	_, exists := fs.files[path]
+	return exists, nil
+}
+
+func (fs *memoryFilesystem) ReadFile(ctx context.Context, path string, writer io.WriterAt) error {
+	data, err := fs.ReadFileBytes(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	_, err = writer.WriteAt(data, 0)
+	return err
+}
+
+func (fs *memoryFilesystem) ReadFileStream(ctx context.Context, path string, writer io.Writer) error {
+	data, err := fs.ReadFileBytes(ctx, path)
+	if err != nil {
+		return err
+	}
+
+	_, err = writer.Write(data)
+	return err
+}
+
+func (fs *memoryFilesystem) ReadFileBytes(_ context.Context, path string) ([]byte, error) {
+	data, exists := fs.files[path]
+	if !exists {
+		return nil, fmt.Errorf("file %q not found", path)
+	}
+
+	return data, nil
+}
+
+func (fs *memoryFilesystem) WriteFileBytes(_ context.Context, path string, data []byte) error {
+	fs.files[path] = data
+	return nil
+}
+
+func (fs *memoryFilesystem) WriteFileStream(ctx context.Context, path string, reader io.Reader) error {
+	buf := bytes.NewBuffer(nil)
+	if _, err := io.Copy(buf, reader); err != nil {
+		return err
+	}
+
+	return fs.WriteFileBytes(ctx, path, buf.Bytes())
+}
+
+func (fs *memoryFilesystem) DeleteFile(_ context.Context, path string) error {
+	delete(fs.files, path)
+	return nil
+}
+
+func (fs *memoryFilesystem) ListFiles(_ context.Context, prefix string) ([]string, error) {
+	var paths []string
+	for path := range fs.files {
+		if strings.HasPrefix(path, prefix) {
+			paths = append(paths, path)
+		}
+	}
+
+	return paths, nil
+}
@@ -182,6 +182,52 @@ func (fs *S3Filesystem) WriteFileStream(ctx context.Context, path string, data i
 	return nil
 }
 
+func (fs *S3Filesystem) DeleteFile(ctx context.Context, path string) error {
+	sess, err := session.NewSession(fs.AwsConfig)
+	if err != nil {
+		return fmt.Errorf("error opening s3 session: %s", err)
+	}
+
+	svc := s3.New(sess)
+
+	if _, err := svc.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: &fs.BucketName,
+		Key:    &path,
+	}); err != nil {
+		return fmt.Errorf("error deleting s3 object by key %q: %s", path, err)
+	}
+
+	fs.logger.Debug(fmt.Sprintf("Deleted %q", path))
+
+	return nil
+}
+
+func (fs *S3Filesystem) ListFiles(ctx context.Context, prefix string) ([]string, error) {
+	sess, err := session.NewSession(fs.AwsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error opening s3 session: %s", err)
+	}
+
+	svc := s3.New(sess)
+
+	var paths []string
+	if err := svc.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: &fs.BucketName,
+		Prefix: &prefix,
+	}, func(page *s3.ListObjectsV2Output, _ bool) bool {
+		for _, object := range page.Contents {
+			paths = append(paths, aws.StringValue(object.Key))
+		}
+		return true
+	}); err != nil {
+		return nil, fmt.Errorf("error listing s3 objects by prefix %q: %s", prefix, err)
+	}
+
+	fs.logger.Debug(fmt.Sprintf("-- S3Filesystem.ListFiles %q -> %d files", prefix, len(paths)))
+
+	return paths, nil
+}
+
 type debugReader struct {
 	origReader io.Reader
 	logger     hclog.Logger
@@ -0,0 +1,323 @@
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/theupdateframework/go-tuf/data"
+	"github.com/theupdateframework/go-tuf/util"
+	"github.com/werf/logboek"
+)
+
+// reportStorageStatus writes the status of each storage mirror into the task log.
+func (repository *S3Repository) reportStorageStatus(ctx context.Context) {
+	mirroredFilesystem, ok := repository.Filesystem.(*MirroredFilesystem)
+	if !ok {
+		return
+	}
+
+	for _, status := range mirroredFilesystem.TakeStatus() {
+		if status.Err != nil {
+			logboek.Context(ctx).Default().LogF("Storage %s: FAILED after %d written files: %s\n", status.Name, status.WrittenFiles, status.Err)
+		} else {
+			logboek.Context(ctx).Default().LogF("Storage %s: %d files written\n", status.Name, status.WrittenFiles)
+		}
+	}
+}
+
+// GetDivergedStorageMirrors returns the storage mirrors which timestamp.json differs from the primary storage one.
+func (repository *S3Repository) GetDivergedStorageMirrors(ctx context.Context) ([]string, error) {
+	mirroredFilesystem, ok := repository.Filesystem.(*MirroredFilesystem)
+	if !ok {
+		return nil, nil
+	}
+
+	exists, err := mirroredFilesystem.Primary.Filesystem.IsFileExist(ctx, "timestamp.json")
+	if err != nil {
+		return nil, fmt.Errorf("error checking existence of timestamp.json in the storage %s: %s", mirroredFilesystem.Primary.Name, err)
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	primaryTimestamp, err := mirroredFilesystem.Primary.Filesystem.ReadFileBytes(ctx, "timestamp.json")
+	if err != nil {
+		return nil, fmt.Errorf("error reading timestamp.json from the storage %s: %s", mirroredFilesystem.Primary.Name, err)
+	}
+
+	var diverged []string
+	for _, mirror := range mirroredFilesystem.Mirrors {
+		exists, err := mirror.Filesystem.IsFileExist(ctx, "timestamp.json")
+		if err != nil {
+			return nil, fmt.Errorf("error checking existence of timestamp.json in the storage mirror %s: %s", mirror.Name, err)
+		}
+
+		if exists {
+			mirrorTimestamp, err := mirror.Filesystem.ReadFileBytes(ctx, "timestamp.json")
+			if err != nil {
+				return nil, fmt.Errorf("error reading timestamp.json from the storage mirror %s: %s", mirror.Name, err)
+			}
+
+			if bytes.Equal(primaryTimestamp, mirrorTimestamp) {
+				continue
+			}
+		}
+
+		diverged = append(diverged, mirror.Name)
+	}
+
+	return diverged, nil
+}
+
+// SyncStorageMirrors copies the published repository from the primary storage into the diverged mirrors.
+// The missing or changed targets and the missing versioned metadata are copied first and timestamp.json is copied last.
+func (repository *S3Repository) SyncStorageMirrors(ctx context.Context) error {
+	diverged, err := repository.GetDivergedStorageMirrors(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(diverged) == 0 {
+		return nil
+	}
+
+	mirroredFilesystem := repository.Filesystem.(*MirroredFilesystem)
+
+	var failed []string
+	for _, mirror := range mirroredFilesystem.Mirrors {
+		isDiverged := false
+		for _, name := range diverged {
+			if name == mirror.Name {
+				isDiverged = true
+			}
+		}
+
+		if !isDiverged {
+			continue
+		}
+
+		logboek.Context(ctx).Default().LogF("Syncing storage mirror %s\n", mirror.Name)
+		repository.logger.Info(fmt.Sprintf("Syncing storage mirror %s", mirror.Name))
+
+		if err := repository.syncStorageMirror(ctx, mirroredFilesystem.Primary.Filesystem, mirror.Filesystem); err != nil {
+			logboek.Context(ctx).Default().LogF("Storage %s: sync FAILED: %s\n", mirror.Name, err)
+			repository.logger.Error(fmt.Sprintf("Storage mirror %s sync failed: %s", mirror.Name, err))
+			failed = append(failed, mirror.Name)
+			continue
+		}
+
+		logboek.Context(ctx).Default().LogF("Storage %s: synced\n", mirror.Name)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("unable to sync storage mirrors: %s", strings.Join(failed, ", "))
+	}
+
+	return nil
+}
+
+func (repository *S3Repository) syncStorageMirror(ctx context.Context, primary, mirror Filesystem) error {
+	meta := make(map[string]json.RawMessage)
+	for _, name := range topLevelManifests {
+		data, err := primary.ReadFileBytes(ctx, name)
+		if err != nil {
+			return fmt.Errorf("error reading %q: %s", name, err)
+		}
+		meta[name] = data
+	}
+
+	root := &data.Root{}
+	if err := unmarshalSignedMeta(meta["root.json"], root); err != nil {
+		return fmt.Errorf("unable to parse root.json: %s", err)
+	}
+
+	targets := &data.Targets{}
+	if err := unmarshalSignedMeta(meta["targets.json"], targets); err != nil {
+		return fmt.Errorf("unable to parse targets.json: %s", err)
+	}
+
+	snapshot := &data.Snapshot{}
+	if err := unmarshalSignedMeta(meta["snapshot.json"], snapshot); err != nil {
+		return fmt.Errorf("unable to parse snapshot.json: %s", err)
+	}
+
+	copyMissingFile := func(filePath string) error {
+		exists, err := mirror.IsFileExist(ctx, filePath)
+		if err != nil {
+			return fmt.Errorf("error checking existence of %q: %s", filePath, err)
+		}
+
+		if exists {
+			return nil
+		}
+
+		if err := CopyFile(ctx, primary, mirror, filePath); err != nil {
+			return fmt.Errorf("error copying %q: %s", filePath, err)
+		}
+
+		return nil
+	}
+
+	mirrorTargets, err := readMirrorTargets(ctx, mirror)
+	if err != nil {
+		return err
+	}
+
+	delegatedTargets, err := readDelegatedTargets(ctx, primary, targets.Targets)
+	if err != nil {
+		return err
+	}
+
+	syncTargets := func(targets data.TargetFiles) error {
+		for name, targetMeta := range targets {
+			// The target at the unhashed path is mutable (e.g. channels), so it is copied again unless the mirror
+			// has published the same target already: the target files are always written before targets.json
+			filePath := path.Join("targets", name)
+			if mirrorTargetMeta, hasKey := mirrorTargets[name]; hasKey && util.TargetFileMetaEqual(mirrorTargetMeta, targetMeta) == nil {
+				if err := copyMissingFile(filePath); err != nil {
+					return err
+				}
+			} else if err := CopyFile(ctx, primary, mirror, filePath); err != nil {
+				return fmt.Errorf("error copying %q: %s", filePath, err)
+			}
+
+			if root.ConsistentSnapshot {
+				for _, hashedPath := range util.HashedPaths(filePath, targetMeta.Hashes) {
+					if err := copyMissingFile(hashedPath); err != nil {
+						return err
+					}
+				}
+			}
+		}
+
+		return nil
+	}
+
+	// The delegated targets go before the delegated roles metadata listed in targets.json
+	if err := syncTargets(delegatedTargets); err != nil {
+		return err
+	}
+
+	if err := syncTargets(targets.Targets); err != nil {
+		return err
+	}
+
+	for version := 1; version <= root.Version; version++ {
+		versionedPath := util.VersionedPath("root.json", version)
+
+		exists, err := primary.IsFileExist(ctx, versionedPath)
+		if err != nil {
+			return fmt.Errorf("error checking existence of %q: %s", versionedPath, err)
+		}
+
+		if exists {
+			if err := copyMissingFile(versionedPath); err != nil {
+				return err
+			}
+		}
+	}
+
+	if root.ConsistentSnapshot {
+		for _, versionedPath := range []string{
+			util.VersionedPath("targets.json", targets.Version),
+			util.VersionedPath("snapshot.json", snapshot.Version),
+		} {
+			if err := CopyFile(ctx, primary, mirror, versionedPath); err != nil {
+				return fmt.Errorf("error copying %q: %s", versionedPath, err)
+			}
+		}
+	}
+
+	for _, name := range atomicCommitOrder {
+		if err := mirror.WriteFileBytes(ctx, name, meta[name]); err != nil {
+			return fmt.Errorf("error writing %q: %s", name, err)
+		}
+	}
+
+	return nil
+}
+
+// readMirrorTargets returns the targets published in the mirror including the delegated ones.
+// The missing or broken metadata means that all the targets listed in it should be copied.
+func readMirrorTargets(ctx context.Context, mirror Filesystem) (data.TargetFiles, error) {
+	exists, err := mirror.IsFileExist(ctx, "targets.json")
+	if err != nil {
+		return nil, fmt.Errorf("error checking existence of %q: %s", "targets.json", err)
+	}
+
+	if !exists {
+		return nil, nil
+	}
+
+	targetsJSON, err := mirror.ReadFileBytes(ctx, "targets.json")
+	if err != nil {
+		return nil, fmt.Errorf("error reading %q: %s", "targets.json", err)
+	}
+
+	targets := &data.Targets{}
+	if err := unmarshalSignedMeta(targetsJSON, targets); err != nil {
+		return nil, nil
+	}
+
+	res := make(data.TargetFiles)
+	for name := range targets.Targets {
+		if !strings.HasPrefix(name, DelegationsDir+"/") {
+			continue
+		}
+
+		delegatedTargets, err := readDelegatedTargets(ctx, mirror, data.TargetFiles{name: targets.Targets[name]})
+		if err != nil {
+			continue
+		}
+
+		for delegatedName, targetMeta := range delegatedTargets {
+			res[delegatedName] = targetMeta
+		}
+	}
+
+	for name, targetMeta := range targets.Targets {
+		res[name] = targetMeta
+	}
+
+	return res, nil
+}
+
+// readDelegatedTargets returns the targets of the delegated roles which metadata is listed in the targets.
+func readDelegatedTargets(ctx context.Context, filesystem Filesystem, targets data.TargetFiles) (data.TargetFiles, error) {
+	res := make(data.TargetFiles)
+	for name := range targets {
+		if !strings.HasPrefix(name, DelegationsDir+"/") {
+			continue
+		}
+
+		filePath := path.Join("targets", name)
+		metaJSON, err := filesystem.ReadFileBytes(ctx, filePath)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %q: %s", filePath, err)
+		}
+
+		delegatedTargets := &DelegatedTargets{}
+		if err := unmarshalSignedMeta(metaJSON, delegatedTargets); err != nil {
+			return nil, fmt.Errorf("unable to parse %q: %s", filePath, err)
+		}
+
+		for delegatedName, targetMeta := range delegatedTargets.Targets {
+			res[delegatedName] = targetMeta
+		}
+	}
+
+	return res, nil
+}
+
+func unmarshalSignedMeta(metaJSON json.RawMessage, v interface{}) error {
+	signed := &data.Signed{}
+	if err := json.Unmarshal(metaJSON, signed); err != nil {
+		return err
+	}
+
+	return json.Unmarshal(signed.Signed, v)
+}
@@ -0,0 +1,101 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyncStorageMirrors_ChangedTarget(t *testing.T) {
+	ctx := context.Background()
+	primary := &memoryFilesystem{files: make(map[string][]byte)}
+	mirror := &recordingFilesystem{memoryFilesystem: &memoryFilesystem{files: make(map[string][]byte)}}
+	filesystem := NewMirroredFilesystem(
+		FilesystemMirror{Name: "primary", Filesystem: primary},
+		[]FilesystemMirror{{Name: "mirror", Filesystem: mirror}},
+		hclog.NewNullLogger(),
+	)
+	repository := newTestRepositoryWithFilesystem(t, filesystem)
+
+	if !assert.Nil(t, repository.StageTarget(ctx, "any-any/channel", strings.NewReader("1.0.0"))) {
+		t.FailNow()
+	}
+	if !assert.Nil(t, repository.CommitStaged(ctx)) {
+		t.FailNow()
+	}
+
+	prevMirrorFiles := make(map[string][]byte)
+	for filePath, data := range mirror.files {
+		prevMirrorFiles[filePath] = data
+	}
+
+	if !assert.Nil(t, repository.StageTarget(ctx, "any-any/channel", strings.NewReader("1.0.1"))) {
+		t.FailNow()
+	}
+	if !assert.Nil(t, repository.CommitStaged(ctx)) {
+		t.FailNow()
+	}
+
+	// The mirror has missed the last commit
+	mirror.files = prevMirrorFiles
+
+	diverged, err := repository.GetDivergedStorageMirrors(ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"mirror"}, diverged)
+
+	mirror.writes = nil
+	if !assert.Nil(t, repository.SyncStorageMirrors(ctx)) {
+		t.FailNow()
+	}
+
+	assert.Equal(t, "1.0.1", string(mirror.files["targets/any-any/channel"]))
+	assert.Equal(t, primary.files["timestamp.json"], mirror.files["timestamp.json"])
+
+	// The target which has not been changed is not copied again
+	assert.Contains(t, mirror.writes, "targets/any-any/channel")
+	assert.NotContains(t, mirror.writes, "targets/any-any/file")
+
+	diverged, err = repository.GetDivergedStorageMirrors(ctx)
+	assert.Nil(t, err)
+	assert.Empty(t, diverged)
+}
+
+func TestMirroredFilesystem_FailedMirror(t *testing.T) {
+	ctx := context.Background()
+	primary := &memoryFilesystem{files: make(map[string][]byte)}
+	failedMirror := &failingFilesystem{memoryFilesystem: &memoryFilesystem{files: make(map[string][]byte)}}
+	mirror := &memoryFilesystem{files: make(map[string][]byte)}
+	filesystem := NewMirroredFilesystem(
+		FilesystemMirror{Name: "primary", Filesystem: primary},
+		[]FilesystemMirror{{Name: "failed", Filesystem: failedMirror}, {Name: "mirror", Filesystem: mirror}},
+		hclog.NewNullLogger(),
+	)
+
+	err := filesystem.WriteFileBytes(ctx, "timestamp.json", []byte("{}"))
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "storage mirror failed")
+	}
+
+	// The mirrors following the failed one are written anyway
+	assert.Equal(t, "{}", string(primary.files["timestamp.json"]))
+	assert.Equal(t, "{}", string(mirror.files["timestamp.json"]))
+
+	status := filesystem.TakeStatus()
+	assert.Nil(t, status[0].Err)
+	assert.Error(t, status[1].Err)
+	assert.Nil(t, status[2].Err)
+	assert.Equal(t, 1, status[2].WrittenFiles)
+}
+
+// failingFilesystem fails all the writes.
+type failingFilesystem struct {
+	*memoryFilesystem
+}
+
+func (fs *failingFilesystem) WriteFileBytes(_ context.Context, _ string, _ []byte) error {
+	return errors.New("write failed")
+}
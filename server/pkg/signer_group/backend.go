@@ -0,0 +1,202 @@
+package signer_group
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+
+	"github.com/werf/trdl/server/pkg/gitsign"
+	"github.com/werf/trdl/server/pkg/pgp"
+	"github.com/werf/trdl/server/pkg/sshsig"
+	"github.com/werf/trdl/server/pkg/util"
+)
+
+const (
+	fieldNameSignerGroupName                               = "name"
+	fieldNameSignerGroupTrustedPGPPublicKeys               = "trusted_pgp_public_keys"
+	fieldNameSignerGroupTrustedSSHPublicKeys               = "trusted_ssh_public_keys"
+	fieldNameSignerGroupTrustedGitsignIdentities           = "trusted_gitsign_identities"
+	fieldNameSignerGroupRequiredNumberOfVerifiedSignatures = "required_number_of_verified_signatures"
+)
+
+func Paths() []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern:         "configure/signer_group/?",
+			HelpSynopsis:    "Configure signer groups",
+			HelpDescription: "Configure named groups of trusted keys and the number of verified signatures required from each group, e.g. 2 signatures from release-managers and 1 from security. Every configured group must be satisfied in addition to the required_number_of_verified_signatures_on_commit",
+			Fields: map[string]*framework.FieldSchema{
+				fieldNameSignerGroupName: {
+					Type:        framework.TypeNameString,
+					Description: "Group name",
+					Required:    true,
+				},
+				fieldNameSignerGroupTrustedPGPPublicKeys: {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "Names of the trusted PGP public keys (configure/trusted_pgp_public_key) belonging to the group",
+					Required:    false,
+				},
+				fieldNameSignerGroupTrustedSSHPublicKeys: {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "Names of the trusted SSH public keys (configure/trusted_ssh_public_key) belonging to the group",
+					Required:    false,
+				},
+				fieldNameSignerGroupTrustedGitsignIdentities: {
+					Type:        framework.TypeCommaStringSlice,
+					Description: "Names of the trusted gitsign identities (configure/gitsign/trusted_identity) belonging to the group",
+					Required:    false,
+				},
+				fieldNameSignerGroupRequiredNumberOfVerifiedSignatures: {
+					Type:        framework.TypeInt,
+					Description: "The required number of verified signatures made with the group keys",
+					Required:    true,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.CreateOperation: &framework.PathOperation{
+					Description: "Add or replace the signer group",
+					Callback:    pathConfigureSignerGroupCreateOrUpdate,
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Description: "Add or replace the signer group",
+					Callback:    pathConfigureSignerGroupCreateOrUpdate,
+				},
+				logical.ReadOperation: &framework.PathOperation{
+					Description: "Get the list of signer groups",
+					Callback:    pathConfigureSignerGroupReadOrList,
+				},
+				logical.ListOperation: &framework.PathOperation{
+					Description: "Get the list of signer groups",
+					Callback:    pathConfigureSignerGroupReadOrList,
+				},
+			},
+		},
+		{
+			Pattern:         "configure/signer_group/" + framework.GenericNameRegex(fieldNameSignerGroupName) + "$",
+			HelpSynopsis:    "Read or delete the configured signer group",
+			HelpDescription: "Read or delete the configured signer group",
+			Fields: map[string]*framework.FieldSchema{
+				fieldNameSignerGroupName: {
+					Type:        framework.TypeNameString,
+					Description: "Group name",
+					Required:    true,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Description: "Get the signer group",
+					Callback:    pathConfigureSignerGroupRead,
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Description: "Delete the signer group",
+					Callback:    pathConfigureSignerGroupDelete,
+				},
+			},
+		},
+	}
+}
+
+func pathConfigureSignerGroupCreateOrUpdate(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
+	if errResp := util.CheckRequiredFields(req, fields); errResp != nil {
+		return errResp, nil
+	}
+
+	group := &signerGroup{
+		Name:                               fields.Get(fieldNameSignerGroupName).(string),
+		TrustedPGPPublicKeys:               fields.Get(fieldNameSignerGroupTrustedPGPPublicKeys).([]string),
+		TrustedSSHPublicKeys:               fields.Get(fieldNameSignerGroupTrustedSSHPublicKeys).([]string),
+		TrustedGitsignIdentities:           fields.Get(fieldNameSignerGroupTrustedGitsignIdentities).([]string),
+		RequiredNumberOfVerifiedSignatures: fields.Get(fieldNameSignerGroupRequiredNumberOfVerifiedSignatures).(int),
+	}
+
+	numberOfKeys := len(group.TrustedPGPPublicKeys) + len(group.TrustedSSHPublicKeys) + len(group.TrustedGitsignIdentities)
+	if numberOfKeys == 0 {
+		return logical.ErrorResponse("At least one of the fields %q, %q or %q must be set", fieldNameSignerGroupTrustedPGPPublicKeys, fieldNameSignerGroupTrustedSSHPublicKeys, fieldNameSignerGroupTrustedGitsignIdentities), nil
+	}
+
+	if group.RequiredNumberOfVerifiedSignatures < 1 || group.RequiredNumberOfVerifiedSignatures > numberOfKeys {
+		return logical.ErrorResponse("Invalid field %q: expected a number from 1 to the number of the group keys (%d)", fieldNameSignerGroupRequiredNumberOfVerifiedSignatures, numberOfKeys), nil
+	}
+
+	for _, keyName := range group.TrustedPGPPublicKeys {
+		key, err := pgp.GetTrustedPGPPublicKey(ctx, req.Storage, keyName)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get trusted PGP public key %q: %s", keyName, err)
+		}
+
+		if key == "" {
+			return logical.ErrorResponse("PGP public key %q not found in storage", keyName), nil
+		}
+	}
+
+	for _, keyName := range group.TrustedSSHPublicKeys {
+		key, err := sshsig.GetTrustedSSHPublicKey(ctx, req.Storage, keyName)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get trusted SSH public key %q: %s", keyName, err)
+		}
+
+		if key == "" {
+			return logical.ErrorResponse("SSH public key %q not found in storage", keyName), nil
+		}
+	}
+
+	for _, identityName := range group.TrustedGitsignIdentities {
+		identity, err := gitsign.GetTrustedIdentity(ctx, req.Storage, identityName)
+		if err != nil {
+			return nil, fmt.Errorf("unable to get trusted gitsign identity %q: %s", identityName, err)
+		}
+
+		if identity == nil {
+			return logical.ErrorResponse("gitsign identity %q not found in storage", identityName), nil
+		}
+	}
+
+	if err := putSignerGroup(ctx, req.Storage, group); err != nil {
+		return nil, fmt.Errorf("unable to put signer group: %s", err)
+	}
+
+	return nil, nil
+}
+
+func pathConfigureSignerGroupReadOrList(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	list, err := req.Storage.List(ctx, storageKeyPrefixSignerGroup)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list %q in storage: %s", storageKeyPrefixSignerGroup, err)
+	}
+
+	return logical.ListResponse(list), nil
+}
+
+func pathConfigureSignerGroupRead(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
+	name := fields.Get(fieldNameSignerGroupName).(string)
+
+	group, err := getSignerGroup(ctx, req.Storage, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if group == nil {
+		return logical.ErrorResponse("Signer group %q not found in storage", name), nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			fieldNameSignerGroupName:                               group.Name,
+			fieldNameSignerGroupTrustedPGPPublicKeys:               group.TrustedPGPPublicKeys,
+			fieldNameSignerGroupTrustedSSHPublicKeys:               group.TrustedSSHPublicKeys,
+			fieldNameSignerGroupTrustedGitsignIdentities:           group.TrustedGitsignIdentities,
+			fieldNameSignerGroupRequiredNumberOfVerifiedSignatures: group.RequiredNumberOfVerifiedSignatures,
+		},
+	}, nil
+}
+
+func pathConfigureSignerGroupDelete(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
+	name := fields.Get(fieldNameSignerGroupName).(string)
+	if err := req.Storage.Delete(ctx, signerGroupStorageKey(name)); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
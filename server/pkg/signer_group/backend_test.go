@@ -0,0 +1,256 @@
+package signer_group
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+
+	trdlGit "github.com/werf/trdl/server/pkg/git"
+	"github.com/werf/trdl/server/pkg/gitsign"
+	gitsignTestutil "github.com/werf/trdl/server/pkg/gitsign/testutil"
+	"github.com/werf/trdl/server/pkg/sshsig"
+)
+
+const (
+	testSSHPublicKey1 = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIE4eBzfw2mwJ6avYKFu5siiWByFBj3ZaifI9AWwRcj+o my_key_1"
+	testSSHPublicKey2 = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAINtMgPwh89rZHcoZFGje/yRe+HaWYygGny1tu+oF8mlU my_key_2"
+)
+
+type pathConfigureSignerGroupCallbacksSuite struct {
+	suite.Suite
+	ctx     context.Context
+	backend logical.Backend
+	req     *logical.Request
+	storage logical.Storage
+}
+
+func (suite *pathConfigureSignerGroupCallbacksSuite) SetupTest() {
+	ctx := context.Background()
+	b := &framework.Backend{}
+	b.Paths = append(append(sshsig.Paths(), gitsign.Paths()...), Paths()...)
+	storage := &logical.InmemStorage{}
+	config := logical.TestBackendConfig()
+	config.StorageView = storage
+	err := b.Setup(ctx, config)
+	assert.Nil(suite.T(), err)
+
+	suite.ctx = ctx
+	suite.backend = b
+	suite.req = &logical.Request{Storage: storage}
+	suite.storage = storage
+
+	for name, key := range map[string]string{"my_key_1": testSSHPublicKey1, "my_key_2": testSSHPublicKey2} {
+		resp, err := suite.backend.HandleRequest(suite.ctx, &logical.Request{
+			Storage:   storage,
+			Path:      "configure/trusted_ssh_public_key",
+			Operation: logical.CreateOperation,
+			Data:      map[string]interface{}{"name": name, "public_key": key},
+		})
+		assert.Nil(suite.T(), err)
+		assert.Nil(suite.T(), resp)
+	}
+}
+
+func (suite *pathConfigureSignerGroupCallbacksSuite) TestCreateOrUpdate() {
+	suite.req.Path = "configure/signer_group"
+	suite.req.Operation = logical.CreateOperation
+	suite.req.Data = dataSignerGroup()
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), resp)
+
+	signerGroups, err := GetSignerGroups(suite.ctx, suite.storage)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), []*trdlGit.SignerGroup{
+		{
+			Name:                               "release-managers",
+			TrustedSSHPublicKeys:               []string{testSSHPublicKey1, testSSHPublicKey2},
+			RequiredNumberOfVerifiedSignatures: 2,
+		},
+	}, signerGroups)
+
+	// The deleted keys are no longer taken into account
+	err = suite.storage.Delete(suite.ctx, "trusted_ssh_public_key/my_key_2")
+	assert.Nil(suite.T(), err)
+
+	signerGroups, err = GetSignerGroups(suite.ctx, suite.storage)
+	assert.Nil(suite.T(), err)
+	if assert.Len(suite.T(), signerGroups, 1) {
+		assert.Equal(suite.T(), []string{testSSHPublicKey1}, signerGroups[0].TrustedSSHPublicKeys)
+	}
+}
+
+func (suite *pathConfigureSignerGroupCallbacksSuite) TestCreateOrUpdate_GitsignIdentities() {
+	sigstore := gitsignTestutil.NewSigstore(suite.T())
+	identity := gitsign.Identity{Subject: "alice@example.com", Issuer: "https://accounts.example.com"}
+
+	for _, req := range []*logical.Request{
+		{
+			Path: "configure/gitsign/trusted_identity",
+			Data: map[string]interface{}{"name": "alice", "subject": identity.Subject, "issuer": identity.Issuer},
+		},
+		{
+			Path: "configure/signer_group",
+			Data: map[string]interface{}{
+				fieldNameSignerGroupName:                               "security",
+				fieldNameSignerGroupTrustedGitsignIdentities:           "alice",
+				fieldNameSignerGroupRequiredNumberOfVerifiedSignatures: 1,
+			},
+		},
+	} {
+		req.Storage = suite.storage
+		req.Operation = logical.CreateOperation
+
+		resp, err := suite.backend.HandleRequest(suite.ctx, req)
+		assert.Nil(suite.T(), err)
+		assert.Nil(suite.T(), resp)
+	}
+
+	// The identities are not taken into account while gitsign is not configured
+	signerGroups, err := GetSignerGroups(suite.ctx, suite.storage)
+	assert.Nil(suite.T(), err)
+	if assert.Len(suite.T(), signerGroups, 1) {
+		assert.Nil(suite.T(), signerGroups[0].GitsignPolicy)
+	}
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, &logical.Request{
+		Storage:   suite.storage,
+		Path:      "configure/gitsign",
+		Operation: logical.CreateOperation,
+		Data:      map[string]interface{}{"fulcio_root": sigstore.FulcioRoot, "rekor_public_key": sigstore.RekorPublicKey},
+	})
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), resp)
+
+	signerGroups, err = GetSignerGroups(suite.ctx, suite.storage)
+	assert.Nil(suite.T(), err)
+	if assert.Len(suite.T(), signerGroups, 1) {
+		assert.Equal(suite.T(), &gitsign.TrustPolicy{
+			FulcioRoots:    sigstore.FulcioRoot,
+			RekorPublicKey: sigstore.RekorPublicKey,
+			Identities:     []gitsign.Identity{identity},
+		}, signerGroups[0].GitsignPolicy)
+	}
+}
+
+func (suite *pathConfigureSignerGroupCallbacksSuite) TestCreateOrUpdate_RequiredFields() {
+	suite.req.Path = "configure/signer_group"
+	suite.req.Operation = logical.CreateOperation
+
+	data := dataSignerGroup()
+	delete(data, fieldNameSignerGroupRequiredNumberOfVerifiedSignatures)
+	suite.req.Data = data
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), logical.ErrorResponse("Required field %q must be set", fieldNameSignerGroupRequiredNumberOfVerifiedSignatures), resp)
+}
+
+func (suite *pathConfigureSignerGroupCallbacksSuite) TestCreateOrUpdate_InvalidFields() {
+	suite.req.Path = "configure/signer_group"
+	suite.req.Operation = logical.CreateOperation
+
+	for _, tc := range []struct {
+		name         string
+		data         map[string]interface{}
+		expectedResp *logical.Response
+	}{
+		{
+			name: "no keys",
+			data: map[string]interface{}{
+				fieldNameSignerGroupName:                               "release-managers",
+				fieldNameSignerGroupRequiredNumberOfVerifiedSignatures: 1,
+			},
+			expectedResp: logical.ErrorResponse("At least one of the fields %q, %q or %q must be set", fieldNameSignerGroupTrustedPGPPublicKeys, fieldNameSignerGroupTrustedSSHPublicKeys, fieldNameSignerGroupTrustedGitsignIdentities),
+		},
+		{
+			name: "required number exceeds the number of keys",
+			data: map[string]interface{}{
+				fieldNameSignerGroupName:                               "release-managers",
+				fieldNameSignerGroupTrustedSSHPublicKeys:               "my_key_1",
+				fieldNameSignerGroupRequiredNumberOfVerifiedSignatures: 2,
+			},
+			expectedResp: logical.ErrorResponse("Invalid field %q: expected a number from 1 to the number of the group keys (%d)", fieldNameSignerGroupRequiredNumberOfVerifiedSignatures, 1),
+		},
+		{
+			name: "unknown key",
+			data: map[string]interface{}{
+				fieldNameSignerGroupName:                               "release-managers",
+				fieldNameSignerGroupTrustedPGPPublicKeys:               "my_key_1",
+				fieldNameSignerGroupRequiredNumberOfVerifiedSignatures: 1,
+			},
+			expectedResp: logical.ErrorResponse("PGP public key %q not found in storage", "my_key_1"),
+		},
+	} {
+		suite.Run(tc.name, func() {
+			suite.req.Data = tc.data
+
+			resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+			assert.Nil(suite.T(), err)
+			assert.Equal(suite.T(), tc.expectedResp, resp)
+		})
+	}
+}
+
+func (suite *pathConfigureSignerGroupCallbacksSuite) TestReadListDelete() {
+	suite.req.Path = "configure/signer_group"
+	suite.req.Operation = logical.CreateOperation
+	suite.req.Data = dataSignerGroup()
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), resp)
+
+	suite.req.Path = "configure/signer_group/release-managers"
+	suite.req.Data = nil
+	suite.req.Operation = logical.ReadOperation
+
+	resp, err = suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	if assert.NotNil(suite.T(), resp) {
+		assert.Equal(suite.T(), map[string]interface{}{
+			fieldNameSignerGroupName:                               "release-managers",
+			fieldNameSignerGroupTrustedPGPPublicKeys:               []string{},
+			fieldNameSignerGroupTrustedSSHPublicKeys:               []string{"my_key_1", "my_key_2"},
+			fieldNameSignerGroupTrustedGitsignIdentities:           []string{},
+			fieldNameSignerGroupRequiredNumberOfVerifiedSignatures: 2,
+		}, resp.Data)
+	}
+
+	suite.req.Path = "configure/signer_group"
+	suite.req.Operation = logical.ListOperation
+
+	resp, err = suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), logical.ListResponse([]string{"release-managers"}), resp)
+
+	suite.req.Path = "configure/signer_group/release-managers"
+	suite.req.Operation = logical.DeleteOperation
+
+	resp, err = suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), resp)
+
+	suite.req.Operation = logical.ReadOperation
+
+	resp, err = suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), logical.ErrorResponse("Signer group %q not found in storage", "release-managers"), resp)
+}
+
+func TestBackendPathConfigureSignerGroupCallbacks(t *testing.T) {
+	suite.Run(t, new(pathConfigureSignerGroupCallbacksSuite))
+}
+
+func dataSignerGroup() map[string]interface{} {
+	return map[string]interface{}{
+		fieldNameSignerGroupName:                               "release-managers",
+		fieldNameSignerGroupTrustedSSHPublicKeys:               "my_key_1,my_key_2",
+		fieldNameSignerGroupRequiredNumberOfVerifiedSignatures: 2,
+	}
+}
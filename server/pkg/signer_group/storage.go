@@ -0,0 +1,132 @@
+package signer_group
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/sdk/logical"
+
+	trdlGit "github.com/werf/trdl/server/pkg/git"
+	"github.com/werf/trdl/server/pkg/gitsign"
+	"github.com/werf/trdl/server/pkg/pgp"
+	"github.com/werf/trdl/server/pkg/sshsig"
+)
+
+const (
+	storageKeyPrefixSignerGroup = "signer_group/"
+)
+
+type signerGroup struct {
+	Name                               string   `json:"name"`
+	TrustedPGPPublicKeys               []string `json:"trusted_pgp_public_keys"`
+	TrustedSSHPublicKeys               []string `json:"trusted_ssh_public_keys"`
+	TrustedGitsignIdentities           []string `json:"trusted_gitsign_identities"`
+	RequiredNumberOfVerifiedSignatures int      `json:"required_number_of_verified_signatures"`
+}
+
+// GetSignerGroups returns the configured signer groups with the names of the trusted keys and identities resolved to the key and identity data.
+// The keys and identities which have been deleted since the group was configured are skipped,
+// the gitsign identities are not taken into account while gitsign is not configured.
+func GetSignerGroups(ctx context.Context, storage logical.Storage) ([]*trdlGit.SignerGroup, error) {
+	list, err := storage.List(ctx, storageKeyPrefixSignerGroup)
+	if err != nil {
+		return nil, err
+	}
+
+	gitsignPolicy, err := gitsign.GetTrustPolicy(ctx, storage)
+	if err != nil {
+		return nil, fmt.Errorf("unable to get gitsign trust policy: %s", err)
+	}
+
+	var signerGroups []*trdlGit.SignerGroup
+	for _, name := range list {
+		group, err := getSignerGroup(ctx, storage, name)
+		if err != nil {
+			return nil, err
+		}
+
+		if group == nil {
+			continue
+		}
+
+		resolvedGroup := &trdlGit.SignerGroup{
+			Name:                               group.Name,
+			RequiredNumberOfVerifiedSignatures: group.RequiredNumberOfVerifiedSignatures,
+		}
+
+		for _, keyName := range group.TrustedPGPPublicKeys {
+			key, err := pgp.GetTrustedPGPPublicKey(ctx, storage, keyName)
+			if err != nil {
+				return nil, fmt.Errorf("unable to get trusted PGP public key %q: %s", keyName, err)
+			}
+
+			if key != "" {
+				resolvedGroup.TrustedPGPPublicKeys = append(resolvedGroup.TrustedPGPPublicKeys, key)
+			}
+		}
+
+		for _, keyName := range group.TrustedSSHPublicKeys {
+			key, err := sshsig.GetTrustedSSHPublicKey(ctx, storage, keyName)
+			if err != nil {
+				return nil, fmt.Errorf("unable to get trusted SSH public key %q: %s", keyName, err)
+			}
+
+			if key != "" {
+				resolvedGroup.TrustedSSHPublicKeys = append(resolvedGroup.TrustedSSHPublicKeys, key)
+			}
+		}
+
+		if gitsignPolicy != nil && len(group.TrustedGitsignIdentities) != 0 {
+			groupGitsignPolicy := *gitsignPolicy
+			groupGitsignPolicy.Identities = nil
+
+			for _, identityName := range group.TrustedGitsignIdentities {
+				identity, err := gitsign.GetTrustedIdentity(ctx, storage, identityName)
+				if err != nil {
+					return nil, fmt.Errorf("unable to get trusted gitsign identity %q: %s", identityName, err)
+				}
+
+				if identity != nil {
+					groupGitsignPolicy.Identities = append(groupGitsignPolicy.Identities, *identity)
+				}
+			}
+
+			resolvedGroup.GitsignPolicy = &groupGitsignPolicy
+		}
+
+		signerGroups = append(signerGroups, resolvedGroup)
+	}
+
+	return signerGroups, nil
+}
+
+func getSignerGroup(ctx context.Context, storage logical.Storage, name string) (*signerGroup, error) {
+	e, err := storage.Get(ctx, signerGroupStorageKey(name))
+	if err != nil {
+		return nil, err
+	}
+
+	if e == nil {
+		return nil, nil
+	}
+
+	group := &signerGroup{}
+	if err := e.DecodeJSON(group); err != nil {
+		return nil, fmt.Errorf("unable to decode signer group %q: %s", name, err)
+	}
+
+	return group, nil
+}
+
+func putSignerGroup(ctx context.Context, storage logical.Storage, group *signerGroup) error {
+	e, err := logical.StorageEntryJSON(signerGroupStorageKey(group.Name), group)
+	if err != nil {
+		return err
+	}
+
+	return storage.Put(ctx, e)
+}
+
+func signerGroupStorageKey(name string) string {
+	return storageKeyPrefixSignerGroup + name
+}
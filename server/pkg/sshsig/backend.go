@@ -0,0 +1,143 @@
+package sshsig
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/werf/trdl/server/pkg/util"
+)
+
+const (
+	fieldNameTrustedSSHPublicKeyName = "name"
+	fieldNameTrustedSSHPublicKeyData = "public_key"
+)
+
+func Paths() []*framework.Path {
+	return []*framework.Path{
+		{
+			Pattern:         "configure/trusted_ssh_public_key/?",
+			HelpSynopsis:    "Configure trusted SSH public keys",
+			HelpDescription: "Configure trusted SSH public keys to check git repository commit and tag signatures made with SSH keys (gpg.format=ssh)",
+			Fields: map[string]*framework.FieldSchema{
+				fieldNameTrustedSSHPublicKeyName: {
+					Type:        framework.TypeNameString,
+					Description: "Key name",
+					Required:    true,
+				},
+				fieldNameTrustedSSHPublicKeyData: {
+					Type:        framework.TypeString,
+					Description: "Key data in the authorized_keys format",
+					Required:    true,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.CreateOperation: &framework.PathOperation{
+					Description: "Add a trusted SSH public key",
+					Callback:    pathConfigureTrustedSSHPublicKeyCreateOrUpdate,
+				},
+				logical.UpdateOperation: &framework.PathOperation{
+					Description: "Add a trusted SSH public key",
+					Callback:    pathConfigureTrustedSSHPublicKeyCreateOrUpdate,
+				},
+				logical.ReadOperation: &framework.PathOperation{
+					Description: "Get the list of trusted SSH public keys",
+					Callback:    pathConfigureTrustedSSHPublicKeyReadOrList,
+				},
+				logical.ListOperation: &framework.PathOperation{
+					Description: "Get the list of trusted SSH public keys",
+					Callback:    pathConfigureTrustedSSHPublicKeyReadOrList,
+				},
+			},
+		},
+		{
+			Pattern:         "configure/trusted_ssh_public_key/" + framework.GenericNameRegex(fieldNameTrustedSSHPublicKeyName) + "$",
+			HelpSynopsis:    "Read or delete the configured trusted SSH public key",
+			HelpDescription: "Read or delete the configured trusted SSH public key",
+			Fields: map[string]*framework.FieldSchema{
+				fieldNameTrustedSSHPublicKeyName: {
+					Type:        framework.TypeNameString,
+					Description: "Key name",
+					Required:    true,
+				},
+			},
+			Operations: map[logical.Operation]framework.OperationHandler{
+				logical.ReadOperation: &framework.PathOperation{
+					Description: "Get the trusted SSH public key",
+					Callback:    pathConfigureTrustedSSHPublicKeyRead,
+				},
+				logical.ListOperation: &framework.PathOperation{
+					Description: "Get the trusted SSH public key",
+					Callback:    pathConfigureTrustedSSHPublicKeyRead,
+				},
+				logical.DeleteOperation: &framework.PathOperation{
+					Description: "Delete the trusted SSH public key",
+					Callback:    pathConfigureTrustedSSHPublicKeyDelete,
+				},
+			},
+		},
+	}
+}
+
+func pathConfigureTrustedSSHPublicKeyCreateOrUpdate(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
+	if errResp := util.CheckRequiredFields(req, fields); errResp != nil {
+		return errResp, nil
+	}
+
+	name := fields.Get(fieldNameTrustedSSHPublicKeyName).(string)
+	key := fields.Get(fieldNameTrustedSSHPublicKeyData).(string)
+
+	if _, _, _, _, err := ssh.ParseAuthorizedKey([]byte(key)); err != nil {
+		return logical.ErrorResponse("Invalid SSH public key: %s", err), nil
+	}
+
+	if err := req.Storage.Put(ctx, &logical.StorageEntry{
+		Key:   trustedSSHPublicKeyStorageKey(name),
+		Value: []byte(key),
+	}); err != nil {
+		return nil, fmt.Errorf("unable to put trusted ssh public key: %s", err)
+	}
+
+	return nil, nil
+}
+
+func pathConfigureTrustedSSHPublicKeyReadOrList(ctx context.Context, req *logical.Request, _ *framework.FieldData) (*logical.Response, error) {
+	list, err := req.Storage.List(ctx, storageKeyPrefixTrustedSSHPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list %q in storage: %s", storageKeyPrefixTrustedSSHPublicKey, err)
+	}
+
+	return logical.ListResponse(list), nil
+}
+
+func pathConfigureTrustedSSHPublicKeyRead(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
+	name := fields.Get(fieldNameTrustedSSHPublicKeyName).(string)
+
+	e, err := req.Storage.Get(ctx, trustedSSHPublicKeyStorageKey(name))
+	if err != nil {
+		return nil, err
+	}
+
+	if e == nil {
+		return logical.ErrorResponse("SSH public key %q not found in storage", name), nil
+	}
+
+	return &logical.Response{
+		Data: map[string]interface{}{
+			"name":       name,
+			"public_key": string(e.Value),
+		},
+	}, nil
+}
+
+func pathConfigureTrustedSSHPublicKeyDelete(ctx context.Context, req *logical.Request, fields *framework.FieldData) (*logical.Response, error) {
+	name := fields.Get(fieldNameTrustedSSHPublicKeyName).(string)
+	if err := req.Storage.Delete(ctx, trustedSSHPublicKeyStorageKey(name)); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
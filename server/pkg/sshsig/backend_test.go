@@ -0,0 +1,217 @@
+package sshsig
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/hashicorp/vault/sdk/framework"
+	"github.com/hashicorp/vault/sdk/logical"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type pathConfigureTrustedSSHPublicKeyCallbacksSuite struct {
+	suite.Suite
+	ctx     context.Context
+	backend logical.Backend
+	req     *logical.Request
+	storage logical.Storage
+}
+
+func (suite *pathConfigureTrustedSSHPublicKeyCallbacksSuite) SetupTest() {
+	ctx := context.Background()
+	b := &framework.Backend{}
+	b.Paths = Paths()
+	storage := &logical.InmemStorage{}
+	config := logical.TestBackendConfig()
+	config.StorageView = storage
+	err := b.Setup(ctx, config)
+	assert.Nil(suite.T(), err)
+
+	suite.ctx = ctx
+	suite.backend = b
+	suite.req = &logical.Request{Storage: storage}
+	suite.storage = storage
+}
+
+func (suite *pathConfigureTrustedSSHPublicKeyCallbacksSuite) TestKeyCreateOrUpdate_SeveralKeys() {
+	suite.req.Path = "configure/trusted_ssh_public_key"
+	suite.req.Operation = logical.CreateOperation
+
+	for _, reqDataKey := range []map[string]interface{}{
+		dataTrustedSSHPublicKey1(),
+		dataTrustedSSHPublicKey2(),
+	} {
+		suite.req.Data = reqDataKey
+		resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+		assert.Nil(suite.T(), err)
+		assert.Nil(suite.T(), resp)
+	}
+
+	keys, err := GetTrustedSSHPublicKeys(suite.ctx, suite.storage)
+	assert.Nil(suite.T(), err)
+
+	for _, reqDataKey := range []map[string]interface{}{
+		dataTrustedSSHPublicKey1(),
+		dataTrustedSSHPublicKey2(),
+	} {
+		assert.Contains(suite.T(), keys, reqDataKey[fieldNameTrustedSSHPublicKeyData])
+	}
+}
+
+func (suite *pathConfigureTrustedSSHPublicKeyCallbacksSuite) TestKeyCreateOrUpdate_RequiredFields() {
+	suite.req.Path = "configure/trusted_ssh_public_key"
+	suite.req.Operation = logical.CreateOperation
+
+	for _, fieldName := range []string{fieldNameTrustedSSHPublicKeyName, fieldNameTrustedSSHPublicKeyData} {
+		suite.Run(fieldName, func() {
+			data := dataTrustedSSHPublicKey1()
+			delete(data, fieldName)
+
+			suite.req.Data = data
+
+			resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+			assert.Nil(suite.T(), err)
+			assert.Equal(suite.T(), logical.ErrorResponse("Required field %q must be set", fieldName), resp)
+		})
+	}
+}
+
+func (suite *pathConfigureTrustedSSHPublicKeyCallbacksSuite) TestReadOrList_NoKeys() {
+	suite.req.Path = "configure/trusted_ssh_public_key"
+	suite.req.Operation = logical.ListOperation
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), logical.ListResponse([]string(nil)), resp)
+}
+
+func (suite *pathConfigureTrustedSSHPublicKeyCallbacksSuite) TestReadOrList_Keys() {
+	var expectedDataKeys []string
+	for _, reqDataKey := range []map[string]interface{}{
+		dataTrustedSSHPublicKey1(),
+		dataTrustedSSHPublicKey2(),
+	} {
+		keyName := reqDataKey[fieldNameTrustedSSHPublicKeyName].(string)
+		keyData := reqDataKey[fieldNameTrustedSSHPublicKeyData].(string)
+		err := suite.storage.Put(suite.ctx, &logical.StorageEntry{
+			Key:   trustedSSHPublicKeyStorageKey(keyName),
+			Value: []byte(keyData),
+		})
+		assert.Nil(suite.T(), err)
+
+		expectedDataKeys = append(expectedDataKeys, keyName)
+	}
+
+	suite.req.Path = "configure/trusted_ssh_public_key"
+	suite.req.Operation = logical.ListOperation
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), logical.ListResponse(expectedDataKeys), resp)
+}
+
+func (suite *pathConfigureTrustedSSHPublicKeyCallbacksSuite) TestKeyRead() {
+	testData := dataTrustedSSHPublicKey1()
+	testKeyName := testData[fieldNameTrustedSSHPublicKeyName].(string)
+	testKeyData := testData[fieldNameTrustedSSHPublicKeyData].(string)
+	err := suite.storage.Put(suite.ctx, &logical.StorageEntry{
+		Key:   trustedSSHPublicKeyStorageKey(testKeyName),
+		Value: []byte(testKeyData),
+	})
+	assert.Nil(suite.T(), err)
+
+	suite.req.Path = fmt.Sprintf("configure/trusted_ssh_public_key/%s", testKeyName)
+	suite.req.Operation = logical.ReadOperation
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	if assert.NotNil(suite.T(), resp) && assert.NotNil(suite.T(), resp.Data) {
+		assert.Equal(
+			suite.T(),
+			map[string]interface{}{
+				fieldNameTrustedSSHPublicKeyName: testKeyName,
+				fieldNameTrustedSSHPublicKeyData: testKeyData,
+			},
+			resp.Data,
+		)
+	}
+}
+
+func (suite *pathConfigureTrustedSSHPublicKeyCallbacksSuite) TestKeyRead_NoKey() {
+	testKeyName := "key_name"
+
+	suite.req.Path = fmt.Sprintf("configure/trusted_ssh_public_key/%s", testKeyName)
+	suite.req.Operation = logical.ReadOperation
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), logical.ErrorResponse("SSH public key %q not found in storage", testKeyName), resp)
+}
+
+func (suite *pathConfigureTrustedSSHPublicKeyCallbacksSuite) TestKeyDelete() {
+	testData := dataTrustedSSHPublicKey1()
+	testKeyName := testData[fieldNameTrustedSSHPublicKeyName].(string)
+	testKeyData := testData[fieldNameTrustedSSHPublicKeyData].(string)
+	err := suite.storage.Put(suite.ctx, &logical.StorageEntry{
+		Key:   trustedSSHPublicKeyStorageKey(testKeyName),
+		Value: []byte(testKeyData),
+	})
+	assert.Nil(suite.T(), err)
+
+	suite.req.Path = fmt.Sprintf("configure/trusted_ssh_public_key/%s", testKeyName)
+	suite.req.Operation = logical.DeleteOperation
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), resp)
+
+	entry, err := suite.storage.Get(suite.ctx, trustedSSHPublicKeyStorageKey(testKeyName))
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), entry)
+}
+
+func (suite *pathConfigureTrustedSSHPublicKeyCallbacksSuite) TestKeyDelete_NoKey() {
+	testKeyName := "key_name"
+
+	suite.req.Path = fmt.Sprintf("configure/trusted_ssh_public_key/%s", testKeyName)
+	suite.req.Operation = logical.DeleteOperation
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	assert.Nil(suite.T(), resp)
+}
+
+func (suite *pathConfigureTrustedSSHPublicKeyCallbacksSuite) TestKeyCreateOrUpdate_InvalidKey() {
+	suite.req.Path = "configure/trusted_ssh_public_key"
+	suite.req.Operation = logical.CreateOperation
+
+	data := dataTrustedSSHPublicKey1()
+	data[fieldNameTrustedSSHPublicKeyData] = "ssh-ed25519 invalid"
+	suite.req.Data = data
+
+	resp, err := suite.backend.HandleRequest(suite.ctx, suite.req)
+	assert.Nil(suite.T(), err)
+	if assert.NotNil(suite.T(), resp) {
+		assert.True(suite.T(), resp.IsError())
+	}
+}
+
+func TestBackendPathConfigureTrustedSSHPublicKeyCallbacks(t *testing.T) {
+	suite.Run(t, new(pathConfigureTrustedSSHPublicKeyCallbacksSuite))
+}
+
+func dataTrustedSSHPublicKey1() map[string]interface{} {
+	return map[string]interface{}{
+		fieldNameTrustedSSHPublicKeyName: "my_key_1",
+		fieldNameTrustedSSHPublicKeyData: "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIE4eBzfw2mwJ6avYKFu5siiWByFBj3ZaifI9AWwRcj+o my_key_1",
+	}
+}
+
+func dataTrustedSSHPublicKey2() map[string]interface{} {
+	return map[string]interface{}{
+		fieldNameTrustedSSHPublicKeyName: "my_key_2",
+		fieldNameTrustedSSHPublicKeyData: "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAINtMgPwh89rZHcoZFGje/yRe+HaWYygGny1tu+oF8mlU my_key_2",
+	}
+}
@@ -0,0 +1,170 @@
+package sshsig
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+
+	"github.com/hashicorp/go-hclog"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	SignatureBegin = "-----BEGIN SSH SIGNATURE-----"
+	signatureEnd   = "-----END SSH SIGNATURE-----"
+
+	signatureMagic     = "SSHSIG"
+	signatureVersion   = 1
+	signatureNamespace = "git"
+)
+
+// IsSSHSignature checks whether the armored signature is an SSH signature (gpg.format=ssh) rather than a PGP signature.
+func IsSSHSignature(signature string) bool {
+	return strings.HasPrefix(strings.TrimSpace(signature), SignatureBegin)
+}
+
+// signature is the SSHSIG blob described in https://github.com/openssh/openssh-portable/blob/master/PROTOCOL.sshsig.
+type signature struct {
+	Magic         [6]byte
+	Version       uint32
+	PublicKey     []byte
+	Namespace     string
+	Reserved      []byte
+	HashAlgorithm string
+	Signature     []byte
+}
+
+type signedData struct {
+	Magic         [6]byte
+	Namespace     string
+	Reserved      []byte
+	HashAlgorithm string
+	Hash          []byte
+}
+
+func parseSignature(armored string) (*signature, error) {
+	armored = strings.TrimSpace(armored)
+	if !strings.HasPrefix(armored, SignatureBegin) || !strings.HasSuffix(armored, signatureEnd) {
+		return nil, fmt.Errorf("armored ssh signature expected")
+	}
+
+	encoded := strings.Join(strings.Fields(strings.TrimSuffix(strings.TrimPrefix(armored, SignatureBegin), signatureEnd)), "")
+	blob, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode ssh signature: %s", err)
+	}
+
+	var sig signature
+	if err := ssh.Unmarshal(blob, &sig); err != nil {
+		return nil, fmt.Errorf("unable to parse ssh signature: %s", err)
+	}
+
+	if string(sig.Magic[:]) != signatureMagic {
+		return nil, fmt.Errorf("invalid ssh signature magic preamble")
+	}
+
+	if sig.Version != signatureVersion {
+		return nil, fmt.Errorf("unsupported ssh signature version %d", sig.Version)
+	}
+
+	if sig.Namespace != signatureNamespace {
+		return nil, fmt.Errorf("unexpected ssh signature namespace %q: expected %q", sig.Namespace, signatureNamespace)
+	}
+
+	return &sig, nil
+}
+
+func verifySignature(sig *signature, signed io.Reader, publicKey ssh.PublicKey) error {
+	if !bytes.Equal(sig.PublicKey, publicKey.Marshal()) {
+		return fmt.Errorf("signature is made by another key")
+	}
+
+	var h hash.Hash
+	switch sig.HashAlgorithm {
+	case "sha256":
+		h = sha256.New()
+	case "sha512":
+		h = sha512.New()
+	default:
+		return fmt.Errorf("unsupported ssh signature hash algorithm %q", sig.HashAlgorithm)
+	}
+
+	if _, err := io.Copy(h, signed); err != nil {
+		return fmt.Errorf("unable to hash signed data: %s", err)
+	}
+
+	data := signedData{
+		Namespace:     sig.Namespace,
+		Reserved:      sig.Reserved,
+		HashAlgorithm: sig.HashAlgorithm,
+		Hash:          h.Sum(nil),
+	}
+	copy(data.Magic[:], signatureMagic)
+
+	var sshSignature ssh.Signature
+	if err := ssh.Unmarshal(sig.Signature, &sshSignature); err != nil {
+		return fmt.Errorf("unable to parse ssh signature blob: %s", err)
+	}
+
+	// PROTOCOL.sshsig requires rsa-sha2-256 or rsa-sha2-512 for RSA keys, the legacy SHA-1 format is not accepted
+	if sshSignature.Format == ssh.SigAlgoRSA {
+		return fmt.Errorf("unsupported ssh signature format %q: rsa-sha2-256 or rsa-sha2-512 expected", sshSignature.Format)
+	}
+
+	return publicKey.Verify(ssh.Marshal(data), &sshSignature)
+}
+
+// VerifySSHSignatures verifies the armored SSH signatures with the trusted keys in the authorized_keys format the same way as pgp.VerifyPGPSignatures does:
+// each key is counted once, the keys which have not verified any signature and the remaining number of the required signatures are returned.
+func VerifySSHSignatures(sshSignatures []string, signedReaderFunc func() (io.Reader, error), sshKeys []string, requiredNumberOfVerifiedSignatures int, logger hclog.Logger) ([]string, int, error) {
+	if requiredNumberOfVerifiedSignatures == 0 {
+		return sshKeys, 0, nil
+	}
+
+	for _, sshSignature := range sshSignatures {
+		sig, err := parseSignature(sshSignature)
+		if err != nil {
+			if logger != nil {
+				logger.Debug(fmt.Sprintf("[DEBUG-SIGNATURES] VerifySSHSignatures -- will skip signature due to error: %s", err))
+			}
+			continue
+		}
+
+		i := 0
+		l := len(sshKeys)
+		for i < l {
+			publicKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(sshKeys[i]))
+			if err != nil {
+				return nil, 0, err
+			}
+
+			signedReader, err := signedReaderFunc()
+			if err != nil {
+				return nil, 0, err
+			}
+
+			if err := verifySignature(sig, signedReader, publicKey); err != nil {
+				if logger != nil {
+					logger.Debug(fmt.Sprintf("[DEBUG-SIGNATURES] VerifySSHSignatures -- will skip sshKey due to error: %s\n>%v<", err, sshKeys[i]))
+				}
+				i++
+				continue
+			}
+
+			requiredNumberOfVerifiedSignatures--
+			if requiredNumberOfVerifiedSignatures == 0 {
+				return sshKeys, 0, nil
+			}
+
+			sshKeys = append(append([]string{}, sshKeys[:i]...), sshKeys[i+1:]...)
+			break
+		}
+	}
+
+	return sshKeys, requiredNumberOfVerifiedSignatures, nil
+}
@@ -0,0 +1,142 @@
+package sshsig
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha512"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestVerifySSHSignatures(t *testing.T) {
+	data := []byte("signed data")
+	signedReaderFunc := func() (io.Reader, error) { return bytes.NewReader(data), nil }
+
+	key1, signature1 := signWithNewKey(t, "ed25519", data)
+	key2, signature2 := signWithNewKey(t, "rsa", data)
+	key3, _ := signWithNewKey(t, "ecdsa", data)
+
+	assert.True(t, IsSSHSignature(signature1))
+
+	t.Run("verified", func(t *testing.T) {
+		_, number, err := VerifySSHSignatures([]string{signature1, signature2}, signedReaderFunc, []string{key1, key2, key3}, 2, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, 0, number)
+	})
+
+	t.Run("not enough signatures", func(t *testing.T) {
+		keys, number, err := VerifySSHSignatures([]string{signature1}, signedReaderFunc, []string{key1, key2, key3}, 2, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, number)
+		assert.Equal(t, []string{key2, key3}, keys)
+	})
+
+	t.Run("the same key is counted once", func(t *testing.T) {
+		_, number, err := VerifySSHSignatures([]string{signature1, signature1}, signedReaderFunc, []string{key1, key2}, 2, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, number)
+	})
+
+	t.Run("untrusted key", func(t *testing.T) {
+		_, number, err := VerifySSHSignatures([]string{signature1, signature2}, signedReaderFunc, []string{key3}, 1, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, number)
+	})
+
+	t.Run("modified data", func(t *testing.T) {
+		modifiedReaderFunc := func() (io.Reader, error) { return strings.NewReader("modified data"), nil }
+		_, number, err := VerifySSHSignatures([]string{signature1}, modifiedReaderFunc, []string{key1}, 1, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, 1, number)
+	})
+}
+
+func TestVerifySSHSignatures_RejectSHA1RSASignature(t *testing.T) {
+	data := []byte("signed data")
+	signedReaderFunc := func() (io.Reader, error) { return bytes.NewReader(data), nil }
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := ssh.NewSignerFromKey(privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := string(ssh.MarshalAuthorizedKey(signer.PublicKey()))
+	sha1Signature := signWithAlgorithm(t, signer.(ssh.AlgorithmSigner), ssh.SigAlgoRSA, data)
+	sha512Signature := signWithAlgorithm(t, signer.(ssh.AlgorithmSigner), ssh.SigAlgoRSASHA2512, data)
+
+	_, number, err := VerifySSHSignatures([]string{sha1Signature}, signedReaderFunc, []string{key}, 1, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, number)
+
+	_, number, err = VerifySSHSignatures([]string{sha512Signature}, signedReaderFunc, []string{key}, 1, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, number)
+}
+
+// signWithAlgorithm makes the armored SSHSIG signature the same way as ssh-keygen -Y sign -n git does, but with the specified signature algorithm.
+func signWithAlgorithm(t *testing.T, signer ssh.AlgorithmSigner, algorithm string, data []byte) string {
+	h := sha512.Sum512(data)
+	toSign := signedData{Namespace: signatureNamespace, HashAlgorithm: "sha512", Hash: h[:]}
+	copy(toSign.Magic[:], signatureMagic)
+
+	sshSignature, err := signer.SignWithAlgorithm(rand.Reader, ssh.Marshal(toSign), algorithm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sig := signature{
+		Version:       signatureVersion,
+		PublicKey:     signer.PublicKey().Marshal(),
+		Namespace:     signatureNamespace,
+		HashAlgorithm: "sha512",
+		Signature:     ssh.Marshal(sshSignature),
+	}
+	copy(sig.Magic[:], signatureMagic)
+
+	return fmt.Sprintf("%s\n%s\n%s\n", SignatureBegin, base64.StdEncoding.EncodeToString(ssh.Marshal(sig)), signatureEnd)
+}
+
+func signWithNewKey(t *testing.T, keyType string, data []byte) (string, string) {
+	dir := t.TempDir()
+	keyPath := filepath.Join(dir, "key")
+	dataPath := filepath.Join(dir, "data")
+
+	if output, err := exec.Command("ssh-keygen", "-q", "-t", keyType, "-N", "", "-f", keyPath).CombinedOutput(); err != nil {
+		t.Fatalf("ssh-keygen failed: %s\n%s", err, output)
+	}
+
+	if err := ioutil.WriteFile(dataPath, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if output, err := exec.Command("ssh-keygen", "-Y", "sign", "-n", "git", "-f", keyPath, dataPath).CombinedOutput(); err != nil {
+		t.Fatalf("ssh-keygen failed: %s\n%s", err, output)
+	}
+
+	publicKey, err := ioutil.ReadFile(keyPath + ".pub")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signature, err := ioutil.ReadFile(dataPath + ".sig")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return strings.TrimSpace(string(publicKey)), string(signature)
+}
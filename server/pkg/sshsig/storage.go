@@ -0,0 +1,49 @@
+package sshsig
+
+import (
+	"context"
+
+	"github.com/hashicorp/vault/sdk/logical"
+)
+
+const (
+	storageKeyPrefixTrustedSSHPublicKey = "trusted_ssh_public_key/"
+)
+
+func GetTrustedSSHPublicKeys(ctx context.Context, storage logical.Storage) ([]string, error) {
+	list, err := storage.List(ctx, storageKeyPrefixTrustedSSHPublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	var trustedSSHPublicKeys []string
+	for _, name := range list {
+		storageEntryKey := trustedSSHPublicKeyStorageKey(name)
+		e, err := storage.Get(ctx, storageEntryKey)
+		if err != nil {
+			return nil, err
+		}
+
+		trustedSSHPublicKeys = append(trustedSSHPublicKeys, string(e.Value))
+	}
+
+	return trustedSSHPublicKeys, nil
+}
+
+// GetTrustedSSHPublicKey returns the trusted key by name or an empty string if the key does not exist.
+func GetTrustedSSHPublicKey(ctx context.Context, storage logical.Storage, name string) (string, error) {
+	e, err := storage.Get(ctx, trustedSSHPublicKeyStorageKey(name))
+	if err != nil {
+		return "", err
+	}
+
+	if e == nil {
+		return "", nil
+	}
+
+	return string(e.Value), nil
+}
+
+func trustedSSHPublicKeyStorageKey(name string) string {
+	return storageKeyPrefixTrustedSSHPublicKey + name
+}
@@ -62,6 +62,12 @@ func (m *Manager) AddTask(ctx context.Context, reqStorage logical.Storage, taskF
 	return taskUUID, err
 }
 
+// SetTaskResult sets the result of the running task, which is returned along with the task status after the task succeeds.
+// The context must be the one passed into the taskFunc.
+func SetTaskResult(ctx context.Context, result map[string]interface{}) {
+	worker.SetResult(ctx, result)
+}
+
 func (m *Manager) doTaskWrap(ctx context.Context, reqStorage logical.Storage, taskFunc func(context.Context, logical.Storage) error, f func(func(ctx context.Context) error) error) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
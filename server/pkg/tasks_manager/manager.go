@@ -38,12 +38,13 @@ func (m *Manager) TaskStartedCallback(ctx context.Context, uuid string) {
 	}
 }
 
-func (m *Manager) TaskSucceededCallback(ctx context.Context, uuid string, log []byte) {
+func (m *Manager) TaskSucceededCallback(ctx context.Context, uuid string, log []byte, result map[string]interface{}) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	if err := switchTaskToCompletedInStorage(ctx, m.Storage, taskStatusSucceeded, uuid, switchTaskToCompletedInStorageOptions{
-		log: log,
+		log:    log,
+		result: result,
 	}); err != nil {
 		panic("runtime error: " + err.Error())
 	}
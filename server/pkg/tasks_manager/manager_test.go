@@ -54,7 +54,7 @@ func TestManager_SucceededCallback(t *testing.T) {
 	t.Run("nonexistent", func(t *testing.T) {
 		assertPanic(
 			t,
-			func() { m.TaskSucceededCallback(ctx, "1", nil) },
+			func() { m.TaskSucceededCallback(ctx, "1", nil, nil) },
 			"runtime error: queued or running task \"1\" not found in storage",
 		)
 	})
@@ -63,7 +63,8 @@ func TestManager_SucceededCallback(t *testing.T) {
 		runningTaskUUID := assertAndAddRunningTaskToStorage(t, ctx, storage)
 
 		taskActionLog := []byte("Hello!")
-		m.TaskSucceededCallback(ctx, runningTaskUUID, taskActionLog)
+		taskActionResult := map[string]interface{}{"key": "value"}
+		m.TaskSucceededCallback(ctx, runningTaskUUID, taskActionLog, taskActionResult)
 
 		runningTask, err := getTaskFromStorage(ctx, storage, taskStateRunning, runningTaskUUID)
 		assert.Nil(t, err)
@@ -75,6 +76,7 @@ func TestManager_SucceededCallback(t *testing.T) {
 			assert.Equal(t, string(taskStatusSucceeded), completedTask.Status)
 			assert.Equal(t, runningTaskUUID, completedTask.UUID)
 			assert.Empty(t, completedTask.Reason)
+			assert.Equal(t, taskActionResult, completedTask.Result)
 		}
 
 		log, err := getTaskLogFromStorage(ctx, storage, runningTaskUUID)
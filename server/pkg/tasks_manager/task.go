@@ -35,11 +35,12 @@ const (
 var taskStateStatusesCompleted = []taskStatus{taskStatusSucceeded, taskStatusFailed, taskStatusCanceled}
 
 type Task struct {
-	UUID     string    `structs:"uuid" json:"uuid"`
-	Status   string    `structs:"status" json:"status"`
-	Reason   string    `structs:"reason" json:"reason"`
-	Created  time.Time `structs:"created" json:"created"`
-	Modified time.Time `structs:"modified" json:"modified"`
+	UUID     string                 `structs:"uuid" json:"uuid"`
+	Status   string                 `structs:"status" json:"status"`
+	Reason   string                 `structs:"reason" json:"reason"`
+	Created  time.Time              `structs:"created" json:"created"`
+	Modified time.Time              `structs:"modified" json:"modified"`
+	Result   map[string]interface{} `structs:"result,omitempty" json:"result,omitempty"`
 }
 
 func newTask() *Task {
@@ -115,6 +116,7 @@ func switchTaskToRunningInStorage(ctx context.Context, storage logical.Storage,
 type switchTaskToCompletedInStorageOptions struct {
 	reason string
 	log    []byte
+	result map[string]interface{}
 }
 
 func switchTaskToCompletedInStorage(ctx context.Context, storage logical.Storage, status taskStatus, uuid string, opts switchTaskToCompletedInStorageOptions) error {
@@ -152,6 +154,7 @@ func switchTaskToCompletedInStorage(ctx context.Context, storage logical.Storage
 		completedTask.Status = string(status)
 		completedTask.Modified = time.Now()
 		completedTask.Reason = opts.reason
+		completedTask.Result = opts.result
 		completedTaskState := taskStatusState(status)
 
 		storageKey := taskStorageKey(completedTaskState, uuid)
@@ -11,5 +11,5 @@ type Interface interface {
 type TaskCallbacksInterface interface {
 	TaskStartedCallback(ctx context.Context, uuid string)
 	TaskFailedCallback(ctx context.Context, uuid string, log []byte, err error)
-	TaskSucceededCallback(ctx context.Context, uuid string, log []byte)
+	TaskSucceededCallback(ctx context.Context, uuid string, log []byte, result map[string]interface{})
 }
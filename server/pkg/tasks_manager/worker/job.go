@@ -2,6 +2,7 @@ package worker
 
 import (
 	"context"
+	"sync"
 
 	"github.com/werf/logboek"
 )
@@ -12,6 +13,7 @@ type Job struct {
 	ctx           context.Context
 	ctxCancelFunc context.CancelFunc
 	buff          *SafeBuffer
+	result        *jobResult
 }
 
 type Task struct {
@@ -20,10 +22,19 @@ type Task struct {
 	Action  func(ctx context.Context) error
 }
 
+type jobResultCtxKey struct{}
+
+type jobResult struct {
+	value map[string]interface{}
+	mu    sync.Mutex
+}
+
 func newJob(task *Task) *Job {
 	buff := NewSafeBuffer()
+	result := &jobResult{}
 	loggerCtx := logboek.NewContext(task.Context, logboek.DefaultLogger().NewSubLogger(buff, buff))
-	jobContext, jobCtxCancelFunc := context.WithCancel(loggerCtx)
+	resultCtx := context.WithValue(loggerCtx, jobResultCtxKey{}, result)
+	jobContext, jobCtxCancelFunc := context.WithCancel(resultCtx)
 
 	return &Job{
 		ctx:           jobContext,
@@ -31,9 +42,29 @@ func newJob(task *Task) *Job {
 		taskUUID:      task.UUID,
 		action:        func() error { return task.Action(jobContext) },
 		buff:          buff,
+		result:        result,
 	}
 }
 
 func (j *Job) Log() []byte {
 	return j.buff.Bytes()
 }
+
+func (j *Job) Result() map[string]interface{} {
+	j.result.mu.Lock()
+	defer j.result.mu.Unlock()
+	return j.result.value
+}
+
+// SetResult sets the result of the job running with the context.
+// The call is ignored if the context does not belong to any job.
+func SetResult(ctx context.Context, value map[string]interface{}) {
+	result, ok := ctx.Value(jobResultCtxKey{}).(*jobResult)
+	if !ok {
+		return
+	}
+
+	result.mu.Lock()
+	defer result.mu.Unlock()
+	result.value = value
+}
@@ -31,7 +31,7 @@ func (w *Worker) Start() {
 				if err := job.action(); err != nil {
 					w.callbacks.TaskFailedCallback(w.ctx, job.taskUUID, job.Log(), err)
 				} else {
-					w.callbacks.TaskSucceededCallback(w.ctx, job.taskUUID, job.Log())
+					w.callbacks.TaskSucceededCallback(w.ctx, job.taskUUID, job.Log(), job.Result())
 				}
 			}()
 		case <-w.ctx.Done():
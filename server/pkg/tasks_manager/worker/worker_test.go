@@ -30,8 +30,8 @@ func (m *MockedTasksCallbacks) TaskFailedCallback(_ context.Context, uuid string
 	m.Called(uuid, log, err)
 }
 
-func (m *MockedTasksCallbacks) TaskSucceededCallback(_ context.Context, uuid string, log []byte) {
-	m.Called(uuid, log)
+func (m *MockedTasksCallbacks) TaskSucceededCallback(_ context.Context, uuid string, log []byte, result map[string]interface{}) {
+	m.Called(uuid, log, result)
 }
 
 func TestWorkerContext(t *testing.T) {
@@ -59,12 +59,14 @@ func TestTaskCallbacks(t *testing.T) {
 		taskUUID         string
 		taskLog          []byte
 		taskErr          error
+		taskResult       map[string]interface{}
 		expectedCallback string
 	}{
 		{
 			testName:         TaskSucceededCallback,
 			taskUUID:         "1",
 			taskLog:          []byte("hello"),
+			taskResult:       map[string]interface{}{"key": "value"},
 			expectedCallback: TaskSucceededCallback,
 		},
 		{
@@ -95,7 +97,7 @@ func TestTaskCallbacks(t *testing.T) {
 			case TaskFailedCallback:
 				mockedTasksCallbacks.On(TaskFailedCallback, c.taskUUID, c.taskLog, c.taskErr).Return()
 			case TaskSucceededCallback:
-				mockedTasksCallbacks.On(TaskSucceededCallback, c.taskUUID, c.taskLog).Return()
+				mockedTasksCallbacks.On(TaskSucceededCallback, c.taskUUID, c.taskLog, c.taskResult).Return()
 			}
 
 			doneCh := make(chan bool)
@@ -106,6 +108,7 @@ func TestTaskCallbacks(t *testing.T) {
 					defer func() { doneCh <- true }()
 
 					logboek.Context(ctx).Log(string(c.taskLog))
+					SetResult(ctx, c.taskResult)
 
 					if c.taskErr != nil {
 						return c.taskErr
@@ -189,7 +192,7 @@ func TestWorker_HoldRunningJobByTaskUUID(t *testing.T) {
 
 	// setup callback expectations
 	mockedTasksCallbacks.On(TaskStartedCallback, taskUUID).Return()
-	mockedTasksCallbacks.On(TaskSucceededCallback, taskUUID, expectedLog).Return()
+	mockedTasksCallbacks.On(TaskSucceededCallback, taskUUID, expectedLog, map[string]interface{}(nil)).Return()
 
 	// start processing tasks
 	go func() {
@@ -14,3 +14,8 @@ gox -osarch="linux/amd64 linux/arm64 darwin/amd64 darwin/arm64 windows/amd64" \
     -output="release-build/$VERSION/{{.OS}}-{{.Arch}}/bin/vault-plugin-secrets-trdl" \
     -ldflags="-s -w" \
         github.com/werf/trdl/server/cmd/vault-plugin-secrets-trdl
+
+gox -osarch="linux/amd64 linux/arm64 darwin/amd64 darwin/arm64 windows/amd64" \
+    -output="release-build/$VERSION/{{.OS}}-{{.Arch}}/bin/trdl-server" \
+    -ldflags="-s -w" \
+        github.com/werf/trdl/server/cmd/trdl-server